@@ -49,7 +49,17 @@ func handleNode(ctx context.Context, renderer render.Renderer, decorator render.
 		http.NotFound(w, r)
 		return
 	}
-	respondWith(w, http.StatusOK, APINode{Node: detailed.MakeNode(topologyID, report, rendered, node)})
+	var opts detailed.RenderOptions
+	if history, err := controlHistory.forOrg(ctx); err == nil {
+		opts.ControlHistory = history
+	}
+	if role, err := UserRole(ctx); err == nil {
+		opts.Role = role
+	}
+	if granted, err := UserCapabilities(ctx); err == nil {
+		opts.GrantedCapabilities = granted
+	}
+	respondWith(w, http.StatusOK, APINode{Node: detailed.MakeNodeWithOptions(topologyID, report, rendered, node, opts)})
 }
 
 // Websocket for the full topology.
@@ -0,0 +1,64 @@
+package app
+
+import (
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// AuditRecord is the trail left by the execution of a control.
+type AuditRecord struct {
+	Timestamp time.Time
+	ProbeID   string
+	NodeID    string
+	Control   string
+	Actor     string // empty if unknown
+	Error     string // set if the control failed
+}
+
+// An AuditSink receives an AuditRecord for every control executed.
+type AuditSink interface {
+	Audit(ctx context.Context, record AuditRecord)
+}
+
+// AuditSinkFunc is an adapter to use a function as an AuditSink.
+type AuditSinkFunc func(context.Context, AuditRecord)
+
+// Audit implements AuditSink.
+func (f AuditSinkFunc) Audit(ctx context.Context, record AuditRecord) {
+	f(ctx, record)
+}
+
+// AuditActor identifies who is executing a control, given the context of
+// the request. The actor is unknown by default; multitenant deployments
+// can set this to their user identification.
+var AuditActor = func(ctx context.Context) (string, error) {
+	return "", nil
+}
+
+var (
+	auditSinksMtx sync.RWMutex
+	auditSinks    []AuditSink
+)
+
+// RegisterAuditSink adds a sink receiving the audit records of controls.
+func RegisterAuditSink(sink AuditSink) {
+	auditSinksMtx.Lock()
+	defer auditSinksMtx.Unlock()
+	auditSinks = append(auditSinks, sink)
+}
+
+func audit(ctx context.Context, record AuditRecord) {
+	auditSinksMtx.RLock()
+	defer auditSinksMtx.RUnlock()
+	if len(auditSinks) == 0 {
+		return
+	}
+	if actor, err := AuditActor(ctx); err == nil {
+		record.Actor = actor
+	}
+	for _, sink := range auditSinks {
+		sink.Audit(ctx, record)
+	}
+}
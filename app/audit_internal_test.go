@@ -0,0 +1,79 @@
+package app
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/weaveworks/common/mtime"
+	"golang.org/x/net/context"
+
+	"github.com/weaveworks/scope/common/xfer"
+	"github.com/weaveworks/scope/test/reflect"
+)
+
+type fakeControlRouter struct {
+	handle func(probeID string, req xfer.Request) (xfer.Response, error)
+}
+
+func (f fakeControlRouter) Handle(_ context.Context, probeID string, req xfer.Request) (xfer.Response, error) {
+	return f.handle(probeID, req)
+}
+
+func (fakeControlRouter) Register(context.Context, string, xfer.ControlHandlerFunc) (int64, error) {
+	return 0, nil
+}
+
+func (fakeControlRouter) Deregister(context.Context, string, int64) error {
+	return nil
+}
+
+func TestControlAudit(t *testing.T) {
+	oldActor := AuditActor
+	defer func() {
+		auditSinks = nil
+		AuditActor = oldActor
+	}()
+	records := []AuditRecord{}
+	RegisterAuditSink(AuditSinkFunc(func(_ context.Context, record AuditRecord) {
+		records = append(records, record)
+	}))
+	AuditActor = func(ctx context.Context) (string, error) {
+		r := ctx.Value(RequestCtxKey).(*http.Request)
+		return r.Header.Get("X-Actor"), nil
+	}
+
+	now := time.Date(2017, 3, 14, 15, 9, 26, 0, time.UTC)
+	mtime.NowForce(now)
+	defer mtime.NowReset()
+
+	router := mux.NewRouter()
+	RegisterControlRoutes(router, fakeControlRouter{func(probeID string, req xfer.Request) (xfer.Response, error) {
+		switch req.Control {
+		case "fails":
+			return xfer.Response{Error: "boom"}, nil
+		case "unroutable":
+			return xfer.Response{}, fmt.Errorf("probe not found")
+		}
+		return xfer.Response{Value: "ok"}, nil
+	}}, nil)
+
+	for _, control := range []string{"restart", "fails", "unroutable"} {
+		req := httptest.NewRequest("POST", "/api/control/probe/node/"+control, strings.NewReader(""))
+		req.Header.Set("X-Actor", "alice")
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	want := []AuditRecord{
+		{Timestamp: now, ProbeID: "probe", NodeID: "node", Control: "restart", Actor: "alice"},
+		{Timestamp: now, ProbeID: "probe", NodeID: "node", Control: "fails", Actor: "alice", Error: "boom"},
+		{Timestamp: now, ProbeID: "probe", NodeID: "node", Control: "unroutable", Actor: "alice", Error: "probe not found"},
+	}
+	if !reflect.DeepEqual(want, records) {
+		t.Errorf("want %v, have %v", want, records)
+	}
+}
@@ -43,6 +43,13 @@ type Adder interface {
 	Add(context.Context, report.Report, []byte) error
 }
 
+// A Throttler is an Adder which can ask probes to hold back their reports
+// while it is overloaded. RetryAfter returns for how long, or zero when
+// reports are welcome.
+type Throttler interface {
+	RetryAfter() time.Duration
+}
+
 // A Collector is a Reporter and an Adder
 type Collector interface {
 	Reporter
@@ -0,0 +1,98 @@
+package app
+
+import (
+	"fmt"
+
+	"github.com/weaveworks/common/mtime"
+	"golang.org/x/net/context"
+
+	"github.com/weaveworks/scope/common/xfer"
+	"github.com/weaveworks/scope/render/detailed"
+	"github.com/weaveworks/scope/report"
+)
+
+// UserRole identifies the role of the user a request is made for, given its
+// context. There are no roles by default, so controls restricted to some
+// roles are denied to everyone; RBAC deployments can set this to their user
+// identification.
+var UserRole = func(ctx context.Context) (string, error) {
+	return "", nil
+}
+
+// UserCapabilities gives the capabilities granted to the user a request is
+// made for, given its context. None are granted by default, so controls
+// requiring a capability are denied to everyone; locked-down deployments
+// can set this to their user identification.
+var UserCapabilities = func(ctx context.Context) (map[string]bool, error) {
+	return nil, nil
+}
+
+// controlForbidden is the error of controls the user isn't allowed.
+type controlForbidden struct {
+	nodeID, control string
+}
+
+func (e controlForbidden) Error() string {
+	return fmt.Sprintf("control %s on node %s is not allowed", e.control, e.nodeID)
+}
+
+// controlReport returns the report controls are authorized against, or nil
+// if there is no reporter to get it from, in which case all controls are
+// allowed.
+func controlReport(ctx context.Context, rep Reporter) (*report.Report, error) {
+	if rep == nil {
+		return nil, nil
+	}
+	rpt, err := rep.Report(ctx, mtime.Now())
+	if err != nil {
+		return nil, err
+	}
+	return &rpt, nil
+}
+
+// authorizeControl checks the user is allowed the control, as the report
+// describes it for the node, and returns that description. Controls the
+// report doesn't have for the node are not allowed, as they aren't offered
+// to anyone.
+func authorizeControl(ctx context.Context, rpt *report.Report, req xfer.Request) (report.Control, error) {
+	if rpt == nil {
+		return report.Control{}, nil
+	}
+	control, ok := findControl(rpt, req.NodeID, req.Control)
+	if !ok {
+		return control, controlForbidden{req.NodeID, req.Control}
+	}
+	role, err := UserRole(ctx)
+	if err != nil {
+		return control, err
+	}
+	if !detailed.RoleAllowed(control, role) {
+		return control, controlForbidden{req.NodeID, req.Control}
+	}
+	granted, err := UserCapabilities(ctx)
+	if err != nil {
+		return control, err
+	}
+	if !detailed.CapabilityGranted(control, granted) {
+		return control, controlForbidden{req.NodeID, req.Control}
+	}
+	return control, nil
+}
+
+// findControl looks for the control in the topology of the node.
+func findControl(rpt *report.Report, nodeID, controlID string) (report.Control, bool) {
+	var (
+		control report.Control
+		found   bool
+	)
+	rpt.WalkTopologies(func(t *report.Topology) {
+		if found {
+			return
+		}
+		if _, ok := t.Nodes[nodeID]; !ok {
+			return
+		}
+		control, found = t.Controls[controlID]
+	})
+	return control, found
+}
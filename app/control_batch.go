@@ -0,0 +1,100 @@
+package app
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/ugorji/go/codec"
+	"golang.org/x/net/context"
+
+	"github.com/weaveworks/scope/common/xfer"
+	"github.com/weaveworks/scope/report"
+)
+
+const (
+	// maxBatchControlTargets is how many nodes a batch control can run on.
+	maxBatchControlTargets = 1000
+	// maxConcurrentBatchControls is how many of the targets of a batch
+	// control it runs on at a time.
+	maxConcurrentBatchControls = 10
+)
+
+// handleBatchControl runs a control on each of the targets of the request,
+// and responds with the result for each of them. Controls failing on some
+// targets don't fail the batch, their errors are in the results. It is
+// blocking.
+func handleBatchControl(cr ControlRouter, rep Reporter) CtxHandlerFunc {
+	return func(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+		var batch xfer.BatchRequest
+		defer r.Body.Close()
+		if err := codec.NewDecoder(r.Body, &codec.JsonHandle{}).Decode(&batch); err != nil {
+			respondWith(w, http.StatusBadRequest, err)
+			return
+		}
+		if err := validateBatch(batch); err != nil {
+			respondWith(w, http.StatusBadRequest, err)
+			return
+		}
+		rpt, err := controlReport(ctx, rep)
+		if err != nil {
+			respondWith(w, http.StatusInternalServerError, err)
+			return
+		}
+		token := r.Header.Get(xfer.ScopeControlTokenHeader)
+		respondWith(w, http.StatusOK, runBatch(ctx, cr, rpt, batch, token))
+	}
+}
+
+func validateBatch(batch xfer.BatchRequest) error {
+	switch {
+	case batch.Control == "":
+		return fmt.Errorf("no control given")
+	case len(batch.Targets) == 0:
+		return fmt.Errorf("no targets given")
+	case len(batch.Targets) > maxBatchControlTargets:
+		return fmt.Errorf("too many targets: %d, at most %d", len(batch.Targets), maxBatchControlTargets)
+	}
+	for _, target := range batch.Targets {
+		if target.ProbeID == "" || target.NodeID == "" {
+			return fmt.Errorf("invalid target %v", target)
+		}
+	}
+	return nil
+}
+
+// runBatch dispatches the control to all the targets of the batch, a few at
+// a time. Given a token, each target gets a token of its own derived from
+// it, so that a resubmitted batch only runs the control once on each.
+func runBatch(ctx context.Context, cr ControlRouter, rpt *report.Report, batch xfer.BatchRequest, token string) xfer.BatchResponse {
+	var (
+		results = make([]xfer.BatchResult, len(batch.Targets))
+		sema    = make(chan struct{}, maxConcurrentBatchControls)
+		wg      sync.WaitGroup
+	)
+	for i, target := range batch.Targets {
+		wg.Add(1)
+		sema <- struct{}{}
+		go func(i int, target xfer.ControlTarget) {
+			defer func() { <-sema; wg.Done() }()
+			req := xfer.Request{
+				NodeID:      target.NodeID,
+				Control:     batch.Control,
+				ControlArgs: batch.ControlArgs,
+			}
+			if token != "" {
+				req.Token = token + "/" + target.ProbeID + "/" + target.NodeID
+			}
+			result := xfer.BatchResult{ProbeID: target.ProbeID, NodeID: target.NodeID}
+			res, err := dispatchControl(ctx, cr, rpt, target.ProbeID, req)
+			if err != nil {
+				result.Error = err.Error()
+			} else {
+				result.Value, result.Error = res.Value, res.Error
+			}
+			results[i] = result
+		}(i, target)
+	}
+	wg.Wait()
+	return xfer.BatchResponse{Results: results}
+}
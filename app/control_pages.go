@@ -0,0 +1,196 @@
+package app
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/weaveworks/common/mtime"
+	"golang.org/x/net/context"
+
+	"github.com/weaveworks/scope/common/xfer"
+)
+
+const (
+	// defaultControlPageSize is how many rows of a tabular control result
+	// are returned at a time, unless the request asks for another page
+	// size.
+	defaultControlPageSize = 100
+	// controlPageTTL is how long the app keeps a tabular control result
+	// after it was last paged through.
+	controlPageTTL = 5 * time.Minute
+	// maxControlTables is how many tabular control results the app keeps
+	// at most, dropping the ones closest to expiring to make room for more.
+	maxControlTables = 100
+)
+
+// controlPages keeps the tabular control results too big to be returned
+// at once, for the rest of their rows to be fetched a page at a time.
+var controlPages = newControlTables()
+
+type controlTables struct {
+	mtx    sync.Mutex
+	tables map[string]*controlTable
+}
+
+// controlTable is a tabular control result, which only the organisation
+// the control was executed for can page through.
+type controlTable struct {
+	table   xfer.Table
+	orgID   string
+	expires time.Time
+}
+
+func newControlTables() *controlTables {
+	return &controlTables{tables: map[string]*controlTable{}}
+}
+
+// paginate cuts the table of the response, if any, down to its first page
+// of size rows, keeping the rest for page to return to the organisation.
+func (c *controlTables) paginate(res xfer.Response, size int, orgID string) (xfer.Response, error) {
+	if res.Table == nil {
+		return res, nil
+	}
+	table := *res.Table
+	table.Offset, table.Total = 0, len(table.Rows)
+	if len(table.Rows) <= size {
+		res.Table = &table
+		return res, nil
+	}
+	id, err := newTableID()
+	if err != nil {
+		return res, err
+	}
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.expire()
+	for len(c.tables) >= maxControlTables {
+		c.evict()
+	}
+	c.tables[id] = &controlTable{table: table, orgID: orgID, expires: mtime.Now().Add(controlPageTTL)}
+	res.Table, res.NextPage = c.page(id, 0, size)
+	return res, nil
+}
+
+// newTableID makes a random, unguessable ID for a table.
+func newTableID() (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(id), nil
+}
+
+// lookup returns the page of size rows the token refers to, and the token of
+// the page after it, if any. It returns false for unknown or expired
+// tokens, and for tables of other organisations.
+func (c *controlTables) lookup(token string, size int, orgID string) (xfer.Response, bool) {
+	id, offset, ok := parsePageToken(token)
+	if !ok {
+		return xfer.Response{}, false
+	}
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.expire()
+	t, ok := c.tables[id]
+	if !ok || t.orgID != orgID || offset >= len(t.table.Rows) {
+		return xfer.Response{}, false
+	}
+	t.expires = mtime.Now().Add(controlPageTTL)
+	table, next := c.page(id, offset, size)
+	return xfer.Response{Table: table, NextPage: next}, true
+}
+
+// page returns size rows of the table id from offset, and the token of the
+// rows after them, if any. The lock must be held.
+func (c *controlTables) page(id string, offset, size int) (*xfer.Table, string) {
+	table := c.tables[id].table
+	end := offset + size
+	next := pageToken(id, end)
+	if end >= len(table.Rows) {
+		end, next = len(table.Rows), ""
+	}
+	table.Rows = table.Rows[offset:end]
+	table.Offset = offset
+	return &table, next
+}
+
+// expire drops the tables which weren't paged through recently. The lock
+// must be held.
+func (c *controlTables) expire() {
+	now := mtime.Now()
+	for id, t := range c.tables {
+		if !now.Before(t.expires) {
+			delete(c.tables, id)
+		}
+	}
+}
+
+// evict drops the table closest to expiring. The lock must be held.
+func (c *controlTables) evict() {
+	var oldest string
+	for id, t := range c.tables {
+		if oldest == "" || t.expires.Before(c.tables[oldest].expires) {
+			oldest = id
+		}
+	}
+	delete(c.tables, oldest)
+}
+
+func pageToken(id string, offset int) string {
+	return fmt.Sprintf("%s-%d", id, offset)
+}
+
+func parsePageToken(token string) (string, int, bool) {
+	parts := strings.SplitN(token, "-", 2)
+	if len(parts) != 2 || parts[0] == "" {
+		return "", 0, false
+	}
+	offset, err := strconv.Atoi(parts[1])
+	if err != nil || offset < 0 {
+		return "", 0, false
+	}
+	return parts[0], offset, true
+}
+
+// pageSize returns the page size asked for in the pageSize query parameter
+// of the request, or the default one.
+func pageSize(r *http.Request) (int, error) {
+	value := r.URL.Query().Get("pageSize")
+	if value == "" {
+		return defaultControlPageSize, nil
+	}
+	size, err := strconv.Atoi(value)
+	if err != nil || size <= 0 {
+		return 0, fmt.Errorf("invalid page size %q", value)
+	}
+	return size, nil
+}
+
+// handleControlPage returns the next page of a tabular control result,
+// given the NextPage token of the previous one.
+func handleControlPage(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	size, err := pageSize(r)
+	if err != nil {
+		respondWith(w, http.StatusBadRequest, err)
+		return
+	}
+	orgID, err := OrgID(ctx)
+	if err != nil {
+		respondWith(w, http.StatusUnauthorized, err)
+		return
+	}
+	res, ok := controlPages.lookup(mux.Vars(r)["token"], size, orgID)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	respondWith(w, http.StatusOK, res)
+}
@@ -1,33 +1,82 @@
 package app
 
 import (
+	"fmt"
 	"net/http"
 	"net/rpc"
+	"sync"
+	"time"
 
 	log "github.com/Sirupsen/logrus"
 	"github.com/gorilla/mux"
 	"github.com/ugorji/go/codec"
+	"github.com/weaveworks/common/mtime"
 	"golang.org/x/net/context"
 
 	"github.com/weaveworks/scope/common/xfer"
+	"github.com/weaveworks/scope/render/detailed"
+	"github.com/weaveworks/scope/report"
 )
 
+// defaultControlTimeout is how long controls not giving a timeout of their
+// own may take.
+const defaultControlTimeout = time.Minute
+
+// controlHistory keeps the recent control results for each node, so they
+// can be included when rendering that node.
+var controlHistory = &controlHistories{histories: map[string]*detailed.ControlHistory{}}
+
+// controlHistories keeps a ControlHistory per organisation, for results not
+// to be shown to other organisations.
+type controlHistories struct {
+	sync.Mutex
+	histories map[string]*detailed.ControlHistory
+}
+
+// forOrg returns the history of the organisation the request is made for.
+func (c *controlHistories) forOrg(ctx context.Context) (*detailed.ControlHistory, error) {
+	orgID, err := OrgID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	c.Lock()
+	defer c.Unlock()
+	history, ok := c.histories[orgID]
+	if !ok {
+		history = detailed.NewControlHistory(detailed.DefaultControlHistorySize, detailed.DefaultControlHistoryNodes)
+		c.histories[orgID] = history
+	}
+	return history, nil
+}
+
 // RegisterControlRoutes registers the various control routes with a http mux.
-func RegisterControlRoutes(router *mux.Router, cr ControlRouter) {
+// Controls are authorized against the reports of rep; with no reporter, all
+// controls are allowed.
+func RegisterControlRoutes(router *mux.Router, cr ControlRouter, rep Reporter) {
 	router.
 		Methods("GET").
 		Path("/api/control/ws").
 		HandlerFunc(requestContextDecorator(handleProbeWS(cr)))
+	router.
+		Methods("POST").
+		Name("api_control_batch").
+		Path("/api/control/batch").
+		HandlerFunc(requestContextDecorator(handleBatchControl(cr, rep)))
 	router.
 		Methods("POST").
 		Name("api_control_probeid_nodeid_control").
 		MatcherFunc(URLMatcher("/api/control/{probeID}/{nodeID}/{control}")).
-		HandlerFunc(requestContextDecorator(handleControl(cr)))
+		HandlerFunc(gzipHandler(requestContextDecorator(handleControl(cr, rep))))
+	router.
+		Methods("GET").
+		Name("api_control_page_token").
+		MatcherFunc(URLMatcher("/api/control/page/{token}")).
+		HandlerFunc(gzipHandler(requestContextDecorator(handleControlPage)))
 }
 
 // handleControl routes control requests from the client to the appropriate
 // probe.  Its is blocking.
-func handleControl(cr ControlRouter) CtxHandlerFunc {
+func handleControl(cr ControlRouter, rep Reporter) CtxHandlerFunc {
 	return func(ctx context.Context, w http.ResponseWriter, r *http.Request) {
 		var (
 			vars        = mux.Vars(r)
@@ -37,6 +86,16 @@ func handleControl(cr ControlRouter) CtxHandlerFunc {
 			controlArgs map[string]string
 		)
 
+		size, err := pageSize(r)
+		if err != nil {
+			respondWith(w, http.StatusBadRequest, err)
+			return
+		}
+		orgID, err := OrgID(ctx)
+		if err != nil {
+			respondWith(w, http.StatusUnauthorized, err)
+			return
+		}
 		if r.ContentLength > 0 {
 			err := codec.NewDecoder(r.Body, &codec.JsonHandle{}).Decode(&controlArgs)
 			defer r.Body.Close()
@@ -46,12 +105,22 @@ func handleControl(cr ControlRouter) CtxHandlerFunc {
 			}
 		}
 
-		result, err := cr.Handle(ctx, probeID, xfer.Request{
+		rpt, err := controlReport(ctx, rep)
+		if err != nil {
+			respondWith(w, http.StatusInternalServerError, err)
+			return
+		}
+		result, err := dispatchControl(ctx, cr, rpt, probeID, xfer.Request{
 			NodeID:      nodeID,
 			Control:     control,
 			ControlArgs: controlArgs,
+			Token:       r.Header.Get(xfer.ScopeControlTokenHeader),
+			WorkingDir:  r.Header.Get(xfer.ScopeControlWorkingDirHeader),
 		})
-		if err != nil {
+		if _, ok := err.(controlForbidden); ok {
+			respondWith(w, http.StatusForbidden, err.Error())
+			return
+		} else if err != nil {
 			respondWith(w, http.StatusBadRequest, err.Error())
 			return
 		}
@@ -59,10 +128,81 @@ func handleControl(cr ControlRouter) CtxHandlerFunc {
 			respondWith(w, http.StatusBadRequest, result.Error)
 			return
 		}
+		if result.Artifact != nil && r.URL.Query().Get("download") == "true" {
+			respondWithArtifact(w, *result.Artifact)
+			return
+		}
+		result, err = controlPages.paginate(result, size, orgID)
+		if err != nil {
+			respondWith(w, http.StatusInternalServerError, err)
+			return
+		}
 		respondWith(w, http.StatusOK, result)
 	}
 }
 
+// dispatchControl routes the control request to the probe, if the user is
+// allowed it, recording it in the audit log and, if it reached the probe, in
+// the control history of the node.
+func dispatchControl(ctx context.Context, cr ControlRouter, rpt *report.Report, probeID string, req xfer.Request) (xfer.Response, error) {
+	control, err := authorizeControl(ctx, rpt, req)
+	var result xfer.Response
+	if err == nil {
+		result, err = handleWithTimeout(ctx, cr, control.Timeout, probeID, req)
+	}
+	now := mtime.Now()
+	record := AuditRecord{
+		Timestamp: now,
+		ProbeID:   probeID,
+		NodeID:    req.NodeID,
+		Control:   req.Control,
+		Error:     result.Error,
+	}
+	if err != nil {
+		record.Error = err.Error()
+	}
+	audit(ctx, record)
+	if err != nil {
+		return result, err
+	}
+	if history, err := controlHistory.forOrg(ctx); err == nil {
+		history.Add(req.NodeID, detailed.ControlResult{
+			Timestamp: now,
+			ProbeID:   probeID,
+			Control:   req.Control,
+			Value:     result.Value,
+			Error:     result.Error,
+		})
+	}
+	return result, nil
+}
+
+// handleWithTimeout routes the control request to the probe, giving up on
+// its response after the timeout, or after defaultControlTimeout if it is
+// zero.
+func handleWithTimeout(ctx context.Context, cr ControlRouter, timeout time.Duration, probeID string, req xfer.Request) (xfer.Response, error) {
+	if timeout <= 0 {
+		timeout = defaultControlTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	type handled struct {
+		result xfer.Response
+		err    error
+	}
+	done := make(chan handled, 1)
+	go func() {
+		result, err := cr.Handle(ctx, probeID, req)
+		done <- handled{result, err}
+	}()
+	select {
+	case h := <-done:
+		return h.result, h.err
+	case <-ctx.Done():
+		return xfer.Response{}, fmt.Errorf("control %s timed out after %v", req.Control, timeout)
+	}
+}
+
 // handleProbeWS accepts websocket connections from the probe and registers
 // them in the control router, such that HandleControl calls can find them.
 func handleProbeWS(cr ControlRouter) CtxHandlerFunc {
@@ -0,0 +1,174 @@
+package app
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/weaveworks/common/mtime"
+	"golang.org/x/net/context"
+
+	"github.com/weaveworks/scope/common/xfer"
+	"github.com/weaveworks/scope/render/detailed"
+	"github.com/weaveworks/scope/report"
+)
+
+func TestControlHistoryPerOrg(t *testing.T) {
+	oldOrgID := OrgID
+	defer func() {
+		OrgID = oldOrgID
+		controlHistory = &controlHistories{histories: map[string]*detailed.ControlHistory{}}
+	}()
+	OrgID = func(ctx context.Context) (string, error) {
+		return ctx.Value(RequestCtxKey).(*http.Request).Header.Get("X-Org"), nil
+	}
+
+	router := mux.NewRouter()
+	RegisterControlRoutes(router, fakeControlRouter{func(probeID string, req xfer.Request) (xfer.Response, error) {
+		return xfer.Response{Value: "ok"}, nil
+	}}, nil)
+	req := httptest.NewRequest("POST", "/api/control/probe/node/restart", strings.NewReader(""))
+	req.Header.Set("X-Org", "org1")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	historyOf := func(org string) []detailed.ControlResult {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("X-Org", org)
+		history, err := controlHistory.forOrg(context.WithValue(context.Background(), RequestCtxKey, req))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return history.Lookup("node")
+	}
+	if have := historyOf("org1"); len(have) != 1 || have[0].Control != "restart" {
+		t.Errorf("Expected the result of the control in the history of org1, got %v", have)
+	}
+	if have := historyOf("org2"); len(have) != 0 {
+		t.Errorf("Expected no results in the history of org2, got %v", have)
+	}
+}
+
+func TestControlTablesCapped(t *testing.T) {
+	tables := newControlTables()
+	res := xfer.Response{Table: &xfer.Table{Rows: [][]string{{"a"}, {"b"}}}}
+	var first string
+	defer mtime.NowReset()
+	for i := 0; i < maxControlTables+1; i++ {
+		mtime.NowForce(time.Unix(int64(i), 0))
+		paged, err := tables.paginate(res, 1, "org")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if i == 0 {
+			first = paged.NextPage
+		}
+	}
+	if len(tables.tables) != maxControlTables {
+		t.Errorf("Expected %d tables, got %d", maxControlTables, len(tables.tables))
+	}
+	if _, ok := tables.lookup(first, 1, "org"); ok {
+		t.Error("Expected the oldest table to be dropped")
+	}
+}
+
+func TestControlRoles(t *testing.T) {
+	oldRole := UserRole
+	defer func() { UserRole = oldRole }()
+	UserRole = func(ctx context.Context) (string, error) {
+		return ctx.Value(RequestCtxKey).(*http.Request).Header.Get("X-Role"), nil
+	}
+
+	rpt := report.MakeReport()
+	rpt.Container.AddNode(report.MakeNode("node"))
+	rpt.Container.Controls.AddControls([]report.Control{
+		{ID: "restart"},
+		{ID: "stop", AllowedRoles: []string{"admin"}},
+	})
+	router := mux.NewRouter()
+	RegisterControlRoutes(router, fakeControlRouter{func(probeID string, req xfer.Request) (xfer.Response, error) {
+		return xfer.Response{Value: "ok"}, nil
+	}}, StaticCollector(rpt))
+
+	for _, c := range []struct {
+		role, control string
+		code          int
+	}{
+		{"", "restart", http.StatusOK},
+		{"", "stop", http.StatusForbidden},
+		{"viewer", "stop", http.StatusForbidden},
+		{"admin", "stop", http.StatusOK},
+		{"admin", "unknown", http.StatusForbidden},
+	} {
+		req := httptest.NewRequest("POST", "/api/control/probe/node/"+c.control, strings.NewReader(""))
+		req.Header.Set("X-Role", c.role)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != c.code {
+			t.Errorf("%s by %q: expected %d, got %d", c.control, c.role, c.code, w.Code)
+		}
+	}
+
+	req := httptest.NewRequest("POST", "/api/control/batch", strings.NewReader(
+		`{"control":"stop","targets":[{"probeId":"probe","nodeId":"node"}]}`))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var res xfer.BatchResponse
+	if err := json.NewDecoder(w.Body).Decode(&res); err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Results) != 1 || res.Results[0].Error == "" {
+		t.Errorf("Expected the batch control to be denied, got %v", res)
+	}
+}
+
+func TestControlCapabilities(t *testing.T) {
+	oldCapabilities := UserCapabilities
+	defer func() { UserCapabilities = oldCapabilities }()
+	UserCapabilities = func(ctx context.Context) (map[string]bool, error) {
+		capability := ctx.Value(RequestCtxKey).(*http.Request).Header.Get("X-Capability")
+		return map[string]bool{capability: true}, nil
+	}
+
+	rpt := report.MakeReport()
+	rpt.Container.AddNode(report.MakeNode("node"))
+	rpt.Container.Controls.AddControl(report.Control{ID: "exec", Capability: "exec"})
+	router := mux.NewRouter()
+	RegisterControlRoutes(router, fakeControlRouter{func(probeID string, req xfer.Request) (xfer.Response, error) {
+		return xfer.Response{Value: "ok"}, nil
+	}}, StaticCollector(rpt))
+
+	for capability, code := range map[string]int{
+		"":     http.StatusForbidden,
+		"logs": http.StatusForbidden,
+		"exec": http.StatusOK,
+	} {
+		req := httptest.NewRequest("POST", "/api/control/probe/node/exec", strings.NewReader(""))
+		req.Header.Set("X-Capability", capability)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != code {
+			t.Errorf("exec granted %q: expected %d, got %d", capability, code, w.Code)
+		}
+	}
+}
+
+func TestControlTimeout(t *testing.T) {
+	rpt := report.MakeReport()
+	rpt.Container.AddNode(report.MakeNode("node"))
+	rpt.Container.Controls.AddControl(report.Control{ID: "backup", Timeout: 10 * time.Millisecond})
+	unblock := make(chan struct{})
+	defer close(unblock)
+	cr := fakeControlRouter{func(probeID string, req xfer.Request) (xfer.Response, error) {
+		<-unblock
+		return xfer.Response{Value: "ok"}, nil
+	}}
+
+	_, err := dispatchControl(context.Background(), cr, &rpt, "probe", xfer.Request{NodeID: "node", Control: "backup"})
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("Expected the control to time out, got %v", err)
+	}
+}
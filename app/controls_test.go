@@ -1,16 +1,22 @@
 package app_test
 
 import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
 	"net"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/ugorji/go/codec"
+	"golang.org/x/net/context"
 
 	"github.com/weaveworks/scope/app"
 	"github.com/weaveworks/scope/common/xfer"
@@ -19,7 +25,7 @@ import (
 
 func TestControl(t *testing.T) {
 	router := mux.NewRouter()
-	app.RegisterControlRoutes(router, app.NewLocalControlRouter())
+	app.RegisterControlRoutes(router, app.NewLocalControlRouter(), nil)
 	server := httptest.NewServer(router)
 	defer server.Close()
 
@@ -77,3 +83,336 @@ func TestControl(t *testing.T) {
 		t.Fatalf("'%s' != 'foo'", response.Value)
 	}
 }
+
+// controlServer serves control routes to a probe "foo" handling controls
+// with the given handler. Stop it with the returned function.
+func controlServer(t *testing.T, controlHandler xfer.ControlHandlerFunc) (*httptest.Server, func()) {
+	router := mux.NewRouter()
+	app.RegisterControlRoutes(router, app.NewLocalControlRouter(), nil)
+	server := httptest.NewServer(router)
+
+	url, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := appclient.NewAppClient(appclient.ProbeConfig{ProbeID: "foo"}, url.Host, *url, controlHandler)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.ControlConnection()
+
+	time.Sleep(100 * time.Millisecond)
+	return server, func() {
+		client.Stop()
+		server.Close()
+	}
+}
+
+func TestControlGzip(t *testing.T) {
+	logs := strings.Repeat("log line\n", 1000)
+	server, stop := controlServer(t, func(req xfer.Request) xfer.Response {
+		if req.Control == "logs" {
+			return xfer.Response{Value: logs}
+		}
+		return xfer.Response{Value: "foo"}
+	})
+	defer stop()
+
+	// Disable the transparent decompression, to see what's on the wire
+	httpClient := http.Client{
+		Timeout:   1 * time.Second,
+		Transport: &http.Transport{DisableCompression: true},
+	}
+	for _, tc := range []struct {
+		control        string
+		acceptEncoding string
+		want           string
+		gzipped        bool
+	}{
+		{"logs", "gzip", logs, true},
+		{"control", "gzip, deflate", "foo", true},
+		{"logs", "", logs, false},
+		{"logs", "deflate, gzip;q=0", logs, false},
+	} {
+		req, err := http.NewRequest("POST", server.URL+"/api/control/foo/nodeid/"+tc.control, strings.NewReader("{}"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Accept-Encoding", tc.acceptEncoding)
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+
+		var body io.Reader = resp.Body
+		if gzipped := resp.Header.Get("Content-Encoding") == "gzip"; gzipped != tc.gzipped {
+			t.Fatalf("%s, %q: expected gzipped %v, got %v", tc.control, tc.acceptEncoding, tc.gzipped, gzipped)
+		} else if gzipped {
+			if body, err = gzip.NewReader(resp.Body); err != nil {
+				t.Fatal(err)
+			}
+		}
+		var response xfer.Response
+		if err := codec.NewDecoder(body, &codec.JsonHandle{}).Decode(&response); err != nil {
+			t.Fatal(err)
+		}
+		if response.Value != tc.want {
+			t.Errorf("%s: the value differs from the %d bytes sent", tc.control, len(tc.want))
+		}
+	}
+}
+
+func TestControlArtifact(t *testing.T) {
+	artifact := xfer.Artifact{Name: "heap.pprof", ContentType: "application/octet-stream", Data: []byte{0x1f, 0x8b, 0, 1}}
+	server, stop := controlServer(t, func(req xfer.Request) xfer.Response {
+		return xfer.Response{Artifact: &artifact}
+	})
+	defer stop()
+
+	// By default, the artifact is described in the response
+	resp, err := http.Post(server.URL+"/api/control/foo/nodeid/profile", "application/json", strings.NewReader("{}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var response xfer.Response
+	if err := codec.NewDecoder(resp.Body, &codec.JsonHandle{}).Decode(&response); err != nil {
+		t.Fatal(err)
+	}
+	if response.Artifact == nil || !reflect.DeepEqual(artifact, *response.Artifact) {
+		t.Errorf("want %v, have %v", artifact, response.Artifact)
+	}
+
+	// Or downloaded
+	resp, err = http.Post(server.URL+"/api/control/foo/nodeid/profile?download=true", "application/json", strings.NewReader("{}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(artifact.Data, data) {
+		t.Errorf("want %v, have %v", artifact.Data, data)
+	}
+	if have := resp.Header.Get("Content-Type"); have != artifact.ContentType {
+		t.Errorf("Expected content type %q, got %q", artifact.ContentType, have)
+	}
+	if have := resp.Header.Get("Content-Disposition"); have != `attachment; filename="heap.pprof"` {
+		t.Errorf("Expected an attachment, got %q", have)
+	}
+}
+
+func TestControlToken(t *testing.T) {
+	tokens := make(chan string, 1)
+	server, stop := controlServer(t, func(req xfer.Request) xfer.Response {
+		tokens <- req.Token
+		return xfer.Response{}
+	})
+	defer stop()
+
+	req, err := http.NewRequest("POST", server.URL+"/api/control/foo/nodeid/restart", strings.NewReader("{}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set(xfer.ScopeControlTokenHeader, "token")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if have := <-tokens; have != "token" {
+		t.Errorf("Expected the token to reach the probe, got %q", have)
+	}
+}
+
+func TestControlWorkingDir(t *testing.T) {
+	workingDirs := make(chan string, 1)
+	server, stop := controlServer(t, func(req xfer.Request) xfer.Response {
+		workingDirs <- req.WorkingDir
+		return xfer.Response{}
+	})
+	defer stop()
+
+	req, err := http.NewRequest("POST", server.URL+"/api/control/foo/nodeid/exec", strings.NewReader("{}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set(xfer.ScopeControlWorkingDirHeader, "/srv/app")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if have := <-workingDirs; have != "/srv/app" {
+		t.Errorf("Expected the working dir to reach the probe, got %q", have)
+	}
+}
+
+func TestControlPagination(t *testing.T) {
+	oldOrgID := app.OrgID
+	defer func() { app.OrgID = oldOrgID }()
+	app.OrgID = func(ctx context.Context) (string, error) {
+		return ctx.Value(app.RequestCtxKey).(*http.Request).Header.Get("X-Org"), nil
+	}
+	table := xfer.Table{Columns: []string{"PID", "Command"}}
+	for i := 0; i < 250; i++ {
+		table.Rows = append(table.Rows, []string{fmt.Sprint(i), fmt.Sprintf("process-%d", i)})
+	}
+	server, stop := controlServer(t, func(req xfer.Request) xfer.Response {
+		if req.Control == "ps" {
+			return xfer.Response{Table: &table}
+		}
+		return xfer.Response{Table: &xfer.Table{Columns: table.Columns, Rows: table.Rows[:3]}}
+	})
+	defer stop()
+
+	decode := func(resp *http.Response) xfer.Response {
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("Expected 200, got %d", resp.StatusCode)
+		}
+		var response xfer.Response
+		if err := codec.NewDecoder(resp.Body, &codec.JsonHandle{}).Decode(&response); err != nil {
+			t.Fatal(err)
+		}
+		return response
+	}
+
+	resp, err := http.Post(server.URL+"/api/control/foo/nodeid/ps?pageSize=100", "application/json", strings.NewReader("{}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	response := decode(resp)
+	firstPage := response.NextPage
+	rows := [][]string{}
+	pages := 0
+	for {
+		pages++
+		if response.Table == nil || response.Table.Total != len(table.Rows) {
+			t.Fatalf("Expected a page of a table of %d rows, got %v", len(table.Rows), response.Table)
+		}
+		if response.Table.Offset != len(rows) {
+			t.Fatalf("Expected the page to start at row %d, got %d", len(rows), response.Table.Offset)
+		}
+		if !reflect.DeepEqual(table.Columns, response.Table.Columns) {
+			t.Errorf("want %v, have %v", table.Columns, response.Table.Columns)
+		}
+		rows = append(rows, response.Table.Rows...)
+		if response.NextPage == "" {
+			break
+		}
+		resp, err := http.Get(server.URL + "/api/control/page/" + response.NextPage + "?pageSize=100")
+		if err != nil {
+			t.Fatal(err)
+		}
+		response = decode(resp)
+	}
+	if pages != 3 {
+		t.Errorf("Expected 3 pages, got %d", pages)
+	}
+	if !reflect.DeepEqual(table.Rows, rows) {
+		t.Errorf("Expected the pages to add up to the table, got %d rows", len(rows))
+	}
+
+	// Small tables are returned at once
+	resp, err = http.Post(server.URL+"/api/control/foo/nodeid/top", "application/json", strings.NewReader("{}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response := decode(resp); response.NextPage != "" || response.Table == nil || len(response.Table.Rows) != 3 {
+		t.Errorf("Expected the whole table in one page, got %v", response)
+	}
+
+	// Other organisations can't page through the table
+	req, err := http.NewRequest("GET", server.URL+"/api/control/page/"+firstPage, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("X-Org", "other")
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected 404 for the table of another organisation, got %d", resp.StatusCode)
+	}
+
+	// Unknown tokens aren't found
+	resp, err = http.Get(server.URL + "/api/control/page/1-100")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown token, got %d", resp.StatusCode)
+	}
+}
+
+func TestControlBatch(t *testing.T) {
+	server, stop := controlServer(t, func(req xfer.Request) xfer.Response {
+		if req.Control != "restart" {
+			return xfer.ResponseErrorf("unexpected control %s", req.Control)
+		}
+		if req.NodeID == "b" {
+			return xfer.ResponseErrorf("%s failed", req.NodeID)
+		}
+		return xfer.Response{Value: req.NodeID + " restarted, " + req.Token}
+	})
+	defer stop()
+
+	post := func(body string) *http.Response {
+		req, err := http.NewRequest("POST", server.URL+"/api/control/batch", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set(xfer.ScopeControlTokenHeader, "token")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	resp := post(`{"control": "restart", "targets": [
+		{"probeId": "foo", "nodeId": "a"},
+		{"probeId": "foo", "nodeId": "b"},
+		{"probeId": "bar", "nodeId": "c"},
+		{"probeId": "foo", "nodeId": "d"}
+	]}`)
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected the batch to succeed, got %d", resp.StatusCode)
+	}
+	var response xfer.BatchResponse
+	if err := codec.NewDecoder(resp.Body, &codec.JsonHandle{}).Decode(&response); err != nil {
+		t.Fatal(err)
+	}
+	// Results are in the order of the targets, each target getting a token
+	// of its own.
+	want := []xfer.BatchResult{
+		{ProbeID: "foo", NodeID: "a", Value: "a restarted, token/foo/a"},
+		{ProbeID: "foo", NodeID: "b", Error: "b failed"},
+		{ProbeID: "bar", NodeID: "c", Error: "probe bar is not connected right now"},
+		{ProbeID: "foo", NodeID: "d", Value: "d restarted, token/foo/d"},
+	}
+	if !reflect.DeepEqual(want, response.Results) {
+		t.Errorf("want %v, have %v", want, response.Results)
+	}
+
+	for _, body := range []string{
+		`{"targets": [{"probeId": "foo", "nodeId": "a"}]}`,
+		`{"control": "restart", "targets": []}`,
+		`{"control": "restart", "targets": [{"probeId": "foo"}]}`,
+		`not json`,
+	} {
+		resp := post(body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("Expected batch %s to be rejected, got %d", body, resp.StatusCode)
+		}
+	}
+}
@@ -259,6 +259,8 @@ func (cr *sqsControlRouter) Handle(ctx context.Context, probeID string, req xfer
 		return response, nil
 	case <-time.After(rpcTimeout):
 		return xfer.Response{}, fmt.Errorf("request timed out")
+	case <-ctx.Done():
+		return xfer.Response{}, ctx.Err()
 	}
 }
 
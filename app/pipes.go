@@ -1,6 +1,7 @@
 package app
 
 import (
+	"io"
 	"net/http"
 
 	log "github.com/Sirupsen/logrus"
@@ -22,6 +23,11 @@ func RegisterPipeRoutes(router *mux.Router, pr PipeRouter) {
 		Path("/api/pipe/{pipeID}").
 		HandlerFunc(requestContextDecorator(handlePipeWs(pr, UIEnd)))
 
+	router.Methods("GET").
+		Name("api_pipe_pipeid_output").
+		Path("/api/pipe/{pipeID}/output").
+		HandlerFunc(requestContextDecorator(handlePipeOutput(pr)))
+
 	router.Methods("GET").
 		Name("api_pipe_pipeid_probe").
 		Path("/api/pipe/{pipeID}/probe").
@@ -72,6 +78,53 @@ func handlePipeWs(pr PipeRouter, end End) CtxHandlerFunc {
 	}
 }
 
+// handlePipeOutput streams the output a control writes to a pipe with an
+// xfer.ChunkWriter, flushing each chunk to the client as it comes. The
+// chunks are acknowledged to the probe once written, for it to hold back
+// the output while the client is slow to read it. The pipe is closed when
+// the client goes away, there being no one left to read the output.
+func handlePipeOutput(pr PipeRouter) CtxHandlerFunc {
+	return func(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+		id := mux.Vars(r)["pipeID"]
+		pipe, endIO, err := pr.Get(ctx, id, UIEnd)
+		if err != nil {
+			log.Debugf("Error getting pipe %s: %v", id, err)
+			http.NotFound(w, r)
+			return
+		}
+		defer pr.Release(ctx, id, UIEnd)
+
+		done := make(chan struct{})
+		defer close(done)
+		go func() {
+			select {
+			case <-r.Context().Done():
+				pipe.Close()
+			case <-done:
+			}
+		}()
+
+		w.Header().Set("Content-Type", "application/octet-stream")
+		flusher, _ := w.(http.Flusher)
+		reader := xfer.NewChunkReader(endIO)
+		for {
+			chunk, err := reader.ReadChunk()
+			if err == io.EOF {
+				return
+			} else if err != nil {
+				log.Errorf("Error reading output of pipe %s: %v", id, err)
+				return
+			}
+			if _, err := w.Write(chunk); err != nil {
+				return
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+	}
+}
+
 func deletePipe(pr PipeRouter) CtxHandlerFunc {
 	return func(ctx context.Context, w http.ResponseWriter, r *http.Request) {
 		pipeID := mux.Vars(r)["pipeID"]
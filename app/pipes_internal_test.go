@@ -3,6 +3,7 @@ package app
 import (
 	"bytes"
 	"fmt"
+	"io/ioutil"
 	"net"
 	"net/http"
 	"net/http/httptest"
@@ -141,3 +142,52 @@ func TestPipeClose(t *testing.T) {
 		return pipe.Closed()
 	})
 }
+
+func TestPipeOutput(t *testing.T) {
+	router := mux.NewRouter()
+	pr := NewLocalPipeRouter()
+	RegisterPipeRoutes(router, pr)
+	defer pr.Stop()
+
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	ip, port, err := net.SplitHostPort(strings.TrimPrefix(server.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	url := url.URL{Scheme: "http", Host: ip + ":" + port}
+	client, err := appclient.NewAppClient(appclient.ProbeConfig{ProbeID: "foo"}, ip+":"+port, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Stop()
+
+	pipeID, pipe, err := controls.NewPipe(adapter{client}, "appid")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pipe.Close()
+
+	// The control streams its output through the probe end of the pipe.
+	output := bytes.Repeat([]byte("0123456789abcdef"), xfer.MaxChunkSize/4)
+	go func() {
+		local, _ := pipe.Ends()
+		w := xfer.NewChunkWriter(local)
+		w.Write(output)
+		w.Close()
+	}()
+
+	resp, err := http.Get(fmt.Sprintf("%s/api/pipe/%s/output", server.URL, pipeID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	have, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK || !bytes.Equal(have, output) {
+		t.Errorf("Expected the output of the pipe, got %d with %d bytes", resp.StatusCode, len(have))
+	}
+}
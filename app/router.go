@@ -7,6 +7,7 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -15,6 +16,7 @@ import (
 	log "github.com/Sirupsen/logrus"
 	"github.com/gorilla/mux"
 	"github.com/ugorji/go/codec"
+	"github.com/weaveworks/common/mtime"
 	"golang.org/x/net/context"
 
 	"github.com/weaveworks/scope/common/hostname"
@@ -87,7 +89,45 @@ func matchURL(r *http.Request, pattern string) (map[string]string, bool) {
 }
 
 func gzipHandler(h http.HandlerFunc) http.HandlerFunc {
-	return handlers.GZIPHandlerFunc(h, nil)
+	return handlers.GZIPHandlerFunc(h, gzipFilter)
+}
+
+// gzipTypes are the content types of the responses gzipped by gzipHandler.
+var gzipTypes = []string{"text", "javascript", "json"}
+
+// gzipFilter says whether to gzip a response: those of gzipTypes are,
+// unless the client refuses gzip, giving it a quality of 0.
+func gzipFilter(w http.ResponseWriter, r *http.Request) bool {
+	if refusesGzip(r.Header.Get("Accept-Encoding")) {
+		return false
+	}
+	for _, t := range gzipTypes {
+		if handlers.HeaderMatch(w.Header(), "Content-Type", handlers.HmContains, t) {
+			return true
+		}
+	}
+	return false
+}
+
+// refusesGzip says whether an Accept-Encoding header gives gzip a quality
+// of 0, e.g. "gzip;q=0".
+func refusesGzip(header string) bool {
+	for _, coding := range strings.Split(header, ",") {
+		params := strings.Split(coding, ";")
+		if !strings.EqualFold(strings.TrimSpace(params[0]), "gzip") {
+			continue
+		}
+		for _, param := range params[1:] {
+			param = strings.TrimSpace(param)
+			if !strings.HasPrefix(param, "q=") {
+				continue
+			}
+			if q, err := strconv.ParseFloat(param[2:], 64); err == nil && q == 0 {
+				return true
+			}
+		}
+	}
+	return false
 }
 
 // RegisterTopologyRoutes registers the various topology routes with a http mux.
@@ -115,8 +155,146 @@ func RegisterTopologyRoutes(router *mux.Router, r Reporter, capabilities map[str
 		gzipHandler(requestContextDecorator(makeProbeHandler(r))))
 }
 
+// OrgID identifies the organisation a request is made for, given its
+// context, for state the app keeps per probe or user not to be mixed up
+// between organisations. There is a single organisation by default;
+// multitenant deployments can set this to their user identification.
+var OrgID = func(ctx context.Context) (string, error) {
+	return "", nil
+}
+
+// probeKey identifies a probe of an organisation.
+type probeKey struct {
+	orgID, probeID string
+}
+
+const (
+	// reportAckTTL is how long the sequence numbers received from a probe
+	// are kept track of after its last report.
+	reportAckTTL = 5 * time.Minute
+	// maxReportGap is how many reports received past a missing one are
+	// kept track of, before the missing one is given up on.
+	maxReportGap = 100
+)
+
+// reportAcks tracks the report sequence numbers received from each probe.
+type reportAcks struct {
+	sync.Mutex
+	probes    map[probeKey]*probeAcks
+	nextSweep time.Time
+}
+
+// probeAcks are the sequence numbers received from a probe: all of them up
+// to contiguous, and those in ahead past it.
+type probeAcks struct {
+	contiguous uint64
+	ahead      map[uint64]struct{}
+	lastSeen   time.Time
+}
+
+// ack records the receipt of a report, and returns the highest sequence
+// number up to which all the reports of the probe were received, for the
+// probe to send its report again if the app missed a report before it.
+// Having received the first report of a probe, the app doesn't know of
+// those it missed before. A report received again means the probe doesn't
+// have the reports missed before it any more, which are given up on.
+func (a *reportAcks) ack(key probeKey, sequence uint64) uint64 {
+	a.Lock()
+	defer a.Unlock()
+	now := mtime.Now()
+	if now.After(a.nextSweep) {
+		for key, acks := range a.probes {
+			if now.Sub(acks.lastSeen) > reportAckTTL {
+				delete(a.probes, key)
+			}
+		}
+		a.nextSweep = now.Add(reportAckTTL)
+	}
+
+	acks, ok := a.probes[key]
+	if !ok {
+		acks = &probeAcks{contiguous: sequence, ahead: map[uint64]struct{}{}}
+		a.probes[key] = acks
+	}
+	acks.lastSeen = now
+	if sequence <= acks.contiguous {
+		return acks.contiguous
+	}
+	if _, resent := acks.ahead[sequence]; resent || len(acks.ahead) >= maxReportGap {
+		acks.contiguous = sequence
+	} else if sequence == acks.contiguous+1 {
+		acks.contiguous++
+	} else {
+		acks.ahead[sequence] = struct{}{}
+	}
+	for next := range acks.ahead {
+		if next <= acks.contiguous {
+			delete(acks.ahead, next)
+		}
+	}
+	for {
+		if _, ok := acks.ahead[acks.contiguous+1]; !ok {
+			break
+		}
+		delete(acks.ahead, acks.contiguous+1)
+		acks.contiguous++
+	}
+	return acks.contiguous
+}
+
+// lastReportTTL is how long the last report of a probe is kept for without
+// the probe sending it again, or a heartbeat standing for it.
+const lastReportTTL = 5 * time.Minute
+
+// lastReport is the last report received from a probe, with the gzipped
+// msgpack it was added with.
+type lastReport struct {
+	rpt      report.Report
+	buf      []byte
+	lastSeen time.Time
+}
+
+// lastReports keeps the last report received from each probe publishing
+// heartbeats, to be added again when the probe sends a heartbeat in place
+// of an unchanged report.
+type lastReports struct {
+	sync.Mutex
+	reports   map[probeKey]*lastReport
+	nextSweep time.Time
+}
+
+func (l *lastReports) set(key probeKey, rpt report.Report, buf []byte) {
+	l.Lock()
+	defer l.Unlock()
+	now := mtime.Now()
+	l.reports[key] = &lastReport{rpt: rpt, buf: buf, lastSeen: now}
+	if now.After(l.nextSweep) {
+		for key, last := range l.reports {
+			if now.Sub(last.lastSeen) > lastReportTTL {
+				delete(l.reports, key)
+			}
+		}
+		l.nextSweep = now.Add(lastReportTTL)
+	}
+}
+
+// get returns the last report received from the probe, if its ID is id.
+func (l *lastReports) get(key probeKey, id string) (lastReport, bool) {
+	l.Lock()
+	defer l.Unlock()
+	last, ok := l.reports[key]
+	now := mtime.Now()
+	if !ok || last.rpt.ID != id || now.Sub(last.lastSeen) > lastReportTTL {
+		return lastReport{}, false
+	}
+	last.lastSeen = now
+	return *last, true
+}
+
 // RegisterReportPostHandler registers the handler for report submission
 func RegisterReportPostHandler(a Adder, router *mux.Router) {
+	acks := &reportAcks{probes: map[probeKey]*probeAcks{}}
+	last := &lastReports{reports: map[probeKey]*lastReport{}}
 	post := router.Methods("POST").Subrouter()
 	post.HandleFunc("/api/report", requestContextDecorator(func(ctx context.Context, w http.ResponseWriter, r *http.Request) {
 		var (
@@ -125,9 +303,20 @@ func RegisterReportPostHandler(a Adder, router *mux.Router) {
 			reader = io.TeeReader(r.Body, &buf)
 		)
 
+		var sequence uint64
+		if header := r.Header.Get(xfer.ScopeReportSequenceHeader); header != "" {
+			var err error
+			if sequence, err = strconv.ParseUint(header, 10, 64); err != nil {
+				respondWith(w, http.StatusBadRequest, fmt.Errorf("Invalid report sequence: %v", header))
+				return
+			}
+		}
+
 		gzipped := strings.Contains(r.Header.Get("Content-Encoding"), "gzip")
+		var gzwriter *gzip.Writer
 		if !gzipped {
-			reader = io.TeeReader(r.Body, gzip.NewWriter(&buf))
+			gzwriter = gzip.NewWriter(&buf)
+			reader = io.TeeReader(r.Body, gzwriter)
 		}
 
 		contentType := r.Header.Get("Content-Type")
@@ -147,6 +336,10 @@ func RegisterReportPostHandler(a Adder, router *mux.Router) {
 			respondWith(w, http.StatusBadRequest, err)
 			return
 		}
+		if gzwriter != nil {
+			// flush the report received uncompressed, for a.Add
+			gzwriter.Close()
+		}
 
 		// a.Add(..., buf) assumes buf is gzip'd msgpack
 		if !isMsgpack {
@@ -159,8 +352,65 @@ func RegisterReportPostHandler(a Adder, router *mux.Router) {
 			respondWith(w, http.StatusInternalServerError, err)
 			return
 		}
+		probeID := r.Header.Get(xfer.ScopeProbeIDHeader)
+		if orgID, err := OrgID(ctx); err == nil {
+			key := probeKey{orgID, probeID}
+			if probeID != "" && r.Header.Get(xfer.ScopeProbeHeartbeatsHeader) == "true" {
+				last.set(key, rpt, buf.Bytes())
+			}
+			if sequence > 0 {
+				ack := acks.ack(key, sequence)
+				w.Header().Set(xfer.ScopeReportAckHeader, strconv.FormatUint(ack, 10))
+			}
+		}
+		setRetryAfter(w, a)
 		w.WriteHeader(http.StatusOK)
 	}))
+
+	// A heartbeat stands for the last report received from the probe,
+	// which is added again. Should the app not have that report, e.g.
+	// having restarted since, the probe is told so with a conflict.
+	post.HandleFunc("/api/report/heartbeat", requestContextDecorator(func(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+		var heartbeat xfer.Heartbeat
+		if err := codec.NewDecoder(r.Body, &codec.JsonHandle{}).Decode(&heartbeat); err != nil {
+			respondWith(w, http.StatusBadRequest, err)
+			return
+		}
+		probeID := r.Header.Get(xfer.ScopeProbeIDHeader)
+		if probeID == "" {
+			probeID = heartbeat.ProbeID
+		}
+		orgID, err := OrgID(ctx)
+		if err != nil {
+			respondWith(w, http.StatusUnauthorized, err)
+			return
+		}
+		received, ok := last.get(probeKey{orgID, probeID}, heartbeat.ReportID)
+		if !ok {
+			respondWith(w, http.StatusConflict, fmt.Errorf("Unknown report %s from probe %s", heartbeat.ReportID, probeID))
+			return
+		}
+		if err := a.Add(ctx, received.rpt, received.buf); err != nil {
+			log.Errorf("Error Adding report: %v", err)
+			respondWith(w, http.StatusInternalServerError, err)
+			return
+		}
+		setRetryAfter(w, a)
+		w.WriteHeader(http.StatusOK)
+	}))
+}
+
+// setRetryAfter asks the probe to hold back its reports, if the adder is a
+// Throttler wanting it to. The delay is rounded up to whole seconds.
+func setRetryAfter(w http.ResponseWriter, a Adder) {
+	t, ok := a.(Throttler)
+	if !ok {
+		return
+	}
+	if d := t.RetryAfter(); d > 0 {
+		seconds := (d + time.Second - 1) / time.Second
+		w.Header().Set(xfer.RetryAfterHeader, strconv.FormatInt(int64(seconds), 10))
+	}
 }
 
 var newVersion = struct {
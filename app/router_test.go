@@ -2,10 +2,13 @@ package app_test
 
 import (
 	"bytes"
+	"encoding/json"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"reflect"
+	"sync"
 	"testing"
 	"time"
 
@@ -13,8 +16,12 @@ import (
 	"github.com/ugorji/go/codec"
 	"golang.org/x/net/context"
 
+	"github.com/weaveworks/common/mtime"
 	"github.com/weaveworks/common/test"
 	"github.com/weaveworks/scope/app"
+	"github.com/weaveworks/scope/common/xfer"
+	"github.com/weaveworks/scope/probe/appclient"
+	"github.com/weaveworks/scope/report"
 	"github.com/weaveworks/scope/test/fixture"
 )
 
@@ -94,3 +101,356 @@ func TestReportPostHandler(t *testing.T) {
 		return buf.Bytes(), err
 	})
 }
+
+func TestReportPostHandlerAcks(t *testing.T) {
+	router := mux.NewRouter()
+	c := app.NewCollector(1 * time.Minute)
+	app.RegisterReportPostHandler(c, router)
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	buf := &bytes.Buffer{}
+	if err := codec.NewEncoder(buf, &codec.MsgpackHandle{}).Encode(fixture.Report); err != nil {
+		t.Fatal(err)
+	}
+	post := func(probeID, sequence string) string {
+		req, err := http.NewRequest("POST", ts.URL+"/api/report", bytes.NewReader(buf.Bytes()))
+		if err != nil {
+			t.Fatalf("Error posting report: %v", err)
+		}
+		req.Header.Set("Content-Type", "application/msgpack")
+		req.Header.Set(xfer.ScopeProbeIDHeader, probeID)
+		if sequence != "" {
+			req.Header.Set(xfer.ScopeReportSequenceHeader, sequence)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Error posting report: %v", err)
+		}
+		resp.Body.Close()
+		if sequence == "invalid" {
+			if resp.StatusCode != http.StatusBadRequest {
+				t.Errorf("Expected an invalid sequence to be rejected, got %d", resp.StatusCode)
+			}
+		} else if resp.StatusCode != http.StatusOK {
+			t.Fatalf("Error posting report: %d", resp.StatusCode)
+		}
+		return resp.Header.Get(xfer.ScopeReportAckHeader)
+	}
+
+	for _, c := range []struct {
+		probeID, sequence, ack string
+	}{
+		{"probe1", "1", "1"},
+		{"probe1", "2", "2"},
+		// Reports past a missing one are not acknowledged, until the
+		// missing one is received.
+		{"probe1", "4", "2"},
+		{"probe1", "3", "4"},
+		{"probe1", "4", "4"},
+		// The missing report is given up on when the probe sends the one
+		// after it again.
+		{"probe1", "6", "4"},
+		{"probe1", "6", "6"},
+		// Reports before the first one received are not known of.
+		{"probe2", "5", "5"},
+		{"probe2", "", ""},
+		{"probe2", "invalid", ""},
+	} {
+		if have := post(c.probeID, c.sequence); have != c.ack {
+			t.Errorf("%s report %q: want ack %q, have %q", c.probeID, c.sequence, c.ack, have)
+		}
+	}
+
+	// The sequence numbers of probes gone quiet are forgotten.
+	mtime.NowForce(time.Now().Add(10 * time.Minute))
+	defer mtime.NowReset()
+	if have := post("probe1", "10"); have != "10" {
+		t.Errorf("Expected the acknowledgements of probe1 to have expired, have %q", have)
+	}
+}
+
+func TestReportPostHandlerResend(t *testing.T) {
+	router := mux.NewRouter()
+	app.RegisterReportPostHandler(app.NewCollector(1*time.Minute), router)
+	var (
+		mtx       sync.Mutex
+		posts     int
+		sequences []string
+		received  = make(chan struct{}, 10)
+	)
+	// The second report gets lost on its way to the app.
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mtx.Lock()
+		posts++
+		lost := posts == 2
+		if !lost {
+			sequences = append(sequences, r.Header.Get(xfer.ScopeReportSequenceHeader))
+		}
+		mtx.Unlock()
+		if !lost {
+			router.ServeHTTP(w, r)
+		}
+		received <- struct{}{}
+	}))
+	defer ts.Close()
+
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := appclient.NewAppClient(appclient.ProbeConfig{ProbeID: "probe1"}, u.Host, *u, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Stop()
+
+	buf := &bytes.Buffer{}
+	if err := codec.NewEncoder(buf, &codec.MsgpackHandle{}).Encode(fixture.Report); err != nil {
+		t.Fatal(err)
+	}
+	wait := func(n int) {
+		for i := 0; i < n; i++ {
+			select {
+			case <-received:
+			case <-time.After(5 * time.Second):
+				t.Fatal("timeout")
+			}
+		}
+	}
+	for _, n := range []int{1, 1, 2} {
+		if err := client.Publish(bytes.NewReader(buf.Bytes())); err != nil {
+			t.Fatal(err)
+		}
+		wait(n)
+	}
+
+	// The report after the lost one isn't acknowledged, and is sent again.
+	mtx.Lock()
+	defer mtx.Unlock()
+	if want := []string{"1", "3", "3"}; !reflect.DeepEqual(want, sequences) {
+		t.Errorf("want sequences %v, have %v", want, sequences)
+	}
+}
+
+type countingAdder struct {
+	ids []string
+}
+
+func (a *countingAdder) Add(_ context.Context, rpt report.Report, _ []byte) error {
+	a.ids = append(a.ids, rpt.ID)
+	return nil
+}
+
+func TestReportPostHandlerHeartbeats(t *testing.T) {
+	oldOrgID := app.OrgID
+	defer func() { app.OrgID = oldOrgID }()
+	app.OrgID = func(ctx context.Context) (string, error) {
+		return ctx.Value(app.RequestCtxKey).(*http.Request).Header.Get("X-Org"), nil
+	}
+	now := time.Now()
+	mtime.NowForce(now)
+	defer mtime.NowReset()
+
+	router := mux.NewRouter()
+	adder := &countingAdder{}
+	app.RegisterReportPostHandler(adder, router)
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	post := func(path, contentType, org string, body []byte, headers map[string]string) int {
+		req, err := http.NewRequest("POST", ts.URL+path, bytes.NewReader(body))
+		if err != nil {
+			t.Fatalf("Error posting to %s: %v", path, err)
+		}
+		req.Header.Set("Content-Type", contentType)
+		req.Header.Set(xfer.ScopeProbeIDHeader, "probe1")
+		req.Header.Set("X-Org", org)
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Error posting to %s: %v", path, err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	heartbeat := func(org, id string) int {
+		body, err := json.Marshal(xfer.Heartbeat{ProbeID: "probe1", Timestamp: time.Now(), ReportID: id})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return post("/api/report/heartbeat", "application/json", org, body, nil)
+	}
+	postReport := func(org string, headers map[string]string) {
+		rpt := fixture.Report.Copy()
+		rpt.ID = "report"
+		buf := &bytes.Buffer{}
+		if err := codec.NewEncoder(buf, &codec.MsgpackHandle{}).Encode(rpt); err != nil {
+			t.Fatal(err)
+		}
+		if have := post("/api/report", "application/msgpack", org, buf.Bytes(), headers); have != http.StatusOK {
+			t.Fatalf("Error posting report: %d", have)
+		}
+	}
+	heartbeats := map[string]string{xfer.ScopeProbeHeartbeatsHeader: "true"}
+
+	// Without a report to stand for, heartbeats conflict.
+	if have := heartbeat("org1", "report"); have != http.StatusConflict {
+		t.Errorf("Expected a heartbeat before any report to conflict, got %d", have)
+	}
+
+	// Reports of probes not publishing heartbeats are not kept.
+	postReport("org1", nil)
+	if have := heartbeat("org1", "report"); have != http.StatusConflict {
+		t.Errorf("Expected a heartbeat from a probe not saying it publishes them to conflict, got %d", have)
+	}
+
+	postReport("org1", heartbeats)
+	if have := heartbeat("org1", "report"); have != http.StatusOK {
+		t.Errorf("Expected the heartbeat to be accepted, got %d", have)
+	}
+	if have := heartbeat("org1", "other"); have != http.StatusConflict {
+		t.Errorf("Expected a heartbeat for another report to conflict, got %d", have)
+	}
+	// Probes of other organisations don't get the report.
+	if have := heartbeat("org2", "report"); have != http.StatusConflict {
+		t.Errorf("Expected a heartbeat from another organisation to conflict, got %d", have)
+	}
+	if want := []string{"report", "report", "report"}; !reflect.DeepEqual(want, adder.ids) {
+		t.Errorf("Expected the report to be added again on heartbeat: %v", test.Diff(want, adder.ids))
+	}
+
+	// Heartbeats keep the report, which expires without them.
+	mtime.NowForce(now.Add(4 * time.Minute))
+	if have := heartbeat("org1", "report"); have != http.StatusOK {
+		t.Errorf("Expected the heartbeat to be accepted, got %d", have)
+	}
+	mtime.NowForce(now.Add(10 * time.Minute))
+	if have := heartbeat("org1", "report"); have != http.StatusConflict {
+		t.Errorf("Expected a heartbeat for an expired report to conflict, got %d", have)
+	}
+}
+
+func TestReportPostHandlerHeartbeatsAppRestart(t *testing.T) {
+	newRouter := func(adder app.Adder) *mux.Router {
+		router := mux.NewRouter()
+		app.RegisterReportPostHandler(adder, router)
+		return router
+	}
+	var (
+		mtx      sync.Mutex
+		adder    = &countingAdder{}
+		router   = newRouter(adder)
+		paths    []string
+		received = make(chan struct{}, 10)
+	)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mtx.Lock()
+		paths = append(paths, r.URL.Path)
+		router := router
+		mtx.Unlock()
+		router.ServeHTTP(w, r)
+		received <- struct{}{}
+	}))
+	defer ts.Close()
+
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := appclient.NewAppClient(appclient.ProbeConfig{ProbeID: "probe1", Heartbeats: true}, u.Host, *u, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Stop()
+
+	rpt := fixture.Report.Copy()
+	rpt.ID = "report"
+	buf := &bytes.Buffer{}
+	if err := codec.NewEncoder(buf, &codec.MsgpackHandle{}).Encode(rpt); err != nil {
+		t.Fatal(err)
+	}
+	heartbeat, err := json.Marshal(xfer.Heartbeat{ProbeID: "probe1", Timestamp: time.Now(), ReportID: rpt.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	publish := func(body []byte, n int) {
+		if err := client.Publish(bytes.NewReader(body)); err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < n; i++ {
+			select {
+			case <-received:
+			case <-time.After(5 * time.Second):
+				t.Fatal("timeout")
+			}
+		}
+	}
+	publish(buf.Bytes(), 1)
+	publish(heartbeat, 1)
+
+	// The restarted app doesn't have the report the heartbeat stands for,
+	// which the probe sends again.
+	restarted := &countingAdder{}
+	mtx.Lock()
+	router = newRouter(restarted)
+	mtx.Unlock()
+	publish(heartbeat, 2)
+	publish(heartbeat, 1)
+
+	mtx.Lock()
+	defer mtx.Unlock()
+	want := []string{"/api/report", "/api/report/heartbeat", "/api/report/heartbeat", "/api/report", "/api/report/heartbeat"}
+	if !reflect.DeepEqual(want, paths) {
+		t.Errorf("Unexpected posts: %v", test.Diff(want, paths))
+	}
+	if want := []string{"report", "report"}; !reflect.DeepEqual(want, restarted.ids) {
+		t.Errorf("Expected the restarted app to get the report again: %v", test.Diff(want, restarted.ids))
+	}
+}
+
+type throttlingAdder struct {
+	countingAdder
+	retryAfter time.Duration
+}
+
+func (a *throttlingAdder) RetryAfter() time.Duration {
+	return a.retryAfter
+}
+
+func TestReportPostHandlerRetryAfter(t *testing.T) {
+	router := mux.NewRouter()
+	adder := &throttlingAdder{}
+	app.RegisterReportPostHandler(adder, router)
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	buf := &bytes.Buffer{}
+	if err := codec.NewEncoder(buf, &codec.MsgpackHandle{}).Encode(fixture.Report); err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []struct {
+		retryAfter time.Duration
+		want       string
+	}{
+		{0, ""},
+		{3 * time.Second, "3"},
+		// Delays are rounded up to whole seconds.
+		{1500 * time.Millisecond, "2"},
+	} {
+		adder.retryAfter = c.retryAfter
+		resp, err := http.Post(ts.URL+"/api/report", "application/msgpack", bytes.NewReader(buf.Bytes()))
+		if err != nil {
+			t.Fatalf("Error posting report: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("Error posting report: %d", resp.StatusCode)
+		}
+		if have := resp.Header.Get(xfer.RetryAfterHeader); have != c.want {
+			t.Errorf("retry after %v: want %q, have %q", c.retryAfter, c.want, have)
+		}
+	}
+}
@@ -1,11 +1,14 @@
 package app
 
 import (
+	"fmt"
 	"net/http"
 
 	"github.com/ugorji/go/codec"
 
 	log "github.com/Sirupsen/logrus"
+
+	"github.com/weaveworks/scope/common/xfer"
 )
 
 func respondWith(w http.ResponseWriter, code int, response interface{}) {
@@ -24,3 +27,15 @@ func respondWith(w http.ResponseWriter, code int, response interface{}) {
 		log.Errorf("Error encoding response: %v", err)
 	}
 }
+
+// respondWithArtifact responds with the file produced by a control, as an
+// attachment for the browser to download.
+func respondWithArtifact(w http.ResponseWriter, artifact xfer.Artifact) {
+	w.Header().Set("Content-Type", artifact.ContentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", artifact.Name))
+	w.Header().Add("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(artifact.Data); err != nil {
+		log.Errorf("Error writing artifact: %v", err)
+	}
+}
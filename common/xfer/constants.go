@@ -12,6 +12,34 @@ const (
 
 	// ScopeProbeVersionHeader is the header we use to carry the probe's version.
 	ScopeProbeVersionHeader = "X-Scope-Probe-Version"
+
+	// ScopeReportSequenceHeader is the header we use to carry the sequence
+	// number of a published report. Sequence numbers increase by one with
+	// each report a probe publishes to an app.
+	ScopeReportSequenceHeader = "X-Scope-Report-Sequence"
+
+	// ScopeReportAckHeader is the header in which the app acknowledges the
+	// highest report sequence number it has received from the probe.
+	ScopeReportAckHeader = "X-Scope-Report-Ack"
+
+	// ScopeProbeHeartbeatsHeader is the header with which a probe
+	// publishing heartbeats says so on its reports, for the app to keep
+	// them until the next heartbeat.
+	ScopeProbeHeartbeatsHeader = "X-Scope-Probe-Heartbeats"
+
+	// RetryAfterHeader is the header in which the app asks the probe to
+	// hold back its reports for a number of seconds, while it is
+	// overloaded.
+	RetryAfterHeader = "Retry-After"
+
+	// ScopeControlTokenHeader is the header carrying the client-generated
+	// token of a control request. Requests with the same token are only
+	// executed once.
+	ScopeControlTokenHeader = "X-Scope-Control-Token"
+
+	// ScopeControlWorkingDirHeader is the header carrying the working
+	// directory of an exec control request.
+	ScopeControlWorkingDirHeader = "X-Scope-Control-Working-Dir"
 )
 
 // ReportPersistenceCapability indicates whether probe reports end up in a
@@ -16,6 +16,36 @@ type Request struct {
 	NodeID      string
 	Control     string
 	ControlArgs map[string]string
+	Token       string // client-generated, so repeated submissions are run only once
+	WorkingDir  string // for exec controls, the directory to run in, if not the default
+}
+
+// BatchRequest is the UI -> App message type for running a control on many
+// nodes at once.
+type BatchRequest struct {
+	Control     string            `json:"control"`
+	ControlArgs map[string]string `json:"controlArgs,omitempty"`
+	Targets     []ControlTarget   `json:"targets"`
+}
+
+// ControlTarget is a node a batch control runs on.
+type ControlTarget struct {
+	ProbeID string `json:"probeId"`
+	NodeID  string `json:"nodeId"`
+}
+
+// BatchResponse is the App -> UI message type for batch controls, holding a
+// result for each of the targets, in the order of the request.
+type BatchResponse struct {
+	Results []BatchResult `json:"results"`
+}
+
+// BatchResult is the result of a batch control on one of its targets.
+type BatchResult struct {
+	ProbeID string      `json:"probeId"`
+	NodeID  string      `json:"nodeId"`
+	Value   interface{} `json:"value,omitempty"`
+	Error   string      `json:"error,omitempty"`
 }
 
 // Response is the Probe -> App -> UI message type for the control RPCs.
@@ -30,6 +60,31 @@ type Response struct {
 
 	// Remove specific fields
 	RemovedNode string `json:"removedNode,omitempty"` // Set if node was removed
+
+	// Artifact specific fields
+	Artifact *Artifact `json:"artifact,omitempty"` // Set if the control produced a file
+
+	// Table specific fields
+	Table    *Table `json:"table,omitempty"`    // Set if the control produced a table, e.g. of processes
+	NextPage string `json:"nextPage,omitempty"` // Set by the app if there are more rows of the table than it returned
+}
+
+// Table is a tabular control result. The app returns large tables a page at
+// a time, the rest of the table being fetched with the NextPage token of
+// the response.
+type Table struct {
+	Columns []string   `json:"columns"`
+	Rows    [][]string `json:"rows"`
+	Offset  int        `json:"offset"` // Index of the first of the rows in the whole table
+	Total   int        `json:"total"`  // Number of rows of the whole table, set by the app
+}
+
+// Artifact is a file produced by a control, e.g. a profile, for the UI to
+// offer as a download.
+type Artifact struct {
+	Name        string `json:"name"`
+	ContentType string `json:"contentType"`
+	Data        []byte `json:"data"`
 }
 
 // Message is the unions of Request, Response and arbitrary Value.
@@ -55,6 +110,21 @@ func (c ControlHandlerFunc) Handle(req Request, res *Response) error {
 	return nil
 }
 
+// ResizeTTYRequest makes a request for the given resize tty control,
+// carrying the new dimensions of the terminal attached to the pipe. It is
+// the counterpart of ResizeTTYControlWrapper.
+func ResizeTTYRequest(nodeID, control, pipeID string, height, width uint) Request {
+	return Request{
+		NodeID:  nodeID,
+		Control: control,
+		ControlArgs: map[string]string{
+			"pipeID": pipeID,
+			"height": strconv.FormatUint(uint64(height), 10),
+			"width":  strconv.FormatUint(uint64(width), 10),
+		},
+	}
+}
+
 // ResizeTTYControlWrapper extracts the arguments needed by the resize tty control handler
 func ResizeTTYControlWrapper(next func(pipeID string, height, width uint) Response) ControlHandlerFunc {
 	return func(req Request) Response {
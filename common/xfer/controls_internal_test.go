@@ -0,0 +1,59 @@
+package xfer
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ugorji/go/codec"
+)
+
+func TestResizeTTYRequestRoundTrip(t *testing.T) {
+	req := ResizeTTYRequest("node", "resize", "pipe", 24, 80)
+
+	// Requests travel over the control channel as JSON
+	buf := &bytes.Buffer{}
+	if err := codec.NewEncoder(buf, &codec.JsonHandle{}).Encode(req); err != nil {
+		t.Fatal(err)
+	}
+	var decoded Request
+	if err := codec.NewDecoder(buf, &codec.JsonHandle{}).Decode(&decoded); err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		havePipeID            string
+		haveHeight, haveWidth uint
+	)
+	handler := ResizeTTYControlWrapper(func(pipeID string, height, width uint) Response {
+		havePipeID, haveHeight, haveWidth = pipeID, height, width
+		return Response{}
+	})
+	var res Response
+	if err := handler.Handle(decoded, &res); err != nil {
+		t.Fatal(err)
+	}
+	if res.Error != "" {
+		t.Fatalf("unexpected error: %s", res.Error)
+	}
+	if havePipeID != "pipe" || haveHeight != 24 || haveWidth != 80 {
+		t.Errorf("want pipe 24x80, have %s %dx%d", havePipeID, haveHeight, haveWidth)
+	}
+}
+
+func TestResizeTTYControlWrapperBadArgs(t *testing.T) {
+	handler := ResizeTTYControlWrapper(func(string, uint, uint) Response {
+		t.Fatal("handler should not be called")
+		return Response{}
+	})
+	for _, args := range []map[string]string{
+		{"height": "24", "width": "80"},
+		{"pipeID": "pipe", "width": "80"},
+		{"pipeID": "pipe", "height": "-1", "width": "80"},
+	} {
+		var res Response
+		handler.Handle(Request{ControlArgs: args}, &res)
+		if res.Error == "" {
+			t.Errorf("expected an error for %v", args)
+		}
+	}
+}
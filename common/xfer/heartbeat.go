@@ -0,0 +1,11 @@
+package xfer
+
+import "time"
+
+// Heartbeat is published by a probe in place of a report with the same
+// content as the last report it published, identified by ReportID.
+type Heartbeat struct {
+	ProbeID   string    `json:"probeID"`
+	Timestamp time.Time `json:"timestamp"`
+	ReportID  string    `json:"reportID"`
+}
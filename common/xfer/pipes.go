@@ -1,6 +1,8 @@
 package xfer
 
 import (
+	"encoding/binary"
+	"fmt"
 	"io"
 	"sync"
 
@@ -164,3 +166,138 @@ func (p *pipe) CopyToWebsocket(end io.ReadWriter, conn Websocket) error {
 		return nil
 	}
 }
+
+const (
+	// MaxChunkSize is the most output a chunk written to a pipe by a
+	// ChunkWriter holds.
+	MaxChunkSize = 32 * 1024
+
+	// chunkWindow is how many chunks a ChunkWriter writes ahead of the
+	// ChunkReader acknowledging them.
+	chunkWindow = 4
+
+	chunkAck = 0x06
+)
+
+// ChunkWriter streams control output through an end of a pipe, in chunks the
+// other end reads with a ChunkReader. Each chunk is its length, as 4 bytes
+// big-endian, followed by the output, and an empty chunk ends the stream.
+// Writes block while chunkWindow chunks are unacknowledged, for the output
+// to be held back to the pace of the reader.
+type ChunkWriter struct {
+	end     io.ReadWriter
+	credits chan struct{}
+}
+
+// NewChunkWriter makes a new ChunkWriter, writing to the end of a pipe and
+// reading the acknowledgements of the reader from it.
+func NewChunkWriter(end io.ReadWriter) *ChunkWriter {
+	w := &ChunkWriter{
+		end:     end,
+		credits: make(chan struct{}, chunkWindow),
+	}
+	for i := 0; i < chunkWindow; i++ {
+		w.credits <- struct{}{}
+	}
+	go w.readAcks()
+	return w
+}
+
+// readAcks gives back the credit of each chunk the reader acknowledges,
+// until the pipe is closed.
+func (w *ChunkWriter) readAcks() {
+	buf := make([]byte, chunkWindow)
+	for {
+		n, err := w.end.Read(buf)
+		for i := 0; i < n; i++ {
+			select {
+			case w.credits <- struct{}{}:
+			default:
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// Write writes p in chunks of at most MaxChunkSize bytes.
+func (w *ChunkWriter) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		n := len(p)
+		if n > MaxChunkSize {
+			n = MaxChunkSize
+		}
+		<-w.credits
+		if err := w.writeChunk(p[:n]); err != nil {
+			return written, err
+		}
+		written += n
+		p = p[n:]
+	}
+	return written, nil
+}
+
+// Close ends the stream, with an empty chunk.
+func (w *ChunkWriter) Close() error {
+	return w.writeChunk(nil)
+}
+
+func (w *ChunkWriter) writeChunk(data []byte) error {
+	chunk := make([]byte, 4+len(data))
+	binary.BigEndian.PutUint32(chunk, uint32(len(data)))
+	copy(chunk[4:], data)
+	_, err := w.end.Write(chunk)
+	return err
+}
+
+// ChunkReader reads the chunks a ChunkWriter streams through the other end
+// of a pipe.
+type ChunkReader struct {
+	end    io.ReadWriter
+	unread bool // a chunk has been returned, and not acknowledged yet
+	done   bool
+}
+
+// NewChunkReader makes a new ChunkReader, reading from the end of a pipe
+// and acknowledging the chunks to it.
+func NewChunkReader(end io.ReadWriter) *ChunkReader {
+	return &ChunkReader{end: end}
+}
+
+// ReadChunk returns the next chunk of output, acknowledging the one it
+// returned before, for the writer to carry on. It returns io.EOF once the
+// stream has ended.
+func (r *ChunkReader) ReadChunk() ([]byte, error) {
+	if r.done {
+		return nil, io.EOF
+	}
+	if r.unread {
+		if _, err := r.end.Write([]byte{chunkAck}); err != nil {
+			return nil, err
+		}
+		r.unread = false
+	}
+	var header [4]byte
+	if _, err := io.ReadFull(r.end, header[:]); err == io.EOF {
+		// The pipe was closed before the end of the stream.
+		return nil, io.ErrUnexpectedEOF
+	} else if err != nil {
+		return nil, err
+	}
+	size := binary.BigEndian.Uint32(header[:])
+	if size == 0 {
+		r.done = true
+		return nil, io.EOF
+	}
+	if size > MaxChunkSize {
+		return nil, fmt.Errorf("chunk of %d bytes, more than %d", size, MaxChunkSize)
+	}
+	chunk := make([]byte, size)
+	if _, err := io.ReadFull(r.end, chunk); err != nil {
+		return nil, err
+	}
+	r.unread = true
+	return chunk, nil
+}
@@ -0,0 +1,126 @@
+package xfer
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestChunkedOutput(t *testing.T) {
+	p := NewPipe()
+	defer p.Close()
+	local, remote := p.Ends()
+
+	payload := []byte(strings.Repeat("0123456789abcdef", 7*MaxChunkSize/32)) // 3.5 chunks
+	errs := make(chan error, 1)
+	go func() {
+		w := NewChunkWriter(local)
+		if _, err := w.Write(payload); err != nil {
+			errs <- err
+			return
+		}
+		errs <- w.Close()
+	}()
+
+	r := NewChunkReader(remote)
+	var chunks [][]byte
+	for {
+		chunk, err := r.ReadChunk()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		chunks = append(chunks, chunk)
+	}
+	if err := <-errs; err != nil {
+		t.Fatal(err)
+	}
+
+	if len(chunks) != 4 {
+		t.Fatalf("Expected 4 chunks, got %d", len(chunks))
+	}
+	for i, chunk := range chunks[:3] {
+		if len(chunk) != MaxChunkSize {
+			t.Errorf("Expected chunk %d to be full, got %d bytes", i, len(chunk))
+		}
+	}
+	if have := bytes.Join(chunks, nil); !bytes.Equal(have, payload) {
+		t.Error("Expected the chunks to make up the output, in order")
+	}
+	if _, err := r.ReadChunk(); err != io.EOF {
+		t.Errorf("Expected the stream to stay ended, got %v", err)
+	}
+}
+
+func TestChunkedOutputUnterminated(t *testing.T) {
+	p := NewPipe()
+	local, remote := p.Ends()
+	go func() {
+		NewChunkWriter(local).Write([]byte("partial"))
+		p.Close()
+	}()
+
+	r := NewChunkReader(remote)
+	if chunk, err := r.ReadChunk(); err != nil || string(chunk) != "partial" {
+		t.Fatalf("Expected the chunk, got %q, %v", chunk, err)
+	}
+	if _, err := r.ReadChunk(); err == nil || err == io.EOF {
+		t.Errorf("Expected an error for a stream closed before its end, got %v", err)
+	}
+}
+
+// lockedBuffer is a bytes.Buffer safe for concurrent use.
+type lockedBuffer struct {
+	sync.Mutex
+	bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.Lock()
+	defer b.Unlock()
+	return b.Buffer.Write(p)
+}
+
+func TestChunkedOutputFlowControl(t *testing.T) {
+	acks, ack := io.Pipe()
+	defer ack.Close()
+	end := struct {
+		io.Reader
+		io.Writer
+	}{acks, &lockedBuffer{}}
+
+	w := NewChunkWriter(end)
+	written := make(chan struct{})
+	go func() {
+		for i := 0; i < chunkWindow+1; i++ {
+			if _, err := w.Write([]byte("chunk")); err != nil {
+				return
+			}
+			written <- struct{}{}
+		}
+	}()
+	wait := func() {
+		select {
+		case <-written:
+		case <-time.After(5 * time.Second):
+			t.Fatal("timeout")
+		}
+	}
+
+	for i := 0; i < chunkWindow; i++ {
+		wait()
+	}
+	select {
+	case <-written:
+		t.Fatal("Expected the writer to wait for the reader past the window")
+	case <-time.After(100 * time.Millisecond):
+	}
+	if _, err := ack.Write([]byte{chunkAck}); err != nil {
+		t.Fatal(err)
+	}
+	wait()
+}
@@ -0,0 +1,111 @@
+package appclient
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"math"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/ugorji/go/codec"
+
+	"github.com/weaveworks/scope/report"
+)
+
+// Compressions AdaptiveEncoder picks from. Apps accept reports both
+// gzipped and uncompressed.
+const (
+	NoCompression       = "none"
+	GzipCompression     = "gzip"
+	FastGzipCompression = "gzip-fast"
+)
+
+const (
+	// Reports sampled with more bits of entropy per byte than this would
+	// barely shrink, so they are sent uncompressed.
+	incompressibleEntropy = 7.5
+	// Reports at least this big are compressed for speed rather than for
+	// size, not to hold up publishing.
+	hugeReportSize = 8 << 20
+
+	entropySampleChunks    = 8
+	entropySampleChunkSize = 4 << 10
+)
+
+// AdaptiveEncoder encodes reports as msgpack, compressed with the
+// compression that best fits the encoded report, as picked by
+// chooseCompression.
+func AdaptiveEncoder(w io.Writer, r report.Report) error {
+	raw := &bytes.Buffer{}
+	if err := codec.NewEncoder(raw, &codec.MsgpackHandle{}).Encode(&r); err != nil {
+		return err
+	}
+	compression := chooseCompression(raw.Bytes())
+	log.Debugf("Publishing report of %d bytes with compression %s", raw.Len(), compression)
+
+	level := gzip.DefaultCompression
+	switch compression {
+	case NoCompression:
+		_, err := w.Write(raw.Bytes())
+		return err
+	case FastGzipCompression:
+		level = gzip.BestSpeed
+	}
+	return report.WriteGzipped(w, level, func(w io.Writer) error {
+		_, err := raw.WriteTo(w)
+		return err
+	})
+}
+
+// chooseCompression picks the compression for an encoded report, from the
+// entropy of a sample of it and its size.
+func chooseCompression(raw []byte) string {
+	switch {
+	case entropy(sample(raw)) > incompressibleEntropy:
+		return NoCompression
+	case len(raw) >= hugeReportSize:
+		return FastGzipCompression
+	}
+	return GzipCompression
+}
+
+// sample returns chunks taken evenly across buf, or buf itself when it is
+// no bigger than the chunks.
+func sample(buf []byte) []byte {
+	if len(buf) <= entropySampleChunks*entropySampleChunkSize {
+		return buf
+	}
+	result := make([]byte, 0, entropySampleChunks*entropySampleChunkSize)
+	stride := (len(buf) - entropySampleChunkSize) / (entropySampleChunks - 1)
+	for i := 0; i < entropySampleChunks; i++ {
+		start := i * stride
+		result = append(result, buf[start:start+entropySampleChunkSize]...)
+	}
+	return result
+}
+
+// entropy is the Shannon entropy of buf, in bits per byte.
+func entropy(buf []byte) float64 {
+	if len(buf) == 0 {
+		return 0
+	}
+	var counts [256]int
+	for _, b := range buf {
+		counts[b]++
+	}
+	result := 0.0
+	for _, count := range counts {
+		if count == 0 {
+			continue
+		}
+		p := float64(count) / float64(len(buf))
+		result -= p * math.Log2(p)
+	}
+	return result
+}
+
+// isGzipped says whether buf starts with the gzip magic number. Encoded
+// reports never do, as they start with a msgpack map.
+func isGzipped(buf []byte) bool {
+	return len(buf) >= 2 && buf[0] == 0x1f && buf[1] == 0x8b
+}
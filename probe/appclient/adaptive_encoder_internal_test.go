@@ -0,0 +1,99 @@
+package appclient
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"math/rand"
+	"testing"
+
+	"github.com/ugorji/go/codec"
+
+	"github.com/weaveworks/scope/report"
+)
+
+func TestAdaptiveEncoder(t *testing.T) {
+	random := rand.New(rand.NewSource(0))
+	randomString := func() string {
+		buf := make([]byte, 1024)
+		random.Read(buf)
+		return string(buf)
+	}
+	reportWith := func(value func() string) report.Report {
+		rpt := report.MakeReport()
+		for i := 0; i < 100; i++ {
+			id := fmt.Sprintf("container-%d", i)
+			rpt.Container.AddNode(report.MakeNodeWith(id, map[string]string{"value": value()}))
+		}
+		return rpt
+	}
+
+	for _, c := range []struct {
+		label string
+		rpt   report.Report
+		want  string
+	}{
+		{"low entropy", reportWith(func() string { return "a-rather-repetitive-container-name" }), GzipCompression},
+		{"high entropy", reportWith(randomString), NoCompression},
+	} {
+		raw := &bytes.Buffer{}
+		if err := codec.NewEncoder(raw, &codec.MsgpackHandle{}).Encode(&c.rpt); err != nil {
+			t.Fatal(err)
+		}
+		if have := chooseCompression(raw.Bytes()); c.want != have {
+			t.Errorf("%s: want %s, have %s", c.label, c.want, have)
+		}
+
+		buf := &bytes.Buffer{}
+		if err := AdaptiveEncoder(buf, c.rpt); err != nil {
+			t.Fatal(err)
+		}
+		if want, have := c.want != NoCompression, isGzipped(buf.Bytes()); want != have {
+			t.Errorf("%s: want gzipped %v, have %v", c.label, want, have)
+		}
+		var reader io.Reader = buf
+		if isGzipped(buf.Bytes()) {
+			gzreader, err := gzip.NewReader(buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			reader = gzreader
+		}
+		var decoded report.Report
+		if err := codec.NewDecoder(reader, &codec.MsgpackHandle{}).Decode(&decoded); err != nil {
+			t.Fatal(err)
+		}
+		for id, node := range c.rpt.Container.Nodes {
+			want, _ := node.Latest.Lookup("value")
+			if have, _ := decoded.Container.Nodes[id].Latest.Lookup("value"); want != have {
+				t.Errorf("%s: report changed by encoding", c.label)
+				break
+			}
+		}
+	}
+
+	if have := chooseCompression(bytes.Repeat([]byte("abc"), hugeReportSize/3+1)); have != FastGzipCompression {
+		t.Errorf("Expected huge reports to be compressed for speed, got %s", have)
+	}
+}
+
+func TestEntropy(t *testing.T) {
+	all := make([]byte, 256)
+	for i := range all {
+		all[i] = byte(i)
+	}
+	for _, c := range []struct {
+		buf  []byte
+		want float64
+	}{
+		{nil, 0},
+		{[]byte("aaaa"), 0},
+		{[]byte("abab"), 1},
+		{all, 8},
+	} {
+		if have := entropy(c.buf); have != c.want {
+			t.Errorf("entropy(%q): want %v, have %v", c.buf, c.want, have)
+		}
+	}
+}
@@ -1,12 +1,15 @@
 package appclient
 
 import (
+	"bytes"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
 	"net/rpc"
 	"net/url"
+	"path/filepath"
+	"strconv"
 	"sync"
 	"time"
 
@@ -14,6 +17,7 @@ import (
 	"github.com/gorilla/websocket"
 	"github.com/hashicorp/go-cleanhttp"
 	"github.com/ugorji/go/codec"
+	"github.com/weaveworks/common/mtime"
 
 	"github.com/weaveworks/scope/common/xfer"
 )
@@ -22,8 +26,16 @@ const (
 	httpClientTimeout = 4 * time.Second
 	initialBackoff    = 1 * time.Second
 	maxBackoff        = 60 * time.Second
+
+	// maxReportResends is how many times a report is re-sent to an app not
+	// acknowledging it, before moving on to the next report.
+	maxReportResends = 3
 )
 
+// errUnknownReport is the error of heartbeats the app doesn't have the
+// report of.
+var errUnknownReport = fmt.Errorf("app doesn't have the report of the heartbeat")
+
 // AppClient is a client to an app, dealing with report publishing, controls and pipes.
 type AppClient interface {
 	Details() (xfer.Details, error)
@@ -57,6 +69,11 @@ type appClient struct {
 	// For publish
 	publishLoop sync.Once
 	readers     chan io.Reader
+	buffer      *diskBuffer // nil unless buffering reports
+
+	// holdBackUntil is until when the app asked reports to be held back,
+	// guarded by mtx.
+	holdBackUntil time.Time
 
 	// For controls
 	control xfer.ControlHandler
@@ -69,6 +86,14 @@ func NewAppClient(pc ProbeConfig, hostname string, target url.URL, control xfer.
 	httpClient.Transport = httpTransport
 	httpClient.Timeout = httpClientTimeout
 
+	var buffer *diskBuffer
+	if pc.BufferDir != "" {
+		var err error
+		if buffer, err = newDiskBuffer(filepath.Join(pc.BufferDir, hostname), pc.BufferKey); err != nil {
+			return nil, err
+		}
+	}
+
 	return &appClient{
 		ProbeConfig: pc,
 		quit:        make(chan struct{}),
@@ -81,6 +106,7 @@ func NewAppClient(pc ProbeConfig, hostname string, target url.URL, control xfer.
 		},
 		conns:   map[string]xfer.Websocket{},
 		readers: make(chan io.Reader, 2),
+		buffer:  buffer,
 		control: control,
 	}, nil
 }
@@ -270,14 +296,26 @@ func (c *appClient) ControlConnection() {
 	}()
 }
 
-func (c *appClient) publish(r io.Reader) error {
-	url := c.url("/api/report")
-	req, err := c.ProbeConfig.authorizedRequest("POST", url, r)
+// publish sends a report with the given sequence number. It returns the
+// highest sequence number acknowledged by the app, if the app supports
+// acknowledgements.
+func (c *appClient) publish(buf []byte, sequence uint64) (uint64, bool, error) {
+	path, contentType := "/api/report", "application/msgpack"
+	if isHeartbeat(buf) {
+		path, contentType = "/api/report/heartbeat", "application/json"
+	}
+	req, err := c.ProbeConfig.authorizedRequest("POST", c.url(path), bytes.NewReader(buf))
 	if err != nil {
-		return err
+		return 0, false, err
+	}
+	req.Header.Set(xfer.ScopeReportSequenceHeader, strconv.FormatUint(sequence, 10))
+	if c.ProbeConfig.Heartbeats && !isHeartbeat(buf) {
+		req.Header.Set(xfer.ScopeProbeHeartbeatsHeader, "true")
 	}
-	req.Header.Set("Content-Encoding", "gzip")
-	req.Header.Set("Content-Type", "application/msgpack")
+	if isGzipped(buf) {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+	req.Header.Set("Content-Type", contentType)
 	// req.Header.Set("Content-Type", "application/binary") // TODO: we should use http.DetectContentType(..) on the gob'ed
 
 	// Make sure this request is cancelled when we stop the client
@@ -285,31 +323,155 @@ func (c *appClient) publish(r io.Reader) error {
 
 	resp, err := c.client.Do(req)
 	if err != nil {
-		return err
+		return 0, false, err
 	}
 	defer resp.Body.Close()
+	c.retryAfter(resp.Header.Get(xfer.RetryAfterHeader))
 
+	if resp.StatusCode == http.StatusConflict && isHeartbeat(buf) {
+		return 0, false, errUnknownReport
+	}
 	if resp.StatusCode != http.StatusOK {
 		text, _ := ioutil.ReadAll(resp.Body)
-		return fmt.Errorf(resp.Status + ": " + string(text))
+		return 0, false, fmt.Errorf(resp.Status + ": " + string(text))
 	}
-	return nil
+	header := resp.Header.Get(xfer.ScopeReportAckHeader)
+	if header == "" {
+		return 0, false, nil
+	}
+	ack, err := strconv.ParseUint(header, 10, 64)
+	if err != nil {
+		return 0, false, fmt.Errorf("invalid report acknowledgement %q: %v", header, err)
+	}
+	return ack, true, nil
+}
+
+// retryAfter records how long the app asked reports to be held back for, as
+// the Retry-After header of a response, in seconds or as a date. The delay
+// is capped at maxBackoff, not to be silenced by a misbehaving app.
+func (c *appClient) retryAfter(header string) {
+	if header == "" {
+		return
+	}
+	now := mtime.Now()
+	var delay time.Duration
+	if seconds, err := strconv.Atoi(header); err == nil {
+		delay = time.Duration(seconds) * time.Second
+	} else if date, err := http.ParseTime(header); err == nil {
+		delay = date.Sub(now)
+	} else {
+		log.Warningf("Invalid %s header from %s: %q", xfer.RetryAfterHeader, c.hostname, header)
+		return
+	}
+	if delay <= 0 {
+		return
+	}
+	if delay > maxBackoff {
+		delay = maxBackoff
+	}
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if until := now.Add(delay); until.After(c.holdBackUntil) {
+		c.holdBackUntil = until
+	}
+}
+
+// HoldBackUntil implements Throttled.
+func (c *appClient) HoldBackUntil() time.Time {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return c.holdBackUntil
 }
 
 func (c *appClient) startPublishing() {
 	go func() {
 		log.Infof("Publish loop for %s starting", c.hostname)
 		defer log.Infof("Publish loop for %s exiting", c.hostname)
+		var (
+			sequence uint64
+			pending  []byte // the report sent last, until acknowledged
+			last     []byte // the last new report sent, heartbeats stand for
+			buffered string // the buffered report pending is replaying, if any
+			resends  int
+		)
 		c.doWithBackoff("publish", func() (bool, error) {
-			r := <-c.readers
-			if r == nil {
-				return true, nil
+			if pending == nil {
+				buf, name, ok, err := c.nextReport()
+				if err != nil {
+					return false, err
+				}
+				if !ok {
+					return true, nil
+				}
+				// Heartbeats stand for the report sent last, and
+				// share its sequence number.
+				if !isHeartbeat(buf) {
+					sequence++
+				}
+				pending, buffered, resends = buf, name, 0
+			}
+			ack, acked, err := c.publish(pending, sequence)
+			if err == errUnknownReport && last != nil {
+				// The app doesn't have the report anymore, e.g. having
+				// restarted since, so send it again.
+				pending = last
+				return false, nil
 			}
-			return false, c.publish(r)
+			if err != nil {
+				// Buffered reports stay in the buffer until replayed.
+				// Heartbeats are not worth replaying.
+				if c.buffer != nil && buffered == "" && !isHeartbeat(pending) {
+					if err := c.buffer.push(pending); err != nil {
+						log.Errorf("Error buffering report to %s: %v", c.hostname, err)
+					}
+				}
+				pending, buffered = nil, ""
+				return false, err
+			}
+			// The app acknowledging a lower sequence number means it
+			// missed the report, so send it again.
+			if acked && ack < sequence && resends < maxReportResends {
+				resends++
+				return false, fmt.Errorf("app acknowledged report %d, re-sending report %d", ack, sequence)
+			}
+			if buffered != "" {
+				if err := c.buffer.remove(buffered); err != nil {
+					log.Errorf("Error removing replayed report to %s: %v", c.hostname, err)
+				}
+			} else if !isHeartbeat(pending) {
+				last = pending
+			}
+			pending, buffered = nil, ""
+			return false, nil
 		})
 	}()
 }
 
+// nextReport returns the next report to publish: a new report if there is
+// one waiting, or else the oldest buffered report, along with its name in
+// the buffer. Without either, it waits for a new report. It returns false
+// once the client is stopped.
+func (c *appClient) nextReport() ([]byte, string, bool, error) {
+	var r io.Reader
+	select {
+	case r = <-c.readers:
+	default:
+		if c.buffer != nil {
+			name, buf, ok, err := c.buffer.oldest()
+			if err != nil || ok {
+				return buf, name, err == nil, err
+			}
+		}
+		r = <-c.readers
+	}
+	if r == nil {
+		return nil, "", false, nil
+	}
+	buf, err := readReport(r)
+	return buf, "", err == nil, err
+}
+
 // Publish implements Publisher
 func (c *appClient) Publish(r io.Reader) error {
 	// Lazily start the background publishing loop.
@@ -3,16 +3,22 @@ package appclient
 import (
 	"compress/gzip"
 	"fmt"
+	"io/ioutil"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"os"
+	"path/filepath"
 	"reflect"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/gorilla/handlers"
 	"github.com/ugorji/go/codec"
+	"github.com/weaveworks/common/mtime"
 	"github.com/weaveworks/common/test"
 	"github.com/weaveworks/scope/common/xfer"
 	"github.com/weaveworks/scope/report"
@@ -225,3 +231,242 @@ func TestStop(t *testing.T) {
 	// Let the server go so that the test can end
 	close(stopHanging)
 }
+
+func TestAppClientHTTP2(t *testing.T) {
+	for _, tc := range []struct {
+		serverHTTP2 bool
+		wantProto   int
+		wantConns   int
+	}{
+		{serverHTTP2: true, wantProto: 2, wantConns: 1},
+		{serverHTTP2: false, wantProto: 1},
+	} {
+		var (
+			mtx    sync.Mutex
+			conns  int
+			protos = map[int]bool{}
+		)
+		handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mtx.Lock()
+			protos[r.ProtoMajor] = true
+			mtx.Unlock()
+			if err := codec.NewEncoder(w, &codec.JsonHandle{}).Encode(xfer.Details{ID: "app"}); err != nil {
+				t.Error(err)
+			}
+		})
+		s := httptest.NewUnstartedServer(handler)
+		s.EnableHTTP2 = tc.serverHTTP2
+		s.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+			if state == http.StateNew {
+				mtx.Lock()
+				conns++
+				mtx.Unlock()
+			}
+		}
+		s.StartTLS()
+
+		u, err := url.Parse(s.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		p, err := NewAppClient(ProbeConfig{Insecure: true, HTTP2: true}, u.Host, *u, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 5; i++ {
+			if _, err := p.Details(); err != nil {
+				t.Fatal(err)
+			}
+		}
+		p.Stop()
+		s.Close()
+
+		mtx.Lock()
+		if !protos[tc.wantProto] || len(protos) != 1 {
+			t.Errorf("Expected requests over HTTP/%d, got %v", tc.wantProto, protos)
+		}
+		if tc.wantConns != 0 && conns != tc.wantConns {
+			t.Errorf("Expected %d connection, got %d", tc.wantConns, conns)
+		}
+		mtx.Unlock()
+	}
+}
+
+func TestAppClientPublishSequence(t *testing.T) {
+	var (
+		mtx       sync.Mutex
+		sequences []string
+		highest   string
+		received  = make(chan struct{}, 10)
+	)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mtx.Lock()
+		sequence := r.Header.Get(xfer.ScopeReportSequenceHeader)
+		// Drop the first report, acknowledging nothing.
+		if len(sequences) == 0 {
+			highest = "0"
+		} else {
+			highest = sequence
+		}
+		sequences = append(sequences, sequence)
+		w.Header().Set(xfer.ScopeReportAckHeader, highest)
+		mtx.Unlock()
+		w.WriteHeader(http.StatusOK)
+		received <- struct{}{}
+	})
+	s := httptest.NewServer(handler)
+	defer s.Close()
+
+	u, err := url.Parse(s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := NewAppClient(ProbeConfig{}, u.Host, *u, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Stop()
+
+	wait := func(n int) {
+		for i := 0; i < n; i++ {
+			select {
+			case <-received:
+			case <-time.After(5 * time.Second):
+				t.Fatal("timeout")
+			}
+		}
+	}
+
+	if err := p.Publish(strings.NewReader("first")); err != nil {
+		t.Fatal(err)
+	}
+	// The unacknowledged report is re-sent with the same sequence number.
+	wait(2)
+	if err := p.Publish(strings.NewReader("second")); err != nil {
+		t.Fatal(err)
+	}
+	wait(1)
+
+	mtx.Lock()
+	defer mtx.Unlock()
+	if want := []string{"1", "1", "2"}; !reflect.DeepEqual(want, sequences) {
+		t.Errorf("want sequences %v, have %v", want, sequences)
+	}
+}
+
+func TestAppClientRetryAfter(t *testing.T) {
+	now := time.Now()
+	mtime.NowForce(now)
+	defer mtime.NowReset()
+
+	retryAfter := make(chan string, 1)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if header := <-retryAfter; header != "" {
+			w.Header().Set(xfer.RetryAfterHeader, header)
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+	s := httptest.NewServer(handler)
+	defer s.Close()
+
+	u, err := url.Parse(s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := NewAppClient(ProbeConfig{}, u.Host, *u, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Stop()
+	c := client.(*appClient)
+
+	for _, step := range []struct {
+		header string
+		want   time.Time
+	}{
+		{"", time.Time{}},
+		{"5", now.Add(5 * time.Second)},
+		// A shorter delay doesn't cut the current one short.
+		{"2", now.Add(5 * time.Second)},
+		{"not a delay", now.Add(5 * time.Second)},
+		// Delays are capped, not to silence the probe for too long.
+		{"3600", now.Add(maxBackoff)},
+	} {
+		retryAfter <- step.header
+		if _, _, err := c.publish([]byte("report"), 1); err != nil {
+			t.Fatal(err)
+		}
+		if have := c.HoldBackUntil(); !have.Equal(step.want) {
+			t.Errorf("Retry-After %q: want %v, have %v", step.header, step.want, have)
+		}
+	}
+}
+
+func TestAppClientBufferReports(t *testing.T) {
+	dir, err := ioutil.TempDir("", "report-buffer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var (
+		mtx      sync.Mutex
+		requests int
+		bodies   []string
+		onDisk   [][]byte
+		received = make(chan struct{}, 10)
+	)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mtx.Lock()
+		defer mtx.Unlock()
+		requests++
+		// The app is down for the first report.
+		if requests == 1 {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			received <- struct{}{}
+			return
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		files, _ := filepath.Glob(filepath.Join(dir, "*", "*"+bufferedReportSuffix))
+		for _, file := range files {
+			contents, _ := ioutil.ReadFile(file)
+			onDisk = append(onDisk, contents)
+		}
+		w.WriteHeader(http.StatusOK)
+		received <- struct{}{}
+	})
+	s := httptest.NewServer(handler)
+	defer s.Close()
+
+	u, err := url.Parse(s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := NewAppClient(ProbeConfig{BufferDir: dir, BufferKey: testBufferKey}, u.Host, *u, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Stop()
+
+	if err := p.Publish(strings.NewReader("buffered report")); err != nil {
+		t.Fatal(err)
+	}
+	// The report is replayed once the app is back.
+	for i := 0; i < 2; i++ {
+		select {
+		case <-received:
+		case <-time.After(5 * time.Second):
+			t.Fatal("timeout")
+		}
+	}
+
+	mtx.Lock()
+	defer mtx.Unlock()
+	if want := []string{"buffered report"}; !reflect.DeepEqual(want, bodies) {
+		t.Errorf("want %v, have %v", want, bodies)
+	}
+	if len(onDisk) != 1 || strings.Contains(string(onDisk[0]), "buffered report") {
+		t.Errorf("Expected the report to be buffered encrypted, got %q", onDisk)
+	}
+}
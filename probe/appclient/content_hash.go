@@ -0,0 +1,258 @@
+package appclient
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io/ioutil"
+)
+
+// contentHash hashes the content of an encoded report, leaving out its ID
+// and its timestamps, which change with every report; see ignoredEntry.
+// Reports encode their maps in no particular order, so rather than hashing
+// the msgpack as it is, the entries of each map are hashed on their own and
+// summed up, which doesn't depend on their order.
+func contentHash(buf []byte) (string, error) {
+	if isGzipped(buf) {
+		reader, err := gzip.NewReader(bytes.NewReader(buf))
+		if err != nil {
+			return "", err
+		}
+		if buf, err = ioutil.ReadAll(reader); err != nil {
+			return "", err
+		}
+	}
+	h := &msgpackHasher{}
+	rest, err := h.value(h.hasher(0), buf, 0, nil, nil)
+	if err != nil {
+		return "", err
+	}
+	if len(rest) > 0 {
+		return "", fmt.Errorf("%d trailing bytes after report", len(rest))
+	}
+	return hex.EncodeToString(h.hashers[0].Sum(nil)), nil
+}
+
+// msgpackHasher hashes msgpack values, with a hasher per level of nested
+// maps, which are reused from one map entry to the next.
+type msgpackHasher struct {
+	hashers []hash.Hash
+}
+
+func (h *msgpackHasher) hasher(depth int) hash.Hash {
+	if depth == len(h.hashers) {
+		h.hashers = append(h.hashers, sha256.New())
+	}
+	hasher := h.hashers[depth]
+	hasher.Reset()
+	return hasher
+}
+
+// The kinds of msgpack values, as far as hashing them goes.
+const (
+	scalarKind = iota
+	arrayKind
+	mapKind
+)
+
+// value writes the value at the start of buf into w, and returns what
+// follows it. depth is how many maps the value is nested in, and parent and
+// grandparent are the keys of the innermost two, nil for those who aren't
+// strings.
+func (h *msgpackHasher) value(w hash.Hash, buf []byte, depth int, grandparent, parent []byte) ([]byte, error) {
+	kind, header, n, err := msgpackHeader(buf)
+	if err != nil {
+		return nil, err
+	}
+	switch kind {
+	case arrayKind:
+		return h.array(w, buf, header, n, depth, grandparent, parent)
+	case mapKind:
+		return h.mapping(w, buf, header, n, depth, grandparent, parent)
+	}
+	return scalar(w, buf, header+n)
+}
+
+// ignoredEntry says whether the entry of a map, given the keys of the maps
+// it is nested in, is left out of the hash for changing with every report
+// regardless of its content: the ID of the report, the timestamps of Latest
+// entries, the time and uptime of the host (host.Timestamp, host.Uptime),
+// and the times of metric samples.
+func ignoredEntry(depth int, grandparent, parent, key []byte) bool {
+	switch {
+	case depth == 0:
+		return string(key) == "ID"
+	case string(grandparent) == "latest", string(grandparent) == "latestControls":
+		return string(key) == "timestamp"
+	case string(parent) == "latest":
+		return string(key) == "ts" || string(key) == "uptime"
+	case string(grandparent) == "metrics":
+		return string(key) == "first" || string(key) == "last"
+	case string(parent) == "samples":
+		return string(key) == "date"
+	}
+	return false
+}
+
+// msgpackHeader reads the type of the value at the start of buf. It returns
+// the kind of the value, the size of its header, and how many bytes follow
+// the header for scalars, or how many elements or entries follow it for
+// arrays and maps.
+func msgpackHeader(buf []byte) (kind, header, n int, err error) {
+	if len(buf) == 0 {
+		return 0, 0, 0, errUnexpectedEnd
+	}
+	b := buf[0]
+	switch {
+	case b <= 0x7f, b >= 0xe0:
+		return scalarKind, 1, 0, nil
+	case b <= 0x8f:
+		return mapKind, 1, int(b & 0x0f), nil
+	case b <= 0x9f:
+		return arrayKind, 1, int(b & 0x0f), nil
+	case b <= 0xbf:
+		return scalarKind, 1, int(b & 0x1f), nil
+	}
+	switch b {
+	case 0xc0, 0xc2, 0xc3:
+		return scalarKind, 1, 0, nil
+	case 0xcc, 0xd0:
+		return scalarKind, 1, 1, nil
+	case 0xcd, 0xd1:
+		return scalarKind, 1, 2, nil
+	case 0xca, 0xce, 0xd2:
+		return scalarKind, 1, 4, nil
+	case 0xcb, 0xcf, 0xd3:
+		return scalarKind, 1, 8, nil
+	case 0xd4, 0xd5, 0xd6, 0xd7, 0xd8:
+		return scalarKind, 2, 1 << (b - 0xd4), nil
+	case 0xc4, 0xd9:
+		n, err := length(buf, 1)
+		return scalarKind, 2, n, err
+	case 0xc5, 0xda:
+		n, err := length(buf, 2)
+		return scalarKind, 3, n, err
+	case 0xc6, 0xdb:
+		n, err := length(buf, 4)
+		return scalarKind, 5, n, err
+	case 0xc7:
+		n, err := length(buf, 1)
+		return scalarKind, 3, n, err
+	case 0xc8:
+		n, err := length(buf, 2)
+		return scalarKind, 4, n, err
+	case 0xc9:
+		n, err := length(buf, 4)
+		return scalarKind, 6, n, err
+	case 0xdc:
+		n, err := length(buf, 2)
+		return arrayKind, 3, n, err
+	case 0xdd:
+		n, err := length(buf, 4)
+		return arrayKind, 5, n, err
+	case 0xde:
+		n, err := length(buf, 2)
+		return mapKind, 3, n, err
+	case 0xdf:
+		n, err := length(buf, 4)
+		return mapKind, 5, n, err
+	}
+	return 0, 0, 0, fmt.Errorf("invalid msgpack type 0x%x", b)
+}
+
+var errUnexpectedEnd = fmt.Errorf("unexpected end of report")
+
+// length reads the big-endian length of size bytes following the type of
+// the value at the start of buf.
+func length(buf []byte, size int) (int, error) {
+	if len(buf) < 1+size {
+		return 0, errUnexpectedEnd
+	}
+	var n uint64
+	for _, b := range buf[1 : 1+size] {
+		n = n<<8 | uint64(b)
+	}
+	return int(n), nil
+}
+
+// scalar writes the n bytes of the value at the start of buf, as they are.
+func scalar(w hash.Hash, buf []byte, n int) ([]byte, error) {
+	if len(buf) < n {
+		return nil, errUnexpectedEnd
+	}
+	w.Write(buf[:n])
+	return buf[n:], nil
+}
+
+// array writes the elements of an array, which are nested in the same maps
+// as the array.
+func (h *msgpackHasher) array(w hash.Hash, buf []byte, header, n, depth int, grandparent, parent []byte) ([]byte, error) {
+	w.Write(buf[:header])
+	buf = buf[header:]
+	for i := 0; i < n; i++ {
+		var err error
+		if buf, err = h.value(w, buf, depth, grandparent, parent); err != nil {
+			return nil, err
+		}
+	}
+	return buf, nil
+}
+
+// mapping writes the sum of the hashes of the entries of a map, but for
+// the ignored ones.
+func (h *msgpackHasher) mapping(w hash.Hash, buf []byte, header, n, depth int, grandparent, parent []byte) ([]byte, error) {
+	var (
+		sum   [sha256.Size / 8]uint64
+		entry [sha256.Size]byte
+		count uint64
+	)
+	buf = buf[header:]
+	for i := 0; i < n; i++ {
+		raw := buf
+		e := h.hasher(depth + 1)
+		rest, err := h.value(e, buf, depth+1, nil, nil)
+		if err != nil {
+			return nil, err
+		}
+		key := stringValue(raw[:len(raw)-len(rest)])
+		if buf, err = h.value(e, rest, depth+1, parent, key); err != nil {
+			return nil, err
+		}
+		if key != nil && ignoredEntry(depth, grandparent, parent, key) {
+			continue
+		}
+		e.Sum(entry[:0])
+		for j := range sum {
+			sum[j] += binary.BigEndian.Uint64(entry[j*8:])
+		}
+		count++
+	}
+	var out [8 + sha256.Size]byte
+	binary.BigEndian.PutUint64(out[:], count)
+	for j, s := range sum {
+		binary.BigEndian.PutUint64(out[8+j*8:], s)
+	}
+	w.Write([]byte{0xdf})
+	w.Write(out[:])
+	return buf, nil
+}
+
+// stringValue returns the bytes of the msgpack value raw, if it is a
+// string, or nil.
+func stringValue(raw []byte) []byte {
+	switch b := raw[0]; {
+	case b >= 0xa0 && b <= 0xbf:
+		return raw[1:]
+	case b == 0xd9:
+		return raw[2:]
+	case b == 0xda:
+		return raw[3:]
+	case b == 0xdb:
+		return raw[5:]
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package appclient
+
+import (
+	"bytes"
+	"compress/gzip"
+	"testing"
+
+	"github.com/ugorji/go/codec"
+)
+
+func TestContentHash(t *testing.T) {
+	// The values are msgpack encoded by hand, for the order of the map
+	// entries to be known.
+	encode := func(values ...interface{}) []byte {
+		buf := &bytes.Buffer{}
+		buf.WriteByte(0x80 | byte(len(values)/2))
+		enc := codec.NewEncoder(buf, &codec.MsgpackHandle{})
+		for _, v := range values {
+			if err := enc.Encode(v); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return buf.Bytes()
+	}
+	hash := func(buf []byte) string {
+		h, err := contentHash(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return h
+	}
+	nested := map[string]int{"x": 1}
+
+	want := hash(encode("ID", "1", "a", 1, "b", []interface{}{"c", nested}))
+	for _, buf := range [][]byte{
+		encode("b", []interface{}{"c", nested}, "a", 1, "ID", "1"),
+		encode("a", 1, "ID", "2", "b", []interface{}{"c", nested}),
+	} {
+		if have := hash(buf); have != want {
+			t.Errorf("Expected the same hash regardless of the order of entries and the ID, got %s != %s", have, want)
+		}
+	}
+	for _, buf := range [][]byte{
+		encode("ID", "1", "a", 2, "b", []interface{}{"c", nested}),
+		encode("ID", "1", "a", 1, "b", []interface{}{nested, "c"}),
+		encode("ID", "1", "a", 1),
+	} {
+		if have := hash(buf); have == want {
+			t.Errorf("Expected different content to hash differently: %x", buf)
+		}
+	}
+
+	gzipped := &bytes.Buffer{}
+	w := gzip.NewWriter(gzipped)
+	w.Write(encode("ID", "1", "a", 1, "b", []interface{}{"c", nested}))
+	w.Close()
+	if have := hash(gzipped.Bytes()); have != want {
+		t.Errorf("Expected a gzipped report to hash the same, got %s != %s", have, want)
+	}
+
+	if _, err := contentHash([]byte{0x82, 0xa1, 'a'}); err == nil {
+		t.Error("Expected a truncated report to fail")
+	}
+}
@@ -0,0 +1,147 @@
+package appclient
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	// maxBufferedReports is how many reports the disk buffer holds, the
+	// oldest being dropped to make room for new ones.
+	maxBufferedReports = 100
+
+	bufferedReportSuffix = ".report"
+)
+
+// diskBuffer keeps the reports which couldn't be published in a directory,
+// encrypted with AES-GCM, until they can be replayed. Reports are replayed
+// oldest first.
+type diskBuffer struct {
+	mtx  sync.Mutex
+	dir  string
+	aead cipher.AEAD
+	next uint64
+}
+
+// newDiskBuffer makes a disk buffer in dir, encrypting reports with key,
+// which must be 16, 24 or 32 bytes long. Reports left in dir by a previous
+// run are kept, to be replayed.
+func newDiskBuffer(dir string, key []byte) (*diskBuffer, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid report buffer key: %v", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	b := &diskBuffer{dir: dir, aead: aead}
+	names, err := b.names()
+	if err != nil {
+		return nil, err
+	}
+	if len(names) > 0 {
+		last, _ := strconv.ParseUint(strings.TrimSuffix(names[len(names)-1], bufferedReportSuffix), 10, 64)
+		b.next = last + 1
+	}
+	return b, nil
+}
+
+// names returns the names of the buffered reports, oldest first.
+func (b *diskBuffer) names() ([]string, error) {
+	files, err := ioutil.ReadDir(b.dir)
+	if err != nil {
+		return nil, err
+	}
+	names := []string{}
+	for _, file := range files {
+		if file.Mode().IsRegular() && strings.HasSuffix(file.Name(), bufferedReportSuffix) {
+			names = append(names, file.Name())
+		}
+	}
+	// Names are zero-padded, so they sort in the order they were written.
+	sort.Strings(names)
+	return names, nil
+}
+
+// push encrypts the report and writes it to the buffer.
+func (b *diskBuffer) push(buf []byte) error {
+	nonce := make([]byte, b.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return err
+	}
+	sealed := b.aead.Seal(nonce, nonce, buf, nil)
+
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	names, err := b.names()
+	if err != nil {
+		return err
+	}
+	for len(names) >= maxBufferedReports {
+		if err := os.Remove(filepath.Join(b.dir, names[0])); err != nil {
+			return err
+		}
+		names = names[1:]
+	}
+	name := fmt.Sprintf("%020d%s", b.next, bufferedReportSuffix)
+	b.next++
+	// Write to a temporary file first, so a half-written report is never
+	// replayed.
+	tmp := filepath.Join(b.dir, name+".tmp")
+	if err := ioutil.WriteFile(tmp, sealed, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(b.dir, name))
+}
+
+// oldest returns the name and decrypted contents of the oldest buffered
+// report, if any. Reports which can't be decrypted are removed.
+func (b *diskBuffer) oldest() (string, []byte, bool, error) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	names, err := b.names()
+	if err != nil || len(names) == 0 {
+		return "", nil, false, err
+	}
+	name := names[0]
+	path := filepath.Join(b.dir, name)
+	sealed, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", nil, false, err
+	}
+	buf, err := b.open(sealed)
+	if err != nil {
+		os.Remove(path)
+		return "", nil, false, fmt.Errorf("dropping buffered report %s: %v", name, err)
+	}
+	return name, buf, true, nil
+}
+
+func (b *diskBuffer) open(sealed []byte) ([]byte, error) {
+	nonceSize := b.aead.NonceSize()
+	if len(sealed) < nonceSize {
+		return nil, fmt.Errorf("truncated report")
+	}
+	return b.aead.Open(nil, sealed[:nonceSize], sealed[nonceSize:], nil)
+}
+
+// remove removes a replayed report from the buffer.
+func (b *diskBuffer) remove(name string) error {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	return os.Remove(filepath.Join(b.dir, name))
+}
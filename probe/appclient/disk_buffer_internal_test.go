@@ -0,0 +1,128 @@
+package appclient
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var testBufferKey = []byte("0123456789abcdef0123456789abcdef")
+
+func TestDiskBuffer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "report-buffer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	b, err := newDiskBuffer(dir, testBufferKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, report := range []string{"first report", "second report"} {
+		if err := b.push([]byte(report)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Reports on disk are encrypted
+	names, err := b.names()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != 2 {
+		t.Fatalf("Expected 2 buffered reports, got %v", names)
+	}
+	for _, name := range names {
+		contents, err := ioutil.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bytes.Contains(contents, []byte("report")) {
+			t.Errorf("Expected %s to be encrypted, got %q", name, contents)
+		}
+	}
+
+	// A buffer reopened on the same directory replays them, oldest first
+	b, err = newDiskBuffer(dir, testBufferKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"first report", "second report"} {
+		name, have, ok, err := b.oldest()
+		if err != nil || !ok {
+			t.Fatalf("Expected a buffered report, got %v, %v", ok, err)
+		}
+		if string(have) != want {
+			t.Errorf("want %q, have %q", want, have)
+		}
+		if err := b.remove(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, _, ok, err := b.oldest(); ok || err != nil {
+		t.Errorf("Expected an empty buffer, got %v, %v", ok, err)
+	}
+}
+
+func TestDiskBufferWrongKey(t *testing.T) {
+	dir, err := ioutil.TempDir("", "report-buffer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if _, err := newDiskBuffer(dir, []byte("short")); err == nil {
+		t.Error("Expected keys of an invalid size to be rejected")
+	}
+
+	b, err := newDiskBuffer(dir, testBufferKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := b.push([]byte("report")); err != nil {
+		t.Fatal(err)
+	}
+	other, err := newDiskBuffer(dir, []byte("fedcba9876543210fedcba9876543210"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Reports which can't be decrypted are dropped
+	if _, _, ok, err := other.oldest(); ok || err == nil {
+		t.Errorf("Expected an error decrypting the report, got %v, %v", ok, err)
+	}
+	if names, _ := b.names(); len(names) != 0 {
+		t.Errorf("Expected the report to be dropped, got %v", names)
+	}
+}
+
+func TestDiskBufferLimit(t *testing.T) {
+	dir, err := ioutil.TempDir("", "report-buffer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	b, err := newDiskBuffer(dir, testBufferKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i <= maxBufferedReports; i++ {
+		if err := b.push([]byte{byte(i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	names, err := b.names()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != maxBufferedReports {
+		t.Errorf("Expected %d buffered reports, got %d", maxBufferedReports, len(names))
+	}
+	// The oldest report made room for the newest
+	if _, have, _, _ := b.oldest(); !bytes.Equal([]byte{1}, have) {
+		t.Errorf("Expected the oldest report to be dropped, got %v", have)
+	}
+}
@@ -0,0 +1,84 @@
+package appclient
+
+import (
+	"errors"
+	"io"
+	"sync"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// A KafkaProducer produces messages to Kafka topics, e.g. by wrapping the
+// synchronous producer of a Kafka client library.
+type KafkaProducer interface {
+	Produce(topic string, key, value []byte) error
+	Close() error
+}
+
+// A KafkaDialer connects a KafkaProducer to a Kafka cluster, given the
+// addresses of some of its brokers.
+type KafkaDialer func(brokers []string) (KafkaProducer, error)
+
+var (
+	kafkaDialerMtx sync.RWMutex
+	kafkaDialer    KafkaDialer
+)
+
+// RegisterKafkaDialer sets the dialer Kafka report publishers connect
+// with. The probe doesn't ship a Kafka client, so builds publishing to
+// Kafka register a dialer wrapping one.
+func RegisterKafkaDialer(dialer KafkaDialer) {
+	kafkaDialerMtx.Lock()
+	defer kafkaDialerMtx.Unlock()
+	kafkaDialer = dialer
+}
+
+// A KafkaReportPublisher produces reports as messages to a Kafka topic, as
+// well as or instead of pushing them to apps, for event-driven pipelines.
+// Messages are keyed by the ID of the probe, so the reports of a probe stay
+// in order on their partition. As for S3ReportPublisher, heartbeats aren't
+// produced.
+type KafkaReportPublisher struct {
+	producer KafkaProducer
+	topic    string
+	probeID  string
+}
+
+// NewKafkaReportPublisher creates a new Kafka report publisher, connected
+// to the brokers with the registered KafkaDialer.
+func NewKafkaReportPublisher(brokers []string, topic, probeID string) (*KafkaReportPublisher, error) {
+	kafkaDialerMtx.RLock()
+	dialer := kafkaDialer
+	kafkaDialerMtx.RUnlock()
+	if dialer == nil {
+		return nil, errors.New("no Kafka dialer registered")
+	}
+	producer, err := dialer(brokers)
+	if err != nil {
+		return nil, err
+	}
+	return &KafkaReportPublisher{
+		producer: producer,
+		topic:    topic,
+		probeID:  probeID,
+	}, nil
+}
+
+// Publish implements Publisher, producing the report to the topic.
+func (p *KafkaReportPublisher) Publish(r io.Reader) error {
+	buf, err := readReport(r)
+	if err != nil {
+		return err
+	}
+	if isHeartbeat(buf) {
+		return nil
+	}
+	return p.producer.Produce(p.topic, []byte(p.probeID), buf)
+}
+
+// Stop implements Publisher, closing the connection to Kafka.
+func (p *KafkaReportPublisher) Stop() {
+	if err := p.producer.Close(); err != nil {
+		log.Warnf("Error closing Kafka producer: %v", err)
+	}
+}
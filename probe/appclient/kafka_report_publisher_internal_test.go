@@ -0,0 +1,93 @@
+package appclient
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/weaveworks/common/mtime"
+
+	"github.com/weaveworks/scope/report"
+)
+
+type kafkaMessage struct {
+	topic      string
+	key, value []byte
+}
+
+// fakeKafkaProducer keeps the messages produced to it.
+type fakeKafkaProducer struct {
+	brokers  []string
+	messages []kafkaMessage
+	closed   bool
+}
+
+func (f *fakeKafkaProducer) Produce(topic string, key, value []byte) error {
+	f.messages = append(f.messages, kafkaMessage{topic, key, value})
+	return nil
+}
+
+func (f *fakeKafkaProducer) Close() error {
+	f.closed = true
+	return nil
+}
+
+func TestKafkaReportPublisher(t *testing.T) {
+	defer RegisterKafkaDialer(nil)
+	if _, err := NewKafkaReportPublisher([]string{"kafka:9092"}, "reports", "probe"); err == nil {
+		t.Fatal("Expected an error without a dialer")
+	}
+
+	fake := &fakeKafkaProducer{}
+	RegisterKafkaDialer(func(brokers []string) (KafkaProducer, error) {
+		fake.brokers = brokers
+		return fake, nil
+	})
+	kafka, err := NewKafkaReportPublisher([]string{"kafka-1:9092", "kafka-2:9092"}, "reports", "probe")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"kafka-1:9092", "kafka-2:9092"}; !reflect.DeepEqual(want, fake.brokers) {
+		t.Errorf("want brokers %v, have %v", want, fake.brokers)
+	}
+	publisher := NewReportPublisher(kafka, true)
+	publisher.EnableHeartbeats("probe")
+
+	// The last report is unchanged, so only a heartbeat is published for
+	// it, which isn't produced
+	mtime.NowForce(time.Unix(1, 0))
+	defer mtime.NowReset()
+	for _, name := range []string{"foo", "bar", "bar"} {
+		rpt := report.MakeReport()
+		rpt.Container.AddNode(report.MakeNodeWith("container", map[string]string{"docker_container_name": name}))
+		rpt.Container.Controls.AddControl(report.Control{ID: "stop"})
+		if err := publisher.Publish(rpt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(fake.messages) != 2 {
+		t.Fatalf("Expected 2 messages, got %d", len(fake.messages))
+	}
+	for i, name := range []string{"foo", "bar"} {
+		message := fake.messages[i]
+		if message.topic != "reports" || string(message.key) != "probe" {
+			t.Errorf("%d: expected a message to reports keyed by probe, got topic %q, key %q", i, message.topic, message.key)
+		}
+		have, err := report.MakeFromBinary(bytes.NewReader(message.value))
+		if err != nil {
+			t.Fatalf("%d: %v", i, err)
+		}
+		if value, _ := have.Container.Nodes["container"].Latest.Lookup("docker_container_name"); value != name {
+			t.Errorf("%d: expected the published report, got %v", i, have)
+		}
+		if len(have.Container.Controls) != 0 {
+			t.Errorf("%d: expected the controls to be left out, got %v", i, have.Container.Controls)
+		}
+	}
+
+	kafka.Stop()
+	if !fake.closed {
+		t.Error("Expected the producer to be closed")
+	}
+}
@@ -5,10 +5,10 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"io/ioutil"
 	"net/url"
 	"strings"
 	"sync"
+	"time"
 
 	log "github.com/Sirupsen/logrus"
 
@@ -44,6 +44,57 @@ type Publisher interface {
 	Stop()
 }
 
+// A Throttled publisher can be asked by apps to hold back reports while they
+// are overloaded. HoldBackUntil returns until when, which is in the past
+// when reports are welcome.
+type Throttled interface {
+	HoldBackUntil() time.Time
+}
+
+// Publishers publishes reports to each of several publishers, e.g. the
+// apps and a report archive.
+type Publishers []Publisher
+
+// Publish implements Publisher by publishing the reader to each of the
+// publishers in turn, as multiClient does.
+func (ps Publishers) Publish(r io.Reader) error {
+	buf, err := readReport(r)
+	if err != nil {
+		return err
+	}
+	errs := []string{}
+	for _, p := range ps {
+		if err := p.Publish(bytes.NewBuffer(buf)); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// Stop implements Publisher.
+func (ps Publishers) Stop() {
+	for _, p := range ps {
+		p.Stop()
+	}
+}
+
+// HoldBackUntil implements Throttled, holding reports back as long as any
+// of the publishers asked to.
+func (ps Publishers) HoldBackUntil() time.Time {
+	var result time.Time
+	for _, p := range ps {
+		if t, ok := p.(Throttled); ok {
+			if until := t.HoldBackUntil(); until.After(result) {
+				result = until
+			}
+		}
+	}
+	return result
+}
+
 // MultiAppClient maintains a set of upstream apps, and ensures we have an
 // AppClient for each one.
 type MultiAppClient interface {
@@ -166,7 +217,7 @@ func (c *multiClient) Stop() {
 // reader, and recreate new readers for each publisher. Note that it will
 // publish to one endpoint for each unique ID. Failed publishes don't count.
 func (c *multiClient) Publish(r io.Reader) error {
-	buf, err := ioutil.ReadAll(r)
+	buf, err := readReport(r)
 	if err != nil {
 		return err
 	}
@@ -175,7 +226,7 @@ func (c *multiClient) Publish(r io.Reader) error {
 	defer c.mtx.Unlock()
 	errs := []string{}
 	for _, c := range c.clients {
-		if err := c.Publish(bytes.NewReader(buf)); err != nil {
+		if err := c.Publish(bytes.NewBuffer(buf)); err != nil {
 			errs = append(errs, err.Error())
 		}
 	}
@@ -185,6 +236,22 @@ func (c *multiClient) Publish(r io.Reader) error {
 	return nil
 }
 
+// HoldBackUntil implements Throttled, holding reports back as long as any
+// of the apps asked to.
+func (c *multiClient) HoldBackUntil() time.Time {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	var result time.Time
+	for _, client := range c.clients {
+		if t, ok := client.(Throttled); ok {
+			if until := t.HoldBackUntil(); until.After(result) {
+				result = until
+			}
+		}
+	}
+	return result
+}
+
 type semaphore chan struct{}
 
 func newSemaphore(n int) semaphore {
@@ -0,0 +1,42 @@
+package appclient
+
+import (
+	"github.com/weaveworks/scope/report"
+)
+
+// normalize collapses the redundant node entries of the report, that is
+// nodes stored under a key other than their ID, e.g. by reporters
+// re-keying nodes while merging, into the entry for their ID, merging
+// their metadata. Topologies without redundant entries are left alone, so
+// the report passed in isn't modified.
+func normalize(r report.Report) report.Report {
+	r.WalkTopologies(func(t *report.Topology) {
+		redundant := false
+		for key, n := range t.Nodes {
+			if n.ID != "" && n.ID != key {
+				redundant = true
+				break
+			}
+		}
+		if !redundant {
+			return
+		}
+		nodes := make(report.Nodes, len(t.Nodes))
+		for key, n := range t.Nodes {
+			if n.ID == "" || n.ID == key {
+				nodes[key] = n
+			}
+		}
+		for key, n := range t.Nodes {
+			if n.ID == "" || n.ID == key {
+				continue
+			}
+			if existing, ok := nodes[n.ID]; ok {
+				n = existing.Merge(n)
+			}
+			nodes[n.ID] = n
+		}
+		t.Nodes = nodes
+	})
+	return r
+}
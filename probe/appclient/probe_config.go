@@ -9,8 +9,10 @@ import (
 	"net/http"
 	"time"
 
+	log "github.com/Sirupsen/logrus"
 	"github.com/certifi/gocertifi"
 	"github.com/hashicorp/go-cleanhttp"
+	"golang.org/x/net/http2"
 
 	"github.com/weaveworks/scope/common/xfer"
 )
@@ -35,6 +37,28 @@ type ProbeConfig struct {
 	ProbeVersion string
 	ProbeID      string
 	Insecure     bool
+
+	// MaxPublishesPerSecond caps the rate at which the probe publishes
+	// reports, merging the excess into the next report sent. The probe
+	// enforces it, with Probe.LimitPublishRate. Zero means no limit.
+	MaxPublishesPerSecond float64
+
+	// HTTP2 negotiates HTTP/2 with apps served over TLS, so requests are
+	// multiplexed over a single connection. Apps not supporting it are
+	// talked to over HTTP/1.1.
+	HTTP2 bool
+
+	// Heartbeats says the probe publishes heartbeats in place of unchanged
+	// reports, which it tells apps on its reports, for them to keep the
+	// report the heartbeats stand for.
+	Heartbeats bool
+
+	// BufferDir, if set, is where reports which couldn't be published are
+	// kept, in a directory per app, to be replayed once the app is back.
+	// They are encrypted with BufferKey, an AES key of 16, 24 or 32
+	// bytes.
+	BufferDir string
+	BufferKey []byte
 }
 
 func (pc ProbeConfig) authorizeHeaders(headers http.Header) {
@@ -65,5 +89,10 @@ func (pc ProbeConfig) getHTTPTransport(hostname string) *http.Transport {
 			ServerName: hostname,
 		}
 	}
+	if pc.HTTP2 {
+		if err := http2.ConfigureTransport(transport); err != nil {
+			log.Warningf("Error enabling HTTP/2, falling back to HTTP/1.1: %v", err)
+		}
+	}
 	return transport
 }
@@ -3,14 +3,38 @@ package appclient
 import (
 	"bytes"
 	"compress/gzip"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"time"
+
+	"github.com/weaveworks/common/mtime"
+
+	"github.com/weaveworks/scope/common/xfer"
 	"github.com/weaveworks/scope/report"
 )
 
+// maxHeartbeats is how many heartbeats are published in a row before the
+// unchanged report is published again, so that an app which lost it, e.g.
+// by restarting, gets it back.
+const maxHeartbeats = 10
+
 // A ReportPublisher uses a buffer pool to serialise reports, which it
 // then passes to a publisher
 type ReportPublisher struct {
 	publisher  Publisher
 	noControls bool
+	encoder    ReportEncoder
+
+	heartbeats bool
+	probeID    string
+	lastHash   string
+	lastID     string // of the last report published in full
+	beats      int
+
+	// lastSize is the encoded size of the last report, which the buffer of
+	// the next one is allocated with, so that it doesn't grow by copying.
+	lastSize int
 }
 
 // NewReportPublisher creates a new report publisher
@@ -18,9 +42,40 @@ func NewReportPublisher(publisher Publisher, noControls bool) *ReportPublisher {
 	return &ReportPublisher{
 		publisher:  publisher,
 		noControls: noControls,
+		encoder:    GzipEncoder,
 	}
 }
 
+// SetEncoder replaces the GzipEncoder reports are serialised with.
+func (p *ReportPublisher) SetEncoder(encoder ReportEncoder) {
+	p.encoder = encoder
+}
+
+// EnableHeartbeats makes the publisher publish a heartbeat, rather than the
+// report, when a report has the same content as the last one published.
+func (p *ReportPublisher) EnableHeartbeats(probeID string) {
+	p.heartbeats = true
+	p.probeID = probeID
+}
+
+// HoldBackUntil returns until when the publisher was asked to hold back
+// reports, if it is Throttled.
+func (p *ReportPublisher) HoldBackUntil() time.Time {
+	if t, ok := p.publisher.(Throttled); ok {
+		return t.HoldBackUntil()
+	}
+	return time.Time{}
+}
+
+// A ReportEncoder serialises and compresses a report onto w.
+type ReportEncoder func(w io.Writer, r report.Report) error
+
+// GzipEncoder encodes reports as a gzipped msgpack, which is what apps
+// expect to be published.
+func GzipEncoder(w io.Writer, r report.Report) error {
+	return r.WriteBinary(w, gzip.DefaultCompression)
+}
+
 // Publish serialises and compresses a report, then passes it to a publisher
 func (p *ReportPublisher) Publish(r report.Report) error {
 	if p.noControls {
@@ -28,7 +83,59 @@ func (p *ReportPublisher) Publish(r report.Report) error {
 			t.Controls = report.Controls{}
 		})
 	}
+	r = normalize(r)
+	// The report is encoded straight into the one buffer which is handed
+	// on, all the way to the request bodies; see readReport. It isn't
+	// streamed into the requests, as its bytes are needed after encoding:
+	// to hash it for heartbeats, to send it again to apps missing it, and
+	// to buffer it on disk while apps are unreachable.
+	buf := bytes.NewBuffer(make([]byte, 0, p.lastSize+p.lastSize/8))
+	if err := p.encoder(buf, r); err != nil {
+		return err
+	}
+	p.lastSize = buf.Len()
+	if p.heartbeats {
+		hash, err := contentHash(buf.Bytes())
+		if err != nil {
+			return err
+		}
+		if hash == p.lastHash && p.beats < maxHeartbeats {
+			p.beats++
+			return p.publishHeartbeat()
+		}
+		// Heartbeats refer to the last report published in full by
+		// its ID.
+		p.lastHash, p.lastID, p.beats = hash, r.ID, 0
+	}
+	return p.publisher.Publish(buf)
+}
+
+// readReport reads a published report. Reports published by a
+// ReportPublisher, or fanned out by a multiClient, come in a bytes.Buffer,
+// whose contents are returned without being copied; they must not be
+// modified.
+func readReport(r io.Reader) ([]byte, error) {
+	if buf, ok := r.(*bytes.Buffer); ok {
+		return buf.Next(buf.Len()), nil
+	}
+	return ioutil.ReadAll(r)
+}
+
+func (p *ReportPublisher) publishHeartbeat() error {
 	buf := &bytes.Buffer{}
-	r.WriteBinary(buf, gzip.DefaultCompression)
+	if err := json.NewEncoder(buf).Encode(xfer.Heartbeat{
+		ProbeID:   p.probeID,
+		Timestamp: mtime.Now(),
+		ReportID:  p.lastID,
+	}); err != nil {
+		return err
+	}
 	return p.publisher.Publish(buf)
 }
+
+// isHeartbeat says whether buf is a heartbeat rather than a report.
+// Heartbeats are JSON objects, while reports start with either the gzip
+// magic number or a msgpack map.
+func isHeartbeat(buf []byte) bool {
+	return len(buf) > 0 && buf[0] == '{'
+}
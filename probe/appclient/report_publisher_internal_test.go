@@ -0,0 +1,373 @@
+package appclient
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"testing"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	logtest "github.com/Sirupsen/logrus/hooks/test"
+	"github.com/weaveworks/common/mtime"
+	"github.com/weaveworks/common/test"
+
+	"github.com/weaveworks/scope/common/xfer"
+	"github.com/weaveworks/scope/probe/controls"
+	"github.com/weaveworks/scope/probe/host"
+	"github.com/weaveworks/scope/report"
+	"github.com/weaveworks/scope/test/reflect"
+)
+
+type publisherFunc func(io.Reader) error
+
+func (f publisherFunc) Publish(r io.Reader) error { return f(r) }
+func (publisherFunc) Stop()                       {}
+
+func TestReportPublisherLogsCompressionRatio(t *testing.T) {
+	defer log.SetLevel(log.GetLevel())
+	log.SetLevel(log.DebugLevel)
+	hook := logtest.NewGlobal()
+	defer hook.Reset()
+
+	rpt := report.MakeReport()
+	for i := 0; i < 100; i++ {
+		id := fmt.Sprintf("container-%d", i)
+		rpt.Container.AddNode(report.MakeNodeWith(id, map[string]string{
+			"docker_container_name": "a-rather-repetitive-container-name",
+		}))
+	}
+
+	published := false
+	publisher := NewReportPublisher(publisherFunc(func(r io.Reader) error {
+		published = true
+		_, err := ioutil.ReadAll(r)
+		return err
+	}), false)
+	if err := publisher.Publish(rpt); err != nil {
+		t.Fatal(err)
+	}
+	if !published {
+		t.Fatal("report was not published")
+	}
+
+	entry := hook.LastEntry()
+	if entry == nil {
+		t.Fatal("expected a debug log entry")
+	}
+	var uncompressed, compressed uint64
+	var ratio float64
+	if _, err := fmt.Sscanf(entry.Message,
+		"Wrote report sizes: uncompressed %d bytes, compressed %d bytes (ratio %f)",
+		&uncompressed, &compressed, &ratio); err != nil {
+		t.Fatalf("unexpected log message %q: %v", entry.Message, err)
+	}
+	if ratio <= 1 {
+		t.Errorf("expected compression ratio > 1, got %.2f (%d/%d)", ratio, uncompressed, compressed)
+	}
+}
+
+func TestReportPublisherNormalizes(t *testing.T) {
+	now := time.Now()
+	rpt := report.MakeReport()
+	want := report.MakeReport()
+	for i := 0; i < 10; i++ {
+		id := report.MakeContainerNodeID(fmt.Sprintf("container-%d", i))
+		node := report.MakeNode(id).WithTopology(report.Container).
+			WithLatest("docker_container_name", now, "a-container-with-a-long-name").
+			WithParents(report.MakeSets().Add(report.Host, report.MakeStringSet(report.MakeHostNodeID("host"))))
+		// A stale copy of the node, under a key of its own, as left by a
+		// reporter re-keying nodes
+		stale := node.WithLatest("docker_container_name", now.Add(-time.Minute), "old-name").
+			WithLatest("docker_image_id", now, "image")
+		rpt.Container.Nodes[id] = node
+		rpt.Container.Nodes[id+";stale"] = stale
+		want.Container.AddNode(node.Merge(stale))
+	}
+
+	var published []byte
+	publisher := NewReportPublisher(publisherFunc(func(r io.Reader) error {
+		var err error
+		published, err = ioutil.ReadAll(r)
+		return err
+	}), false)
+	if err := publisher.Publish(rpt); err != nil {
+		t.Fatal(err)
+	}
+	var unnormalized bytes.Buffer
+	if err := GzipEncoder(&unnormalized, rpt); err != nil {
+		t.Fatal(err)
+	}
+	if len(published) >= unnormalized.Len() {
+		t.Errorf("Expected the normalized report to be smaller, got %d >= %d bytes", len(published), unnormalized.Len())
+	}
+
+	decode := func(b []byte) report.Report {
+		r, err := report.MakeFromBinary(bytes.NewReader(b))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return *r
+	}
+	var expected bytes.Buffer
+	if err := GzipEncoder(&expected, want); err != nil {
+		t.Fatal(err)
+	}
+	if have, want := decode(published), decode(expected.Bytes()); !reflect.DeepEqual(want, have) {
+		t.Error(test.Diff(want, have))
+	}
+	if len(rpt.Container.Nodes) != 20 {
+		t.Errorf("Expected the published report to be left alone, got %d nodes", len(rpt.Container.Nodes))
+	}
+}
+
+func TestReportPublisherHeartbeats(t *testing.T) {
+	now := time.Now()
+	makeReport := func(name string) report.Report {
+		rpt := report.MakeReport()
+		for i := 0; i < 10; i++ {
+			id := report.MakeContainerNodeID(fmt.Sprintf("container-%d", i))
+			rpt.Container.AddNode(report.MakeNode(id).WithTopology(report.Container).
+				WithLatest("docker_container_name", now, name).
+				WithMetrics(report.Metrics{
+					"cpu":    report.MakeSingletonMetric(now, 1),
+					"memory": report.MakeSingletonMetric(now, 2),
+				}))
+		}
+		return rpt
+	}
+
+	var published [][]byte
+	publisher := NewReportPublisher(publisherFunc(func(r io.Reader) error {
+		buf, err := ioutil.ReadAll(r)
+		published = append(published, buf)
+		return err
+	}), false)
+	publisher.EnableHeartbeats("probe")
+	publish := func(rpt report.Report) []byte {
+		if err := publisher.Publish(rpt); err != nil {
+			t.Fatal(err)
+		}
+		return published[len(published)-1]
+	}
+
+	first := publish(makeReport("a"))
+	if isHeartbeat(first) {
+		t.Fatal("Expected the first report to be published in full")
+	}
+	rpt, err := report.MakeFromBinary(bytes.NewReader(first))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The same content, in a report of its own, gets a heartbeat.
+	beat := publish(makeReport("a"))
+	if !isHeartbeat(beat) {
+		t.Fatal("Expected a heartbeat for an unchanged report")
+	}
+	var heartbeat xfer.Heartbeat
+	if err := json.Unmarshal(beat, &heartbeat); err != nil {
+		t.Fatal(err)
+	}
+	if heartbeat.ProbeID != "probe" || heartbeat.ReportID != rpt.ID {
+		t.Errorf("Expected a heartbeat from probe for report %s, got %+v", rpt.ID, heartbeat)
+	}
+
+	// A changed report is published in full.
+	if changed := publish(makeReport("b")); isHeartbeat(changed) {
+		t.Fatal("Expected a changed report to be published in full")
+	}
+
+	// An unchanged report is published in full again after maxHeartbeats
+	// heartbeats.
+	for i := 0; i < maxHeartbeats; i++ {
+		if !isHeartbeat(publish(makeReport("b"))) {
+			t.Fatalf("Expected heartbeat %d", i)
+		}
+	}
+	if isHeartbeat(publish(makeReport("b"))) {
+		t.Fatal("Expected the report to be published in full after the heartbeats")
+	}
+}
+
+// recordingClient keeps the reports published to it.
+type recordingClient struct {
+	AppClient
+	published [][]byte
+}
+
+func (c *recordingClient) Publish(r io.Reader) error {
+	buf, err := readReport(r)
+	c.published = append(c.published, buf)
+	return err
+}
+
+func makeBigReport() report.Report {
+	now := time.Now()
+	rpt := report.MakeReport()
+	for i := 0; i < 2000; i++ {
+		id := report.MakeContainerNodeID(fmt.Sprintf("container-%d", i))
+		rpt.Container.AddNode(report.MakeNode(id).WithTopology(report.Container).
+			WithLatest("docker_container_name", now, fmt.Sprintf("container-name-%d", i)).
+			WithLatest("docker_image_id", now, fmt.Sprintf("%064x", i*7919)).
+			WithMetrics(report.Metrics{
+				"cpu":    report.MakeSingletonMetric(now, float64(i)),
+				"memory": report.MakeSingletonMetric(now, float64(i*2)),
+			}))
+	}
+	return rpt
+}
+
+func TestReportPublisherRoundTrip(t *testing.T) {
+	rpt := makeBigReport()
+	clients := []*recordingClient{{}, {}}
+	multi := &multiClient{clients: map[string]AppClient{"a": clients[0], "b": clients[1]}}
+	publisher := NewReportPublisher(multi, false)
+	for i := 0; i < 2; i++ {
+		if err := publisher.Publish(rpt); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var expected bytes.Buffer
+	if err := GzipEncoder(&expected, normalize(rpt)); err != nil {
+		t.Fatal(err)
+	}
+	want, err := report.MakeFromBinary(bytes.NewReader(expected.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range clients {
+		if len(c.published) != 2 {
+			t.Fatalf("Expected 2 reports, got %d", len(c.published))
+		}
+		for _, buf := range c.published {
+			have, err := report.MakeFromBinary(bytes.NewReader(buf))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(*want, *have) {
+				t.Error(test.Diff(*want, *have))
+			}
+		}
+	}
+	// Every app is sent the report from the buffer it was encoded into.
+	if &clients[0].published[1][0] != &clients[1].published[1][0] {
+		t.Error("Expected the apps to share the encoded report")
+	}
+}
+
+func BenchmarkReportPublisherPublish(b *testing.B) {
+	rpt := makeBigReport()
+	clients := map[string]AppClient{}
+	for i := 0; i < 3; i++ {
+		clients[fmt.Sprint(i)] = &recordingClient{}
+	}
+	multi := &multiClient{clients: clients}
+	publisher := NewReportPublisher(multi, false)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := publisher.Publish(rpt); err != nil {
+			b.Fatal(err)
+		}
+		for _, c := range clients {
+			c.(*recordingClient).published = nil
+		}
+	}
+}
+
+// fanOut benchmarks handing an encoded report to three apps.
+func fanOut(b *testing.B) {
+	var encoded bytes.Buffer
+	if err := GzipEncoder(&encoded, makeBigReport()); err != nil {
+		b.Fatal(err)
+	}
+	clients := map[string]AppClient{}
+	for i := 0; i < 3; i++ {
+		clients[fmt.Sprint(i)] = &recordingClient{}
+	}
+	multi := &multiClient{clients: clients}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := multi.Publish(bytes.NewBuffer(encoded.Bytes())); err != nil {
+			b.Fatal(err)
+		}
+		for _, c := range clients {
+			c.(*recordingClient).published = nil
+		}
+	}
+}
+
+func BenchmarkMultiClientPublish(b *testing.B) {
+	fanOut(b)
+}
+
+func TestReportPublisherHeartbeatsHostReports(t *testing.T) {
+	defer mtime.NowReset()
+	var (
+		oldGetKernelReleaseAndVersion = host.GetKernelReleaseAndVersion
+		oldGetLoad                    = host.GetLoad
+		oldGetUptime                  = host.GetUptime
+		oldGetCPUUsagePercent         = host.GetCPUUsagePercent
+		oldGetMemoryUsageBytes        = host.GetMemoryUsageBytes
+		oldGetLocalNetworks           = host.GetLocalNetworks
+	)
+	defer func() {
+		host.GetKernelReleaseAndVersion = oldGetKernelReleaseAndVersion
+		host.GetLoad = oldGetLoad
+		host.GetUptime = oldGetUptime
+		host.GetCPUUsagePercent = oldGetCPUUsagePercent
+		host.GetMemoryUsageBytes = oldGetMemoryUsageBytes
+		host.GetLocalNetworks = oldGetLocalNetworks
+	}()
+	uptime, cpu := time.Hour, 30.0
+	host.GetKernelReleaseAndVersion = func() (string, string, error) { return "release", "version", nil }
+	host.GetLoad = func(now time.Time) report.Metrics {
+		return report.Metrics{host.Load1: report.MakeSingletonMetric(now, 1.0)}
+	}
+	host.GetUptime = func() (time.Duration, error) { return uptime, nil }
+	host.GetCPUUsagePercent = func() (float64, float64) { return cpu, 100.0 }
+	host.GetMemoryUsageBytes = func() (float64, float64) { return 60.0, 100.0 }
+	host.GetLocalNetworks = func() ([]*net.IPNet, error) { return nil, nil }
+
+	var published [][]byte
+	publisher := NewReportPublisher(publisherFunc(func(r io.Reader) error {
+		buf, err := ioutil.ReadAll(r)
+		published = append(published, buf)
+		return err
+	}), false)
+	publisher.EnableHeartbeats("probe")
+	reporter := host.NewReporter("hostid", "hostname", "probe", "", nil, controls.NewDefaultHandlerRegistry())
+	publish := func(now time.Time) []byte {
+		mtime.NowForce(now)
+		rpt, err := reporter.Report()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := publisher.Publish(rpt); err != nil {
+			t.Fatal(err)
+		}
+		return published[len(published)-1]
+	}
+
+	// Reports taken at different times, of a host that didn't change, only
+	// differ in their timestamps, and get a heartbeat.
+	start := time.Now()
+	if isHeartbeat(publish(start)) {
+		t.Fatal("Expected the first report to be published in full")
+	}
+	uptime += 15 * time.Second
+	if !isHeartbeat(publish(start.Add(15 * time.Second))) {
+		t.Fatal("Expected a heartbeat for a report of an unchanged host")
+	}
+
+	cpu = 40.0
+	if isHeartbeat(publish(start.Add(30 * time.Second))) {
+		t.Fatal("Expected a report with a changed metric to be published in full")
+	}
+}
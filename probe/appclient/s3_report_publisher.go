@@ -0,0 +1,59 @@
+package appclient
+
+import (
+	"bytes"
+	"io"
+	"path"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/weaveworks/common/mtime"
+)
+
+// An S3ReportPublisher archives reports to an S3-compatible object store,
+// as well as or instead of pushing them to apps. Each report is written to
+// its own object, keyed by the prefix and the time of publication in
+// nanoseconds, so keys sort chronologically. Heartbeats aren't archived,
+// only reports published in full are.
+type S3ReportPublisher struct {
+	s3     s3iface.S3API
+	bucket string
+	prefix string
+}
+
+// NewS3ReportPublisher creates a new S3 report publisher. The config
+// selects the endpoint and credentials, as for the app's S3 store.
+func NewS3ReportPublisher(config *aws.Config, bucket, prefix string) *S3ReportPublisher {
+	return &S3ReportPublisher{
+		s3:     s3.New(session.New(config)),
+		bucket: bucket,
+		prefix: prefix,
+	}
+}
+
+// Publish implements Publisher, writing the report to the bucket.
+func (p *S3ReportPublisher) Publish(r io.Reader) error {
+	buf, err := readReport(r)
+	if err != nil {
+		return err
+	}
+	if isHeartbeat(buf) {
+		return nil
+	}
+	_, err = p.s3.PutObject(&s3.PutObjectInput{
+		Body:   bytes.NewReader(buf),
+		Bucket: aws.String(p.bucket),
+		Key:    aws.String(p.key()),
+	})
+	return err
+}
+
+// Stop implements Publisher.
+func (p *S3ReportPublisher) Stop() {}
+
+func (p *S3ReportPublisher) key() string {
+	return path.Join(p.prefix, strconv.FormatInt(mtime.Now().UnixNano(), 10))
+}
@@ -0,0 +1,84 @@
+package appclient
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/weaveworks/common/mtime"
+
+	"github.com/weaveworks/scope/report"
+)
+
+// fakeS3 keeps the objects PUT to it, by path.
+type fakeS3 struct {
+	sync.Mutex
+	objects map[string][]byte
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "PUT" {
+		http.Error(w, "unexpected method", http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	f.Lock()
+	f.objects[r.URL.Path] = body
+	f.Unlock()
+}
+
+func TestS3ReportPublisher(t *testing.T) {
+	fake := &fakeS3{objects: map[string][]byte{}}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	publisher := NewReportPublisher(NewS3ReportPublisher(&aws.Config{
+		Endpoint:         aws.String(server.URL),
+		Region:           aws.String("us-east-1"),
+		S3ForcePathStyle: aws.Bool(true),
+		Credentials:      credentials.NewStaticCredentials("id", "secret", ""),
+	}, "bucket", "probes/foo"), true)
+	publisher.EnableHeartbeats("probe")
+
+	rpt := report.MakeReport()
+	rpt.Container.AddNode(report.MakeNodeWith("container", map[string]string{"docker_container_name": "foo"}))
+	rpt.Container.Controls.AddControl(report.Control{ID: "stop"})
+
+	// The second report is unchanged, so only a heartbeat is published for
+	// it, which isn't archived
+	defer mtime.NowReset()
+	for i, ts := range []time.Time{time.Unix(0, 1000000000), time.Unix(0, 2000000000)} {
+		mtime.NowForce(ts)
+		if err := publisher.Publish(rpt.Copy()); err != nil {
+			t.Fatalf("%d: %v", i, err)
+		}
+	}
+	if len(fake.objects) != 1 {
+		t.Errorf("Expected a single object, got %v", fake.objects)
+	}
+
+	body, ok := fake.objects["/bucket/probes/foo/1000000000"]
+	if !ok {
+		t.Fatalf("Expected object /bucket/probes/foo/1000000000, got %v", fake.objects)
+	}
+	have, err := report.MakeFromBinary(bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := have.Container.Nodes["container"]; !ok {
+		t.Errorf("Expected the published report, got %v", have)
+	}
+	if len(have.Container.Controls) != 0 {
+		t.Errorf("Expected the controls to be left out, got %v", have.Container.Controls)
+	}
+}
@@ -2,6 +2,8 @@ package awsecs
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	log "github.com/Sirupsen/logrus"
@@ -13,20 +15,22 @@ import (
 
 // TaskFamily is the key that stores the task family of an ECS Task
 const (
-	Cluster             = "ecs_cluster"
-	CreatedAt           = "ecs_created_at"
-	TaskFamily          = "ecs_task_family"
-	ServiceDesiredCount = "ecs_service_desired_count"
-	ServiceRunningCount = "ecs_service_running_count"
-	ScaleUp             = "ecs_scale_up"
-	ScaleDown           = "ecs_scale_down"
+	Cluster                = "ecs_cluster"
+	CreatedAt              = "ecs_created_at"
+	TaskFamily             = "ecs_task_family"
+	TaskDefinitionRevision = "ecs_task_definition_revision"
+	ServiceDesiredCount    = "ecs_service_desired_count"
+	ServiceRunningCount    = "ecs_service_running_count"
+	ScaleUp                = "ecs_scale_up"
+	ScaleDown              = "ecs_scale_down"
 )
 
 var (
 	taskMetadata = report.MetadataTemplates{
-		Cluster:    {ID: Cluster, Label: "Cluster", From: report.FromLatest, Priority: 0},
-		CreatedAt:  {ID: CreatedAt, Label: "Created At", From: report.FromLatest, Priority: 1, Datatype: "datetime"},
-		TaskFamily: {ID: TaskFamily, Label: "Family", From: report.FromLatest, Priority: 2},
+		Cluster:                {ID: Cluster, Label: "Cluster", From: report.FromLatest, Priority: 0},
+		CreatedAt:              {ID: CreatedAt, Label: "Created At", From: report.FromLatest, Priority: 1, Datatype: "datetime"},
+		TaskFamily:             {ID: TaskFamily, Label: "Family", From: report.FromLatest, Priority: 2},
+		TaskDefinitionRevision: {ID: TaskDefinitionRevision, Label: "Revision", From: report.FromLatest, Priority: 3, Datatype: "number"},
 	}
 	serviceMetadata = report.MetadataTemplates{
 		Cluster:             {ID: Cluster, Label: "Cluster", From: report.FromLatest, Priority: 0},
@@ -176,6 +180,9 @@ func (r Reporter) Tag(rpt report.Report) (report.Report, error) {
 				Cluster:    cluster,
 				CreatedAt:  task.CreatedAt.Format(time.RFC3339Nano),
 			})
+			if revision, ok := taskDefinitionRevision(task.TaskDefinitionARN); ok {
+				node = node.WithLatests(map[string]string{TaskDefinitionRevision: revision})
+			}
 			rpt.ECSTask = rpt.ECSTask.AddNode(node)
 
 			// parents sets to merge into all matching container nodes
@@ -230,6 +237,25 @@ func (r Reporter) Name() string {
 	return "awsecs"
 }
 
+// taskDefinitionRevision extracts the revision from a task definition ARN,
+// of the form arn:aws:ecs:region:account:task-definition/family:revision.
+func taskDefinitionRevision(arn string) (string, bool) {
+	i := strings.LastIndex(arn, "task-definition/")
+	if i < 0 {
+		return "", false
+	}
+	definition := arn[i+len("task-definition/"):]
+	j := strings.LastIndex(definition, ":")
+	if j < 0 {
+		return "", false
+	}
+	revision := definition[j+1:]
+	if _, err := strconv.ParseUint(revision, 10, 64); err != nil {
+		return "", false
+	}
+	return revision, true
+}
+
 // Stop unregisters controls.
 func (r *Reporter) Stop() {
 	r.handlerRegistry.Batch([]string{
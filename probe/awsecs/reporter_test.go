@@ -16,7 +16,7 @@ var (
 	testFamily            = "test-family"
 	testTaskARN           = "arn:aws:ecs:us-east-1:123456789012:task/12345678-9abc-def0-1234-56789abcdef0"
 	testTaskCreatedAt     = time.Unix(1483228800, 0)
-	testTaskDefinitionARN = "arn:aws:ecs:us-east-1:123456789012:task-definition/deadbeef-dead-beef-dead-beefdeadbeef"
+	testTaskDefinitionARN = "arn:aws:ecs:us-east-1:123456789012:task-definition/test-family:3"
 	testTaskStartedAt     = time.Unix(1483228805, 0)
 	testDeploymentID      = "ecs-svc/1121123211234321"
 	testServiceName       = "test-service"
@@ -139,9 +139,10 @@ func TestTagReport(t *testing.T) {
 		t.Fatalf("Result report did not contain task %v: %v", testTaskARN, rpt.ECSTask.Nodes)
 	}
 	taskExpected := map[string]string{
-		awsecs.TaskFamily: testFamily,
-		awsecs.Cluster:    testCluster,
-		awsecs.CreatedAt:  testTaskCreatedAt.Format(time.RFC3339Nano),
+		awsecs.TaskFamily:             testFamily,
+		awsecs.Cluster:                testCluster,
+		awsecs.CreatedAt:              testTaskCreatedAt.Format(time.RFC3339Nano),
+		awsecs.TaskDefinitionRevision: "3",
 	}
 	for key, expectedValue := range taskExpected {
 		value, ok := task.Latest.Lookup(key)
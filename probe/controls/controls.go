@@ -1,9 +1,15 @@
 package controls
 
 import (
+	"bytes"
+	"fmt"
+	"sort"
 	"sync"
+	"time"
 
+	"github.com/weaveworks/common/mtime"
 	"github.com/weaveworks/scope/common/xfer"
+	"github.com/weaveworks/scope/report"
 )
 
 // HandlerRegistryBackend is an interface for storing control request
@@ -68,10 +74,65 @@ func (b *defaultBackend) Handler(control string) (xfer.ControlHandlerFunc, bool)
 	return handler, ok
 }
 
+// tokenWindow is how long the response to a control request with a token
+// is kept, to answer repeated requests with the same token.
+const tokenWindow = 30 * time.Second
+
 // HandlerRegistry uses backend for storing and retrieving control
 // requests handlers.
 type HandlerRegistry struct {
 	backend HandlerRegistryBackend
+
+	cacheMtx  sync.Mutex
+	cacheTTLs map[string]time.Duration
+	cache     map[cacheKey]cachedResponse
+
+	schemasMtx sync.RWMutex
+	schemas    map[string]report.Control
+
+	tokensMtx sync.Mutex
+	tokens    map[tokenKey]*tokenResponse
+}
+
+// cacheKey identifies the requests which get the same response: those for
+// the same control on the same node, with the same arguments and working
+// directory. args is the canonical form of the arguments, given by
+// argsKey.
+type cacheKey struct {
+	nodeID, control, args, workingDir string
+}
+
+// argsKey makes a string of control arguments, the same regardless of the
+// order of the map.
+func argsKey(args map[string]string) string {
+	keys := make([]string, 0, len(args))
+	for k := range args {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var buf bytes.Buffer
+	for _, k := range keys {
+		fmt.Fprintf(&buf, "%q=%q;", k, args[k])
+	}
+	return buf.String()
+}
+
+type tokenKey struct {
+	nodeID, control, token string
+}
+
+// tokenResponse is the response to a request with a token. done is closed
+// once the response is set, so repeated requests arriving while the first
+// one runs wait for it.
+type tokenResponse struct {
+	done     chan struct{}
+	response xfer.Response
+	expires  time.Time
+}
+
+type cachedResponse struct {
+	response xfer.Response
+	expires  time.Time
 }
 
 // NewDefaultHandlerRegistry creates a registry with a default
@@ -83,7 +144,37 @@ func NewDefaultHandlerRegistry() *HandlerRegistry {
 // NewHandlerRegistry creates a registry with a custom backend.
 func NewHandlerRegistry(backend HandlerRegistryBackend) *HandlerRegistry {
 	return &HandlerRegistry{
-		backend: backend,
+		backend:   backend,
+		cacheTTLs: map[string]time.Duration{},
+		cache:     map[cacheKey]cachedResponse{},
+		schemas:   map[string]report.Control{},
+		tokens:    map[tokenKey]*tokenResponse{},
+	}
+}
+
+// CacheReadOnly caches the successful responses of the read-only controls
+// amongst controls for ttl, by node, so repeated requests within that
+// window are answered without running the handler again.
+func (r *HandlerRegistry) CacheReadOnly(ttl time.Duration, controls ...report.Control) {
+	r.cacheMtx.Lock()
+	defer r.cacheMtx.Unlock()
+	for _, control := range controls {
+		if control.ReadOnly {
+			r.cacheTTLs[control.ID] = ttl
+		}
+	}
+}
+
+// ValidateParams checks the arguments of the requests for the controls
+// with parameters against them, rejecting invalid requests before they
+// reach the handler.
+func (r *HandlerRegistry) ValidateParams(controls ...report.Control) {
+	r.schemasMtx.Lock()
+	defer r.schemasMtx.Unlock()
+	for _, control := range controls {
+		if len(control.Params) > 0 {
+			r.schemas[control.ID] = control
+		}
 	}
 }
 
@@ -114,14 +205,86 @@ func (r *HandlerRegistry) Batch(toRemove []string, toAdd map[string]xfer.Control
 	}
 }
 
-// HandleControlRequest performs a control request.
+// HandleControlRequest performs a control request. Requests repeating the
+// token of a request made within the last tokenWindow are not performed
+// again, and get the response to the original request.
 func (r *HandlerRegistry) HandleControlRequest(req xfer.Request) xfer.Response {
+	if req.Token == "" {
+		return r.handleControlRequest(req)
+	}
+
+	key := tokenKey{req.NodeID, req.Control, req.Token}
+	now := mtime.Now()
+	r.tokensMtx.Lock()
+	for k, t := range r.tokens {
+		if !t.expires.IsZero() && !now.Before(t.expires) {
+			delete(r.tokens, k)
+		}
+	}
+	if t, ok := r.tokens[key]; ok {
+		r.tokensMtx.Unlock()
+		<-t.done
+		return t.response
+	}
+	t := &tokenResponse{done: make(chan struct{})}
+	r.tokens[key] = t
+	r.tokensMtx.Unlock()
+
+	res := r.handleControlRequest(req)
+	r.tokensMtx.Lock()
+	t.response = res
+	t.expires = mtime.Now().Add(tokenWindow)
+	r.tokensMtx.Unlock()
+	close(t.done)
+	return res
+}
+
+func (r *HandlerRegistry) handleControlRequest(req xfer.Request) xfer.Response {
 	h, ok := r.handler(req.Control)
 	if !ok {
 		return xfer.ResponseErrorf("Control %q not recognised", req.Control)
 	}
 
-	return h(req)
+	r.schemasMtx.RLock()
+	schema, ok := r.schemas[req.Control]
+	r.schemasMtx.RUnlock()
+	if ok {
+		args, err := schema.ValidateArgs(req.ControlArgs)
+		if err != nil {
+			return xfer.ResponseError(err)
+		}
+		req.ControlArgs = args
+	}
+
+	r.cacheMtx.Lock()
+	ttl, cacheable := r.cacheTTLs[req.Control]
+	r.cacheMtx.Unlock()
+	if !cacheable {
+		return h(req)
+	}
+	key := cacheKey{req.NodeID, req.Control, argsKey(req.ControlArgs), req.WorkingDir}
+	now := mtime.Now()
+	r.cacheMtx.Lock()
+	cached, ok := r.cache[key]
+	r.cacheMtx.Unlock()
+	if ok && now.Before(cached.expires) {
+		return cached.response
+	}
+
+	res := h(req)
+	r.cacheMtx.Lock()
+	for k, c := range r.cache {
+		if !now.Before(c.expires) {
+			delete(r.cache, k)
+		}
+	}
+	if res.Error == "" {
+		r.cache[key] = cachedResponse{res, now.Add(ttl)}
+	} else {
+		delete(r.cache, key)
+	}
+	r.cacheMtx.Unlock()
+	return res
 }
 
 func (r *HandlerRegistry) handler(control string) (xfer.ControlHandlerFunc, bool) {
@@ -0,0 +1,32 @@
+package controls
+
+import (
+	"testing"
+	"time"
+
+	"github.com/weaveworks/common/mtime"
+	"github.com/weaveworks/scope/common/xfer"
+	"github.com/weaveworks/scope/report"
+)
+
+func TestControlsCacheSweep(t *testing.T) {
+	now := time.Now()
+	mtime.NowForce(now)
+	defer mtime.NowReset()
+
+	registry := NewDefaultHandlerRegistry()
+	registry.Register("describe", func(req xfer.Request) xfer.Response {
+		return xfer.Response{Value: req.NodeID}
+	})
+	registry.CacheReadOnly(10*time.Second, report.Control{ID: "describe", ReadOnly: true})
+	for _, nodeID := range []string{"a", "b", "c"} {
+		registry.HandleControlRequest(xfer.Request{NodeID: nodeID, Control: "describe"})
+	}
+
+	// Caching a response drops the expired ones.
+	mtime.NowForce(now.Add(time.Minute))
+	registry.HandleControlRequest(xfer.Request{NodeID: "d", Control: "describe"})
+	if len(registry.cache) != 1 {
+		t.Errorf("Expected the expired responses to be dropped, got %d cached", len(registry.cache))
+	}
+}
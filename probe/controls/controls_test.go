@@ -1,12 +1,16 @@
 package controls_test
 
 import (
+	"fmt"
 	"reflect"
 	"testing"
+	"time"
 
+	"github.com/weaveworks/common/mtime"
 	"github.com/weaveworks/common/test"
 	"github.com/weaveworks/scope/common/xfer"
 	"github.com/weaveworks/scope/probe/controls"
+	"github.com/weaveworks/scope/report"
 )
 
 func TestControls(t *testing.T) {
@@ -41,3 +45,167 @@ func TestControlsNotFound(t *testing.T) {
 		t.Fatal(test.Diff(want, have))
 	}
 }
+
+func TestControlsCacheReadOnly(t *testing.T) {
+	now := time.Now()
+	mtime.NowForce(now)
+	defer mtime.NowReset()
+
+	calls := map[string]int{}
+	registry := controls.NewDefaultHandlerRegistry()
+	for _, control := range []string{"describe", "restart"} {
+		control := control
+		registry.Register(control, func(req xfer.Request) xfer.Response {
+			calls[control]++
+			return xfer.Response{Value: fmt.Sprintf("%s %s #%d", control, req.NodeID, calls[control])}
+		})
+	}
+	registry.CacheReadOnly(10*time.Second,
+		report.Control{ID: "describe", ReadOnly: true},
+		report.Control{ID: "restart"},
+	)
+
+	handle := func(nodeID, control string, args map[string]string, workingDir string) interface{} {
+		return registry.HandleControlRequest(xfer.Request{NodeID: nodeID, Control: control, ControlArgs: args, WorkingDir: workingDir}).Value
+	}
+	for _, tc := range []struct {
+		advance         time.Duration
+		nodeID, control string
+		args            map[string]string
+		workingDir      string
+		want            string
+	}{
+		{0, "a", "describe", nil, "", "describe a #1"},
+		{5 * time.Second, "a", "describe", nil, "", "describe a #1"}, // cached
+		{0, "b", "describe", nil, "", "describe b #2"},               // cached per node
+		{0, "a", "describe", map[string]string{"x": "1"}, "", "describe a #3"},
+		{0, "a", "describe", map[string]string{"x": "1"}, "", "describe a #3"}, // cached per arguments
+		{0, "a", "describe", map[string]string{"x": "2"}, "", "describe a #4"},
+		{0, "a", "describe", nil, "/tmp", "describe a #5"}, // cached per working directory
+		{0, "a", "restart", nil, "", "restart a #1"},
+		{0, "a", "restart", nil, "", "restart a #2"},                 // not read-only
+		{6 * time.Second, "a", "describe", nil, "", "describe a #6"}, // expired
+	} {
+		now = now.Add(tc.advance)
+		mtime.NowForce(now)
+		if have := handle(tc.nodeID, tc.control, tc.args, tc.workingDir); have != tc.want {
+			t.Errorf("want %q, have %q", tc.want, have)
+		}
+	}
+}
+
+func TestControlsValidateParams(t *testing.T) {
+	registry := controls.NewDefaultHandlerRegistry()
+	var handled map[string]string
+	registry.Register("scale", func(req xfer.Request) xfer.Response {
+		handled = req.ControlArgs
+		return xfer.Response{}
+	})
+	registry.ValidateParams(report.Control{
+		ID: "scale",
+		Params: []report.ControlParam{
+			{Name: "replicas", Type: report.IntegerControlParam, Required: true},
+			{Name: "force", Type: report.BooleanControlParam, Default: "false"},
+			{Name: "reason", Type: report.StringControlParam},
+		},
+	})
+
+	for _, tc := range []struct {
+		args map[string]string
+		want map[string]string // nil if the request is invalid
+	}{
+		{
+			args: map[string]string{"replicas": "3"},
+			want: map[string]string{"replicas": "3", "force": "false"},
+		},
+		{
+			args: map[string]string{"replicas": "0", "force": "true", "reason": "load"},
+			want: map[string]string{"replicas": "0", "force": "true", "reason": "load"},
+		},
+		{args: nil},
+		{args: map[string]string{"force": "true"}},
+		{args: map[string]string{"replicas": "three"}},
+		{args: map[string]string{"replicas": "3", "force": "maybe"}},
+	} {
+		handled = nil
+		res := registry.HandleControlRequest(xfer.Request{Control: "scale", ControlArgs: tc.args})
+		if tc.want == nil {
+			if res.Error == "" || handled != nil {
+				t.Errorf("Expected %v to be rejected before execution, got %v", tc.args, res)
+			}
+			continue
+		}
+		if res.Error != "" {
+			t.Errorf("Expected %v to be valid, got %q", tc.args, res.Error)
+		}
+		if !reflect.DeepEqual(tc.want, handled) {
+			t.Errorf("want %v, have %v", tc.want, handled)
+		}
+	}
+}
+
+func TestControlsTokens(t *testing.T) {
+	now := time.Now()
+	mtime.NowForce(now)
+	defer mtime.NowReset()
+
+	calls := 0
+	registry := controls.NewDefaultHandlerRegistry()
+	registry.Register("restart", func(req xfer.Request) xfer.Response {
+		calls++
+		return xfer.Response{Value: fmt.Sprintf("restart %s #%d", req.NodeID, calls)}
+	})
+
+	handle := func(nodeID, token string) interface{} {
+		return registry.HandleControlRequest(xfer.Request{NodeID: nodeID, Control: "restart", Token: token}).Value
+	}
+	for _, tc := range []struct {
+		advance       time.Duration
+		nodeID, token string
+		want          string
+	}{
+		{0, "a", "t1", "restart a #1"},
+		{time.Second, "a", "t1", "restart a #1"}, // repeated
+		{0, "a", "t2", "restart a #2"},
+		{0, "b", "t1", "restart b #3"}, // tokens are per node
+		{0, "a", "", "restart a #4"},
+		{0, "a", "", "restart a #5"},             // no token, no deduplication
+		{time.Minute, "a", "t1", "restart a #6"}, // expired
+	} {
+		now = now.Add(tc.advance)
+		mtime.NowForce(now)
+		if have := handle(tc.nodeID, tc.token); have != tc.want {
+			t.Errorf("want %q, have %q", tc.want, have)
+		}
+	}
+}
+
+func TestControlsTokensConcurrent(t *testing.T) {
+	var (
+		calls   = 0
+		release = make(chan struct{})
+	)
+	registry := controls.NewDefaultHandlerRegistry()
+	registry.Register("restart", func(req xfer.Request) xfer.Response {
+		calls++
+		<-release
+		return xfer.Response{Value: "restarted"}
+	})
+
+	responses := make(chan xfer.Response)
+	for i := 0; i < 2; i++ {
+		go func() {
+			responses <- registry.HandleControlRequest(xfer.Request{NodeID: "a", Control: "restart", Token: "t1"})
+		}()
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	for i := 0; i < 2; i++ {
+		if res := <-responses; res.Value != "restarted" {
+			t.Errorf("Expected the original response, got %v", res)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("Expected a single execution, got %d", calls)
+	}
+}
@@ -29,7 +29,9 @@ const (
 	ContainerState         = "docker_container_state"
 	ContainerStateHuman    = "docker_container_state_human"
 	ContainerUptime        = "docker_container_uptime"
+	ContainerStartedAt     = "docker_container_started_at"
 	ContainerRestartCount  = "docker_container_restart_count"
+	ContainerRestarts      = "docker_container_restarts"
 	ContainerNetworkMode   = "docker_container_network_mode"
 
 	NetworkRxDropped = "network_rx_dropped"
@@ -52,12 +54,26 @@ const (
 	CPUUsageInKernelmode = "docker_cpu_usage_in_kernelmode"
 	CPUSystemCPUUsage    = "docker_cpu_system_cpu_usage"
 
+	BlockIOReadRate  = "docker_block_io_read_bytes_per_second"
+	BlockIOWriteRate = "docker_block_io_write_bytes_per_second"
+
 	NetworkModeHost = "host"
 
+	// ContainerHealth is the key of the status of the health check of a
+	// container, one of the Health values, for containers with one.
+	ContainerHealth = "docker_container_health"
+	HealthStarting  = "starting"
+	HealthHealthy   = "healthy"
+	HealthUnhealthy = "unhealthy"
+
 	LabelPrefix = "docker_label_"
 	EnvPrefix   = "docker_env_"
 
 	stopTimeout = 10
+
+	// maxRestartHistory is how many of the latest restarts of a container
+	// are reported.
+	maxRestartHistory = 10
 )
 
 // These 'constants' are used for node states.
@@ -103,6 +119,7 @@ type container struct {
 	latestStats            docker.Stats
 	pendingStats           [60]docker.Stats
 	numPending             int
+	restarts               []report.Sample
 	hostID                 string
 	baseNode               report.Node
 	noCommandLineArguments bool
@@ -124,6 +141,15 @@ func NewContainer(c *docker.Container, hostID string, noCommandLineArguments boo
 func (c *container) UpdateState(container *docker.Container) {
 	c.Lock()
 	defer c.Unlock()
+	if container.RestartCount > c.container.RestartCount {
+		c.restarts = append(c.restarts, report.Sample{
+			Timestamp: container.State.StartedAt,
+			Value:     float64(container.RestartCount),
+		})
+		if len(c.restarts) > maxRestartHistory {
+			c.restarts = c.restarts[len(c.restarts)-maxRestartHistory:]
+		}
+	}
 	c.container = container
 }
 
@@ -345,6 +371,46 @@ func (c *container) cpuPercentMetric(stats []docker.Stats) report.Metric {
 	return report.MakeMetric(samples).WithMax(100.0)
 }
 
+// blockIOBytes sums the bytes read and written by the container across
+// devices. ok is false if the stats carry no block I/O accounting.
+func blockIOBytes(s docker.Stats) (read, written uint64, ok bool) {
+	for _, entry := range s.BlkioStats.IOServiceBytesRecursive {
+		switch entry.Op {
+		case "Read":
+			read += entry.Value
+		case "Write":
+			written += entry.Value
+		}
+	}
+	return read, written, len(s.BlkioStats.IOServiceBytesRecursive) > 0
+}
+
+// blockIORateMetrics returns the rates at which the container reads and
+// writes to block devices, if docker accounts for them.
+func (c *container) blockIORateMetrics(stats []docker.Stats) (read, write report.Metric, ok bool) {
+	if len(stats) < 2 {
+		return read, write, false
+	}
+	readSamples := make([]report.Sample, 0, len(stats)-1)
+	writeSamples := make([]report.Sample, 0, len(stats)-1)
+	previous := stats[0]
+	for _, s := range stats[1:] {
+		prevRead, prevWritten, prevOK := blockIOBytes(previous)
+		curRead, curWritten, curOK := blockIOBytes(s)
+		seconds := s.Read.Sub(previous.Read).Seconds()
+		previous = s
+		if !prevOK || !curOK || seconds <= 0 || curRead < prevRead || curWritten < prevWritten {
+			continue
+		}
+		readSamples = append(readSamples, report.Sample{Timestamp: s.Read, Value: float64(curRead-prevRead) / seconds})
+		writeSamples = append(writeSamples, report.Sample{Timestamp: s.Read, Value: float64(curWritten-prevWritten) / seconds})
+	}
+	if len(readSamples) == 0 {
+		return read, write, false
+	}
+	return report.MakeMetric(readSamples), report.MakeMetric(writeSamples), true
+}
+
 func (c *container) metrics() report.Metrics {
 	if c.numPending == 0 {
 		return report.Metrics{}
@@ -354,6 +420,10 @@ func (c *container) metrics() report.Metrics {
 		MemoryUsage:   c.memoryUsageMetric(pendingStats),
 		CPUTotalUsage: c.cpuPercentMetric(pendingStats),
 	}
+	if read, write, ok := c.blockIORateMetrics(pendingStats); ok {
+		result[BlockIOReadRate] = read
+		result[BlockIOWriteRate] = write
+	}
 
 	// leave one stat to help with relative metrics
 	c.pendingStats[0] = c.pendingStats[c.numPending-1]
@@ -411,6 +481,7 @@ func (c *container) controlsMap() map[string]report.NodeControlData {
 		ExecContainer:    {Dead: !running},
 		StartContainer:   {Dead: !stopped},
 		RemoveContainer:  {Dead: !stopped},
+		InspectContainer: {Dead: false},
 	}
 }
 
@@ -431,6 +502,7 @@ func (c *container) GetNode() report.Node {
 			networkMode = c.container.HostConfig.NetworkMode
 		}
 		latest[ContainerUptime] = uptime.String()
+		latest[ContainerStartedAt] = c.container.State.StartedAt.Format(time.RFC3339Nano)
 		latest[ContainerRestartCount] = strconv.Itoa(c.container.RestartCount)
 		latest[ContainerNetworkMode] = networkMode
 	}
@@ -438,6 +510,12 @@ func (c *container) GetNode() report.Node {
 	result := c.baseNode.WithLatests(latest)
 	result = result.WithLatestControls(controls)
 	result = result.WithMetrics(c.metrics())
+	if len(c.restarts) > 0 {
+		// The restart history is the series of the restart count, with a
+		// sample at each time the container started again.
+		restarts := append([]report.Sample(nil), c.restarts...)
+		result = result.WithMetric(ContainerRestarts, report.MakeMetric(restarts))
+	}
 	return result
 }
 
@@ -68,6 +68,7 @@ func TestContainer(t *testing.T) {
 			docker.ExecContainer:    {Dead: false},
 			docker.StartContainer:   {Dead: true},
 			docker.RemoveContainer:  {Dead: true},
+			docker.InspectContainer: {Dead: false},
 		}
 		want := report.MakeNodeWith("ping;<container>", map[string]string{
 			"docker_container_command":     "ping foo.bar.local",
@@ -80,6 +81,7 @@ func TestContainer(t *testing.T) {
 			"docker_container_state":       "running",
 			"docker_container_state_human": c.Container().State.String(),
 			"docker_container_uptime":      uptime.String(),
+			"docker_container_started_at":  startTime.Format(time.RFC3339Nano),
 			"docker_env_FOO":               "secret-bar",
 		}).WithLatestControls(
 			controls,
@@ -128,6 +130,97 @@ func TestContainer(t *testing.T) {
 	}
 }
 
+func TestContainerBlockIO(t *testing.T) {
+	now := time.Unix(12345, 67890).UTC()
+	mtime.NowForce(now)
+	defer mtime.NowReset()
+
+	c := docker.NewContainer(container1, "scope", false, false)
+	s := newMockStatsGatherer()
+	if err := c.StartGatheringStats(s); err != nil {
+		t.Errorf("%v", err)
+	}
+	defer c.StopGatheringStats()
+
+	blockIOStats := func(read time.Time, readBytes, writtenBytes uint64) *client.Stats {
+		stats := &client.Stats{}
+		stats.Read = read
+		stats.BlkioStats.IOServiceBytesRecursive = []client.BlkioStatsEntry{
+			{Major: 8, Op: "Read", Value: readBytes / 2},
+			{Major: 8, Op: "Write", Value: writtenBytes},
+			{Major: 9, Op: "Read", Value: readBytes / 2},
+			{Major: 9, Op: "Total", Value: readBytes + writtenBytes},
+		}
+		return stats
+	}
+	s.Send(blockIOStats(now, 1000, 500))
+	s.Send(blockIOStats(now.Add(2*time.Second), 5000, 700))
+
+	rates := func() interface{} {
+		result := []float64{}
+		metrics := c.GetNode().Metrics
+		for _, id := range []string{docker.BlockIOReadRate, docker.BlockIOWriteRate} {
+			if sample, ok := metrics[id].LastSample(); ok {
+				result = append(result, sample.Value)
+			}
+		}
+		return result
+	}
+	test.Poll(t, 100*time.Millisecond, []float64{2000, 100}, rates)
+}
+
+func TestContainerWithoutBlockIO(t *testing.T) {
+	now := time.Unix(12345, 67890).UTC()
+	c := docker.NewContainer(container1, "scope", false, false)
+	s := newMockStatsGatherer()
+	if err := c.StartGatheringStats(s); err != nil {
+		t.Errorf("%v", err)
+	}
+	defer c.StopGatheringStats()
+
+	for _, read := range []time.Time{now, now.Add(time.Second)} {
+		stats := &client.Stats{}
+		stats.Read = read
+		s.Send(stats)
+	}
+	// Both stats are in once the CPU usage, relative between stats, has
+	// a sample.
+	var node report.Node
+	test.Poll(t, 100*time.Millisecond, true, func() interface{} {
+		node = c.GetNode()
+		return node.Metrics[docker.CPUTotalUsage].Len() > 0
+	})
+	if _, ok := node.Metrics[docker.BlockIOReadRate]; ok {
+		t.Errorf("Expected no block I/O metrics without block I/O accounting")
+	}
+}
+
+func TestContainerRestarts(t *testing.T) {
+	c := docker.NewContainer(container1, "scope", false, false)
+	if _, ok := c.GetNode().Metrics[docker.ContainerRestarts]; ok {
+		t.Errorf("Expected no restart history before the container restarts")
+	}
+
+	restarted := *container1
+	for i, startedAt := range []time.Time{startTime.Add(time.Minute), startTime.Add(3 * time.Minute)} {
+		restarted.RestartCount = i + 1
+		restarted.State.StartedAt = startedAt
+		update := restarted
+		c.UpdateState(&update)
+	}
+	// Updates without a restart leave the history alone.
+	update := restarted
+	c.UpdateState(&update)
+
+	want := []report.Sample{
+		{Timestamp: startTime.Add(time.Minute), Value: 1},
+		{Timestamp: startTime.Add(3 * time.Minute), Value: 2},
+	}
+	if have := c.GetNode().Metrics[docker.ContainerRestarts].Samples; !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
+
 func TestContainerHidingArgs(t *testing.T) {
 	const hostID = "scope"
 	c := docker.NewContainer(container1, hostID, true, false)
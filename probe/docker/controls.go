@@ -1,6 +1,9 @@
 package docker
 
 import (
+	"encoding/json"
+	"strings"
+
 	docker_client "github.com/fsouza/go-dockerclient"
 
 	log "github.com/Sirupsen/logrus"
@@ -20,6 +23,7 @@ const (
 	RemoveContainer  = "docker_remove_container"
 	AttachContainer  = "docker_attach_container"
 	ExecContainer    = "docker_exec_container"
+	InspectContainer = "docker_inspect_container"
 	ResizeExecTTY    = "docker_resize_exec_tty"
 
 	waitTime = 10
@@ -62,6 +66,20 @@ func (r *registry) removeContainer(containerID string, req xfer.Request) xfer.Re
 	}
 }
 
+// inspectContainer describes the container as the Docker API does, in
+// JSON. It only reads the container, so its responses can be cached.
+func (r *registry) inspectContainer(containerID string, _ xfer.Request) xfer.Response {
+	c, err := r.client.InspectContainer(containerID)
+	if err != nil {
+		return xfer.ResponseError(err)
+	}
+	buf, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return xfer.ResponseError(err)
+	}
+	return xfer.Response{Value: string(buf)}
+}
+
 func (r *registry) attachContainer(containerID string, req xfer.Request) xfer.Response {
 	c, ok := r.GetContainer(containerID)
 	if !ok {
@@ -106,13 +124,24 @@ func (r *registry) attachContainer(containerID string, req xfer.Request) xfer.Re
 	}
 }
 
+// execCommand is the shell command exec runs, starting root's shell, in
+// workingDir if given. The Docker API we use has no working directory for
+// execs, so the shell changes to it.
+func execCommand(workingDir string) string {
+	command := "TERM=xterm exec $( (type getent > /dev/null 2>&1  && getent passwd root | cut -d: -f7 2>/dev/null) || echo /bin/sh)"
+	if workingDir == "" {
+		return command
+	}
+	return "cd '" + strings.Replace(workingDir, "'", `'\''`, -1) + "' && " + command
+}
+
 func (r *registry) execContainer(containerID string, req xfer.Request) xfer.Response {
 	exec, err := r.client.CreateExec(docker_client.CreateExecOptions{
 		AttachStdin:  true,
 		AttachStdout: true,
 		AttachStderr: true,
 		Tty:          true,
-		Cmd:          []string{"/bin/sh", "-l", "-c", "TERM=xterm exec $( (type getent > /dev/null 2>&1  && getent passwd root | cut -d: -f7 2>/dev/null) || echo /bin/sh)"},
+		Cmd:          []string{"/bin/sh", "-l", "-c", execCommand(req.WorkingDir)},
 		Container:    containerID,
 	})
 	if err != nil {
@@ -200,6 +229,7 @@ func (r *registry) registerControls() {
 		RemoveContainer:  captureContainerID(r.removeContainer),
 		AttachContainer:  captureContainerID(r.attachContainer),
 		ExecContainer:    captureContainerID(r.execContainer),
+		InspectContainer: captureContainerID(r.inspectContainer),
 		ResizeExecTTY:    xfer.ResizeTTYControlWrapper(r.resizeExecTTY),
 	}
 	r.handlerRegistry.Batch(nil, controls)
@@ -215,6 +245,7 @@ func (r *registry) deregisterControls() {
 		RemoveContainer,
 		AttachContainer,
 		ExecContainer,
+		InspectContainer,
 		ResizeExecTTY,
 	}
 	r.handlerRegistry.Batch(controls, nil)
@@ -1,8 +1,10 @@
 package docker_test
 
 import (
+	"encoding/json"
 	"io"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -104,3 +106,85 @@ func TestPipes(t *testing.T) {
 		}
 	})
 }
+
+func TestExecWorkingDir(t *testing.T) {
+	oldNewPipe := controls.NewPipe
+	defer func() { controls.NewPipe = oldNewPipe }()
+	controls.NewPipe = func(_ controls.PipeClient, _ string) (string, xfer.Pipe, error) {
+		return "pipeid", mockPipe{}, nil
+	}
+
+	mdc := newMockClient()
+	setupStubs(mdc, func() {
+		hr := controls.NewDefaultHandlerRegistry()
+		registry, _ := docker.NewRegistry(docker.RegistryOptions{
+			Interval:        10 * time.Second,
+			HandlerRegistry: hr,
+		})
+		defer registry.Stop()
+
+		test.Poll(t, 100*time.Millisecond, true, func() interface{} {
+			_, ok := registry.GetContainer("ping")
+			return ok
+		})
+
+		for _, workingDir := range []string{"", "/srv/it's here"} {
+			result := hr.HandleControlRequest(xfer.Request{
+				Control:    docker.ExecContainer,
+				NodeID:     report.MakeContainerNodeID("ping"),
+				WorkingDir: workingDir,
+			})
+			if result.Error != "" {
+				t.Fatal(result.Error)
+			}
+		}
+		mdc.RLock()
+		defer mdc.RUnlock()
+		if len(mdc.execs) != 2 {
+			t.Fatalf("Expected two execs, got %d", len(mdc.execs))
+		}
+		plain, inDir := mdc.execs[0].Cmd, mdc.execs[1].Cmd
+		if strings.Contains(plain[len(plain)-1], "cd ") {
+			t.Errorf("Expected no change of directory without a working dir: %v", plain)
+		}
+		if want := `cd '/srv/it'\''s here' && `; !strings.HasPrefix(inDir[len(inDir)-1], want) {
+			t.Errorf("Expected the command to start with %q: %v", want, inDir)
+		}
+	})
+}
+
+func TestInspectControl(t *testing.T) {
+	mdc := newMockClient()
+	setupStubs(mdc, func() {
+		hr := controls.NewDefaultHandlerRegistry()
+		registry, _ := docker.NewRegistry(docker.RegistryOptions{
+			Interval:        10 * time.Second,
+			HandlerRegistry: hr,
+		})
+		defer registry.Stop()
+
+		result := hr.HandleControlRequest(xfer.Request{
+			Control: docker.InspectContainer,
+			NodeID:  report.MakeContainerNodeID("ping"),
+		})
+		if result.Error != "" {
+			t.Fatal(result.Error)
+		}
+		value, _ := result.Value.(string)
+		var inspected struct{ ID, Name string }
+		if err := json.Unmarshal([]byte(value), &inspected); err != nil {
+			t.Fatal(err)
+		}
+		if inspected.ID != "ping" || inspected.Name != "pong" {
+			t.Errorf("Expected the container to be described, got %s", value)
+		}
+
+		result = hr.HandleControlRequest(xfer.Request{
+			Control: docker.InspectContainer,
+			NodeID:  report.MakeContainerNodeID("unknown"),
+		})
+		if result.Error == "" {
+			t.Error("Expected an error for an unknown container")
+		}
+	})
+}
@@ -96,6 +96,7 @@ type mockDockerClient struct {
 	apiImages     []client.APIImages
 	networks      []client.Network
 	events        []chan<- *client.APIEvents
+	execs         []client.CreateExecOptions
 }
 
 func (m *mockDockerClient) ListContainers(client.ListContainersOptions) ([]client.APIContainers, error) {
@@ -185,7 +186,10 @@ func (m *mockDockerClient) AttachToContainerNonBlocking(_ client.AttachToContain
 	return mockCloseWaiter{}, nil
 }
 
-func (m *mockDockerClient) CreateExec(client.CreateExecOptions) (*client.Exec, error) {
+func (m *mockDockerClient) CreateExec(opts client.CreateExecOptions) (*client.Exec, error) {
+	m.Lock()
+	defer m.Unlock()
+	m.execs = append(m.execs, opts)
 	return &client.Exec{ID: "id"}, nil
 }
 
@@ -271,8 +275,9 @@ var (
 	apiContainer2       = client.APIContainers{ID: "wiff"}
 	renamedAPIContainer = client.APIContainers{ID: "renamed"}
 	apiImage1           = client.APIImages{
-		ID:       "baz",
-		RepoTags: []string{"bang", "not-chosen"},
+		ID:          "baz",
+		RepoTags:    []string{"bang", "not-chosen"},
+		RepoDigests: []string{"bang@sha256:0123456789abcdef"},
 		Labels: map[string]string{
 			"imgfoo1": "bar1",
 			"imgfoo2": "bar2",
@@ -17,6 +17,7 @@ const (
 	ImageID          = "docker_image_id"
 	ImageName        = "docker_image_name"
 	ImageSize        = "docker_image_size"
+	ImageDigest      = "docker_image_digest"
 	ImageVirtualSize = "docker_image_virtual_size"
 	ImageLabelPrefix = "docker_image_label_"
 	IsInHostNetwork  = "docker_is_in_host_network"
@@ -44,10 +45,14 @@ var (
 	ContainerMetricTemplates = report.MetricTemplates{
 		CPUTotalUsage: {ID: CPUTotalUsage, Label: "CPU", Format: report.PercentFormat, Priority: 1},
 		MemoryUsage:   {ID: MemoryUsage, Label: "Memory", Format: report.FilesizeFormat, Priority: 2},
+
+		BlockIOReadRate:  {ID: BlockIOReadRate, Label: "Disk Read/s", Format: report.FilesizeFormat, Priority: 3},
+		BlockIOWriteRate: {ID: BlockIOWriteRate, Label: "Disk Write/s", Format: report.FilesizeFormat, Priority: 4},
 	}
 
 	ContainerImageMetadataTemplates = report.MetadataTemplates{
 		report.Container: {ID: report.Container, Label: "# Containers", From: report.FromCounters, Datatype: "number", Priority: 2},
+		ImageDigest:      {ID: ImageDigest, Label: "Digest", From: report.FromLatest, Truncate: 19, Priority: 3},
 	}
 
 	ContainerTableTemplates = report.TableTemplates{
@@ -134,6 +139,13 @@ var (
 			Icon:  "fa-trash-o",
 			Rank:  8,
 		},
+		{
+			ID:       InspectContainer,
+			Human:    "Inspect",
+			Icon:     "fa-info-circle",
+			Rank:     9,
+			ReadOnly: true,
+		},
 	}
 
 	SwarmServiceMetadataTemplates = report.MetadataTemplates{
@@ -280,6 +292,9 @@ func (r *Reporter) containerImageTopology() report.Topology {
 		if len(image.RepoTags) > 0 {
 			latests[ImageName] = image.RepoTags[0]
 		}
+		if digest, ok := imageDigest(image); ok {
+			latests[ImageDigest] = digest
+		}
 		nodeID := report.MakeContainerImageNodeID(imageID)
 		node := report.MakeNodeWith(nodeID, latests)
 		node = node.AddPrefixPropertyList(ImageLabelPrefix, image.Labels)
@@ -308,6 +323,18 @@ func (r *Reporter) swarmServiceTopology() report.Topology {
 	return report.MakeTopology().WithMetadataTemplates(SwarmServiceMetadataTemplates)
 }
 
+// imageDigest returns the content digest of the image, e.g. sha256:..., from
+// the first of its repository digests. Images which were never pulled from
+// or pushed to a registry have none.
+func imageDigest(image docker_client.APIImages) (string, bool) {
+	for _, repoDigest := range image.RepoDigests {
+		if i := strings.LastIndex(repoDigest, "@"); i >= 0 {
+			return repoDigest[i+1:], true
+		}
+	}
+	return "", false
+}
+
 // Docker sometimes prefixes ids with a "type" annotation, but it renders a bit
 // ugly and isn't necessary, so we should strip it off
 func trimImageID(id string) string {
@@ -118,6 +118,7 @@ func TestReporter(t *testing.T) {
 		for k, want := range map[string]string{
 			docker.ImageID:                      imageID,
 			docker.ImageName:                    "bang",
+			docker.ImageDigest:                  "sha256:0123456789abcdef",
 			docker.ImageLabelPrefix + "imgfoo1": "bar1",
 			docker.ImageLabelPrefix + "imgfoo2": "bar2",
 		} {
@@ -101,12 +101,15 @@ func (t *connectionTracker) ReportConnections(rpt *report.Report) {
 
 func (t *connectionTracker) performFlowWalk(rpt *report.Report, seenTuples *map[string]fourTuple) {
 	// Consult the flowWalker for short-lived connections
-	extraNodeInfo := map[string]string{
-		Conntracked: "true",
-	}
 	t.flowWalker.walkFlows(func(f flow, alive bool) {
 		tuple := flowToTuple(f)
 		(*seenTuples)[tuple.key()] = tuple
+		extraNodeInfo := map[string]string{
+			Conntracked: "true",
+		}
+		if f.Independent.State != "" {
+			extraNodeInfo[ConnectionState] = f.Independent.State
+		}
 		t.addConnection(rpt, tuple, "", extraNodeInfo, extraNodeInfo)
 	})
 }
@@ -12,12 +12,16 @@ import (
 // Node metadata keys.
 const (
 	Conntracked     = "conntracked"
+	ConnectionState = "conn_state"
 	EBPF            = "eBPF"
 	Procspied       = "procspied"
 	ReverseDNSNames = "reverse_dns_names"
 	SnoopedDNSNames = "snooped_dns_names"
 )
 
+// StateEstablished is the ConnectionState of an established TCP connection.
+const StateEstablished = "ESTABLISHED"
+
 // ReporterConfig are the config options for the endpoint reporter.
 type ReporterConfig struct {
 	HostID       string
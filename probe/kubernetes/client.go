@@ -31,6 +31,7 @@ type Client interface {
 	WalkDaemonSets(f func(DaemonSet) error) error
 	WalkReplicationControllers(f func(ReplicationController) error) error
 	WalkNodes(f func(*api.Node) error) error
+	WalkEvents(f func(*api.Event) error) error
 
 	WatchPods(f func(Event, Pod))
 
@@ -38,6 +39,9 @@ type Client interface {
 	DeletePod(namespaceID, podID string) error
 	ScaleUp(resource, namespaceID, id string) error
 	ScaleDown(resource, namespaceID, id string) error
+	Scale(resource, namespaceID, id string, replicas int) error
+	CordonNode(name string) error
+	UncordonNode(name string) error
 }
 
 type client struct {
@@ -52,6 +56,7 @@ type client struct {
 	daemonSetStore             *cache.StoreToDaemonSetLister
 	replicationControllerStore *cache.StoreToReplicationControllerLister
 	nodeStore                  *cache.StoreToNodeLister
+	eventStore                 cache.Store
 
 	podWatchesMutex sync.Mutex
 	podWatches      []func(Event, Pod)
@@ -157,6 +162,7 @@ func NewClient(config ClientConfig) (Client, error) {
 	result.serviceStore = &cache.StoreToServiceLister{Store: result.setupStore(c, "services", &api.Service{}, nil)}
 	result.replicationControllerStore = &cache.StoreToReplicationControllerLister{Store: result.setupStore(c, "replicationcontrollers", &api.ReplicationController{}, nil)}
 	result.nodeStore = &cache.StoreToNodeLister{Store: result.setupStore(c, "nodes", &api.Node{}, nil)}
+	result.eventStore = result.setupStore(c, "events", &api.Event{}, nil)
 
 	// We list deployments here to check if this version of kubernetes is >= 1.2.
 	// We would use NegotiateVersion, but Kubernetes 1.1 "supports"
@@ -300,6 +306,16 @@ func (c *client) WalkNodes(f func(*api.Node) error) error {
 	return nil
 }
 
+// WalkEvents calls f for each event
+func (c *client) WalkEvents(f func(*api.Event) error) error {
+	for _, e := range c.eventStore.List() {
+		if err := f(e.(*api.Event)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (c *client) GetLogs(namespaceID, podID string) (io.ReadCloser, error) {
 	return c.client.RESTClient.Get().
 		Namespace(namespaceID).
@@ -331,6 +347,12 @@ func (c *client) ScaleDown(resource, namespaceID, id string) error {
 	})
 }
 
+func (c *client) Scale(resource, namespaceID, id string, replicas int) error {
+	return c.modifyScale(resource, namespaceID, id, func(scale *extensions.Scale) {
+		scale.Spec.Replicas = replicas
+	})
+}
+
 func (c *client) modifyScale(resource, namespace, id string, f func(*extensions.Scale)) error {
 	scaler := c.extensionsClient.Scales(namespace)
 	scale, err := scaler.Get(resource, id)
@@ -342,6 +364,28 @@ func (c *client) modifyScale(resource, namespace, id string, f func(*extensions.
 	return err
 }
 
+func (c *client) CordonNode(name string) error {
+	return c.modifyNode(name, func(node *api.Node) {
+		node.Spec.Unschedulable = true
+	})
+}
+
+func (c *client) UncordonNode(name string) error {
+	return c.modifyNode(name, func(node *api.Node) {
+		node.Spec.Unschedulable = false
+	})
+}
+
+func (c *client) modifyNode(name string, f func(*api.Node)) error {
+	node, err := c.client.Nodes().Get(name)
+	if err != nil {
+		return err
+	}
+	f(node)
+	_, err = c.client.Nodes().Update(node)
+	return err
+}
+
 func (c *client) Stop() {
 	close(c.quit)
 }
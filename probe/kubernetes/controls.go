@@ -3,6 +3,7 @@ package kubernetes
 import (
 	"io"
 	"io/ioutil"
+	"strconv"
 
 	"github.com/weaveworks/scope/common/xfer"
 	"github.com/weaveworks/scope/probe/controls"
@@ -15,8 +16,16 @@ const (
 	DeletePod = "kubernetes_delete_pod"
 	ScaleUp   = "kubernetes_scale_up"
 	ScaleDown = "kubernetes_scale_down"
+	Scale     = "kubernetes_scale"
+
+	CordonNode   = "kubernetes_cordon_node"
+	UncordonNode = "kubernetes_uncordon_node"
 )
 
+// ScaleReplicas is the argument of the Scale control holding the desired
+// number of replicas.
+const ScaleReplicas = "replicas"
+
 // GetLogs is the control to get the logs for a kubernetes pod
 func (r *Reporter) GetLogs(req xfer.Request, namespaceID, podID string) xfer.Response {
 	readCloser, err := r.client.GetLogs(namespaceID, podID)
@@ -143,13 +152,53 @@ func (r *Reporter) ScaleDown(req xfer.Request, resource, namespace, id string) x
 	return xfer.ResponseError(r.client.ScaleDown(resource, namespace, id))
 }
 
+// Scale is the control to scale a deployment or replica set to the number
+// of replicas passed in the ScaleReplicas argument
+func (r *Reporter) Scale(req xfer.Request, resource, namespace, id string) xfer.Response {
+	replicas, err := strconv.Atoi(req.ControlArgs[ScaleReplicas])
+	if err != nil || replicas < 0 {
+		return xfer.ResponseErrorf("Invalid number of replicas: %q", req.ControlArgs[ScaleReplicas])
+	}
+	return xfer.ResponseError(r.client.Scale(resource, namespace, id, replicas))
+}
+
+// CaptureNode is exported for testing
+func (r *Reporter) CaptureNode(f func(xfer.Request, string) xfer.Response) func(xfer.Request) xfer.Response {
+	return func(req xfer.Request) xfer.Response {
+		if req.NodeID != report.MakeHostNodeID(r.hostID) {
+			return xfer.ResponseErrorf("Invalid ID: %s", req.NodeID)
+		}
+		r.nodeNameMtx.Lock()
+		nodeName := r.nodeName
+		r.nodeNameMtx.Unlock()
+		if nodeName == "" {
+			return xfer.ResponseErrorf("Node not found: %s", req.NodeID)
+		}
+		return f(req, nodeName)
+	}
+}
+
+// CordonNode is the control to mark the local node as unschedulable
+func (r *Reporter) CordonNode(req xfer.Request, nodeName string) xfer.Response {
+	return xfer.ResponseError(r.client.CordonNode(nodeName))
+}
+
+// UncordonNode is the control to mark the local node as schedulable
+func (r *Reporter) UncordonNode(req xfer.Request, nodeName string) xfer.Response {
+	return xfer.ResponseError(r.client.UncordonNode(nodeName))
+}
+
 func (r *Reporter) registerControls() {
 	controls := map[string]xfer.ControlHandlerFunc{
-		GetLogs:   r.CapturePod(r.GetLogs),
-		DeletePod: r.CapturePod(r.deletePod),
-		ScaleUp:   r.CaptureResource(r.ScaleUp),
-		ScaleDown: r.CaptureResource(r.ScaleDown),
+		GetLogs:      r.CapturePod(r.GetLogs),
+		DeletePod:    r.CapturePod(r.deletePod),
+		ScaleUp:      r.CaptureResource(r.ScaleUp),
+		ScaleDown:    r.CaptureResource(r.ScaleDown),
+		Scale:        r.CaptureResource(r.Scale),
+		CordonNode:   r.CaptureNode(r.CordonNode),
+		UncordonNode: r.CaptureNode(r.UncordonNode),
 	}
+	r.handlerRegistry.ValidateParams(ScalingControls...)
 	r.handlerRegistry.Batch(nil, controls)
 }
 
@@ -159,6 +208,9 @@ func (r *Reporter) deregisterControls() {
 		DeletePod,
 		ScaleUp,
 		ScaleDown,
+		Scale,
+		CordonNode,
+		UncordonNode,
 	}
 	r.handlerRegistry.Batch(controls, nil)
 }
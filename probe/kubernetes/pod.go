@@ -1,23 +1,88 @@
 package kubernetes
 
 import (
+	"sort"
+	"strconv"
+	"strings"
+
 	"github.com/weaveworks/scope/report"
 	"k8s.io/kubernetes/pkg/api"
 )
 
 // These constants are keys used in node metadata
 const (
-	State           = "kubernetes_state"
-	IsInHostNetwork = "kubernetes_is_in_host_network"
+	State            = "kubernetes_state"
+	IsInHostNetwork  = "kubernetes_is_in_host_network"
+	PhaseTransitions = "kubernetes_phase_transitions"
 
 	StateDeleted = "deleted"
 )
 
+// These constants are keys used in the metadata of pods, holding how they
+// are scheduled: whether they are yet, SchedulingScheduled or
+// SchedulingPending, and the node selector they are scheduled by, as
+// comma-separated key=value pairs. Scheduled pods also have the NodeName
+// of their node.
+const (
+	SchedulingStatus = "kubernetes_scheduling_status"
+	NodeSelector     = "kubernetes_node_selector"
+
+	SchedulingScheduled = "scheduled"
+	SchedulingPending   = "pending"
+)
+
+// These constants are keys used in the metadata of the containers of pods,
+// holding their resource requests and limits. CPU is in millicores, memory
+// in bytes.
+const (
+	CPURequest    = "kubernetes_cpu_request"
+	CPULimit      = "kubernetes_cpu_limit"
+	MemoryRequest = "kubernetes_memory_request"
+	MemoryLimit   = "kubernetes_memory_limit"
+)
+
+// These constants are keys used in the metadata of the containers of pods
+// with readiness or liveness probes, holding the status of the probes,
+// ProbePassing or ProbeFailing.
+const (
+	ReadinessProbe = "kubernetes_readiness_probe"
+	LivenessProbe  = "kubernetes_liveness_probe"
+
+	ProbePassing = "passing"
+	ProbeFailing = "failing"
+)
+
+// These constants are keys used in the metadata of the containers of pods
+// with a security context, holding the user ID they run as, whether they
+// are privileged, "true" or "false", and the capabilities they add and
+// drop, as comma-separated lists.
+const (
+	RunAsUser           = "kubernetes_run_as_user"
+	Privileged          = "kubernetes_privileged"
+	CapabilitiesAdded   = "kubernetes_capabilities_added"
+	CapabilitiesDropped = "kubernetes_capabilities_dropped"
+)
+
+// EventPrefix prefixes the keys of the events of pods in their metadata, e.g.
+// Scheduled, Pulled, Started or Killing, one key per event. The values are
+// the reason and message of the events, as "Reason: message", set at the
+// time the events last happened.
+const EventPrefix = "kubernetes_event_"
+
+// ImagePullPolicy is a key used in the metadata of the containers of pods,
+// holding the pull policy of their image: Always, IfNotPresent or Never.
+const ImagePullPolicy = "kubernetes_image_pull_policy"
+
 // Pod represents a Kubernetes pod
 type Pod interface {
 	Meta
 	AddParent(topology, id string)
 	NodeName() string
+	State() string
+	ContainerResources(name string) map[string]string
+	ContainerProbes(name string) map[string]string
+	ContainerPullPolicy(name string) (string, bool)
+	ContainerSecurityContext(name string) map[string]string
 	GetNode(probeID string) report.Node
 }
 
@@ -57,6 +122,118 @@ func (p *pod) NodeName() string {
 	return p.Spec.NodeName
 }
 
+// ContainerResources returns the resource requests and limits set on the
+// container of the pod with the given name, keyed by CPURequest etc.
+func (p *pod) ContainerResources(name string) map[string]string {
+	result := map[string]string{}
+	for _, c := range p.Spec.Containers {
+		if c.Name != name {
+			continue
+		}
+		if q, ok := c.Resources.Requests[api.ResourceCPU]; ok {
+			result[CPURequest] = strconv.FormatInt(q.MilliValue(), 10)
+		}
+		if q, ok := c.Resources.Limits[api.ResourceCPU]; ok {
+			result[CPULimit] = strconv.FormatInt(q.MilliValue(), 10)
+		}
+		if q, ok := c.Resources.Requests[api.ResourceMemory]; ok {
+			result[MemoryRequest] = strconv.FormatInt(q.Value(), 10)
+		}
+		if q, ok := c.Resources.Limits[api.ResourceMemory]; ok {
+			result[MemoryLimit] = strconv.FormatInt(q.Value(), 10)
+		}
+	}
+	return result
+}
+
+// ContainerProbes returns the status of the readiness and liveness probes
+// of the container of the pod with the given name, keyed by ReadinessProbe
+// and LivenessProbe. Kubernetes doesn't keep the result of liveness probes,
+// but restarts containers failing them, so a liveness probe is taken to be
+// passing while its container is running.
+func (p *pod) ContainerProbes(name string) map[string]string {
+	result := map[string]string{}
+	var status *api.ContainerStatus
+	for i := range p.Status.ContainerStatuses {
+		if p.Status.ContainerStatuses[i].Name == name {
+			status = &p.Status.ContainerStatuses[i]
+		}
+	}
+	if status == nil {
+		return result
+	}
+	for _, c := range p.Spec.Containers {
+		if c.Name != name {
+			continue
+		}
+		if c.ReadinessProbe != nil {
+			result[ReadinessProbe] = probeStatus(status.Ready)
+		}
+		if c.LivenessProbe != nil {
+			result[LivenessProbe] = probeStatus(status.State.Running != nil)
+		}
+	}
+	return result
+}
+
+// ContainerPullPolicy returns the image pull policy of the container of the
+// pod with the given name, if set.
+func (p *pod) ContainerPullPolicy(name string) (string, bool) {
+	for _, c := range p.Spec.Containers {
+		if c.Name == name && c.ImagePullPolicy != "" {
+			return string(c.ImagePullPolicy), true
+		}
+	}
+	return "", false
+}
+
+// ContainerSecurityContext returns the security context of the container of
+// the pod with the given name, keyed by RunAsUser etc. Containers run as the
+// user of the pod, unless they set their own.
+func (p *pod) ContainerSecurityContext(name string) map[string]string {
+	result := map[string]string{}
+	if sc := p.Spec.SecurityContext; sc != nil && sc.RunAsUser != nil {
+		result[RunAsUser] = strconv.FormatInt(*sc.RunAsUser, 10)
+	}
+	for _, c := range p.Spec.Containers {
+		if c.Name != name || c.SecurityContext == nil {
+			continue
+		}
+		sc := c.SecurityContext
+		if sc.RunAsUser != nil {
+			result[RunAsUser] = strconv.FormatInt(*sc.RunAsUser, 10)
+		}
+		if sc.Privileged != nil {
+			result[Privileged] = strconv.FormatBool(*sc.Privileged)
+		}
+		if sc.Capabilities != nil {
+			if len(sc.Capabilities.Add) > 0 {
+				result[CapabilitiesAdded] = joinCapabilities(sc.Capabilities.Add)
+			}
+			if len(sc.Capabilities.Drop) > 0 {
+				result[CapabilitiesDropped] = joinCapabilities(sc.Capabilities.Drop)
+			}
+		}
+	}
+	return result
+}
+
+func joinCapabilities(capabilities []api.Capability) string {
+	names := make([]string, len(capabilities))
+	for i, c := range capabilities {
+		names[i] = string(c)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+func probeStatus(passing bool) string {
+	if passing {
+		return ProbePassing
+	}
+	return ProbeFailing
+}
+
 func (p *pod) GetNode(probeID string) report.Node {
 	latests := map[string]string{
 		State: p.State(),
@@ -68,6 +245,20 @@ func (p *pod) GetNode(probeID string) report.Node {
 		latests[IsInHostNetwork] = "true"
 	}
 
+	latests[SchedulingStatus] = SchedulingPending
+	if p.Spec.NodeName != "" {
+		latests[SchedulingStatus] = SchedulingScheduled
+		latests[NodeName] = p.Spec.NodeName
+	}
+	if len(p.Spec.NodeSelector) > 0 {
+		selector := make([]string, 0, len(p.Spec.NodeSelector))
+		for k, v := range p.Spec.NodeSelector {
+			selector = append(selector, k+"="+v)
+		}
+		sort.Strings(selector)
+		latests[NodeSelector] = strings.Join(selector, ", ")
+	}
+
 	return p.MetaNode(report.MakePodNodeID(p.UID())).WithLatests(latests).
 		WithParents(p.parents).
 		WithLatestActiveControls(GetLogs, DeletePod)
@@ -58,5 +58,5 @@ func (r *replicaSet) GetNode(probeID string) report.Node {
 		DesiredReplicas:       fmt.Sprint(r.Spec.Replicas),
 		FullyLabeledReplicas:  fmt.Sprint(r.Status.FullyLabeledReplicas),
 		report.ControlProbeID: probeID,
-	}).WithParents(r.parents).WithLatestActiveControls(ScaleUp, ScaleDown)
+	}).WithParents(r.parents).WithLatestActiveControls(ScaleUp, ScaleDown, Scale)
 }
@@ -2,8 +2,12 @@ package kubernetes
 
 import (
 	"fmt"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 
+	"k8s.io/kubernetes/pkg/api"
 	"k8s.io/kubernetes/pkg/labels"
 
 	log "github.com/Sirupsen/logrus"
@@ -21,8 +25,12 @@ const (
 	ObservedGeneration = "kubernetes_observed_generation"
 	Replicas           = "kubernetes_replicas"
 	DesiredReplicas    = "kubernetes_desired_replicas"
+	NodeName           = "kubernetes_node_name"
 )
 
+// maxPodEvents is how many of the latest events of each pod are reported.
+const maxPodEvents = 10
+
 // Exposed for testing
 var (
 	PodMetadataTemplates = report.MetadataTemplates{
@@ -31,10 +39,28 @@ var (
 		report.Container: {ID: report.Container, Label: "# Containers", From: report.FromCounters, Datatype: "number", Priority: 4},
 		Namespace:        {ID: Namespace, Label: "Namespace", From: report.FromLatest, Priority: 5},
 		Created:          {ID: Created, Label: "Created", From: report.FromLatest, Datatype: "datetime", Priority: 6},
+		PhaseTransitions: {ID: PhaseTransitions, Label: "Phase Transitions", From: report.FromLatest, Datatype: "number", Priority: 7},
 	}
 
 	PodMetricTemplates = docker.ContainerMetricTemplates
 
+	// ContainerMetadataTemplates are added by the tagger to the containers
+	// of pods with resource requests or limits, with probes, with an image
+	// pull policy, or with a security context.
+	ContainerMetadataTemplates = report.MetadataTemplates{
+		CPURequest:          {ID: CPURequest, Label: "CPU Request (m)", From: report.FromLatest, Datatype: "number", Priority: 20},
+		CPULimit:            {ID: CPULimit, Label: "CPU Limit (m)", From: report.FromLatest, Datatype: "number", Priority: 21},
+		MemoryRequest:       {ID: MemoryRequest, Label: "Memory Request", From: report.FromLatest, Datatype: "number", Priority: 22},
+		MemoryLimit:         {ID: MemoryLimit, Label: "Memory Limit", From: report.FromLatest, Datatype: "number", Priority: 23},
+		ReadinessProbe:      {ID: ReadinessProbe, Label: "Readiness", From: report.FromLatest, Priority: 24},
+		LivenessProbe:       {ID: LivenessProbe, Label: "Liveness", From: report.FromLatest, Priority: 25},
+		ImagePullPolicy:     {ID: ImagePullPolicy, Label: "Pull Policy", From: report.FromLatest, Priority: 26},
+		RunAsUser:           {ID: RunAsUser, Label: "Run As User", From: report.FromLatest, Priority: 27},
+		Privileged:          {ID: Privileged, Label: "Privileged", From: report.FromLatest, Priority: 28},
+		CapabilitiesAdded:   {ID: CapabilitiesAdded, Label: "Added Capabilities", From: report.FromLatest, Priority: 29},
+		CapabilitiesDropped: {ID: CapabilitiesDropped, Label: "Dropped Capabilities", From: report.FromLatest, Priority: 30},
+	}
+
 	ServiceMetadataTemplates = report.MetadataTemplates{
 		Namespace:  {ID: Namespace, Label: "Namespace", From: report.FromLatest, Priority: 2},
 		Created:    {ID: Created, Label: "Created", From: report.FromLatest, Datatype: "datetime", Priority: 3},
@@ -97,6 +123,30 @@ var (
 			Icon:  "fa-plus",
 			Rank:  1,
 		},
+		{
+			ID:    Scale,
+			Human: "Scale",
+			Icon:  "fa-arrows-v",
+			Rank:  2,
+			Params: []report.ControlParam{
+				{Name: ScaleReplicas, Label: "Replicas", Type: report.IntegerControlParam, Required: true},
+			},
+		},
+	}
+
+	NodeControls = []report.Control{
+		{
+			ID:    CordonNode,
+			Human: "Cordon",
+			Icon:  "fa-ban",
+			Rank:  1,
+		},
+		{
+			ID:    UncordonNode,
+			Human: "Uncordon",
+			Icon:  "fa-check-circle",
+			Rank:  1,
+		},
 	}
 )
 
@@ -109,6 +159,51 @@ type Reporter struct {
 	hostID          string
 	handlerRegistry *controls.HandlerRegistry
 	kubeletPort     uint
+
+	nodeNameMtx sync.Mutex
+	nodeName    string // kubernetes name of the local node, once known
+
+	podPhases podPhases
+}
+
+// podPhases counts the phase transitions of pods, keyed by UID, as seen
+// across reports.
+type podPhases struct {
+	sync.Mutex
+	phases map[string]podPhase
+}
+
+type podPhase struct {
+	phase       string
+	transitions int
+}
+
+// observe records the current phase of the pod, and returns how many times
+// its phase changed since it was first seen.
+func (p *podPhases) observe(uid, phase string) int {
+	p.Lock()
+	defer p.Unlock()
+	if p.phases == nil {
+		p.phases = map[string]podPhase{}
+	}
+	previous, ok := p.phases[uid]
+	if ok && previous.phase != phase {
+		previous.transitions++
+	}
+	previous.phase = phase
+	p.phases[uid] = previous
+	return previous.transitions
+}
+
+// retain forgets the pods not in uids.
+func (p *podPhases) retain(uids map[string]struct{}) {
+	p.Lock()
+	defer p.Unlock()
+	for uid := range p.phases {
+		if _, ok := uids[uid]; !ok {
+			delete(p.phases, uid)
+		}
+	}
 }
 
 // NewReporter makes a new Reporter
@@ -133,7 +228,7 @@ func (r *Reporter) Stop() {
 }
 
 // Name of this reporter, for metrics gathering
-func (Reporter) Name() string { return "K8s" }
+func (*Reporter) Name() string { return "K8s" }
 
 func (r *Reporter) podEvent(e Event, pod Pod) {
 	switch e {
@@ -181,8 +276,15 @@ func isPauseContainer(n report.Node, rpt report.Report) bool {
 	return false
 }
 
-// Tag adds pod parents to container nodes.
+// Tag adds pod parents to container nodes, the resource requests and
+// limits set on them, and the status of their probes.
 func (r *Reporter) Tag(rpt report.Report) (report.Report, error) {
+	pods := map[string]Pod{}
+	r.client.WalkPods(func(p Pod) error {
+		pods[p.UID()] = p
+		return nil
+	})
+	withMetadata := false
 	for id, n := range rpt.Container.Nodes {
 		uid, ok := n.Latest.Lookup(docker.LabelPrefix + "io.kubernetes.pod.uid")
 		if !ok {
@@ -194,11 +296,34 @@ func (r *Reporter) Tag(rpt report.Report) (report.Report, error) {
 			n = n.WithLatest(report.DoesNotMakeConnections, mtime.Now(), "")
 		}
 
+		if p, ok := pods[uid]; ok {
+			name, _ := n.Latest.Lookup(docker.LabelPrefix + "io.kubernetes.container.name")
+			if resources := p.ContainerResources(name); len(resources) > 0 {
+				n = n.WithLatests(resources)
+				withMetadata = true
+			}
+			if probes := p.ContainerProbes(name); len(probes) > 0 {
+				n = n.WithLatests(probes)
+				withMetadata = true
+			}
+			if policy, ok := p.ContainerPullPolicy(name); ok {
+				n = n.WithLatests(map[string]string{ImagePullPolicy: policy})
+				withMetadata = true
+			}
+			if security := p.ContainerSecurityContext(name); len(security) > 0 {
+				n = n.WithLatests(security)
+				withMetadata = true
+			}
+		}
+
 		rpt.Container.Nodes[id] = n.WithParents(report.EmptySets.Add(
 			report.Pod,
 			report.EmptyStringSet.Add(report.MakePodNodeID(uid)),
 		))
 	}
+	if withMetadata {
+		rpt.Container = rpt.Container.WithMetadataTemplates(ContainerMetadataTemplates)
+	}
 	return rpt, nil
 }
 
@@ -209,10 +334,6 @@ func (r *Reporter) Report() (report.Report, error) {
 	if err != nil {
 		return result, err
 	}
-	hostTopology := r.hostTopology(services)
-	if err != nil {
-		return result, err
-	}
 	daemonSetTopology, daemonSets, err := r.daemonSetTopology()
 	if err != nil {
 		return result, err
@@ -225,7 +346,11 @@ func (r *Reporter) Report() (report.Report, error) {
 	if err != nil {
 		return result, err
 	}
-	podTopology, err := r.podTopology(services, replicaSets, daemonSets)
+	podTopology, nodeName, err := r.podTopology(services, replicaSets, daemonSets)
+	if err != nil {
+		return result, err
+	}
+	hostTopology, err := r.hostTopology(services, nodeName)
 	if err != nil {
 		return result, err
 	}
@@ -262,7 +387,10 @@ func (r *Reporter) serviceTopology() (report.Topology, []Service, error) {
 //        The right way of fixing this is performing DNAT mapping on persistent
 //        connections for which we don't have a robust solution
 //        (see https://github.com/weaveworks/scope/issues/1491)
-func (r *Reporter) hostTopology(services []Service) report.Topology {
+//
+// The host is also given the controls to cordon or uncordon it, if we
+// know which kubernetes node it is.
+func (r *Reporter) hostTopology(services []Service, nodeName string) (report.Topology, error) {
 	localNetworks := report.EmptyStringSet
 	for _, service := range services {
 		localNetworks = localNetworks.Add(service.ClusterIP() + "/32")
@@ -270,7 +398,30 @@ func (r *Reporter) hostTopology(services []Service) report.Topology {
 	node := report.MakeNode(report.MakeHostNodeID(r.hostID))
 	node = node.WithSets(report.EmptySets.
 		Add(host.LocalNetworks, localNetworks))
-	return report.MakeTopology().AddNode(node)
+	result := report.MakeTopology()
+	result.Controls.AddControls(NodeControls)
+
+	r.nodeNameMtx.Lock()
+	r.nodeName = nodeName
+	r.nodeNameMtx.Unlock()
+	if nodeName == "" {
+		return result.AddNode(node), nil
+	}
+	err := r.client.WalkNodes(func(n *api.Node) error {
+		if n.Name != nodeName {
+			return nil
+		}
+		control := CordonNode
+		if n.Spec.Unschedulable {
+			control = UncordonNode
+		}
+		node = node.WithLatests(map[string]string{
+			NodeName:              nodeName,
+			report.ControlProbeID: r.probeID,
+		}).WithLatestActiveControls(control)
+		return nil
+	})
+	return result.AddNode(node), err
 }
 
 func (r *Reporter) deploymentTopology(probeID string) (report.Topology, []Deployment, error) {
@@ -368,7 +519,9 @@ func match(namespace string, selector labels.Selector, topology, id string) func
 	}
 }
 
-func (r *Reporter) podTopology(services []Service, replicaSets []ReplicaSet, daemonSets []DaemonSet) (report.Topology, error) {
+// podTopology also returns the kubernetes name of the local node, if the
+// local pods could be told apart.
+func (r *Reporter) podTopology(services []Service, replicaSets []ReplicaSet, daemonSets []DaemonSet) (report.Topology, string, error) {
 	var (
 		pods = report.MakeTopology().
 			WithMetadataTemplates(PodMetadataTemplates).
@@ -387,6 +540,8 @@ func (r *Reporter) podTopology(services []Service, replicaSets []ReplicaSet, dae
 		Human: "Delete",
 		Icon:  "fa-trash-o",
 		Rank:  1,
+
+		ConfirmationText: "Are you sure you want to delete this pod?",
 	})
 	for _, service := range services {
 		selectors = append(selectors, match(
@@ -399,7 +554,7 @@ func (r *Reporter) podTopology(services []Service, replicaSets []ReplicaSet, dae
 	for _, replicaSet := range replicaSets {
 		selector, err := replicaSet.Selector()
 		if err != nil {
-			return pods, err
+			return pods, "", err
 		}
 		selectors = append(selectors, match(
 			replicaSet.Namespace(),
@@ -411,7 +566,7 @@ func (r *Reporter) podTopology(services []Service, replicaSets []ReplicaSet, dae
 	for _, daemonSet := range daemonSets {
 		selector, err := daemonSet.Selector()
 		if err != nil {
-			return pods, err
+			return pods, "", err
 		}
 		selectors = append(selectors, match(
 			daemonSet.Namespace(),
@@ -433,18 +588,62 @@ func (r *Reporter) podTopology(services []Service, replicaSets []ReplicaSet, dae
 	if errUIDs != nil {
 		log.Warnf("Cannot obtain local pods, reporting all (which may impact performance): %v", errUIDs)
 	}
+	events, errEvents := podEvents(r.client)
+	if errEvents != nil {
+		log.Warnf("Cannot obtain the events of pods: %v", errEvents)
+	}
+	nodeName := ""
+	seen := map[string]struct{}{}
 	err := r.client.WalkPods(func(p Pod) error {
 		// filter out non-local pods
 		if errUIDs == nil {
 			if _, ok := localPodUIDs[p.UID()]; !ok {
 				return nil
 			}
+			nodeName = p.NodeName()
 		}
 		for _, selector := range selectors {
 			selector(p)
 		}
-		pods = pods.AddNode(p.GetNode(r.probeID))
+		node := p.GetNode(r.probeID)
+		seen[p.UID()] = struct{}{}
+		if transitions := r.podPhases.observe(p.UID(), p.State()); transitions > 0 {
+			node = node.WithLatests(map[string]string{PhaseTransitions: strconv.Itoa(transitions)})
+		}
+		for _, e := range events[p.UID()] {
+			node = node.WithLatest(EventPrefix+string(e.UID), e.LastTimestamp.Time, e.Reason+": "+e.Message)
+		}
+		pods = pods.AddNode(node)
 		return nil
 	})
-	return pods, err
+	if err == nil {
+		r.podPhases.retain(seen)
+	}
+	return pods, nodeName, err
 }
+
+// podEvents returns the latest maxPodEvents events of each pod, keyed by the
+// UID of the pod.
+func podEvents(client Client) (map[string][]*api.Event, error) {
+	result := map[string][]*api.Event{}
+	err := client.WalkEvents(func(e *api.Event) error {
+		if e.InvolvedObject.Kind == "Pod" {
+			uid := string(e.InvolvedObject.UID)
+			result[uid] = append(result[uid], e)
+		}
+		return nil
+	})
+	for uid, events := range result {
+		sort.Sort(eventsByTime(events))
+		if len(events) > maxPodEvents {
+			result[uid] = events[len(events)-maxPodEvents:]
+		}
+	}
+	return result, err
+}
+
+type eventsByTime []*api.Event
+
+func (s eventsByTime) Len() int           { return len(s) }
+func (s eventsByTime) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s eventsByTime) Less(i, j int) bool { return s[i].LastTimestamp.Before(s[j].LastTimestamp) }
@@ -6,8 +6,10 @@ import (
 	"io/ioutil"
 	"strings"
 	"testing"
+	"time"
 
 	"k8s.io/kubernetes/pkg/api"
+	"k8s.io/kubernetes/pkg/api/resource"
 	"k8s.io/kubernetes/pkg/api/unversioned"
 	"k8s.io/kubernetes/pkg/types"
 
@@ -28,7 +30,10 @@ var (
 		Kind:       "Pod",
 		APIVersion: "v1",
 	}
-	apiPod1 = api.Pod{
+	podUser    = int64(1000)
+	rootUser   = int64(0)
+	privileged = true
+	apiPod1    = api.Pod{
 		TypeMeta: podTypeMeta,
 		ObjectMeta: api.ObjectMeta{
 			Name:              "pong-a",
@@ -40,15 +45,44 @@ var (
 		Status: api.PodStatus{
 			HostIP: "1.2.3.4",
 			ContainerStatuses: []api.ContainerStatus{
-				{ContainerID: "container1"},
+				{
+					ContainerID: "container1",
+					Name:        "frontend",
+					State:       api.ContainerState{Running: &api.ContainerStateRunning{}},
+				},
 				{ContainerID: "container2"},
 			},
 		},
 		Spec: api.PodSpec{
-			NodeName: nodeName,
+			NodeName:     nodeName,
+			NodeSelector: map[string]string{"disk": "ssd", "zone": "a"},
 			SecurityContext: &api.PodSecurityContext{
 				HostNetwork: true,
+				RunAsUser:   &podUser,
 			},
+			Containers: []api.Container{{
+				Name: "frontend",
+				Resources: api.ResourceRequirements{
+					Requests: api.ResourceList{
+						api.ResourceCPU:    resource.MustParse("250m"),
+						api.ResourceMemory: resource.MustParse("64Mi"),
+					},
+					Limits: api.ResourceList{
+						api.ResourceMemory: resource.MustParse("128Mi"),
+					},
+				},
+				ReadinessProbe:  &api.Probe{},
+				LivenessProbe:   &api.Probe{},
+				ImagePullPolicy: api.PullAlways,
+				SecurityContext: &api.SecurityContext{
+					Privileged: &privileged,
+					RunAsUser:  &rootUser,
+					Capabilities: &api.Capabilities{
+						Add:  []api.Capability{"SYS_TIME", "NET_ADMIN"},
+						Drop: []api.Capability{"MKNOD"},
+					},
+				},
+			}},
 		},
 	}
 	apiPod2 = api.Pod{
@@ -116,7 +150,11 @@ func newMockClient() *mockClient {
 type mockClient struct {
 	pods     []kubernetes.Pod
 	services []kubernetes.Service
+	nodes    []*api.Node
+	events   []*api.Event
 	logs     map[string]io.ReadCloser
+	cordoned map[string]bool
+	scaled   map[string]int
 }
 
 func (c *mockClient) Stop() {}
@@ -148,7 +186,20 @@ func (c *mockClient) WalkReplicaSets(f func(kubernetes.ReplicaSet) error) error
 func (c *mockClient) WalkReplicationControllers(f func(kubernetes.ReplicationController) error) error {
 	return nil
 }
-func (*mockClient) WalkNodes(f func(*api.Node) error) error {
+func (c *mockClient) WalkNodes(f func(*api.Node) error) error {
+	for _, node := range c.nodes {
+		if err := f(node); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+func (c *mockClient) WalkEvents(f func(*api.Event) error) error {
+	for _, e := range c.events {
+		if err := f(e); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 func (*mockClient) WatchPods(func(kubernetes.Event, kubernetes.Pod)) {}
@@ -168,6 +219,27 @@ func (c *mockClient) ScaleUp(resource, namespaceID, id string) error {
 func (c *mockClient) ScaleDown(resource, namespaceID, id string) error {
 	return nil
 }
+func (c *mockClient) Scale(resource, namespaceID, id string, replicas int) error {
+	if c.scaled == nil {
+		c.scaled = map[string]int{}
+	}
+	c.scaled[resource+";"+namespaceID+";"+id] = replicas
+	return nil
+}
+func (c *mockClient) CordonNode(name string) error {
+	if c.cordoned == nil {
+		c.cordoned = map[string]bool{}
+	}
+	c.cordoned[name] = true
+	return nil
+}
+func (c *mockClient) UncordonNode(name string) error {
+	if c.cordoned == nil {
+		c.cordoned = map[string]bool{}
+	}
+	c.cordoned[name] = false
+	return nil
+}
 
 type mockPipeClient map[string]xfer.Pipe
 
@@ -206,9 +278,12 @@ func TestReporter(t *testing.T) {
 		latest        map[string]string
 	}{
 		{pod1ID, serviceID, map[string]string{
-			kubernetes.Name:      "pong-a",
-			kubernetes.Namespace: "ping",
-			kubernetes.Created:   pod1.Created(),
+			kubernetes.Name:             "pong-a",
+			kubernetes.Namespace:        "ping",
+			kubernetes.Created:          pod1.Created(),
+			kubernetes.NodeName:         nodeName,
+			kubernetes.SchedulingStatus: kubernetes.SchedulingScheduled,
+			kubernetes.NodeSelector:     "disk=ssd, zone=a",
 		}},
 		{pod2ID, serviceID, map[string]string{
 			kubernetes.Name:      "pong-b",
@@ -251,6 +326,50 @@ func TestReporter(t *testing.T) {
 	}
 }
 
+func TestReporterPhaseTransitions(t *testing.T) {
+	oldGetNodeName := kubernetes.GetLocalPodUIDs
+	defer func() { kubernetes.GetLocalPodUIDs = oldGetNodeName }()
+	kubernetes.GetLocalPodUIDs = func(string) (map[string]struct{}, error) {
+		return map[string]struct{}{pod1UID: {}, pod2UID: {}}, nil
+	}
+
+	client := newMockClient()
+	reporter := kubernetes.NewReporter(client, nil, "", "foo", nil, controls.NewDefaultHandlerRegistry(), 0)
+	defer reporter.Stop()
+	withPhase := func(p api.Pod, phase api.PodPhase) kubernetes.Pod {
+		p.Status.Phase = phase
+		return kubernetes.NewPod(&p)
+	}
+	transitions := func(phase1, phase2 api.PodPhase) map[string]string {
+		client.pods = []kubernetes.Pod{withPhase(apiPod1, phase1), withPhase(apiPod2, phase2)}
+		rpt, err := reporter.Report()
+		if err != nil {
+			t.Fatal(err)
+		}
+		result := map[string]string{}
+		for _, uid := range []string{pod1UID, pod2UID} {
+			if value, ok := rpt.Pod.Nodes[report.MakePodNodeID(uid)].Latest.Lookup(kubernetes.PhaseTransitions); ok {
+				result[uid] = value
+			}
+		}
+		return result
+	}
+
+	for _, step := range []struct {
+		phase1, phase2 api.PodPhase
+		want           map[string]string
+	}{
+		{api.PodPending, api.PodRunning, map[string]string{}},
+		{api.PodRunning, api.PodRunning, map[string]string{pod1UID: "1"}},
+		{api.PodFailed, api.PodRunning, map[string]string{pod1UID: "2"}},
+		{api.PodRunning, api.PodFailed, map[string]string{pod1UID: "3", pod2UID: "1"}},
+	} {
+		if have := transitions(step.phase1, step.phase2); !reflect.DeepEqual(step.want, have) {
+			t.Errorf("phases %s, %s: want %v, have %v", step.phase1, step.phase2, step.want, have)
+		}
+	}
+}
+
 func TestTagger(t *testing.T) {
 	rpt := report.MakeReport()
 	rpt.Container.AddNode(report.MakeNodeWith("container1", map[string]string{
@@ -270,6 +389,201 @@ func TestTagger(t *testing.T) {
 	}
 }
 
+func TestTaggerContainerResources(t *testing.T) {
+	rpt := report.MakeReport()
+	rpt.Container.AddNode(report.MakeNodeWith("frontend", map[string]string{
+		docker.LabelPrefix + "io.kubernetes.pod.uid":        pod1UID,
+		docker.LabelPrefix + "io.kubernetes.container.name": "frontend",
+	}))
+	rpt.Container.AddNode(report.MakeNodeWith("sidecar", map[string]string{
+		docker.LabelPrefix + "io.kubernetes.pod.uid":        pod1UID,
+		docker.LabelPrefix + "io.kubernetes.container.name": "sidecar",
+	}))
+
+	hr := controls.NewDefaultHandlerRegistry()
+	rpt, err := kubernetes.NewReporter(newMockClient(), nil, "", "", nil, hr, 0).Tag(rpt)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	for key, want := range map[string]string{
+		kubernetes.CPURequest:    "250",
+		kubernetes.MemoryRequest: "67108864",
+		kubernetes.MemoryLimit:   "134217728",
+	} {
+		if have, _ := rpt.Container.Nodes["frontend"].Latest.Lookup(key); have != want {
+			t.Errorf("Expected %s to be %q, got %q", key, want, have)
+		}
+	}
+	if _, ok := rpt.Container.Nodes["frontend"].Latest.Lookup(kubernetes.CPULimit); ok {
+		t.Errorf("Expected no CPU limit")
+	}
+	if _, ok := rpt.Container.Nodes["sidecar"].Latest.Lookup(kubernetes.CPURequest); ok {
+		t.Errorf("Expected no resources on a container without a spec")
+	}
+	if _, ok := rpt.Container.MetadataTemplates[kubernetes.CPURequest]; !ok {
+		t.Errorf("Expected the resource metadata templates")
+	}
+}
+
+func TestPodScheduling(t *testing.T) {
+	pending := apiPod2
+	pending.Spec.NodeName = ""
+	node := kubernetes.NewPod(&pending).GetNode("")
+	if have, _ := node.Latest.Lookup(kubernetes.SchedulingStatus); have != kubernetes.SchedulingPending {
+		t.Errorf("Expected a pod without a node to be pending, got %q", have)
+	}
+	for _, key := range []string{kubernetes.NodeName, kubernetes.NodeSelector} {
+		if _, ok := node.Latest.Lookup(key); ok {
+			t.Errorf("Expected no %s on a pending pod without a node selector", key)
+		}
+	}
+}
+
+func TestTaggerContainerProbes(t *testing.T) {
+	rpt := report.MakeReport()
+	rpt.Container.AddNode(report.MakeNodeWith("frontend", map[string]string{
+		docker.LabelPrefix + "io.kubernetes.pod.uid":        pod1UID,
+		docker.LabelPrefix + "io.kubernetes.container.name": "frontend",
+	}))
+	rpt.Container.AddNode(report.MakeNodeWith("sidecar", map[string]string{
+		docker.LabelPrefix + "io.kubernetes.pod.uid":        pod1UID,
+		docker.LabelPrefix + "io.kubernetes.container.name": "sidecar",
+	}))
+
+	hr := controls.NewDefaultHandlerRegistry()
+	rpt, err := kubernetes.NewReporter(newMockClient(), nil, "", "", nil, hr, 0).Tag(rpt)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// The frontend is running, but not ready
+	for key, want := range map[string]string{
+		kubernetes.ReadinessProbe: kubernetes.ProbeFailing,
+		kubernetes.LivenessProbe:  kubernetes.ProbePassing,
+	} {
+		if have, _ := rpt.Container.Nodes["frontend"].Latest.Lookup(key); have != want {
+			t.Errorf("Expected %s to be %q, got %q", key, want, have)
+		}
+	}
+	if _, ok := rpt.Container.Nodes["sidecar"].Latest.Lookup(kubernetes.ReadinessProbe); ok {
+		t.Errorf("Expected no probe status on a container without probes")
+	}
+	if _, ok := rpt.Container.MetadataTemplates[kubernetes.ReadinessProbe]; !ok {
+		t.Errorf("Expected the probe metadata templates")
+	}
+}
+
+func TestReporterPodEvents(t *testing.T) {
+	oldGetNodeName := kubernetes.GetLocalPodUIDs
+	defer func() { kubernetes.GetLocalPodUIDs = oldGetNodeName }()
+	kubernetes.GetLocalPodUIDs = func(string) (map[string]struct{}, error) {
+		return map[string]struct{}{pod1UID: {}, pod2UID: {}}, nil
+	}
+
+	now := time.Now().Round(time.Second)
+	event := func(uid, kind, objectUID, reason, message string, at time.Time) *api.Event {
+		return &api.Event{
+			ObjectMeta:     api.ObjectMeta{UID: types.UID(uid)},
+			InvolvedObject: api.ObjectReference{Kind: kind, UID: types.UID(objectUID)},
+			Reason:         reason,
+			Message:        message,
+			LastTimestamp:  unversioned.NewTime(at),
+		}
+	}
+	client := newMockClient()
+	client.events = []*api.Event{
+		event("e2", "Pod", pod1UID, "Pulled", "Container image pulled", now.Add(-time.Minute)),
+		event("e1", "Pod", pod1UID, "Scheduled", "Assigned to node1", now.Add(-2*time.Minute)),
+		event("e3", "Service", serviceUID, "CreatedLoadBalancer", "Created load balancer", now),
+	}
+	hr := controls.NewDefaultHandlerRegistry()
+	rpt, err := kubernetes.NewReporter(client, nil, "", "foo", nil, hr, 0).Report()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range []struct {
+		key, value string
+		at         time.Time
+	}{
+		{kubernetes.EventPrefix + "e1", "Scheduled: Assigned to node1", now.Add(-2 * time.Minute)},
+		{kubernetes.EventPrefix + "e2", "Pulled: Container image pulled", now.Add(-time.Minute)},
+	} {
+		value, at, ok := rpt.Pod.Nodes[report.MakePodNodeID(pod1UID)].Latest.LookupEntry(c.key)
+		if !ok || value != c.value || !at.Equal(c.at) {
+			t.Errorf("Expected event %s %q at %v, got %q at %v", c.key, c.value, c.at, value, at)
+		}
+	}
+	rpt.Pod.Nodes[report.MakePodNodeID(pod2UID)].Latest.ForEach(func(key string, _ time.Time, _ string) {
+		if strings.HasPrefix(key, kubernetes.EventPrefix) {
+			t.Errorf("Expected no events on pod2, got %s", key)
+		}
+	})
+}
+
+func TestTaggerContainerPullPolicy(t *testing.T) {
+	rpt := report.MakeReport()
+	rpt.Container.AddNode(report.MakeNodeWith("frontend", map[string]string{
+		docker.LabelPrefix + "io.kubernetes.pod.uid":        pod1UID,
+		docker.LabelPrefix + "io.kubernetes.container.name": "frontend",
+	}))
+	rpt.Container.AddNode(report.MakeNodeWith("sidecar", map[string]string{
+		docker.LabelPrefix + "io.kubernetes.pod.uid":        pod1UID,
+		docker.LabelPrefix + "io.kubernetes.container.name": "sidecar",
+	}))
+
+	hr := controls.NewDefaultHandlerRegistry()
+	rpt, err := kubernetes.NewReporter(newMockClient(), nil, "", "", nil, hr, 0).Tag(rpt)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if have, _ := rpt.Container.Nodes["frontend"].Latest.Lookup(kubernetes.ImagePullPolicy); have != string(api.PullAlways) {
+		t.Errorf("Expected pull policy %q, got %q", api.PullAlways, have)
+	}
+	if _, ok := rpt.Container.Nodes["sidecar"].Latest.Lookup(kubernetes.ImagePullPolicy); ok {
+		t.Errorf("Expected no pull policy on a container not in the pod spec")
+	}
+}
+
+func TestTaggerContainerSecurityContext(t *testing.T) {
+	rpt := report.MakeReport()
+	rpt.Container.AddNode(report.MakeNodeWith("frontend", map[string]string{
+		docker.LabelPrefix + "io.kubernetes.pod.uid":        pod1UID,
+		docker.LabelPrefix + "io.kubernetes.container.name": "frontend",
+	}))
+	rpt.Container.AddNode(report.MakeNodeWith("sidecar", map[string]string{
+		docker.LabelPrefix + "io.kubernetes.pod.uid":        pod1UID,
+		docker.LabelPrefix + "io.kubernetes.container.name": "sidecar",
+	}))
+
+	hr := controls.NewDefaultHandlerRegistry()
+	rpt, err := kubernetes.NewReporter(newMockClient(), nil, "", "", nil, hr, 0).Tag(rpt)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// The frontend is privileged, and runs as root
+	for key, want := range map[string]string{
+		kubernetes.RunAsUser:           "0",
+		kubernetes.Privileged:          "true",
+		kubernetes.CapabilitiesAdded:   "NET_ADMIN, SYS_TIME",
+		kubernetes.CapabilitiesDropped: "MKNOD",
+	} {
+		if have, _ := rpt.Container.Nodes["frontend"].Latest.Lookup(key); have != want {
+			t.Errorf("Expected %s to be %q, got %q", key, want, have)
+		}
+	}
+	// The sidecar runs as the user of the pod
+	if have, _ := rpt.Container.Nodes["sidecar"].Latest.Lookup(kubernetes.RunAsUser); have != "1000" {
+		t.Errorf("Expected the sidecar to run as the user of the pod, got %q", have)
+	}
+	if _, ok := rpt.Container.Nodes["sidecar"].Latest.Lookup(kubernetes.Privileged); ok {
+		t.Errorf("Expected no privileged flag on a container without a security context")
+	}
+}
+
 type callbackReadCloser struct {
 	io.Reader
 	close func() error
@@ -355,3 +669,118 @@ func TestReporterGetLogs(t *testing.T) {
 		t.Errorf("Expected pipe to close the underlying log stream")
 	}
 }
+
+func TestReporterNodeControls(t *testing.T) {
+	oldGetNodeName := kubernetes.GetLocalPodUIDs
+	defer func() { kubernetes.GetLocalPodUIDs = oldGetNodeName }()
+
+	hostID := report.MakeHostNodeID("foo")
+	activeControls := func(rpt report.Report) []string {
+		result := []string{}
+		rpt.Host.Nodes[hostID].LatestControls.ForEach(func(id string, _ time.Time, data report.NodeControlData) {
+			if !data.Dead {
+				result = append(result, id)
+			}
+		})
+		return result
+	}
+
+	for _, c := range []struct {
+		unschedulable bool
+		want          []string
+	}{
+		{false, []string{kubernetes.CordonNode}},
+		{true, []string{kubernetes.UncordonNode}},
+	} {
+		kubernetes.GetLocalPodUIDs = func(string) (map[string]struct{}, error) {
+			return map[string]struct{}{pod1UID: {}}, nil
+		}
+		client := newMockClient()
+		client.nodes = []*api.Node{{
+			ObjectMeta: api.ObjectMeta{Name: nodeName},
+			Spec:       api.NodeSpec{Unschedulable: c.unschedulable},
+		}}
+		hr := controls.NewDefaultHandlerRegistry()
+		reporter := kubernetes.NewReporter(client, nil, "probe", "foo", nil, hr, 0)
+		rpt, err := reporter.Report()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if have := activeControls(rpt); !reflect.DeepEqual(c.want, have) {
+			t.Errorf("want %v, have %v", c.want, have)
+		}
+		for _, id := range []string{kubernetes.CordonNode, kubernetes.UncordonNode} {
+			if _, ok := rpt.Host.Controls[id]; !ok {
+				t.Errorf("Expected host topology to have control %s", id)
+			}
+		}
+
+		// The control acts on the kubernetes node
+		resp := reporter.CaptureNode(reporter.CordonNode)(xfer.Request{NodeID: hostID, Control: kubernetes.CordonNode})
+		if resp.Error != "" || !client.cordoned[nodeName] {
+			t.Errorf("Expected node to be cordoned, got %v", resp)
+		}
+		resp = reporter.CaptureNode(reporter.UncordonNode)(xfer.Request{NodeID: hostID, Control: kubernetes.UncordonNode})
+		if resp.Error != "" || client.cordoned[nodeName] {
+			t.Errorf("Expected node to be uncordoned, got %v", resp)
+		}
+		resp = reporter.CaptureNode(reporter.CordonNode)(xfer.Request{NodeID: report.MakeHostNodeID("bar")})
+		if want := "Invalid ID: " + report.MakeHostNodeID("bar"); resp.Error != want {
+			t.Errorf("want %q, got %q", want, resp.Error)
+		}
+	}
+
+	// Without local pods we can't tell which kubernetes node we are on, so
+	// the host gets no controls
+	kubernetes.GetLocalPodUIDs = func(string) (map[string]struct{}, error) {
+		return nil, fmt.Errorf("kubelet unreachable")
+	}
+	client := newMockClient()
+	client.nodes = []*api.Node{{ObjectMeta: api.ObjectMeta{Name: nodeName}}}
+	hr := controls.NewDefaultHandlerRegistry()
+	rpt, _ := kubernetes.NewReporter(client, nil, "probe", "foo", nil, hr, 0).Report()
+	if have := activeControls(rpt); len(have) != 0 {
+		t.Errorf("Expected no controls, got %v", have)
+	}
+}
+
+func TestReporterScale(t *testing.T) {
+	client := newMockClient()
+	hr := controls.NewDefaultHandlerRegistry()
+	reporter := kubernetes.NewReporter(client, nil, "probe", "foo", nil, hr, 0)
+	rpt, err := reporter.Report()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []report.ControlParam{
+		{Name: kubernetes.ScaleReplicas, Label: "Replicas", Type: report.IntegerControlParam, Required: true},
+	}
+	for _, topology := range []report.Topology{rpt.Deployment, rpt.ReplicaSet} {
+		control, ok := topology.Controls[kubernetes.Scale]
+		if !ok {
+			t.Fatalf("Expected the scale control, got %v", topology.Controls)
+		}
+		if !reflect.DeepEqual(want, control.Params) {
+			t.Errorf("want %v, have %v", want, control.Params)
+		}
+	}
+
+	resp := reporter.Scale(xfer.Request{
+		Control:     kubernetes.Scale,
+		ControlArgs: map[string]string{kubernetes.ScaleReplicas: "3"},
+	}, "deployment", "ping", "pong")
+	if resp.Error != "" || client.scaled["deployment;ping;pong"] != 3 {
+		t.Errorf("Expected the deployment to be scaled to 3 replicas, got %v, %v", resp, client.scaled)
+	}
+
+	for _, replicas := range []string{"", "many", "-1"} {
+		resp := reporter.Scale(xfer.Request{
+			Control:     kubernetes.Scale,
+			ControlArgs: map[string]string{kubernetes.ScaleReplicas: replicas},
+		}, "deployment", "ping", "pong")
+		if resp.Error == "" {
+			t.Errorf("Expected an error for %q replicas", replicas)
+		}
+	}
+}
@@ -6,6 +6,7 @@ import (
 
 	log "github.com/Sirupsen/logrus"
 	"github.com/armon/go-metrics"
+	"github.com/weaveworks/common/mtime"
 
 	"github.com/weaveworks/scope/probe/appclient"
 	"github.com/weaveworks/scope/report"
@@ -29,8 +30,27 @@ type Probe struct {
 
 	spiedReports    chan report.Report
 	shortcutReports chan report.Report
+
+	// Only accessed by the publish loop.
+	publishBudget *tokenBucket
+	heldBack      *report.Report
 }
 
+// Priority is the lane a report is queued in for publication. Reports
+// queued in a higher priority lane are published before those queued
+// earlier in a lower one.
+type Priority int
+
+// The publication lanes, from lowest to highest priority.
+const (
+	// LowPriority reports are merged and published on the publish
+	// interval. This is where the bulk, spied reports go.
+	LowPriority Priority = iota
+	// HighPriority reports, typically partial reports carrying control
+	// or topology changes, are published as soon as possible.
+	HighPriority
+)
+
 // Tagger tags nodes with value-add node metadata.
 type Tagger interface {
 	Name() string
@@ -81,6 +101,28 @@ func New(
 	return result
 }
 
+// LimitPublishRate caps the number of reports published per second. The
+// reports over budget are held back, and merged into the next one
+// published. It must be called before Start; zero means no limit.
+func (p *Probe) LimitPublishRate(maxPerSecond float64) {
+	p.publishBudget = nil
+	if maxPerSecond > 0 {
+		p.publishBudget = newTokenBucket(maxPerSecond)
+	}
+}
+
+// SetReportEncoder replaces the encoder reports are published with, e.g.
+// by appclient.AdaptiveEncoder. It must be called before Start.
+func (p *Probe) SetReportEncoder(encoder appclient.ReportEncoder) {
+	p.publisher.SetEncoder(encoder)
+}
+
+// EnableHeartbeats makes the probe publish heartbeats in place of reports
+// unchanged since the last one published. It must be called before Start.
+func (p *Probe) EnableHeartbeats(probeID string) {
+	p.publisher.EnableHeartbeats(probeID)
+}
+
 // AddTagger adds a new Tagger to the Probe
 func (p *Probe) AddTagger(ts ...Tagger) {
 	p.taggers = append(p.taggers, ts...)
@@ -112,8 +154,21 @@ func (p *Probe) Stop() {
 // Publish will queue a report for immediate publication,
 // bypassing the spy tick
 func (p *Probe) Publish(rpt report.Report) {
+	p.PublishWithPriority(rpt, HighPriority)
+}
+
+// PublishWithPriority queues a report for publication in the lane of the
+// given priority.
+func (p *Probe) PublishWithPriority(rpt report.Report, priority Priority) {
 	rpt = p.tag(rpt)
-	p.shortcutReports <- rpt
+	p.lane(priority) <- rpt
+}
+
+func (p *Probe) lane(priority Priority) chan report.Report {
+	if priority >= HighPriority {
+		return p.shortcutReports
+	}
+	return p.spiedReports
 }
 
 func (p *Probe) spyLoop() {
@@ -200,6 +255,21 @@ ForLoop:
 		}
 	}
 
+	if p.heldBack != nil {
+		rpt = p.heldBack.Merge(rpt)
+		p.heldBack = nil
+	}
+	// Reports are held back while the app asks for it, as well as when over
+	// budget.
+	if mtime.Now().Before(p.publisher.HoldBackUntil()) {
+		p.heldBack = &rpt
+		return
+	}
+	if p.publishBudget != nil && !p.publishBudget.take() {
+		p.heldBack = &rpt
+		return
+	}
+
 	if err := p.publisher.Publish(rpt.BackwardCompatible()); err != nil {
 		log.Infof("publish: %v", err)
 	}
@@ -210,6 +280,15 @@ func (p *Probe) publishLoop() {
 	pubTick := time.Tick(p.publishInterval)
 
 	for {
+		// Serve the high priority lane first, so its reports don't wait
+		// behind a publish tick which is due at the same time.
+		select {
+		case rpt := <-p.shortcutReports:
+			p.drainAndPublish(rpt, p.shortcutReports)
+			continue
+		default:
+		}
+
 		select {
 		case <-pubTick:
 			p.drainAndPublish(report.MakeReport(), p.spiedReports)
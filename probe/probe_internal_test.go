@@ -3,6 +3,7 @@ package probe
 import (
 	"compress/gzip"
 	"io"
+	"sort"
 	"testing"
 	"time"
 
@@ -97,3 +98,160 @@ func TestProbe(t *testing.T) {
 		return <-pub.have
 	})
 }
+
+type blockingPublisher struct {
+	mockPublisher
+	release chan struct{}
+}
+
+func (m blockingPublisher) Publish(in io.Reader) error {
+	err := m.mockPublisher.Publish(in)
+	<-m.release
+	return err
+}
+
+func TestProbePublishesHighPriorityFirst(t *testing.T) {
+	pub := blockingPublisher{mockPublisher{make(chan report.Report, 10)}, make(chan struct{})}
+	p := New(time.Hour, 10*time.Millisecond, pub, false)
+
+	withNode := func(id string) report.Report {
+		r := report.MakeReport()
+		r.Endpoint.AddNode(report.MakeNode(id))
+		return r
+	}
+	published := func() string {
+		r := <-pub.have
+		pub.release <- struct{}{}
+		for id := range r.Endpoint.Nodes {
+			return id
+		}
+		return ""
+	}
+
+	p.PublishWithPriority(withNode("low"), LowPriority)
+	p.Publish(withNode("high1"))
+	p.done.Add(1)
+	go p.publishLoop()
+	defer p.Stop()
+	defer close(pub.release)
+
+	// While the first report is being published, the publish tick becomes
+	// due, and another high priority report is queued.
+	r := <-pub.have
+	time.Sleep(20 * time.Millisecond)
+	p.Publish(withNode("high2"))
+	pub.release <- struct{}{}
+	if _, ok := r.Endpoint.Nodes["high1"]; !ok {
+		t.Fatalf("Expected the first high priority report first, got %v", r)
+	}
+
+	if have := published(); have != "high2" {
+		t.Errorf("Expected the second high priority report before the earlier low priority one, got %q", have)
+	}
+	if have := published(); have != "low" {
+		t.Errorf("Expected the low priority report last, got %q", have)
+	}
+}
+
+func TestProbeLimitPublishRate(t *testing.T) {
+	now := time.Now()
+	mtime.NowForce(now)
+	defer mtime.NowReset()
+
+	pub := mockPublisher{make(chan report.Report, 10)}
+	p := New(time.Hour, time.Hour, pub, false)
+	p.LimitPublishRate(2)
+
+	withNode := func(id string) report.Report {
+		r := report.MakeReport()
+		r.Endpoint.AddNode(report.MakeNode(id))
+		return r
+	}
+	// published returns the IDs of the nodes of each report published
+	published := func() [][]string {
+		result := [][]string{}
+		for {
+			select {
+			case r := <-pub.have:
+				ids := []string{}
+				for id := range r.Endpoint.Nodes {
+					ids = append(ids, id)
+				}
+				sort.Strings(ids)
+				result = append(result, ids)
+			default:
+				return result
+			}
+		}
+	}
+
+	// A burst of five reports within a second: the budget lets two
+	// through, the rest are held back.
+	for _, id := range []string{"a", "b", "c", "d", "e"} {
+		p.drainAndPublish(withNode(id), p.spiedReports)
+	}
+	if have, want := published(), [][]string{{"a"}, {"b"}}; !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+
+	// Once the budget refills, the held back reports are merged into the
+	// next one sent.
+	mtime.NowForce(now.Add(500 * time.Millisecond))
+	p.drainAndPublish(withNode("f"), p.spiedReports)
+	if have, want := published(), [][]string{{"c", "d", "e", "f"}}; !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
+
+type throttledPublisher struct {
+	mockPublisher
+	until time.Time
+}
+
+func (t *throttledPublisher) HoldBackUntil() time.Time {
+	return t.until
+}
+
+func TestProbeHoldBack(t *testing.T) {
+	now := time.Now()
+	mtime.NowForce(now)
+	defer mtime.NowReset()
+
+	pub := &throttledPublisher{mockPublisher: mockPublisher{make(chan report.Report, 10)}}
+	p := New(time.Hour, time.Hour, pub, false)
+
+	withNode := func(id string) report.Report {
+		r := report.MakeReport()
+		r.Endpoint.AddNode(report.MakeNode(id))
+		return r
+	}
+	published := func() []string {
+		select {
+		case r := <-pub.have:
+			ids := []string{}
+			for id := range r.Endpoint.Nodes {
+				ids = append(ids, id)
+			}
+			sort.Strings(ids)
+			return ids
+		default:
+			return nil
+		}
+	}
+
+	// While the app asks for it, reports are held back.
+	pub.until = now.Add(10 * time.Second)
+	p.drainAndPublish(withNode("a"), p.spiedReports)
+	mtime.NowForce(now.Add(5 * time.Second))
+	p.drainAndPublish(withNode("b"), p.spiedReports)
+	if have := published(); have != nil {
+		t.Errorf("want nothing published, have %v", have)
+	}
+
+	// Afterwards, they are merged into the next report published.
+	mtime.NowForce(now.Add(10 * time.Second))
+	p.drainAndPublish(withNode("c"), p.spiedReports)
+	if have, want := published(), []string{"a", "b", "c"}; !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
@@ -0,0 +1,136 @@
+package process
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"runtime/pprof"
+	"strconv"
+	"time"
+
+	"github.com/weaveworks/common/mtime"
+	"github.com/weaveworks/scope/common/xfer"
+	"github.com/weaveworks/scope/probe/controls"
+	"github.com/weaveworks/scope/report"
+)
+
+// Control IDs used by the profiler.
+const (
+	HeapProfile = "process_heap_profile"
+	CPUProfile  = "process_cpu_profile"
+)
+
+// CPUProfileSeconds is the argument of the CPUProfile control holding how
+// long to profile for.
+const CPUProfileSeconds = "seconds"
+
+// maxCPUProfileSeconds is the longest a CPU profile can be captured for, as
+// the control handler blocks while it is.
+const maxCPUProfileSeconds = 30
+
+// ProfileContentType is the content type of the profile artifacts.
+const ProfileContentType = "application/octet-stream"
+
+// ProfileControls are the controls added by the profiler.
+var ProfileControls = []report.Control{
+	{
+		ID:    HeapProfile,
+		Human: "Heap profile",
+		Icon:  "fa-pie-chart",
+		Rank:  10,
+	},
+	{
+		ID:    CPUProfile,
+		Human: "CPU profile",
+		Icon:  "fa-area-chart",
+		Rank:  11,
+		Params: []report.ControlParam{
+			{Name: CPUProfileSeconds, Label: "Seconds", Type: report.IntegerControlParam, Default: "10"},
+		},
+	},
+}
+
+// Profiler adds controls capturing Go runtime profiles to the process node
+// of the probe. That is the only process it knows how to profile: other
+// processes, and containers, don't get the controls. Profiles are returned
+// as artifacts, in the pprof format.
+type Profiler struct {
+	nodeID          string
+	probeID         string
+	handlerRegistry *controls.HandlerRegistry
+}
+
+// NewProfiler makes a new Profiler, and registers its controls.
+func NewProfiler(hostID, probeID string, handlerRegistry *controls.HandlerRegistry) *Profiler {
+	p := &Profiler{
+		nodeID:          report.MakeProcessNodeID(hostID, strconv.Itoa(os.Getpid())),
+		probeID:         probeID,
+		handlerRegistry: handlerRegistry,
+	}
+	handlerRegistry.ValidateParams(ProfileControls...)
+	handlerRegistry.Batch(nil, map[string]xfer.ControlHandlerFunc{
+		HeapProfile: p.heapProfile,
+		CPUProfile:  p.cpuProfile,
+	})
+	return p
+}
+
+// Name of this tagger, for metrics gathering
+func (*Profiler) Name() string { return "Profiler" }
+
+// Stop deregisters the controls of the profiler.
+func (p *Profiler) Stop() {
+	p.handlerRegistry.Batch([]string{HeapProfile, CPUProfile}, nil)
+}
+
+// Tag implements Tagger, adding the controls to the node of the probe
+// process, if it was reported.
+func (p *Profiler) Tag(r report.Report) (report.Report, error) {
+	n, ok := r.Process.Nodes[p.nodeID]
+	if !ok {
+		return r, nil
+	}
+	r.Process.Controls.AddControls(ProfileControls)
+	r.Process.Nodes[p.nodeID] = n.
+		WithLatest(report.ControlProbeID, mtime.Now(), p.probeID).
+		WithLatestActiveControls(HeapProfile, CPUProfile)
+	return r, nil
+}
+
+func (p *Profiler) heapProfile(req xfer.Request) xfer.Response {
+	if req.NodeID != p.nodeID {
+		return xfer.ResponseErrorf("Profiling not supported for %s", req.NodeID)
+	}
+	buf := &bytes.Buffer{}
+	if err := pprof.WriteHeapProfile(buf); err != nil {
+		return xfer.ResponseError(err)
+	}
+	return p.artifact("heap", buf)
+}
+
+func (p *Profiler) cpuProfile(req xfer.Request) xfer.Response {
+	if req.NodeID != p.nodeID {
+		return xfer.ResponseErrorf("Profiling not supported for %s", req.NodeID)
+	}
+	seconds, err := strconv.Atoi(req.ControlArgs[CPUProfileSeconds])
+	if err != nil || seconds <= 0 || seconds > maxCPUProfileSeconds {
+		return xfer.ResponseErrorf("Invalid number of seconds: %q, must be between 1 and %d", req.ControlArgs[CPUProfileSeconds], maxCPUProfileSeconds)
+	}
+	buf := &bytes.Buffer{}
+	if err := pprof.StartCPUProfile(buf); err != nil {
+		return xfer.ResponseError(err)
+	}
+	time.Sleep(time.Duration(seconds) * time.Second)
+	pprof.StopCPUProfile()
+	return p.artifact("cpu", buf)
+}
+
+func (p *Profiler) artifact(kind string, buf *bytes.Buffer) xfer.Response {
+	return xfer.Response{
+		Artifact: &xfer.Artifact{
+			Name:        fmt.Sprintf("%s-%d.pprof", kind, os.Getpid()),
+			ContentType: ProfileContentType,
+			Data:        buf.Bytes(),
+		},
+	}
+}
@@ -0,0 +1,72 @@
+package process_test
+
+import (
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/weaveworks/scope/common/xfer"
+	"github.com/weaveworks/scope/probe/controls"
+	"github.com/weaveworks/scope/probe/process"
+	"github.com/weaveworks/scope/report"
+)
+
+func TestProfiler(t *testing.T) {
+	var (
+		hr         = controls.NewDefaultHandlerRegistry()
+		profiler   = process.NewProfiler("host", "probe", hr)
+		selfNodeID = report.MakeProcessNodeID("host", strconv.Itoa(os.Getpid()))
+		otherID    = report.MakeProcessNodeID("host", "1")
+	)
+	defer profiler.Stop()
+
+	rpt := report.MakeReport()
+	rpt.Process.AddNode(report.MakeNodeWith(selfNodeID, map[string]string{process.PID: strconv.Itoa(os.Getpid())}))
+	rpt.Process.AddNode(report.MakeNodeWith(otherID, map[string]string{process.PID: "1"}))
+	rpt, err := profiler.Tag(rpt)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the probe process gets the controls
+	for _, control := range []string{process.HeapProfile, process.CPUProfile} {
+		if _, ok := rpt.Process.Controls[control]; !ok {
+			t.Errorf("Expected the %s control in the topology", control)
+		}
+		if data, ok := rpt.Process.Nodes[selfNodeID].LatestControls.Lookup(control); !ok || data.Dead {
+			t.Errorf("Expected the %s control on the probe process", control)
+		}
+		if _, ok := rpt.Process.Nodes[otherID].LatestControls.Lookup(control); ok {
+			t.Errorf("Expected no %s control on other processes", control)
+		}
+	}
+	if probeID, _ := rpt.Process.Nodes[selfNodeID].Latest.Lookup(report.ControlProbeID); probeID != "probe" {
+		t.Errorf("Expected the probe ID to route the controls, got %q", probeID)
+	}
+
+	for _, req := range []xfer.Request{
+		{NodeID: selfNodeID, Control: process.HeapProfile},
+		{NodeID: selfNodeID, Control: process.CPUProfile, ControlArgs: map[string]string{process.CPUProfileSeconds: "1"}},
+	} {
+		res := hr.HandleControlRequest(req)
+		if res.Error != "" {
+			t.Fatalf("%s: unexpected error %q", req.Control, res.Error)
+		}
+		if res.Artifact == nil || res.Artifact.Name == "" || res.Artifact.ContentType != process.ProfileContentType {
+			t.Fatalf("%s: expected a profile artifact, got %v", req.Control, res.Artifact)
+		}
+		if len(res.Artifact.Data) == 0 {
+			t.Errorf("%s: expected a non-empty profile", req.Control)
+		}
+	}
+
+	if res := hr.HandleControlRequest(xfer.Request{NodeID: otherID, Control: process.HeapProfile}); res.Error == "" {
+		t.Errorf("Expected profiling other processes to fail")
+	}
+	for _, seconds := range []string{"0", "-1", "31"} {
+		req := xfer.Request{NodeID: selfNodeID, Control: process.CPUProfile, ControlArgs: map[string]string{process.CPUProfileSeconds: seconds}}
+		if res := hr.HandleControlRequest(req); res.Error == "" {
+			t.Errorf("Expected a CPU profile of %s seconds to be rejected", seconds)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package process
 import (
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/weaveworks/common/mtime"
 	"github.com/weaveworks/scope/report"
@@ -18,15 +19,18 @@ const (
 	CPUUsage       = "process_cpu_usage_percent"
 	MemoryUsage    = "process_memory_usage_bytes"
 	OpenFilesCount = "open_files_count"
+	StartTime      = "process_start_time"
+	NetNamespace   = "process_net_namespace"
 )
 
 // Exposed for testing
 var (
 	MetadataTemplates = report.MetadataTemplates{
-		PID:     {ID: PID, Label: "PID", From: report.FromLatest, Datatype: "number", Priority: 1},
-		Cmdline: {ID: Cmdline, Label: "Command", From: report.FromLatest, Priority: 2},
-		PPID:    {ID: PPID, Label: "Parent PID", From: report.FromLatest, Datatype: "number", Priority: 3},
-		Threads: {ID: Threads, Label: "# Threads", From: report.FromLatest, Datatype: "number", Priority: 4},
+		PID:          {ID: PID, Label: "PID", From: report.FromLatest, Datatype: "number", Priority: 1},
+		Cmdline:      {ID: Cmdline, Label: "Command", From: report.FromLatest, Priority: 2},
+		PPID:         {ID: PPID, Label: "Parent PID", From: report.FromLatest, Datatype: "number", Priority: 3},
+		Threads:      {ID: Threads, Label: "# Threads", From: report.FromLatest, Datatype: "number", Priority: 4},
+		NetNamespace: {ID: NetNamespace, Label: "Network Namespace", From: report.FromLatest, Priority: 5},
 	}
 
 	MetricTemplates = report.MetricTemplates{
@@ -107,6 +111,14 @@ func (r *Reporter) processTopology() (report.Topology, error) {
 			node = node.WithLatests(map[string]string{PPID: strconv.Itoa(p.PPID)})
 		}
 
+		if !p.StartTime.IsZero() {
+			node = node.WithLatests(map[string]string{StartTime: p.StartTime.UTC().Format(time.RFC3339Nano)})
+		}
+
+		if p.NetNamespace > 0 {
+			node = node.WithLatests(map[string]string{NetNamespace: strconv.FormatUint(p.NetNamespace, 10)})
+		}
+
 		if deltaTotal > 0 {
 			cpuUsage := float64(p.Jiffies-prev.Jiffies) / float64(deltaTotal) * 100.
 			node = node.WithMetric(CPUUsage, report.MakeSingletonMetric(now, cpuUsage).WithMax(maxCPU))
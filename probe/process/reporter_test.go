@@ -24,8 +24,8 @@ func (m *mockWalker) Walk(f func(process.Process, process.Process)) error {
 var processes = []process.Process{
 	{PID: 1, PPID: 0, Name: "init"},
 	{PID: 2, PPID: 1, Name: "bash"},
-	{PID: 3, PPID: 1, Name: "apache", Threads: 2},
-	{PID: 4, PPID: 2, Name: "ping", Cmdline: "ping foo.bar.local"},
+	{PID: 3, PPID: 1, Name: "apache", Threads: 2, StartTime: time.Date(2017, 3, 14, 15, 9, 26, 0, time.UTC)},
+	{PID: 4, PPID: 2, Name: "ping", Cmdline: "ping foo.bar.local", NetNamespace: 4026531993},
 	{PID: 5, PPID: 1, Cmdline: "tail -f /var/log/syslog"},
 }
 
@@ -93,6 +93,34 @@ func TestThreadCounts(t *testing.T) {
 	testReporter(t, false, test)
 }
 
+func TestStartTime(t *testing.T) {
+	test := func(rpt report.Report) {
+		node := rpt.Process.Nodes[report.MakeProcessNodeID("", "3")]
+		if startTime, ok := node.Latest.Lookup(process.StartTime); !ok || startTime != "2017-03-14T15:09:26Z" {
+			t.Errorf("Expected the start time of pid 3 apache, got %q", startTime)
+		}
+		node = rpt.Process.Nodes[report.MakeProcessNodeID("", "2")]
+		if startTime, ok := node.Latest.Lookup(process.StartTime); ok {
+			t.Errorf("Expected no start time for pid 2 bash, got %q", startTime)
+		}
+	}
+	testReporter(t, false, test)
+}
+
+func TestNetNamespace(t *testing.T) {
+	test := func(rpt report.Report) {
+		node := rpt.Process.Nodes[report.MakeProcessNodeID("", "4")]
+		if netns, ok := node.Latest.Lookup(process.NetNamespace); !ok || netns != "4026531993" {
+			t.Errorf("Expected the network namespace of pid 4 ping, got %q", netns)
+		}
+		node = rpt.Process.Nodes[report.MakeProcessNodeID("", "2")]
+		if netns, ok := node.Latest.Lookup(process.NetNamespace); ok {
+			t.Errorf("Expected no network namespace for pid 2 bash, got %q", netns)
+		}
+	}
+	testReporter(t, false, test)
+}
+
 func TestCmdline(t *testing.T) {
 	test := func(rpt report.Report) {
 		node, ok := rpt.Process.Nodes[report.MakeProcessNodeID("", "4")]
@@ -1,6 +1,9 @@
 package process
 
-import "sync"
+import (
+	"sync"
+	"time"
+)
 
 // Process represents a single process.
 type Process struct {
@@ -14,6 +17,8 @@ type Process struct {
 	OpenFilesCount    int
 	OpenFilesLimit    uint64
 	IsWaitingInAccept bool
+	StartTime         time.Time
+	NetNamespace      uint64
 }
 
 // Walker is something that walks the /proc directory
@@ -8,11 +8,14 @@ import (
 	"path"
 	"strconv"
 	"strings"
+	"syscall"
+	"time"
 
 	linuxproc "github.com/c9s/goprocinfo/linux"
 	"github.com/coocood/freecache"
 
 	"github.com/weaveworks/common/fs"
+	"github.com/weaveworks/common/mtime"
 	"github.com/weaveworks/scope/probe/host"
 )
 
@@ -36,6 +39,10 @@ var (
 const (
 	limitsCacheTimeout  = 60
 	cmdlineCacheTimeout = 60
+
+	// clockTicks is the unit of the process start times in
+	// /proc/<pid>/stat (USER_HZ), fixed at 100 on all supported platforms.
+	clockTicks = 100
 )
 
 // NewWalker creates a new process Walker.
@@ -71,7 +78,7 @@ func parseIntWithSpaces(buf *[]byte, pos *int) (ret int) {
 }
 
 // readStats reads and parses '/proc/<pid>/stat' files
-func readStats(path string) (ppid, threads int, jiffies, rss, rssLimit uint64, err error) {
+func readStats(path string) (ppid, threads int, jiffies, startTicks, rss, rssLimit uint64, err error) {
 	const (
 		// /proc/<pid>/stat field positions, counting from zero
 		// see "man 5 proc"
@@ -79,6 +86,7 @@ func readStats(path string) (ppid, threads int, jiffies, rss, rssLimit uint64, e
 		procStatFieldUserJiffies int = 13
 		procStatFieldSysJiffies  int = 14
 		procStatFieldThreads     int = 19
+		procStatFieldStartTime   int = 21
 		procStatFieldRssPages    int = 23
 		procStatFieldRssLimit    int = 24
 	)
@@ -106,7 +114,10 @@ func readStats(path string) (ppid, threads int, jiffies, rss, rssLimit uint64, e
 	skipNSpaces(&buf, &pos, procStatFieldThreads-procStatFieldSysJiffies)
 	threads = parseIntWithSpaces(&buf, &pos)
 
-	skipNSpaces(&buf, &pos, procStatFieldRssPages-procStatFieldThreads)
+	skipNSpaces(&buf, &pos, procStatFieldStartTime-procStatFieldThreads)
+	startTicks = parseUint64WithSpaces(&buf, &pos)
+
+	skipNSpaces(&buf, &pos, procStatFieldRssPages-procStatFieldStartTime)
 	rssPages = parseUint64WithSpaces(&buf, &pos)
 
 	pos++ // 1 space between rssPages and rssLimit
@@ -204,13 +215,18 @@ func (w *walker) Walk(f func(Process, Process)) error {
 		return err
 	}
 
+	var bootTime time.Time
+	if uptime, err := host.GetUptime(); err == nil {
+		bootTime = mtime.Now().Add(-uptime)
+	}
+
 	for _, filename := range dirEntries {
 		pid, err := strconv.Atoi(filename)
 		if err != nil {
 			continue
 		}
 
-		ppid, threads, jiffies, rss, rssLimit, err := readStats(path.Join(w.procRoot, filename, "stat"))
+		ppid, threads, jiffies, startTicks, rss, rssLimit, err := readStats(path.Join(w.procRoot, filename, "stat"))
 		if err != nil {
 			continue
 		}
@@ -248,6 +264,17 @@ func (w *walker) Walk(f func(Process, Process)) error {
 			isWaitingInAccept = IsProcInAccept(w.procRoot, filename)
 		}
 
+		var startTime time.Time
+		if startTicks > 0 && !bootTime.IsZero() {
+			startTime = bootTime.Add(time.Duration(startTicks) * time.Second / clockTicks)
+		}
+
+		var netNamespace uint64
+		var statT syscall.Stat_t
+		if err := fs.Stat(path.Join(w.procRoot, filename, "ns", "net"), &statT); err == nil {
+			netNamespace = statT.Ino
+		}
+
 		f(Process{
 			PID:               pid,
 			PPID:              ppid,
@@ -260,6 +287,8 @@ func (w *walker) Walk(f func(Process, Process)) error {
 			OpenFilesCount:    openFilesCount,
 			OpenFilesLimit:    openFilesLimit,
 			IsWaitingInAccept: isWaitingInAccept,
+			StartTime:         startTime,
+			NetNamespace:      netNamespace,
 		}, Process{})
 	}
 
@@ -0,0 +1,38 @@
+package probe
+
+import (
+	"time"
+
+	"github.com/weaveworks/common/mtime"
+)
+
+// tokenBucket budgets the publications of the probe. It holds up to a
+// second's worth of tokens, refilled at rate tokens per second, and each
+// publication takes one.
+type tokenBucket struct {
+	rate, capacity, tokens float64
+	last                   time.Time
+}
+
+func newTokenBucket(rate float64) *tokenBucket {
+	capacity := rate
+	if capacity < 1 {
+		capacity = 1
+	}
+	return &tokenBucket{rate: rate, capacity: capacity, tokens: capacity, last: mtime.Now()}
+}
+
+// take takes a token, if one is available.
+func (b *tokenBucket) take() bool {
+	now := mtime.Now()
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.capacity {
+		b.tokens = b.capacity
+	}
+	b.last = now
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
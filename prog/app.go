@@ -59,7 +59,7 @@ func router(collector app.Collector, controlRouter app.ControlRouter, pipeRouter
 	router.Path("/metrics").Handler(prometheus.Handler())
 
 	app.RegisterReportPostHandler(collector, router)
-	app.RegisterControlRoutes(router, controlRouter)
+	app.RegisterControlRoutes(router, controlRouter, collector)
 	app.RegisterPipeRoutes(router, pipeRouter)
 	app.RegisterTopologyRoutes(router, collector, capabilities)
 
@@ -236,6 +236,7 @@ func appMain(flags appFlags) {
 	if flags.userIDHeader != "" {
 		userIDer = multitenant.UserIDHeader(flags.userIDHeader)
 	}
+	app.OrgID = userIDer
 
 	collector, err := collectorFactory(
 		userIDer, flags.collectorURL, flags.s3URL, flags.natsHostname, flags.memcachedHostname,
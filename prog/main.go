@@ -96,6 +96,13 @@ type probeFlags struct {
 	token                  string
 	httpListen             string
 	publishInterval        time.Duration
+	maxPublishesPerSecond  float64
+	http2                  bool
+	adaptiveCompression    bool
+	heartbeats             bool
+	bufferDir              string
+	bufferKeyFile          string
+	s3URL                  string
 	spyInterval            time.Duration
 	pluginsRoot            string
 	insecure               bool
@@ -104,6 +111,7 @@ type probeFlags struct {
 	resolver               string
 	noApp                  bool
 	noControls             bool
+	controlCacheTTL        time.Duration
 	noCommandLineArguments bool
 	noEnvironmentVariables bool
 
@@ -274,9 +282,17 @@ func setupFlags(flags *flags) {
 	flag.StringVar(&flags.probe.token, probeTokenFlag, "", "Token to use to authenticate with cloud.weave.works")
 	flag.StringVar(&flags.probe.httpListen, "probe.http.listen", "", "listen address for HTTP profiling and instrumentation server")
 	flag.DurationVar(&flags.probe.publishInterval, "probe.publish.interval", 3*time.Second, "publish (output) interval")
+	flag.Float64Var(&flags.probe.maxPublishesPerSecond, "probe.publish.max-rate", 0, "maximum number of reports published per second, merging the excess (0 means no limit)")
+	flag.BoolVar(&flags.probe.http2, "probe.publish.http2", false, "publish over HTTP/2, reusing a single connection, when the app supports it")
+	flag.BoolVar(&flags.probe.adaptiveCompression, "probe.publish.adaptive-compression", false, "compress each report as suits it best, e.g. not at all when incompressible")
+	flag.BoolVar(&flags.probe.heartbeats, "probe.publish.heartbeats", false, "publish a heartbeat in place of a report unchanged since the last one published")
+	flag.StringVar(&flags.probe.bufferDir, "probe.publish.buffer-dir", "", "directory to buffer reports which couldn't be published in, encrypted, until the app is back (empty means no buffering)")
+	flag.StringVar(&flags.probe.bufferKeyFile, "probe.publish.buffer-key-file", "", "file holding the AES key to encrypt buffered reports with, hex encoded (32, 48 or 64 hex digits, for a key of 16, 24 or 32 bytes)")
+	flag.StringVar(&flags.probe.s3URL, "probe.publish.s3", "", "S3 URL, as s3://key:secret@region/bucket/prefix, to archive reports to as well (empty means no archiving)")
 	flag.DurationVar(&flags.probe.spyInterval, "probe.spy.interval", time.Second, "spy (scan) interval")
 	flag.StringVar(&flags.probe.pluginsRoot, "probe.plugins.root", "/var/run/scope/plugins", "Root directory to search for plugins")
 	flag.BoolVar(&flags.probe.noControls, "probe.no-controls", false, "Disable controls (e.g. start/stop containers, terminals, logs ...)")
+	flag.DurationVar(&flags.probe.controlCacheTTL, "probe.controls.cache-ttl", 10*time.Second, "how long the responses of read-only controls (e.g. container inspection) are reused for, for the same request (0 means no caching)")
 	flag.BoolVar(&flags.probe.noCommandLineArguments, "probe.omit.cmd-args", false, "Disable collection of command-line arguments")
 	flag.BoolVar(&flags.probe.noEnvironmentVariables, "probe.omit.env-vars", false, "Disable collection of environment variables")
 
@@ -1,13 +1,18 @@
 package main
 
 import (
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
 	"math/rand"
 	"net"
 	"net/http"
 	_ "net/http/pprof"
 	"net/url"
 	"os"
+	"path"
 	"strconv"
+	"strings"
 	"time"
 
 	log "github.com/Sirupsen/logrus"
@@ -87,6 +92,28 @@ func maybeExportProfileData(flags probeFlags) {
 	}
 }
 
+// s3ReportPublisher archives reports to the bucket of the S3 URL, under
+// its path and the ID of the probe.
+func s3ReportPublisher(s3URL, probeID string) (*appclient.S3ReportPublisher, error) {
+	parsed, err := url.Parse(s3URL)
+	if err != nil {
+		return nil, err
+	}
+	config, err := awsConfigFromURL(parsed)
+	if err != nil {
+		return nil, err
+	}
+	parts := strings.SplitN(strings.TrimPrefix(parsed.Path, "/"), "/", 2)
+	if parts[0] == "" {
+		return nil, fmt.Errorf("no bucket in %s", s3URL)
+	}
+	prefix := probeID
+	if len(parts) == 2 {
+		prefix = path.Join(parts[1], probeID)
+	}
+	return appclient.NewS3ReportPublisher(config, parts[0], prefix), nil
+}
+
 // Main runs the probe
 func probeMain(flags probeFlags, targets []appclient.Target) {
 	setLogLevel(flags.logLevel)
@@ -113,21 +140,42 @@ func probeMain(flags probeFlags, targets []appclient.Target) {
 	log.Infof("probe starting, version %s, ID %s", version, probeID)
 	checkNewScopeVersion(flags)
 
+	var bufferKey []byte
+	if flags.bufferDir != "" {
+		key, err := ioutil.ReadFile(flags.bufferKeyFile)
+		if err != nil {
+			log.Fatalf("Error reading report buffer key: %v", err)
+		}
+		// The key is hex encoded, and may be followed by a newline.
+		if bufferKey, err = hex.DecodeString(strings.TrimSpace(string(key))); err != nil {
+			log.Fatalf("Error decoding report buffer key, expected hex digits: %v", err)
+		}
+	}
+
 	handlerRegistry := controls.NewDefaultHandlerRegistry()
+	if flags.controlCacheTTL > 0 {
+		handlerRegistry.CacheReadOnly(flags.controlCacheTTL, docker.ContainerControls...)
+	}
+	probeConfig := appclient.ProbeConfig{
+		ProbeVersion: version,
+		ProbeID:      probeID,
+		Insecure:     flags.insecure,
+
+		MaxPublishesPerSecond: flags.maxPublishesPerSecond,
+		HTTP2:                 flags.http2,
+		Heartbeats:            flags.heartbeats,
+		BufferDir:             flags.bufferDir,
+		BufferKey:             bufferKey,
+	}
 	clientFactory := func(hostname string, url url.URL) (appclient.AppClient, error) {
-		token := flags.token
+		config := probeConfig
+		config.Token = flags.token
 		if url.User != nil {
-			token = url.User.Username()
+			config.Token = url.User.Username()
 			url.User = nil // erase credentials, as we use a special header
 		}
-		probeConfig := appclient.ProbeConfig{
-			Token:        token,
-			ProbeVersion: version,
-			ProbeID:      probeID,
-			Insecure:     flags.insecure,
-		}
 		return appclient.NewAppClient(
-			probeConfig, hostname, url,
+			config, hostname, url,
 			xfer.ControlHandlerFunc(handlerRegistry.HandleControlRequest),
 		)
 	}
@@ -149,7 +197,23 @@ func probeMain(flags probeFlags, targets []appclient.Target) {
 	}
 	defer resolver.Stop()
 
-	p := probe.New(flags.spyInterval, flags.publishInterval, clients, flags.noControls)
+	var publisher appclient.Publisher = clients
+	if flags.s3URL != "" {
+		s3Publisher, err := s3ReportPublisher(flags.s3URL, probeID)
+		if err != nil {
+			log.Fatalf("Error archiving reports to S3: %v", err)
+		}
+		publisher = appclient.Publishers{clients, s3Publisher}
+	}
+
+	p := probe.New(flags.spyInterval, flags.publishInterval, publisher, flags.noControls)
+	p.LimitPublishRate(probeConfig.MaxPublishesPerSecond)
+	if flags.adaptiveCompression {
+		p.SetReportEncoder(appclient.AdaptiveEncoder)
+	}
+	if flags.heartbeats {
+		p.EnableHeartbeats(probeID)
+	}
 
 	hostReporter := host.NewReporter(hostID, hostName, probeID, version, clients, handlerRegistry)
 	defer hostReporter.Stop()
@@ -161,6 +225,9 @@ func probeMain(flags probeFlags, targets []appclient.Target) {
 		processCache = process.NewCachingWalker(process.NewWalker(flags.procRoot, false))
 		p.AddTicker(processCache)
 		p.AddReporter(process.NewReporter(processCache, hostID, process.GetDeltaTotalJiffies, flags.noCommandLineArguments))
+		profiler := process.NewProfiler(hostID, probeID, handlerRegistry)
+		defer profiler.Stop()
+		p.AddTagger(profiler)
 	}
 
 	dnsSnooper, err := endpoint.NewDNSSnooper()
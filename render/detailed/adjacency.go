@@ -0,0 +1,73 @@
+package detailed
+
+import (
+	"strconv"
+
+	"github.com/ugorji/go/codec"
+)
+
+// Graph is a node and its connection peers, in the nodes and edges
+// shape generic graph libraries (d3, cytoscape...) expect.
+type Graph struct {
+	Nodes []GraphNode `json:"nodes"`
+	Edges []GraphEdge `json:"edges"`
+}
+
+// GraphNode is a vertex of a Graph.
+type GraphNode struct {
+	ID    string `json:"id"`
+	Label string `json:"label"`
+}
+
+// GraphEdge is a directed edge of a Graph, with one edge per
+// connection row.
+type GraphEdge struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+	Port   string `json:"port,omitempty"`
+	Count  int    `json:"count"`
+}
+
+// Graph derives the graph of the node and its peers from its
+// connection summaries. Peers without a node, such as networks
+// aggregating several remotes, are identified by their label.
+func (n Node) Graph() Graph {
+	result := Graph{
+		Nodes: []GraphNode{{ID: n.ID, Label: n.Label}},
+		Edges: []GraphEdge{},
+	}
+	seen := map[string]struct{}{n.ID: {}}
+	for _, summary := range n.Connections {
+		for _, row := range summary.Connections {
+			peer := row.NodeID
+			if peer == "" {
+				peer = row.Label
+			}
+			if _, ok := seen[peer]; !ok {
+				seen[peer] = struct{}{}
+				result.Nodes = append(result.Nodes, GraphNode{ID: peer, Label: row.Label})
+			}
+			edge := GraphEdge{Source: peer, Target: n.ID}
+			if summary.ID == outgoingConnectionsID {
+				edge.Source, edge.Target = n.ID, peer
+			}
+			for _, m := range row.Metadata {
+				switch m.ID {
+				case portKey:
+					edge.Port = m.Value
+				case countKey:
+					edge.Count, _ = strconv.Atoi(m.Value)
+				}
+			}
+			result.Edges = append(result.Edges, edge)
+		}
+	}
+	return result
+}
+
+// AdjacencyJSON is Graph, encoded as JSON.
+func (n Node) AdjacencyJSON() ([]byte, error) {
+	var buf []byte
+	err := codec.NewEncoderBytes(&buf, &codec.JsonHandle{}).Encode(n.Graph())
+	return buf, err
+}
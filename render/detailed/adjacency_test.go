@@ -0,0 +1,59 @@
+package detailed_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/weaveworks/common/test"
+	"github.com/weaveworks/scope/render"
+	"github.com/weaveworks/scope/render/detailed"
+	"github.com/weaveworks/scope/test/fixture"
+	"github.com/weaveworks/scope/test/reflect"
+)
+
+func TestNodeAdjacency(t *testing.T) {
+	renderableNodes := render.ContainerRenderer.Render(fixture.Report, nil)
+
+	// Inbound rows become edges towards the node
+	server := detailed.MakeNode("containers", fixture.Report, renderableNodes, renderableNodes[fixture.ServerContainerNodeID])
+	want := detailed.Graph{
+		Nodes: []detailed.GraphNode{
+			{ID: fixture.ServerContainerNodeID, Label: server.Label},
+			{ID: fixture.ClientContainerNodeID, Label: fixture.ClientContainerName},
+			{ID: render.IncomingInternetID, Label: fixture.RandomClientIP},
+		},
+		Edges: []detailed.GraphEdge{
+			{Source: fixture.ClientContainerNodeID, Target: fixture.ServerContainerNodeID, Port: "80", Count: 2},
+			{Source: render.IncomingInternetID, Target: fixture.ServerContainerNodeID, Port: "80", Count: 1},
+		},
+	}
+	if have := server.Graph(); !reflect.DeepEqual(want, have) {
+		t.Error(test.Diff(want, have))
+	}
+
+	// Outbound rows become edges away from it
+	client := detailed.MakeNode("containers", fixture.Report, renderableNodes, renderableNodes[fixture.ClientContainerNodeID])
+	have := client.Graph()
+	wantEdges := []detailed.GraphEdge{
+		{Source: fixture.ClientContainerNodeID, Target: fixture.ServerContainerNodeID, Port: "80", Count: 2},
+	}
+	if !reflect.DeepEqual(wantEdges, have.Edges) {
+		t.Error(test.Diff(wantEdges, have.Edges))
+	}
+
+	// The JSON has the shape graph libraries expect
+	buf, err := server.AdjacencyJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded struct {
+		Nodes []map[string]interface{} `json:"nodes"`
+		Edges []map[string]interface{} `json:"edges"`
+	}
+	if err := json.Unmarshal(buf, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded.Nodes) != 3 || len(decoded.Edges) != 2 || decoded.Edges[0]["source"] != fixture.ClientContainerNodeID {
+		t.Errorf("Unexpected adjacency JSON: %s", buf)
+	}
+}
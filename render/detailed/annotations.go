@@ -0,0 +1,44 @@
+package detailed
+
+import (
+	"sync"
+
+	"github.com/weaveworks/scope/report"
+)
+
+// Annotation is a piece of information about a node, overlaid by an
+// external source (e.g. "owned by team X").
+type Annotation struct {
+	ID    string `json:"id"`
+	Label string `json:"label"`
+	Value string `json:"value"`
+}
+
+// AnnotationProvider produces the annotations for a node, if any.
+type AnnotationProvider func(report.Node) []Annotation
+
+var (
+	annotationProvidersMtx sync.RWMutex
+	annotationProviders    []AnnotationProvider
+)
+
+// RegisterAnnotationProvider adds a provider which is consulted when
+// summarizing nodes. Providers are consulted in the order they were
+// registered and their annotations concatenated.
+func RegisterAnnotationProvider(provider AnnotationProvider) {
+	annotationProvidersMtx.Lock()
+	defer annotationProvidersMtx.Unlock()
+	annotationProviders = append(annotationProviders, provider)
+}
+
+// NodeAnnotations returns the annotations of all registered providers for
+// a node.
+func NodeAnnotations(n report.Node) []Annotation {
+	annotationProvidersMtx.RLock()
+	defer annotationProvidersMtx.RUnlock()
+	var result []Annotation
+	for _, provider := range annotationProviders {
+		result = append(result, provider(n)...)
+	}
+	return result
+}
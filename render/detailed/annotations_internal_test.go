@@ -0,0 +1,62 @@
+package detailed
+
+import (
+	"testing"
+
+	"github.com/weaveworks/common/test"
+	"github.com/weaveworks/scope/probe/docker"
+	"github.com/weaveworks/scope/probe/host"
+	"github.com/weaveworks/scope/report"
+	"github.com/weaveworks/scope/test/reflect"
+)
+
+func TestAnnotationProviders(t *testing.T) {
+	defer func() { annotationProviders = nil }()
+
+	RegisterAnnotationProvider(func(n report.Node) []Annotation {
+		if n.ID != "a" {
+			return nil
+		}
+		return []Annotation{{ID: "owner", Label: "Owner", Value: "team X"}}
+	})
+	RegisterAnnotationProvider(func(n report.Node) []Annotation {
+		if n.Topology != report.Container {
+			return nil
+		}
+		return []Annotation{{ID: "pagerduty", Label: "PagerDuty", Value: "service Y"}}
+	})
+
+	r := report.MakeReport()
+	r.Container.AddNode(report.MakeNodeWith("a", map[string]string{docker.ContainerName: "a"}).WithTopology(report.Container))
+	r.Container.AddNode(report.MakeNodeWith("b", map[string]string{docker.ContainerName: "b"}).WithTopology(report.Container))
+	r.Host.AddNode(report.MakeNodeWith("c", map[string]string{host.HostName: "c"}).WithTopology(report.Host))
+
+	for _, tc := range []struct {
+		node report.Node
+		want []Annotation
+	}{
+		{
+			node: r.Container.Nodes["a"],
+			want: []Annotation{
+				{ID: "owner", Label: "Owner", Value: "team X"},
+				{ID: "pagerduty", Label: "PagerDuty", Value: "service Y"},
+			},
+		},
+		{
+			node: r.Container.Nodes["b"],
+			want: []Annotation{{ID: "pagerduty", Label: "PagerDuty", Value: "service Y"}},
+		},
+		{
+			node: r.Host.Nodes["c"],
+			want: nil,
+		},
+	} {
+		summary, ok := MakeNodeSummary(r, tc.node)
+		if !ok {
+			t.Fatalf("Expected %s to be summarizable", tc.node.ID)
+		}
+		if !reflect.DeepEqual(tc.want, summary.Annotations) {
+			t.Errorf("%s: %s", tc.node.ID, test.Diff(tc.want, summary.Annotations))
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package detailed
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/weaveworks/scope/report"
+)
+
+// Badge flags a node as crossing a threshold, e.g. "CPU > 80%".
+type Badge struct {
+	Label string `json:"label"`
+	Level string `json:"level"`
+}
+
+// ThresholdRule badges the nodes whose latest value of a metric is above
+// Threshold, or below it if Below is set.
+type ThresholdRule struct {
+	Threshold float64
+	Below     bool
+	Label     string
+	Level     string
+}
+
+var (
+	thresholdRulesMtx sync.RWMutex
+	thresholdRules    = map[string][]ThresholdRule{}
+)
+
+// RegisterThresholdRule adds a rule for the given metric, which is
+// evaluated when summarizing nodes.
+func RegisterThresholdRule(metricID string, rule ThresholdRule) {
+	thresholdRulesMtx.Lock()
+	defer thresholdRulesMtx.Unlock()
+	thresholdRules[metricID] = append(thresholdRules[metricID], rule)
+}
+
+// NodeBadges returns the badges of the registered rules the node matches,
+// by metric ID, and then in the order the rules were registered.
+func NodeBadges(n report.Node) []Badge {
+	thresholdRulesMtx.RLock()
+	defer thresholdRulesMtx.RUnlock()
+	metricIDs := []string{}
+	for metricID := range thresholdRules {
+		metricIDs = append(metricIDs, metricID)
+	}
+	sort.Strings(metricIDs)
+
+	var result []Badge
+	for _, metricID := range metricIDs {
+		metric, ok := n.Metrics[metricID]
+		if !ok {
+			continue
+		}
+		sample, ok := metric.LastSample()
+		if !ok {
+			continue
+		}
+		for _, rule := range thresholdRules[metricID] {
+			if (!rule.Below && sample.Value > rule.Threshold) || (rule.Below && sample.Value < rule.Threshold) {
+				result = append(result, Badge{Label: rule.Label, Level: rule.Level})
+			}
+		}
+	}
+	return result
+}
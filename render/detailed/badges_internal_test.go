@@ -0,0 +1,62 @@
+package detailed
+
+import (
+	"testing"
+	"time"
+
+	"github.com/weaveworks/common/test"
+	"github.com/weaveworks/scope/probe/docker"
+	"github.com/weaveworks/scope/report"
+	"github.com/weaveworks/scope/test/reflect"
+)
+
+func TestThresholdRules(t *testing.T) {
+	defer func() { thresholdRules = map[string][]ThresholdRule{} }()
+
+	RegisterThresholdRule(docker.CPUTotalUsage, ThresholdRule{Threshold: 80, Label: "CPU > 80%", Level: "warning"})
+	RegisterThresholdRule(docker.CPUTotalUsage, ThresholdRule{Threshold: 95, Label: "CPU > 95%", Level: "critical"})
+	RegisterThresholdRule(docker.MemoryUsage, ThresholdRule{Threshold: 1 << 20, Below: true, Label: "Memory < 1MB", Level: "info"})
+
+	now := time.Now()
+	container := func(id string, cpu, memory float64) report.Node {
+		return report.MakeNodeWith(id, map[string]string{docker.ContainerName: id}).WithTopology(report.Container).WithMetrics(report.Metrics{
+			docker.CPUTotalUsage: report.MakeSingletonMetric(now, cpu),
+			docker.MemoryUsage:   report.MakeSingletonMetric(now, memory),
+		})
+	}
+	r := report.MakeReport()
+	for _, tc := range []struct {
+		node report.Node
+		want []Badge
+	}{
+		{
+			node: container("idle", 10, 1<<30),
+			want: nil,
+		},
+		{
+			node: container("busy", 85, 1<<30),
+			want: []Badge{{Label: "CPU > 80%", Level: "warning"}},
+		},
+		{
+			node: container("overloaded", 99, 1<<10),
+			want: []Badge{
+				{Label: "CPU > 80%", Level: "warning"},
+				{Label: "CPU > 95%", Level: "critical"},
+				{Label: "Memory < 1MB", Level: "info"},
+			},
+		},
+		{
+			node: report.MakeNodeWith("no-metrics", map[string]string{docker.ContainerName: "no-metrics"}).WithTopology(report.Container),
+			want: nil,
+		},
+	} {
+		r.Container.AddNode(tc.node)
+		summary, ok := MakeNodeSummary(r, tc.node)
+		if !ok {
+			t.Fatalf("Expected %s to be summarizable", tc.node.ID)
+		}
+		if !reflect.DeepEqual(tc.want, summary.Badges) {
+			t.Errorf("%s: %s", tc.node.ID, test.Diff(tc.want, summary.Badges))
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package detailed
+
+import (
+	"sort"
+
+	"github.com/ugorji/go/codec"
+
+	"github.com/weaveworks/scope/report"
+)
+
+// canonicalNode returns a copy of the node with all its lists sorted by
+// ID, so that renderings of the same input are identical regardless of the
+// order of maps they were derived from. Slices are copied before being
+// sorted, as summaries may be shared between nodes.
+func canonicalNode(n Node) Node {
+	n.NodeSummary = canonicalSummary(n.NodeSummary)
+
+	controls := append([]ControlInstance{}, n.Controls...)
+	sort.Sort(controlInstancesCanonical(controls))
+	n.Controls = controls
+
+	if n.Children != nil {
+		children := make([]NodeSummaryGroup, len(n.Children))
+		for i, group := range n.Children {
+			nodes := make([]NodeSummary, len(group.Nodes))
+			for j, node := range group.Nodes {
+				nodes[j] = canonicalSummary(node)
+			}
+			sort.Sort(nodeSummariesByID(nodes))
+			group.Nodes = nodes
+			group.Columns = canonicalColumns(group.Columns)
+			children[i] = group
+		}
+		sort.Sort(nodeSummaryGroupsCanonical(children))
+		n.Children = children
+	}
+
+	if n.Connections != nil {
+		connections := make([]ConnectionsSummary, len(n.Connections))
+		for i, summary := range n.Connections {
+			rows := make([]Connection, len(summary.Connections))
+			for j, row := range summary.Connections {
+				row.Metadata = canonicalMetadata(row.Metadata)
+				rows[j] = row
+			}
+			sort.Sort(connectionsByID(rows))
+			summary.Connections = rows
+			summary.Columns = canonicalColumns(summary.Columns)
+			connections[i] = summary
+		}
+		sort.Sort(connectionsSummariesByID(connections))
+		n.Connections = connections
+	}
+	return n
+}
+
+func canonicalSummary(s NodeSummary) NodeSummary {
+	s.Metadata = canonicalMetadata(s.Metadata)
+	if s.Metrics != nil {
+		metrics := append([]report.MetricRow{}, s.Metrics...)
+		sort.Sort(metricRowsByID(metrics))
+		s.Metrics = metrics
+	}
+	if s.Parents != nil {
+		parents := append([]Parent{}, s.Parents...)
+		sort.Sort(parentsCanonical(parents))
+		s.Parents = parents
+	}
+	if s.Tables != nil {
+		tables := make([]report.Table, len(s.Tables))
+		for i, table := range s.Tables {
+			table.Rows = append([]report.Row{}, table.Rows...)
+			sort.Sort(tableRowsByID(table.Rows))
+			tables[i] = table
+		}
+		sort.Sort(tablesByID(tables))
+		s.Tables = tables
+	}
+	if s.Annotations != nil {
+		annotations := append([]Annotation{}, s.Annotations...)
+		sort.Sort(annotationsByID(annotations))
+		s.Annotations = annotations
+	}
+	if s.Links != nil {
+		links := append([]ExternalLink{}, s.Links...)
+		sort.Sort(externalLinksByID(links))
+		s.Links = links
+	}
+	if s.Processes != nil {
+		processes := make([]NodeSummary, len(s.Processes))
+		for i, p := range s.Processes {
+			processes[i] = canonicalSummary(p)
+		}
+		sort.Sort(nodeSummariesByID(processes))
+		s.Processes = processes
+	}
+	return s
+}
+
+func canonicalMetadata(rows []report.MetadataRow) []report.MetadataRow {
+	if rows == nil {
+		return nil
+	}
+	result := append([]report.MetadataRow{}, rows...)
+	sort.Sort(metadataRowsByID(result))
+	return result
+}
+
+func canonicalColumns(columns []Column) []Column {
+	if columns == nil {
+		return nil
+	}
+	result := append([]Column{}, columns...)
+	sort.Sort(columnsByID(result))
+	return result
+}
+
+// CanonicalJSON encodes the node as JSON, with map keys sorted. Rendered
+// with the Canonical option, the same input always encodes to the same
+// bytes.
+func (n Node) CanonicalJSON() ([]byte, error) {
+	var buf []byte
+	handle := &codec.JsonHandle{}
+	handle.Canonical = true
+	err := codec.NewEncoderBytes(&buf, handle).Encode(n)
+	return buf, err
+}
+
+type controlInstancesCanonical []ControlInstance
+
+func (s controlInstancesCanonical) Len() int      { return len(s) }
+func (s controlInstancesCanonical) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s controlInstancesCanonical) Less(i, j int) bool {
+	if s[i].Control.ID != s[j].Control.ID {
+		return s[i].Control.ID < s[j].Control.ID
+	}
+	if s[i].NodeID != s[j].NodeID {
+		return s[i].NodeID < s[j].NodeID
+	}
+	return s[i].ProbeID < s[j].ProbeID
+}
+
+type nodeSummaryGroupsCanonical []NodeSummaryGroup
+
+func (s nodeSummaryGroupsCanonical) Len() int      { return len(s) }
+func (s nodeSummaryGroupsCanonical) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s nodeSummaryGroupsCanonical) Less(i, j int) bool {
+	if s[i].TopologyID != s[j].TopologyID {
+		return s[i].TopologyID < s[j].TopologyID
+	}
+	if s[i].ID != s[j].ID {
+		return s[i].ID < s[j].ID
+	}
+	return s[i].Label < s[j].Label
+}
+
+type parentsCanonical []Parent
+
+func (s parentsCanonical) Len() int      { return len(s) }
+func (s parentsCanonical) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s parentsCanonical) Less(i, j int) bool {
+	if s[i].TopologyID != s[j].TopologyID {
+		return s[i].TopologyID < s[j].TopologyID
+	}
+	return s[i].ID < s[j].ID
+}
+
+type connectionsSummariesByID []ConnectionsSummary
+
+func (s connectionsSummariesByID) Len() int           { return len(s) }
+func (s connectionsSummariesByID) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s connectionsSummariesByID) Less(i, j int) bool { return s[i].ID < s[j].ID }
+
+type columnsByID []Column
+
+func (s columnsByID) Len() int           { return len(s) }
+func (s columnsByID) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s columnsByID) Less(i, j int) bool { return s[i].ID < s[j].ID }
+
+type metadataRowsByID []report.MetadataRow
+
+func (s metadataRowsByID) Len() int           { return len(s) }
+func (s metadataRowsByID) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s metadataRowsByID) Less(i, j int) bool { return s[i].ID < s[j].ID }
+
+type metricRowsByID []report.MetricRow
+
+func (s metricRowsByID) Len() int           { return len(s) }
+func (s metricRowsByID) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s metricRowsByID) Less(i, j int) bool { return s[i].ID < s[j].ID }
+
+type tablesByID []report.Table
+
+func (s tablesByID) Len() int           { return len(s) }
+func (s tablesByID) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s tablesByID) Less(i, j int) bool { return s[i].ID < s[j].ID }
+
+type tableRowsByID []report.Row
+
+func (s tableRowsByID) Len() int           { return len(s) }
+func (s tableRowsByID) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s tableRowsByID) Less(i, j int) bool { return s[i].ID < s[j].ID }
+
+type annotationsByID []Annotation
+
+func (s annotationsByID) Len() int           { return len(s) }
+func (s annotationsByID) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s annotationsByID) Less(i, j int) bool { return s[i].ID < s[j].ID }
+
+type externalLinksByID []ExternalLink
+
+func (s externalLinksByID) Len() int           { return len(s) }
+func (s externalLinksByID) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s externalLinksByID) Less(i, j int) bool { return s[i].ID < s[j].ID }
@@ -0,0 +1,58 @@
+package detailed_test
+
+import (
+	"bytes"
+	"sort"
+	"testing"
+
+	"github.com/weaveworks/scope/render"
+	"github.com/weaveworks/scope/render/detailed"
+	"github.com/weaveworks/scope/test/fixture"
+)
+
+func TestMakeNodeCanonical(t *testing.T) {
+	renderableNodes := render.HostRenderer.Render(fixture.Report, nil)
+	renderableNode := renderableNodes[fixture.ClientHostNodeID]
+	opts := detailed.RenderOptions{Canonical: true, Debug: true, Neighborhood: true}
+
+	var first []byte
+	for i := 0; i < 20; i++ {
+		node := detailed.MakeNodeWithOptions("hosts", fixture.Report, renderableNodes, renderableNode, opts)
+		have, err := node.CanonicalJSON()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if first == nil {
+			first = have
+			continue
+		}
+		if !bytes.Equal(first, have) {
+			t.Fatalf("Expected identical renderings, got:\n%s\n%s", first, have)
+		}
+	}
+
+	node := detailed.MakeNodeWithOptions("hosts", fixture.Report, renderableNodes, renderableNode, opts)
+	topologyIDs := []string{}
+	for _, group := range node.Children {
+		topologyIDs = append(topologyIDs, group.TopologyID)
+		columnIDs := []string{}
+		for _, column := range group.Columns {
+			columnIDs = append(columnIDs, column.ID)
+		}
+		if !sort.StringsAreSorted(columnIDs) {
+			t.Errorf("Expected the columns of %s sorted, got %v", group.TopologyID, columnIDs)
+		}
+	}
+	if len(topologyIDs) < 2 || !sort.StringsAreSorted(topologyIDs) {
+		t.Errorf("Expected children groups sorted, got %v", topologyIDs)
+	}
+	for _, summary := range node.Connections {
+		ids := []string{}
+		for _, row := range summary.Connections {
+			ids = append(ids, row.ID)
+		}
+		if !sort.StringsAreSorted(ids) {
+			t.Errorf("Expected the rows of %s sorted, got %v", summary.ID, ids)
+		}
+	}
+}
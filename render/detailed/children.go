@@ -0,0 +1,1000 @@
+package detailed
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/weaveworks/common/mtime"
+	"github.com/weaveworks/scope/probe/awsecs"
+	"github.com/weaveworks/scope/probe/docker"
+	"github.com/weaveworks/scope/probe/kubernetes"
+	"github.com/weaveworks/scope/probe/process"
+	"github.com/weaveworks/scope/report"
+)
+
+// UptimeID is the ID of the uptime column of the children groups whose
+// nodes report a start time.
+const UptimeID = "uptime"
+
+// AgeID is the ID of the age column of children, shown with the AgeColumn
+// option.
+const AgeID = "age"
+
+// PublishedPortsID is the ID of the column of the ports containers publish
+// on their host, shown with the ContainerNetworking option.
+const PublishedPortsID = "published_ports"
+
+// startTimeKeys are the latest metadata keys holding the start time of
+// the nodes of each topology, from which their uptime is computed.
+var startTimeKeys = map[string]string{
+	report.Container: docker.ContainerStartedAt,
+	report.Process:   process.StartTime,
+}
+
+// creationTimeKeys are the latest metadata keys holding the creation time
+// of the nodes of each topology, from which their age is computed.
+var creationTimeKeys = map[string]string{
+	report.Container:  docker.ContainerCreated,
+	report.Pod:        kubernetes.Created,
+	report.Service:    kubernetes.Created,
+	report.Deployment: kubernetes.Created,
+	report.DaemonSet:  kubernetes.Created,
+	report.ReplicaSet: kubernetes.Created,
+	report.ECSTask:    awsecs.CreatedAt,
+}
+
+// ageColumns are the columns added to children groups by the AgeColumn
+// option, when some children report their creation time.
+var ageColumns = []Column{{ID: AgeID, Label: "Age", Datatype: duration}}
+
+var (
+	childColumnsMtx sync.RWMutex
+	childColumns    = map[string][]Column{}
+)
+
+// RegisterChildColumns adds columns to the groups of children from the
+// given topology, after the built-in ones. Columns with a ControlID are
+// filled from the control history passed in the render options.
+func RegisterChildColumns(topologyID string, columns ...Column) {
+	childColumnsMtx.Lock()
+	defer childColumnsMtx.Unlock()
+	childColumns[topologyID] = append(childColumns[topologyID], columns...)
+}
+
+// withChildColumns returns a copy of the group with the columns registered
+// for the topology appended, and those referencing a control filled in.
+func withChildColumns(group NodeSummaryGroup, topologyID string, history *ControlHistory) NodeSummaryGroup {
+	childColumnsMtx.RLock()
+	extra := childColumns[topologyID]
+	childColumnsMtx.RUnlock()
+	if len(extra) == 0 {
+		return group
+	}
+	columns := make([]Column, 0, len(group.Columns)+len(extra))
+	group.Columns = append(append(columns, group.Columns...), extra...)
+	if history == nil {
+		return group
+	}
+	for i, node := range group.Nodes {
+		for _, column := range extra {
+			if column.ControlID == "" {
+				continue
+			}
+			result, ok := history.Latest(node.ID, column.ControlID)
+			if !ok {
+				continue
+			}
+			// Summaries may be shared between nodes, so don't append to
+			// their metadata in place.
+			metadata := make([]report.MetadataRow, len(node.Metadata), len(node.Metadata)+1)
+			copy(metadata, node.Metadata)
+			node.Metadata = append(metadata, report.MetadataRow{
+				ID:    column.ID,
+				Label: column.Label,
+				Value: controlResultValue(result),
+			})
+		}
+		group.Nodes[i] = node
+	}
+	return group
+}
+
+// controlResultValue renders a control result as a cell value.
+func controlResultValue(result ControlResult) string {
+	if result.Error != "" {
+		return result.Error
+	}
+	if result.Value == nil {
+		return ""
+	}
+	return fmt.Sprint(result.Value)
+}
+
+// withUptime returns a copy of the summary of the child with its uptime
+// added to the metadata, if the child reports when it started.
+func withUptime(summary NodeSummary, child report.Node) NodeSummary {
+	return withTimeSince(summary, child, startTimeKeys, UptimeID, "Uptime")
+}
+
+// withAge returns a copy of the summary of the child with its age added
+// to the metadata, if the child reports when it was created.
+func withAge(summary NodeSummary, child report.Node) NodeSummary {
+	return withTimeSince(summary, child, creationTimeKeys, AgeID, "Age")
+}
+
+// withTimeSince adds to the metadata of the summary a duration row with
+// the time elapsed since the time the child holds under the key for its
+// topology.
+func withTimeSince(summary NodeSummary, child report.Node, keys map[string]string, id, label string) NodeSummary {
+	key, ok := keys[child.Topology]
+	if !ok {
+		return summary
+	}
+	value, ok := child.Latest.Lookup(key)
+	if !ok {
+		return summary
+	}
+	since, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return summary
+	}
+	metadata := make([]report.MetadataRow, len(summary.Metadata), len(summary.Metadata)+1)
+	copy(metadata, summary.Metadata)
+	summary.Metadata = append(metadata, report.MetadataRow{
+		ID:       id,
+		Label:    label,
+		Value:    formatDuration(mtime.Now().Sub(since)),
+		Datatype: duration,
+	})
+	return summary
+}
+
+// restartHistoryTemplate renders the restart history of containers, shown
+// with the RestartHistory option.
+var restartHistoryTemplate = report.MetricTemplate{
+	ID:       docker.ContainerRestarts,
+	Label:    "Restarts",
+	Format:   report.IntegerFormat,
+	Priority: 10,
+}
+
+// restartHistoryColumns are the columns of the restart count of containers,
+// shown with the RestartHistory option.
+var restartHistoryColumns = []Column{
+	{ID: docker.ContainerRestarts, Label: "Restarts", Datatype: number},
+}
+
+// withRestartHistory adds a metric row of the restart history of the
+// container to its summary, if the container has restarted.
+func withRestartHistory(summary NodeSummary, n report.Node) NodeSummary {
+	if n.Topology != report.Container {
+		return summary
+	}
+	rows := restartHistoryTemplate.MetricRows(n)
+	if len(rows) == 0 {
+		return summary
+	}
+	metrics := make([]report.MetricRow, len(summary.Metrics), len(summary.Metrics)+len(rows))
+	copy(metrics, summary.Metrics)
+	summary.Metrics = append(metrics, rows...)
+	return summary
+}
+
+// pressureTemplates render the pressure stall information of hosts and
+// containers, shown with the ResourcePressure option.
+var pressureTemplates = []report.MetricTemplate{
+	{ID: report.CPUPressure, Label: "CPU Pressure", Format: report.PercentFormat, Priority: 20},
+	{ID: report.MemoryPressure, Label: "Memory Pressure", Format: report.PercentFormat, Priority: 21},
+	{ID: report.IOPressure, Label: "IO Pressure", Format: report.PercentFormat, Priority: 22},
+}
+
+// pressureColumns are the columns of the pressure stall information of
+// containers, shown with the ResourcePressure option.
+var pressureColumns = []Column{
+	{ID: report.CPUPressure, Label: "CPU Pressure", Datatype: number},
+	{ID: report.MemoryPressure, Label: "Mem. Pressure", Datatype: number},
+	{ID: report.IOPressure, Label: "IO Pressure", Datatype: number},
+}
+
+// Pressures, in percent of time stalled, from which hosts and containers
+// get a badge.
+const (
+	pressureWarning  = 10
+	pressureCritical = 40
+)
+
+// withPressure adds metric rows of the pressure stall information of the
+// host or container to its summary, with a badge for each resource under
+// pressure, as far as the node reports them.
+func withPressure(summary NodeSummary, n report.Node) NodeSummary {
+	if n.Topology != report.Host && n.Topology != report.Container {
+		return summary
+	}
+	var (
+		rows   []report.MetricRow
+		badges []Badge
+	)
+	for _, template := range pressureTemplates {
+		row, ok := metricRow(summary, template.ID)
+		if !ok {
+			templated := template.MetricRows(n)
+			if len(templated) == 0 {
+				continue
+			}
+			row = templated[0]
+			rows = append(rows, row)
+		}
+		switch {
+		case row.Value >= pressureCritical:
+			badges = append(badges, Badge{Label: template.Label, Level: "critical"})
+		case row.Value >= pressureWarning:
+			badges = append(badges, Badge{Label: template.Label, Level: "warning"})
+		}
+	}
+	if len(rows) > 0 {
+		metrics := make([]report.MetricRow, len(summary.Metrics), len(summary.Metrics)+len(rows))
+		copy(metrics, summary.Metrics)
+		summary.Metrics = append(metrics, rows...)
+	}
+	if len(badges) > 0 {
+		all := make([]Badge, len(summary.Badges), len(summary.Badges)+len(badges))
+		copy(all, summary.Badges)
+		summary.Badges = append(all, badges...)
+	}
+	return summary
+}
+
+// healthCheckColumns are the columns of the health-check status of
+// containers, shown with the HealthCheckStatus option.
+var healthCheckColumns = []Column{
+	{ID: docker.ContainerHealth, Label: "Health"},
+}
+
+// healthCheckBadges are the badges of the health-check statuses of
+// containers.
+var healthCheckBadges = map[string]Badge{
+	docker.HealthStarting:  {Label: "Health: starting", Level: "warning"},
+	docker.HealthHealthy:   {Label: "Health: healthy", Level: "info"},
+	docker.HealthUnhealthy: {Label: "Health: unhealthy", Level: "critical"},
+}
+
+// withHealthCheck adds the health-check status of the container to its
+// summary, as a metadata row and a badge, if the container has one.
+func withHealthCheck(summary NodeSummary, n report.Node) NodeSummary {
+	if n.Topology != report.Container {
+		return summary
+	}
+	status, ok := n.Latest.Lookup(docker.ContainerHealth)
+	if !ok {
+		return summary
+	}
+	if !hasRow(summary, docker.ContainerHealth) {
+		metadata := make([]report.MetadataRow, len(summary.Metadata), len(summary.Metadata)+1)
+		copy(metadata, summary.Metadata)
+		summary.Metadata = append(metadata, report.MetadataRow{
+			ID:    docker.ContainerHealth,
+			Label: "Health",
+			Value: status,
+		})
+	}
+	if badge, ok := healthCheckBadges[status]; ok {
+		badges := make([]Badge, len(summary.Badges), len(summary.Badges)+1)
+		copy(badges, summary.Badges)
+		summary.Badges = append(badges, badge)
+	}
+	return summary
+}
+
+// networkingColumns are the columns of the network mode and published ports
+// of containers, shown with the ContainerNetworking option.
+var networkingColumns = []Column{
+	{ID: docker.ContainerNetworkMode, Label: "Network Mode"},
+	{ID: PublishedPortsID, Label: "Published Ports"},
+}
+
+// withNetworking adds to the metadata of the summary of a container its
+// network mode and the ports it publishes on its host, as far as the
+// container reports them.
+func withNetworking(summary NodeSummary, n report.Node) NodeSummary {
+	if n.Topology != report.Container {
+		return summary
+	}
+	var rows []report.MetadataRow
+	if mode, ok := n.Latest.Lookup(docker.ContainerNetworkMode); ok && mode != "" && !hasRow(summary, docker.ContainerNetworkMode) {
+		rows = append(rows, report.MetadataRow{ID: docker.ContainerNetworkMode, Label: "Network Mode", Value: mode})
+	}
+	if ports, ok := n.Sets.Lookup(docker.ContainerPorts); ok {
+		// Ports are published as host-ip:host-port->container-port/proto;
+		// the others are only exposed.
+		published := []string{}
+		for _, port := range ports {
+			if strings.Contains(port, "->") {
+				published = append(published, port)
+			}
+		}
+		if len(published) > 0 {
+			rows = append(rows, report.MetadataRow{ID: PublishedPortsID, Label: "Published Ports", Value: strings.Join(published, ", ")})
+		}
+	}
+	if len(rows) == 0 {
+		return summary
+	}
+	metadata := make([]report.MetadataRow, len(summary.Metadata), len(summary.Metadata)+len(rows))
+	copy(metadata, summary.Metadata)
+	summary.Metadata = append(metadata, rows...)
+	return summary
+}
+
+// securityTemplates render the security context of Kubernetes containers,
+// shown with the SecurityContext option.
+var securityTemplates = []report.MetadataTemplate{
+	{ID: kubernetes.RunAsUser, Label: "Run As User", From: report.FromLatest, Priority: 27},
+	{ID: kubernetes.Privileged, Label: "Privileged", From: report.FromLatest, Priority: 28},
+	{ID: kubernetes.CapabilitiesAdded, Label: "Added Capabilities", From: report.FromLatest, Priority: 29},
+	{ID: kubernetes.CapabilitiesDropped, Label: "Dropped Capabilities", From: report.FromLatest, Priority: 30},
+}
+
+// securityColumns are the columns of the security context of containers,
+// shown with the SecurityContext option.
+var securityColumns = []Column{
+	{ID: kubernetes.RunAsUser, Label: "User"},
+	{ID: kubernetes.Privileged, Label: "Privileged"},
+}
+
+// withSecurityContext adds the security context of the container to its
+// summary, as metadata rows, and badges for privileged containers, for
+// containers running as root and for the capabilities they add, as far as
+// the container reports it.
+func withSecurityContext(summary NodeSummary, n report.Node) NodeSummary {
+	if n.Topology != report.Container {
+		return summary
+	}
+	var rows []report.MetadataRow
+	for _, template := range securityTemplates {
+		if !hasRow(summary, template.ID) {
+			rows = append(rows, template.MetadataRows(n)...)
+		}
+	}
+	var badges []Badge
+	if privileged, _ := n.Latest.Lookup(kubernetes.Privileged); privileged == "true" {
+		badges = append(badges, Badge{Label: "Privileged", Level: "critical"})
+	}
+	if user, ok := n.Latest.Lookup(kubernetes.RunAsUser); ok && user == "0" {
+		badges = append(badges, Badge{Label: "Runs as root", Level: "warning"})
+	}
+	if added, ok := n.Latest.Lookup(kubernetes.CapabilitiesAdded); ok && added != "" {
+		badges = append(badges, Badge{Label: "Capabilities: " + added, Level: "warning"})
+	}
+	if len(rows) > 0 {
+		metadata := make([]report.MetadataRow, len(summary.Metadata), len(summary.Metadata)+len(rows))
+		copy(metadata, summary.Metadata)
+		summary.Metadata = append(metadata, rows...)
+	}
+	if len(badges) > 0 {
+		all := make([]Badge, len(summary.Badges), len(summary.Badges)+len(badges))
+		copy(all, summary.Badges)
+		summary.Badges = append(all, badges...)
+	}
+	return summary
+}
+
+// provenanceColumns are the columns of the digest and pull policy of the
+// images of containers, shown with the ImageProvenance option.
+var provenanceColumns = []Column{
+	{ID: docker.ImageDigest, Label: "Digest"},
+	{ID: kubernetes.ImagePullPolicy, Label: "Pull Policy"},
+}
+
+// withImageProvenance adds to the metadata of the summary of a container or
+// image the digest of the image, and the pull policy of the image of
+// Kubernetes containers, as far as they are known. Containers take the
+// digest of their parent image.
+func withImageProvenance(summary NodeSummary, r report.Report, n report.Node) NodeSummary {
+	if n.Topology != report.Container && n.Topology != report.ContainerImage {
+		return summary
+	}
+	var rows []report.MetadataRow
+	if digest, ok := imageDigest(r, n); ok && !hasRow(summary, docker.ImageDigest) {
+		rows = append(rows, report.MetadataRow{ID: docker.ImageDigest, Label: "Digest", Value: digest, Truncate: 19})
+	}
+	if policy, ok := n.Latest.Lookup(kubernetes.ImagePullPolicy); ok && policy != "" && !hasRow(summary, kubernetes.ImagePullPolicy) {
+		rows = append(rows, report.MetadataRow{ID: kubernetes.ImagePullPolicy, Label: "Pull Policy", Value: policy})
+	}
+	if len(rows) == 0 {
+		return summary
+	}
+	metadata := make([]report.MetadataRow, len(summary.Metadata), len(summary.Metadata)+len(rows))
+	copy(metadata, summary.Metadata)
+	summary.Metadata = append(metadata, rows...)
+	return summary
+}
+
+// imageDigest returns the digest of the image, or of the parent image of
+// the container.
+func imageDigest(r report.Report, n report.Node) (string, bool) {
+	if digest, ok := n.Latest.Lookup(docker.ImageDigest); ok && digest != "" {
+		return digest, true
+	}
+	images, _ := n.Parents.Lookup(report.ContainerImage)
+	for _, id := range images {
+		if image, ok := r.ContainerImage.Nodes[id]; ok {
+			if digest, ok := image.Latest.Lookup(docker.ImageDigest); ok && digest != "" {
+				return digest, true
+			}
+		}
+	}
+	return "", false
+}
+
+// runtimeTemplate renders the container runtime of containers and hosts,
+// shown with the Runtime option.
+var runtimeTemplate = report.MetadataTemplate{ID: report.ContainerRuntime, Label: "Runtime", From: report.FromLatest, Priority: 11}
+
+// runtimeColumns are the columns of the container runtime of containers,
+// shown with the Runtime option.
+var runtimeColumns = []Column{
+	{ID: report.ContainerRuntime, Label: "Runtime"},
+}
+
+// withRuntime adds the container runtime of the container or host to the
+// metadata of its summary, if the node reports it.
+func withRuntime(summary NodeSummary, n report.Node) NodeSummary {
+	if (n.Topology != report.Container && n.Topology != report.Host) || hasRow(summary, report.ContainerRuntime) {
+		return summary
+	}
+	rows := runtimeTemplate.MetadataRows(n)
+	if len(rows) == 0 {
+		return summary
+	}
+	metadata := make([]report.MetadataRow, len(summary.Metadata), len(summary.Metadata)+len(rows))
+	copy(metadata, summary.Metadata)
+	summary.Metadata = append(metadata, rows...)
+	return summary
+}
+
+// schedulingTemplates render how pods are scheduled, shown with the
+// PodScheduling option.
+var schedulingTemplates = []report.MetadataTemplate{
+	{ID: kubernetes.NodeName, Label: "Node", From: report.FromLatest, Priority: 8},
+	{ID: kubernetes.SchedulingStatus, Label: "Scheduling", From: report.FromLatest, Priority: 9},
+	{ID: kubernetes.NodeSelector, Label: "Node Selector", From: report.FromLatest, Priority: 10},
+}
+
+// schedulingColumns are the columns of how pods are scheduled, shown with
+// the PodScheduling option.
+var schedulingColumns = []Column{
+	{ID: kubernetes.NodeName, Label: "Node"},
+	{ID: kubernetes.SchedulingStatus, Label: "Scheduling"},
+}
+
+// withScheduling adds to the metadata of the summary of a pod how it is
+// scheduled, as far as the pod reports it.
+func withScheduling(summary NodeSummary, n report.Node) NodeSummary {
+	if n.Topology != report.Pod {
+		return summary
+	}
+	var rows []report.MetadataRow
+	for _, template := range schedulingTemplates {
+		if !hasRow(summary, template.ID) {
+			rows = append(rows, template.MetadataRows(n)...)
+		}
+	}
+	if len(rows) == 0 {
+		return summary
+	}
+	metadata := make([]report.MetadataRow, len(summary.Metadata), len(summary.Metadata)+len(rows))
+	copy(metadata, summary.Metadata)
+	summary.Metadata = append(metadata, rows...)
+	return summary
+}
+
+// defaultTopologies hold the labels every report declares for its
+// topologies, which groupLabel doesn't take as ones of their own.
+var defaultTopologies = report.MakeReport()
+
+// groupLabel labels the group of children of the topology with the label
+// the report declares for it, e.g. by a plugin, falling back to the given
+// label.
+func groupLabel(r report.Report, topologyID, fallback string) string {
+	t, ok := r.Topology(topologyID)
+	if !ok {
+		return fallback
+	}
+	defaults, _ := defaultTopologies.Topology(topologyID)
+	switch {
+	case t.LabelPlural != "" && t.LabelPlural != defaults.LabelPlural:
+		return t.LabelPlural
+	case t.Label != "" && t.Label != defaults.Label:
+		return t.Label
+	}
+	return fallback
+}
+
+// labelPrefixes are the prefixes of the latest metadata keys of the labels
+// of nodes, which LabelTags turns into tags.
+var labelPrefixes = []string{docker.LabelPrefix, docker.ImageLabelPrefix, kubernetes.LabelPrefix}
+
+// withTags sets the tags of the summary to the labels of the node, as
+// sorted key=value pairs.
+func withTags(summary NodeSummary, n report.Node) NodeSummary {
+	var tags []string
+	n.Latest.ForEach(func(key string, _ time.Time, value string) {
+		for _, prefix := range labelPrefixes {
+			if label, ok := report.WithoutPrefix(key, prefix); ok {
+				tags = append(tags, label+"="+value)
+				break
+			}
+		}
+	})
+	sort.Strings(tags)
+	summary.Tags = tags
+	return summary
+}
+
+// withProbeIDs sets the probe IDs of the summary to the ID of the probe
+// which reported the node, if known.
+func withProbeIDs(summary NodeSummary, n report.Node) NodeSummary {
+	if probeID, ok := n.Latest.Lookup(report.ControlProbeID); ok {
+		summary.ProbeIDs = []string{probeID}
+	}
+	return summary
+}
+
+// excluded says whether the node has any of the key/value pairs of the
+// filter in its latest metadata.
+func excluded(n report.Node, filter map[string]string) bool {
+	for key, value := range filter {
+		if v, ok := n.Latest.Lookup(key); ok && v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// stale says whether the node hasn't been seen for longer than staleAfter.
+// Nodes without any timestamped metadata are never stale.
+func stale(n report.Node, staleAfter time.Duration) bool {
+	if staleAfter <= 0 {
+		return false
+	}
+	var lastSeen time.Time
+	n.Latest.ForEach(func(_ string, ts time.Time, _ string) {
+		if ts.After(lastSeen) {
+			lastSeen = ts
+		}
+	})
+	return !lastSeen.IsZero() && mtime.Now().Sub(lastSeen) > staleAfter
+}
+
+// percentOfGroup returns a copy of the group, where the values of metric
+// columns are replaced by the percentage of the group's total they
+// represent. A total of zero yields zero for every node.
+func percentOfGroup(group NodeSummaryGroup) NodeSummaryGroup {
+	columns := make([]Column, len(group.Columns))
+	copy(columns, group.Columns)
+	// The summaries may be shared with other groups through the summary
+	// cache, so their metrics are copied before being changed.
+	nodes := make([]NodeSummary, len(group.Nodes))
+	for i, node := range group.Nodes {
+		node.Metrics = append([]report.MetricRow(nil), node.Metrics...)
+		nodes[i] = node
+	}
+	group.Nodes = nodes
+	for i, column := range columns {
+		if column.Datatype != number {
+			continue
+		}
+		total, isMetric := 0.0, false
+		for _, node := range group.Nodes {
+			if row, ok := metricRow(node, column.ID); ok {
+				total += row.Value
+				isMetric = true
+			}
+		}
+		if !isMetric {
+			continue
+		}
+		for _, node := range group.Nodes {
+			for j, row := range node.Metrics {
+				if row.ID != column.ID {
+					continue
+				}
+				if total == 0 {
+					row.Value = 0
+				} else {
+					row.Value = row.Value / total * 100
+				}
+				row.Format = report.PercentFormat
+				node.Metrics[j] = row
+			}
+		}
+		columns[i].PercentOfGroup = true
+	}
+	group.Columns = columns
+	return group
+}
+
+func metricRow(node NodeSummary, id string) (report.MetricRow, bool) {
+	for _, row := range node.Metrics {
+		if row.ID == id {
+			return row, true
+		}
+	}
+	return report.MetricRow{}, false
+}
+
+// withoutZeroMetricColumns returns a copy of the group without the number
+// columns none of its nodes have a metadata row or a non-zero metric row
+// for.
+func withoutZeroMetricColumns(group NodeSummaryGroup) NodeSummaryGroup {
+	columns := []Column{}
+	for _, column := range group.Columns {
+		if column.Datatype != number || hasNonZeroRow(group.Nodes, column.ID) {
+			columns = append(columns, column)
+		}
+	}
+	group.Columns = columns
+	return group
+}
+
+func hasNonZeroRow(nodes []NodeSummary, id string) bool {
+	for _, node := range nodes {
+		if row, ok := metricRow(node, id); ok && row.Value != 0 {
+			return true
+		}
+		for _, row := range node.Metadata {
+			if row.ID == id {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// withOptionalColumns returns a copy of the group with the optional columns
+// some of its nodes have a metric or metadata row for.
+func withOptionalColumns(group NodeSummaryGroup, optional []Column) NodeSummaryGroup {
+	var present []Column
+	for _, column := range optional {
+		for _, node := range group.Nodes {
+			if hasRow(node, column.ID) {
+				present = append(present, column)
+				break
+			}
+		}
+	}
+	if len(present) == 0 {
+		return group
+	}
+	columns := make([]Column, 0, len(group.Columns)+len(present))
+	group.Columns = append(append(columns, group.Columns...), present...)
+	return group
+}
+
+// hasRow says whether the node has a metric or metadata row with the ID.
+func hasRow(node NodeSummary, id string) bool {
+	if _, ok := metricRow(node, id); ok {
+		return true
+	}
+	for _, row := range node.Metadata {
+		if row.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// containerResourceColumns are the columns of the resource requests and
+// limits of Kubernetes containers, shown with the ContainerResources option.
+var containerResourceColumns = []Column{
+	{ID: kubernetes.CPURequest, Label: "CPU Req.", Datatype: number},
+	{ID: kubernetes.CPULimit, Label: "CPU Limit", Datatype: number},
+	{ID: kubernetes.MemoryRequest, Label: "Mem. Req.", Datatype: number},
+	{ID: kubernetes.MemoryLimit, Label: "Mem. Limit", Datatype: number},
+}
+
+// probeStatusColumns are the columns of the status of the readiness and
+// liveness probes of Kubernetes containers, shown with the ProbeStatuses
+// option.
+var probeStatusColumns = []Column{
+	{ID: kubernetes.ReadinessProbe, Label: "Readiness"},
+	{ID: kubernetes.LivenessProbe, Label: "Liveness"},
+}
+
+// openFilesColumns are the columns of the number of open file descriptors
+// of processes, shown with the OpenFiles option.
+var openFilesColumns = []Column{
+	{ID: process.OpenFilesCount, Label: "Open Files", Datatype: number},
+}
+
+// commandColumns are the columns of the command lines of processes, shown
+// with the TruncateCommands option.
+var commandColumns = []Column{
+	{ID: process.Cmdline, Label: "Command"},
+}
+
+// OtherChildrenLabel labels the group of children without the metadata key
+// they are grouped by.
+const OtherChildrenLabel = "Other"
+
+// regroupChildren turns groups of children by topology into groups by the
+// given values, keyed by child ID. A group has the columns of all the
+// topologies of its children, and keeps the topology only if they all
+// share it.
+func regroupChildren(groups []NodeSummaryGroup, values map[string]string) []NodeSummaryGroup {
+	byValue := map[string]*NodeSummaryGroup{}
+	for _, group := range groups {
+		for _, node := range group.Nodes {
+			value := values[node.ID]
+			regrouped, ok := byValue[value]
+			if !ok {
+				label := value
+				if label == "" {
+					label = OtherChildrenLabel
+				}
+				regrouped = &NodeSummaryGroup{
+					ID:         value,
+					Label:      label,
+					TopologyID: group.TopologyID,
+				}
+				byValue[value] = regrouped
+			}
+			if regrouped.TopologyID != group.TopologyID {
+				regrouped.TopologyID = ""
+			}
+			regrouped.Columns = mergeColumns(regrouped.Columns, group.Columns)
+			regrouped.Nodes = append(regrouped.Nodes, node)
+		}
+	}
+
+	result := make([]NodeSummaryGroup, 0, len(byValue))
+	for _, group := range byValue {
+		sort.Sort(nodeSummariesByID(group.Nodes))
+		result = append(result, *group)
+	}
+	sort.Sort(nodeSummaryGroupsByValue(result))
+	return result
+}
+
+// groupByNetNamespace splits a group of processes into a group per
+// network namespace, given the namespaces of the processes by child ID.
+// Processes without a namespace are kept under the label of the group.
+func groupByNetNamespace(group NodeSummaryGroup, netNamespaces map[string]string) []NodeSummaryGroup {
+	if len(group.Nodes) == 0 {
+		return []NodeSummaryGroup{group}
+	}
+	result := regroupChildren([]NodeSummaryGroup{group}, netNamespaces)
+	for i := range result {
+		if result[i].ID == "" {
+			result[i].Label = group.Label
+		} else {
+			result[i].Label = fmt.Sprintf("%s in netns %s", group.Label, result[i].ID)
+		}
+	}
+	return result
+}
+
+// nestProcesses moves the summaries of the processes into the summaries of
+// the containers they run in, given the containers of each process by ID.
+// Processes not in any of the containers are left alone; the processes
+// are dropped altogether when they have all been nested.
+func nestProcesses(summaries map[string][]NodeSummary, processContainers map[string][]string) {
+	containers := map[string]int{}
+	for i, container := range summaries[report.Container] {
+		containers[container.ID] = i
+	}
+	processes, ok := summaries[report.Process]
+	if !ok || len(containers) == 0 {
+		return
+	}
+	remaining := []NodeSummary{}
+	nested := false
+	for _, p := range processes {
+		i, ok := -1, false
+		for _, containerID := range processContainers[p.ID] {
+			if i, ok = containers[containerID]; ok {
+				break
+			}
+		}
+		if !ok {
+			remaining = append(remaining, p)
+			continue
+		}
+		container := &summaries[report.Container][i]
+		container.Processes = append(container.Processes, p)
+		nested = true
+	}
+	for _, container := range summaries[report.Container] {
+		sort.Sort(nodeSummariesByID(container.Processes))
+	}
+	if nested && len(remaining) == 0 {
+		delete(summaries, report.Process)
+		return
+	}
+	summaries[report.Process] = remaining
+}
+
+// mergeColumns appends the columns missing from a to it.
+func mergeColumns(a, b []Column) []Column {
+	if a == nil {
+		a = []Column{}
+	}
+outer:
+	for _, column := range b {
+		for _, existing := range a {
+			if existing.ID == column.ID {
+				continue outer
+			}
+		}
+		a = append(a, column)
+	}
+	return a
+}
+
+// nodeSummaryGroupsBySize sorts groups of children by their number of
+// nodes, biggest first.
+type nodeSummaryGroupsBySize []NodeSummaryGroup
+
+func (s nodeSummaryGroupsBySize) Len() int           { return len(s) }
+func (s nodeSummaryGroupsBySize) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s nodeSummaryGroupsBySize) Less(i, j int) bool { return len(s[i].Nodes) > len(s[j].Nodes) }
+
+// nodeSummaryGroupsByValue sorts regrouped children by value, with children
+// without a value last.
+type nodeSummaryGroupsByValue []NodeSummaryGroup
+
+func (s nodeSummaryGroupsByValue) Len() int      { return len(s) }
+func (s nodeSummaryGroupsByValue) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s nodeSummaryGroupsByValue) Less(i, j int) bool {
+	if (s[i].ID == "") != (s[j].ID == "") {
+		return s[j].ID == ""
+	}
+	return s[i].ID < s[j].ID
+}
+
+// mergedContainersColumn counts the containers merged into a row by
+// mergeChildrenByImage.
+var mergedContainersColumn = Column{ID: report.Container, Label: "# Containers", Datatype: number}
+
+// mergeChildrenByImage merges the container summaries of the group sharing
+// an image, given by child ID, into one row per image, with their metrics
+// summed and the number of containers counted.
+func mergeChildrenByImage(group NodeSummaryGroup, r report.Report, imageIDs map[string]string) NodeSummaryGroup {
+	byImage := map[string][]NodeSummary{}
+	nodes := []NodeSummary{}
+	for _, node := range group.Nodes {
+		if imageID := imageIDs[node.ID]; imageID != "" {
+			byImage[imageID] = append(byImage[imageID], node)
+		} else {
+			nodes = append(nodes, node)
+		}
+	}
+	merged := false
+	for imageID, summaries := range byImage {
+		if len(summaries) == 1 {
+			nodes = append(nodes, summaries[0])
+			continue
+		}
+		nodes = append(nodes, mergeImageSummaries(r, imageID, summaries))
+		merged = true
+	}
+	if !merged {
+		return group
+	}
+	sort.Sort(nodeSummariesByID(nodes))
+	group.Nodes = nodes
+	columns := make([]Column, len(group.Columns))
+	copy(columns, group.Columns)
+	group.Columns = mergeColumns(columns, []Column{mergedContainersColumn})
+	return group
+}
+
+// mergeImageSummaries merges the summaries of containers of an image. The
+// result stands for several containers, so it isn't linkable.
+func mergeImageSummaries(r report.Report, imageID string, summaries []NodeSummary) NodeSummary {
+	imageNodeID := report.MakeContainerImageNodeID(imageID)
+	label := imageID
+	if image, ok := r.ContainerImage.Nodes[imageNodeID]; ok {
+		if name, ok := image.Latest.Lookup(docker.ImageName); ok {
+			label = name
+		}
+	}
+	result := NodeSummary{
+		ID:    imageNodeID,
+		Label: label,
+		Rank:  summaries[0].Rank,
+		Shape: summaries[0].Shape,
+		Metadata: []report.MetadataRow{{
+			ID:       mergedContainersColumn.ID,
+			Label:    mergedContainersColumn.Label,
+			Value:    strconv.Itoa(len(summaries)),
+			Datatype: number,
+		}},
+	}
+	result.Metrics = sumMetrics(summaries)
+	result.ProbeIDs = mergeProbeIDs(summaries)
+	return result
+}
+
+// mergeProbeIDs is the sorted union of the probe IDs of the summaries.
+func mergeProbeIDs(summaries []NodeSummary) []string {
+	set := report.MakeStringSet()
+	for _, summary := range summaries {
+		set = set.Add(summary.ProbeIDs...)
+	}
+	if len(set) == 0 {
+		return nil
+	}
+	return []string(set)
+}
+
+// sumMetrics sums the metric rows of the summaries by ID, in the order
+// they are first seen.
+func sumMetrics(summaries []NodeSummary) []report.MetricRow {
+	result := []report.MetricRow{}
+	for _, summary := range summaries {
+		for _, row := range summary.Metrics {
+			i := 0
+			for i < len(result) && result[i].ID != row.ID {
+				i++
+			}
+			if i == len(result) {
+				sum := row
+				sum.Value = 0
+				sum.Metric = &report.Metric{}
+				result = append(result, sum)
+			}
+			result[i].Value += row.Value
+			if row.Metric != nil {
+				result[i].Metric.Min += row.Metric.Min
+				result[i].Metric.Max += row.Metric.Max
+			}
+		}
+	}
+	return result
+}
+
+// nodeSummariesByMetric sorts node summaries by the value of a metric,
+// highest first, and those without it last.
+type nodeSummariesByMetric struct {
+	nodes    []NodeSummary
+	metricID string
+}
+
+func (s nodeSummariesByMetric) Len() int      { return len(s.nodes) }
+func (s nodeSummariesByMetric) Swap(i, j int) { s.nodes[i], s.nodes[j] = s.nodes[j], s.nodes[i] }
+func (s nodeSummariesByMetric) Less(i, j int) bool {
+	a, aok := metricRow(s.nodes[i], s.metricID)
+	b, bok := metricRow(s.nodes[j], s.metricID)
+	if aok != bok {
+		return aok
+	}
+	return a.Value > b.Value
+}
+
+// OtherChildrenID is the ID of the row summarizing the children left out
+// by topChildren.
+const OtherChildrenID = "others"
+
+// topChildren returns a copy of the group keeping only its n children with
+// the highest value of the metric, highest first, and a row with the
+// metrics of the others summed. Children without the metric rank last.
+func topChildren(group NodeSummaryGroup, n int, metricID string) NodeSummaryGroup {
+	if len(group.Nodes) <= n {
+		return group
+	}
+	nodes := make([]NodeSummary, len(group.Nodes))
+	copy(nodes, group.Nodes)
+	sort.Stable(nodeSummariesByMetric{nodes, metricID})
+	others := nodes[n:]
+	group.Nodes = append(nodes[:n:n], NodeSummary{
+		ID:      OtherChildrenID,
+		Label:   fmt.Sprintf("%d others", len(others)),
+		Metrics: sumMetrics(others),
+	})
+	return group
+}
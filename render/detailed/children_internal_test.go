@@ -0,0 +1,65 @@
+package detailed
+
+import (
+	"testing"
+	"time"
+
+	"github.com/weaveworks/scope/probe/docker"
+	"github.com/weaveworks/scope/report"
+	"github.com/weaveworks/scope/test/reflect"
+)
+
+func TestChildrenControlResultColumn(t *testing.T) {
+	defer func() { childColumns = map[string][]Column{} }()
+	RegisterChildColumns(report.Container, Column{ID: "health", Label: "Health", ControlID: "health_check"})
+
+	r := report.MakeReport()
+	pod := report.MakeNode("pod").WithTopology(report.Pod)
+	for _, id := range []string{"a", "b", "c"} {
+		c := report.MakeNodeWith(id, map[string]string{docker.ContainerName: id}).WithTopology(report.Container)
+		r.Container.AddNode(c)
+		pod = pod.WithChild(c)
+	}
+	r.Pod.AddNode(pod)
+
+	history := NewControlHistory(DefaultControlHistorySize, DefaultControlHistoryNodes)
+	now := time.Now()
+	history.Add("a", ControlResult{Timestamp: now, Control: "health_check", Value: "degraded"})
+	history.Add("a", ControlResult{Timestamp: now.Add(time.Second), Control: "health_check", Value: "ok"})
+	history.Add("a", ControlResult{Timestamp: now.Add(2 * time.Second), Control: "restart"})
+	history.Add("b", ControlResult{Timestamp: now, Control: "health_check", Error: "timeout"})
+
+	groups := children(r, pod, RenderOptions{ControlHistory: history}, nil)
+	if len(groups) != 1 {
+		t.Fatalf("Expected one group, got %v", groups)
+	}
+	group := groups[0]
+	if last := group.Columns[len(group.Columns)-1]; last.ID != "health" || last.ControlID != "health_check" {
+		t.Errorf("Expected the health column last, got %v", group.Columns)
+	}
+
+	have := map[string]string{}
+	for _, node := range group.Nodes {
+		for _, row := range node.Metadata {
+			if row.ID == "health" {
+				have[node.ID] = row.Value
+			}
+		}
+	}
+	// The latest result of the control wins, and children without
+	// results have an empty cell
+	want := map[string]string{"a": "ok", "b": "timeout"}
+	if !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+
+	// Without a history, the column is there but empty
+	groups = children(r, pod, RenderOptions{}, nil)
+	for _, node := range groups[0].Nodes {
+		for _, row := range node.Metadata {
+			if row.ID == "health" {
+				t.Errorf("Expected no health value without history, got %v", row)
+			}
+		}
+	}
+}
@@ -0,0 +1,1633 @@
+package detailed_test
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/weaveworks/common/mtime"
+	"github.com/weaveworks/scope/probe/awsecs"
+	"github.com/weaveworks/scope/probe/docker"
+	"github.com/weaveworks/scope/probe/kubernetes"
+	"github.com/weaveworks/scope/probe/process"
+	"github.com/weaveworks/scope/render/detailed"
+	"github.com/weaveworks/scope/report"
+	"github.com/weaveworks/scope/test/reflect"
+)
+
+// podWithContainers makes a report with a single pod, parent of the given
+// containers.
+func podWithContainers(containers ...report.Node) (report.Report, report.Node) {
+	r := report.MakeReport()
+	r.Container = r.Container.WithMetricTemplates(report.MetricTemplates{
+		docker.CPUTotalUsage: {ID: docker.CPUTotalUsage, Label: "CPU", Format: report.PercentFormat},
+		docker.MemoryUsage:   {ID: docker.MemoryUsage, Label: "Memory", Format: report.FilesizeFormat},
+	})
+	pod := report.MakeNodeWith("pod", map[string]string{kubernetes.Name: "pod"}).WithTopology(report.Pod)
+	for _, c := range containers {
+		c = c.WithTopology(report.Container)
+		r.Container.AddNode(c)
+		pod = pod.WithChild(c)
+	}
+	r.Pod.AddNode(pod)
+	return r, pod
+}
+
+func containerWithMetrics(id string, cpu, memory float64) report.Node {
+	now := time.Now()
+	return report.MakeNodeWith(id, map[string]string{docker.ContainerName: id}).WithMetrics(report.Metrics{
+		docker.CPUTotalUsage: report.MakeSingletonMetric(now, cpu),
+		docker.MemoryUsage:   report.MakeSingletonMetric(now, memory),
+	})
+}
+
+func metricValues(group detailed.NodeSummaryGroup, id string) []float64 {
+	values := []float64{}
+	for _, node := range group.Nodes {
+		for _, row := range node.Metrics {
+			if row.ID == id {
+				values = append(values, row.Value)
+			}
+		}
+	}
+	return values
+}
+
+func TestChildrenPercentOfGroup(t *testing.T) {
+	r, pod := podWithContainers(
+		containerWithMetrics("a", 1, 0),
+		containerWithMetrics("b", 3, 0),
+	)
+	ns := report.Nodes{pod.ID: pod}
+	opts := detailed.RenderOptions{PercentOfGroup: true}
+
+	// By default values are absolute
+	plain := detailed.MakeNode("pods", r, ns, pod)
+	if have := metricValues(plain.Children[0], docker.CPUTotalUsage); have[0] != 1 || have[1] != 3 {
+		t.Errorf("Expected absolute values, got %v", have)
+	}
+
+	have := detailed.MakeNodeWithOptions("pods", r, ns, pod, opts)
+	group := have.Children[0]
+	cpu := metricValues(group, docker.CPUTotalUsage)
+	if cpu[0] != 25 || cpu[1] != 75 {
+		t.Errorf("Expected 25%% and 75%%, got %v", cpu)
+	}
+	if sum := cpu[0] + cpu[1]; math.Abs(sum-100) > 0.001 {
+		t.Errorf("Expected percentages to sum to 100, got %v", sum)
+	}
+	for _, column := range group.Columns {
+		if column.Datatype == "number" && !column.PercentOfGroup {
+			t.Errorf("Expected column %s to be marked as percent of group", column.ID)
+		}
+	}
+
+	// A zero total shouldn't make a mess
+	memory := metricValues(group, docker.MemoryUsage)
+	if memory[0] != 0 || memory[1] != 0 {
+		t.Errorf("Expected zero percentages for a zero total, got %v", memory)
+	}
+
+	// The column specs themselves must not be affected
+	again := detailed.MakeNode("pods", r, ns, pod)
+	for _, column := range again.Children[0].Columns {
+		if column.PercentOfGroup {
+			t.Errorf("Expected column %s not to be marked as percent of group", column.ID)
+		}
+	}
+}
+
+func TestChildrenPercentOfGroupSharedChildren(t *testing.T) {
+	r, pod := podWithContainers(
+		containerWithMetrics("a", 1, 0),
+		containerWithMetrics("b", 3, 0),
+	)
+	c := containerWithMetrics("c", 1, 0).WithTopology(report.Container)
+	r.Container.AddNode(c)
+	other := report.MakeNodeWith("other", map[string]string{kubernetes.Name: "other"}).WithTopology(report.Pod).
+		WithChild(r.Container.Nodes["a"]).
+		WithChild(c)
+	r.Pod.AddNode(other)
+	ns := report.Nodes{pod.ID: pod, other.ID: other}
+
+	// Children summaries are shared between the nodes, and must not be
+	// turned into percentages twice.
+	nodes := detailed.MakeNodesWithOptions("pods", r, ns, detailed.RenderOptions{PercentOfGroup: true, Focused: true})
+	want := map[string][]float64{pod.ID: {25, 75}, other.ID: {50, 50}}
+	for _, node := range nodes {
+		if have := metricValues(node.Children[0], docker.CPUTotalUsage); !reflect.DeepEqual(want[node.ID], have) {
+			t.Errorf("%s: want %v, have %v", node.ID, want[node.ID], have)
+		}
+	}
+}
+
+func TestChildrenHideZeroMetricColumns(t *testing.T) {
+	r, pod := podWithContainers(
+		containerWithMetrics("a", 0, 0),
+		containerWithMetrics("b", 3, 0),
+	)
+	ns := report.Nodes{pod.ID: pod}
+	columnIDs := func(node detailed.Node) []string {
+		result := []string{}
+		for _, column := range node.Children[0].Columns {
+			result = append(result, column.ID)
+		}
+		return result
+	}
+
+	// By default all columns are kept
+	want := []string{docker.CPUTotalUsage, docker.MemoryUsage, detailed.UptimeID}
+	if have := columnIDs(detailed.MakeNode("pods", r, ns, pod)); !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+
+	// The all-zero memory column is dropped, the partly non-zero CPU one
+	// is kept
+	want = []string{docker.CPUTotalUsage, detailed.UptimeID}
+	have := columnIDs(detailed.MakeNodeWithOptions("pods", r, ns, pod, detailed.RenderOptions{HideZeroMetricColumns: true}))
+	if !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
+
+func TestChildrenExclude(t *testing.T) {
+	hidden := docker.LabelPrefix + "scope.weave.works/hidden"
+	r, pod := podWithContainers(
+		report.MakeNodeWith("a", map[string]string{docker.ContainerName: "a", hidden: "true"}),
+		report.MakeNodeWith("b", map[string]string{docker.ContainerName: "b", hidden: "false"}),
+		report.MakeNodeWith("c", map[string]string{docker.ContainerName: "c"}),
+	)
+	ns := report.Nodes{pod.ID: pod}
+
+	ids := func(node detailed.Node) []string {
+		result := []string{}
+		for _, group := range node.Children {
+			for _, child := range group.Nodes {
+				result = append(result, child.ID)
+			}
+		}
+		return result
+	}
+
+	if have := ids(detailed.MakeNode("pods", r, ns, pod)); !reflect.DeepEqual([]string{"a", "b", "c"}, have) {
+		t.Errorf("Expected all children by default, got %v", have)
+	}
+	have := ids(detailed.MakeNodeWithOptions("pods", r, ns, pod, detailed.RenderOptions{
+		ExcludeChildren: map[string]string{hidden: "true"},
+	}))
+	if !reflect.DeepEqual([]string{"b", "c"}, have) {
+		t.Errorf("Expected hidden child to be excluded, got %v", have)
+	}
+}
+
+func TestChildrenFocused(t *testing.T) {
+	hidden := docker.LabelPrefix + "scope.weave.works/hidden"
+	r, pod := podWithContainers(
+		containerWithMetrics("a", 1, 0).WithLatests(map[string]string{hidden: "true"}),
+		containerWithMetrics("b", 3, 0),
+	)
+	ns := report.Nodes{pod.ID: pod}
+	exclude := map[string]string{hidden: "true"}
+
+	samples := func(node detailed.Node) map[string]int {
+		result := map[string]int{}
+		for _, group := range node.Children {
+			for _, child := range group.Nodes {
+				for _, row := range child.Metrics {
+					if row.ID == docker.CPUTotalUsage {
+						result[child.ID] = row.Metric.Len()
+					}
+				}
+			}
+		}
+		return result
+	}
+
+	lean := detailed.MakeNodeWithOptions("pods", r, ns, pod, detailed.RenderOptions{ExcludeChildren: exclude})
+	if want, have := map[string]int{"b": 0}, samples(lean); !reflect.DeepEqual(want, have) {
+		t.Errorf("Expected truncated samples and filtered children, want %v, have %v", want, have)
+	}
+
+	focused := detailed.MakeNodeWithOptions("pods", r, ns, pod, detailed.RenderOptions{ExcludeChildren: exclude, Focused: true})
+	if want, have := map[string]int{"a": 1, "b": 1}, samples(focused); !reflect.DeepEqual(want, have) {
+		t.Errorf("Expected samples and all children when focused, want %v, have %v", want, have)
+	}
+}
+
+func TestChildrenGroupBy(t *testing.T) {
+	app := docker.LabelPrefix + "app"
+	r, pod := podWithContainers(
+		report.MakeNodeWith("a", map[string]string{docker.ContainerName: "a", app: "web"}),
+		report.MakeNodeWith("b", map[string]string{docker.ContainerName: "b", app: "db"}),
+		report.MakeNodeWith("c", map[string]string{docker.ContainerName: "c", app: "web"}),
+		report.MakeNodeWith("d", map[string]string{docker.ContainerName: "d"}),
+	)
+	ns := report.Nodes{pod.ID: pod}
+
+	have := detailed.MakeNodeWithOptions("pods", r, ns, pod, detailed.RenderOptions{GroupChildrenBy: app})
+	type group struct {
+		label, topologyID string
+		ids               []string
+	}
+	groups := []group{}
+	for _, g := range have.Children {
+		ids := []string{}
+		for _, child := range g.Nodes {
+			ids = append(ids, child.ID)
+		}
+		groups = append(groups, group{g.Label, g.TopologyID, ids})
+		if len(g.Columns) == 0 {
+			t.Errorf("Expected group %s to keep the containers' columns", g.Label)
+		}
+	}
+	want := []group{
+		{"db", "containers", []string{"b"}},
+		{"web", "containers", []string{"a", "c"}},
+		{detailed.OtherChildrenLabel, "containers", []string{"d"}},
+	}
+	if !reflect.DeepEqual(want, groups) {
+		t.Errorf("want %v, have %v", want, groups)
+	}
+}
+
+func TestChildrenGroupProcessesByNetNamespace(t *testing.T) {
+	r := report.MakeReport()
+	h := report.MakeNodeWith(report.MakeHostNodeID("h"), map[string]string{}).WithTopology(report.Host)
+	for _, p := range []struct{ pid, netns string }{{"1", "4026531993"}, {"2", "4026532201"}, {"3", "4026531993"}, {"4", ""}} {
+		latests := map[string]string{process.PID: p.pid, process.Name: "p" + p.pid}
+		if p.netns != "" {
+			latests[process.NetNamespace] = p.netns
+		}
+		child := report.MakeNodeWith("p"+p.pid, latests).WithTopology(report.Process)
+		r.Process.AddNode(child)
+		h = h.WithChild(child)
+	}
+	r.Host.AddNode(h)
+	ns := report.Nodes{h.ID: h}
+
+	groups := func(node detailed.Node) map[string][]string {
+		result := map[string][]string{}
+		for _, g := range node.Children {
+			for _, child := range g.Nodes {
+				result[g.Label] = append(result[g.Label], child.ID)
+			}
+		}
+		return result
+	}
+	want := map[string][]string{
+		"Processes in netns 4026531993": {"p1", "p3"},
+		"Processes in netns 4026532201": {"p2"},
+		"Processes":                     {"p4"},
+	}
+	have := groups(detailed.MakeNodeWithOptions("hosts", r, ns, h, detailed.RenderOptions{GroupProcessesByNetNamespace: true}))
+	if !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+	want = map[string][]string{"Processes": {"p1", "p2", "p3", "p4"}}
+	if have := groups(detailed.MakeNode("hosts", r, ns, h)); !reflect.DeepEqual(want, have) {
+		t.Errorf("Expected a single group by default, want %v, have %v", want, have)
+	}
+}
+
+func TestChildrenNestProcessesInContainers(t *testing.T) {
+	r, pod := podWithContainers(containerWithMetrics("a", 1, 1), containerWithMetrics("b", 1, 1))
+	for _, p := range []struct{ pid, container string }{{"1", "a"}, {"2", "b"}, {"3", "a"}, {"4", ""}} {
+		child := report.MakeNodeWith("p"+p.pid, map[string]string{process.PID: p.pid, process.Name: "p" + p.pid}).WithTopology(report.Process)
+		if p.container != "" {
+			child = child.WithParents(report.MakeSets().Add(report.Container, report.MakeStringSet(p.container)))
+		}
+		r.Process.AddNode(child)
+		pod = pod.WithChild(child)
+	}
+	ns := report.Nodes{pod.ID: pod}
+
+	type nesting map[string][]string
+	nested := func(node detailed.Node) (nesting, []string) {
+		containers, processes := nesting{}, []string{}
+		for _, group := range node.Children {
+			for _, child := range group.Nodes {
+				switch group.TopologyID {
+				case "containers":
+					containers[child.ID] = []string{}
+					for _, p := range child.Processes {
+						containers[child.ID] = append(containers[child.ID], p.ID)
+					}
+				case "processes":
+					processes = append(processes, child.ID)
+				}
+			}
+		}
+		return containers, processes
+	}
+
+	containers, processes := nested(detailed.MakeNodeWithOptions("pods", r, ns, pod, detailed.RenderOptions{NestProcessesInContainers: true}))
+	if want := (nesting{"a": {"p1", "p3"}, "b": {"p2"}}); !reflect.DeepEqual(want, containers) {
+		t.Errorf("want %v, have %v", want, containers)
+	}
+	if want := []string{"p4"}; !reflect.DeepEqual(want, processes) {
+		t.Errorf("Expected the process outside containers to remain a sibling, want %v, have %v", want, processes)
+	}
+
+	containers, processes = nested(detailed.MakeNode("pods", r, ns, pod))
+	if want := (nesting{"a": {}, "b": {}}); !reflect.DeepEqual(want, containers) {
+		t.Errorf("Expected no nesting by default, got %v", containers)
+	}
+	if want := []string{"p1", "p2", "p3", "p4"}; !reflect.DeepEqual(want, processes) {
+		t.Errorf("want %v, have %v", want, processes)
+	}
+}
+
+func TestChildrenStaleAfter(t *testing.T) {
+	now := time.Date(2017, 3, 14, 15, 9, 26, 0, time.UTC)
+	mtime.NowForce(now)
+	defer mtime.NowReset()
+
+	r, pod := podWithContainers(
+		report.MakeNode("fresh").WithLatest(docker.ContainerName, now.Add(-time.Minute), "fresh"),
+		report.MakeNode("stale").WithLatest(docker.ContainerName, now.Add(-time.Hour), "stale"),
+	)
+	ns := report.Nodes{pod.ID: pod}
+
+	ids := func(node detailed.Node) []string {
+		result := []string{}
+		for _, group := range node.Children {
+			for _, child := range group.Nodes {
+				result = append(result, child.ID)
+			}
+		}
+		return result
+	}
+
+	if have := ids(detailed.MakeNode("pods", r, ns, pod)); !reflect.DeepEqual([]string{"fresh", "stale"}, have) {
+		t.Errorf("Expected all children by default, got %v", have)
+	}
+	have := ids(detailed.MakeNodeWithOptions("pods", r, ns, pod, detailed.RenderOptions{StaleAfter: 10 * time.Minute}))
+	if !reflect.DeepEqual([]string{"fresh"}, have) {
+		t.Errorf("Expected the stale child to be dropped, got %v", have)
+	}
+}
+
+func TestChildrenUptime(t *testing.T) {
+	now := time.Date(2017, 3, 14, 15, 9, 26, 0, time.UTC)
+	mtime.NowForce(now)
+	defer mtime.NowReset()
+
+	started := func(d time.Duration) string { return now.Add(-d).Format(time.RFC3339Nano) }
+	r, pod := podWithContainers(
+		report.MakeNodeWith("days", map[string]string{docker.ContainerStartedAt: started(76*time.Hour + 5*time.Minute)}),
+		report.MakeNodeWith("minutes", map[string]string{docker.ContainerStartedAt: started(5*time.Minute + 6*time.Second)}),
+		report.MakeNodeWith("unknown", map[string]string{docker.ContainerName: "unknown"}),
+	)
+	node := detailed.MakeNode("pods", r, report.Nodes{pod.ID: pod}, pod)
+	if len(node.Children) != 1 {
+		t.Fatalf("Expected one group of children, got %v", node.Children)
+	}
+	group := node.Children[0]
+
+	datatype := ""
+	for _, column := range group.Columns {
+		if column.ID == detailed.UptimeID {
+			datatype = column.Datatype
+		}
+	}
+	if datatype != "duration" {
+		t.Errorf("Expected a duration uptime column, got %q", datatype)
+	}
+
+	have := map[string]string{}
+	for _, child := range group.Nodes {
+		for _, row := range child.Metadata {
+			if row.ID == detailed.UptimeID {
+				have[child.ID] = row.Value
+			}
+		}
+	}
+	want := map[string]string{"days": "3d 4h", "minutes": "5m 6s"}
+	if !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
+
+func TestChildrenAge(t *testing.T) {
+	now := time.Date(2017, 3, 14, 15, 9, 26, 0, time.UTC)
+	mtime.NowForce(now)
+	defer mtime.NowReset()
+	created := func(d time.Duration) string { return now.Add(-d).Format(time.RFC3339Nano) }
+
+	ages := func(topologyID string, r report.Report, n report.Node, opts detailed.RenderOptions) (bool, map[string]string) {
+		group := detailed.MakeNodeWithOptions(topologyID, r, report.Nodes{n.ID: n}, n, opts).Children[0]
+		hasColumn := false
+		for _, column := range group.Columns {
+			if column.ID == detailed.AgeID && column.Datatype == "duration" {
+				hasColumn = true
+			}
+		}
+		values := map[string]string{}
+		for _, child := range group.Nodes {
+			for _, row := range child.Metadata {
+				if row.ID == detailed.AgeID {
+					values[child.ID] = row.Value
+				}
+			}
+		}
+		return hasColumn, values
+	}
+
+	// Containers, from their docker creation time
+	containers, pod := podWithContainers(
+		report.MakeNodeWith("a", map[string]string{docker.ContainerName: "a", docker.ContainerCreated: created(2 * time.Hour)}),
+		report.MakeNodeWith("b", map[string]string{docker.ContainerName: "b"}),
+	)
+	// Pods, from their kubernetes creation time
+	pods := report.MakeReport()
+	deployment := report.MakeNodeWith("deployment", map[string]string{kubernetes.Name: "deployment"}).WithTopology(report.Deployment)
+	for id, d := range map[string]time.Duration{"c": 5 * time.Minute, "d": 26 * time.Hour} {
+		p := report.MakeNodeWith(id, map[string]string{kubernetes.Name: id, kubernetes.Created: created(d)}).WithTopology(report.Pod)
+		pods.Pod.AddNode(p)
+		deployment = deployment.WithChild(p)
+	}
+	pods.Deployment.AddNode(deployment)
+	// ECS tasks, from their creation time
+	tasks := report.MakeReport()
+	service := report.MakeNode(report.MakeECSServiceNodeID("cluster", "service")).WithTopology(report.ECSService)
+	task := report.MakeNodeWith("e", map[string]string{awsecs.TaskFamily: "family", awsecs.CreatedAt: created(90 * time.Second)}).WithTopology(report.ECSTask)
+	tasks.ECSTask.AddNode(task)
+	service = service.WithChild(task)
+	tasks.ECSService.AddNode(service)
+
+	for _, tc := range []struct {
+		topologyID string
+		r          report.Report
+		n          report.Node
+		want       map[string]string
+	}{
+		{"pods", containers, pod, map[string]string{"a": "2h 0m"}},
+		{"deployments", pods, deployment, map[string]string{"c": "5m 0s", "d": "1d 2h"}},
+		{"ecs-services", tasks, service, map[string]string{"e": "1m 30s"}},
+	} {
+		if hasColumn, _ := ages(tc.topologyID, tc.r, tc.n, detailed.RenderOptions{}); hasColumn {
+			t.Errorf("%s: expected no age column by default", tc.topologyID)
+		}
+		hasColumn, have := ages(tc.topologyID, tc.r, tc.n, detailed.RenderOptions{AgeColumn: true})
+		if !hasColumn || !reflect.DeepEqual(tc.want, have) {
+			t.Errorf("%s: expected an age column with %v, got %v (column: %v)", tc.topologyID, tc.want, have, hasColumn)
+		}
+	}
+
+	// Omitted when no child reports its creation time
+	r, pod := podWithContainers(report.MakeNodeWith("b", map[string]string{docker.ContainerName: "b"}))
+	if hasColumn, _ := ages("pods", r, pod, detailed.RenderOptions{AgeColumn: true}); hasColumn {
+		t.Errorf("Expected no age column")
+	}
+}
+
+func TestChildrenBlockIOColumns(t *testing.T) {
+	columnIDs := func(group detailed.NodeSummaryGroup) map[string]bool {
+		ids := map[string]bool{}
+		for _, column := range group.Columns {
+			ids[column.ID] = true
+		}
+		return ids
+	}
+
+	// Without block I/O accounting, the disk columns are left out
+	r, pod := podWithContainers(containerWithMetrics("a", 1, 0))
+	have := columnIDs(detailed.MakeNode("pods", r, report.Nodes{pod.ID: pod}, pod).Children[0])
+	if have[docker.BlockIOReadRate] || have[docker.BlockIOWriteRate] {
+		t.Errorf("Expected no disk columns, got %v", have)
+	}
+
+	now := time.Now()
+	r, pod = podWithContainers(
+		containerWithMetrics("a", 1, 0),
+		containerWithMetrics("b", 1, 0).WithMetrics(report.Metrics{
+			docker.BlockIOReadRate:  report.MakeSingletonMetric(now, 1024),
+			docker.BlockIOWriteRate: report.MakeSingletonMetric(now, 2048),
+		}),
+	)
+	r.Container = r.Container.WithMetricTemplates(report.MetricTemplates{
+		docker.BlockIOReadRate:  {ID: docker.BlockIOReadRate, Label: "Disk Read/s", Format: report.FilesizeFormat},
+		docker.BlockIOWriteRate: {ID: docker.BlockIOWriteRate, Label: "Disk Write/s", Format: report.FilesizeFormat},
+	})
+	group := detailed.MakeNode("pods", r, report.Nodes{pod.ID: pod}, pod).Children[0]
+	have = columnIDs(group)
+	if !have[docker.BlockIOReadRate] || !have[docker.BlockIOWriteRate] {
+		t.Errorf("Expected disk columns, got %v", have)
+	}
+	if values := metricValues(group, docker.BlockIOWriteRate); !reflect.DeepEqual(values, []float64{2048}) {
+		t.Errorf("Expected the write rate of b, got %v", values)
+	}
+}
+
+func TestChildrenPhaseTransitions(t *testing.T) {
+	deploymentWithPods := func(pods ...report.Node) (report.Report, report.Node) {
+		r := report.MakeReport()
+		r.Pod = r.Pod.WithMetadataTemplates(kubernetes.PodMetadataTemplates)
+		deployment := report.MakeNodeWith("deployment", map[string]string{kubernetes.Name: "deployment"}).WithTopology(report.Deployment)
+		for _, pod := range pods {
+			pod = pod.WithTopology(report.Pod)
+			r.Pod.AddNode(pod)
+			deployment = deployment.WithChild(pod)
+		}
+		r.Deployment.AddNode(deployment)
+		return r, deployment
+	}
+	transitions := func(r report.Report, deployment report.Node) (bool, map[string]string) {
+		group := detailed.MakeNode("deployments", r, report.Nodes{deployment.ID: deployment}, deployment).Children[0]
+		hasColumn := false
+		for _, column := range group.Columns {
+			if column.ID == kubernetes.PhaseTransitions {
+				hasColumn = true
+			}
+		}
+		values := map[string]string{}
+		for _, node := range group.Nodes {
+			for _, row := range node.Metadata {
+				if row.ID == kubernetes.PhaseTransitions {
+					values[node.ID] = row.Value
+				}
+			}
+		}
+		return hasColumn, values
+	}
+
+	r, deployment := deploymentWithPods(
+		report.MakeNodeWith("a", map[string]string{kubernetes.Name: "a", kubernetes.PhaseTransitions: "4"}),
+		report.MakeNodeWith("b", map[string]string{kubernetes.Name: "b"}),
+	)
+	hasColumn, values := transitions(r, deployment)
+	if want := map[string]string{"a": "4"}; !hasColumn || !reflect.DeepEqual(want, values) {
+		t.Errorf("Expected a transitions column with %v, got %v (column: %v)", want, values, hasColumn)
+	}
+
+	// Omitted when no pod has transitioned
+	r, deployment = deploymentWithPods(report.MakeNodeWith("b", map[string]string{kubernetes.Name: "b"}))
+	if hasColumn, _ := transitions(r, deployment); hasColumn {
+		t.Errorf("Expected no transitions column")
+	}
+}
+
+func TestChildrenTaskDefinitionRevision(t *testing.T) {
+	serviceWithTasks := func(tasks ...report.Node) (report.Report, report.Node) {
+		r := report.MakeReport()
+		r.ECSTask = r.ECSTask.WithMetadataTemplates(report.MetadataTemplates{
+			awsecs.TaskDefinitionRevision: {ID: awsecs.TaskDefinitionRevision, Label: "Revision", From: report.FromLatest, Datatype: "number"},
+		})
+		service := report.MakeNode(report.MakeECSServiceNodeID("cluster", "service")).WithTopology(report.ECSService)
+		for _, task := range tasks {
+			task = task.WithTopology(report.ECSTask)
+			r.ECSTask.AddNode(task)
+			service = service.WithChild(task)
+		}
+		r.ECSService.AddNode(service)
+		return r, service
+	}
+	revisions := func(r report.Report, service report.Node) (bool, map[string]string) {
+		group := detailed.MakeNode("ecs-services", r, report.Nodes{service.ID: service}, service).Children[0]
+		hasColumn := false
+		for _, column := range group.Columns {
+			if column.ID == awsecs.TaskDefinitionRevision {
+				hasColumn = true
+			}
+		}
+		values := map[string]string{}
+		for _, node := range group.Nodes {
+			for _, row := range node.Metadata {
+				if row.ID == awsecs.TaskDefinitionRevision {
+					values[node.ID] = row.Value
+				}
+			}
+		}
+		return hasColumn, values
+	}
+
+	r, service := serviceWithTasks(
+		report.MakeNodeWith("a", map[string]string{awsecs.TaskFamily: "family", awsecs.TaskDefinitionRevision: "3"}),
+		report.MakeNodeWith("b", map[string]string{awsecs.TaskFamily: "family"}),
+	)
+	hasColumn, values := revisions(r, service)
+	if want := map[string]string{"a": "3"}; !hasColumn || !reflect.DeepEqual(want, values) {
+		t.Errorf("Expected a revision column with %v, got %v (column: %v)", want, values, hasColumn)
+	}
+
+	// Omitted when no task has a revision
+	r, service = serviceWithTasks(report.MakeNodeWith("b", map[string]string{awsecs.TaskFamily: "family"}))
+	if hasColumn, _ := revisions(r, service); hasColumn {
+		t.Errorf("Expected no revision column")
+	}
+}
+
+func TestChildrenOriginProbeIDs(t *testing.T) {
+	probe := func(c report.Node, probeID string) report.Node {
+		return c.WithLatests(map[string]string{report.ControlProbeID: probeID, docker.ImageID: "nginx"})
+	}
+	r, pod := podWithContainers(
+		probe(containerWithMetrics("a", 1, 10), "probe1"),
+		probe(containerWithMetrics("b", 2, 20), "probe2"),
+		containerWithMetrics("c", 4, 40),
+	)
+	ns := report.Nodes{pod.ID: pod}
+	probeIDs := func(opts detailed.RenderOptions) map[string][]string {
+		result := map[string][]string{}
+		for _, node := range detailed.MakeNodeWithOptions("pods", r, ns, pod, opts).Children[0].Nodes {
+			result[node.ID] = node.ProbeIDs
+		}
+		return result
+	}
+
+	// Off by default
+	if want := (map[string][]string{"a": nil, "b": nil, "c": nil}); !reflect.DeepEqual(want, probeIDs(detailed.RenderOptions{})) {
+		t.Errorf("Expected no probe IDs by default, got %v", probeIDs(detailed.RenderOptions{}))
+	}
+
+	want := map[string][]string{"a": {"probe1"}, "b": {"probe2"}, "c": nil}
+	if have := probeIDs(detailed.RenderOptions{OriginProbeIDs: true}); !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+
+	// Merged children have the probe IDs of all their members
+	want = map[string][]string{"c": nil, report.MakeContainerImageNodeID("nginx"): {"probe1", "probe2"}}
+	if have := probeIDs(detailed.RenderOptions{OriginProbeIDs: true, MergeChildrenByImage: true}); !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
+
+func TestChildrenTopN(t *testing.T) {
+	r, pod := podWithContainers(
+		containerWithMetrics("a", 1, 10),
+		containerWithMetrics("b", 4, 20),
+		containerWithMetrics("c", 2, 30),
+		containerWithMetrics("d", 3, 40),
+	)
+	ns := report.Nodes{pod.ID: pod}
+
+	// By default all children are kept
+	plain := detailed.MakeNode("pods", r, ns, pod)
+	if have := len(plain.Children[0].Nodes); have != 4 {
+		t.Errorf("Expected all 4 children, got %d", have)
+	}
+
+	group := detailed.MakeNodeWithOptions("pods", r, ns, pod, detailed.RenderOptions{
+		TopChildren:   2,
+		TopChildrenBy: docker.CPUTotalUsage,
+	}).Children[0]
+	ids := []string{}
+	for _, node := range group.Nodes {
+		ids = append(ids, node.ID)
+	}
+	if want := []string{"b", "d", detailed.OtherChildrenID}; !reflect.DeepEqual(want, ids) {
+		t.Fatalf("want %v, have %v", want, ids)
+	}
+	others := group.Nodes[2]
+	if others.Label != "2 others" {
+		t.Errorf("Expected the remainder to be labelled \"2 others\", got %q", others.Label)
+	}
+	if others.Linkable {
+		t.Errorf("Expected the remainder not to be linkable")
+	}
+	want := map[string]float64{docker.CPUTotalUsage: 3, docker.MemoryUsage: 40}
+	for _, row := range others.Metrics {
+		if row.Value != want[row.ID] {
+			t.Errorf("Expected the %s of the remainder to be %v, got %v", row.ID, want[row.ID], row.Value)
+		}
+	}
+	if len(others.Metrics) != len(want) {
+		t.Errorf("Expected %d metrics on the remainder, got %v", len(want), others.Metrics)
+	}
+
+	// Focused nodes keep all their children
+	focused := detailed.MakeNodeWithOptions("pods", r, ns, pod, detailed.RenderOptions{
+		TopChildren:   2,
+		TopChildrenBy: docker.CPUTotalUsage,
+		Focused:       true,
+	})
+	if have := len(focused.Children[0].Nodes); have != 4 {
+		t.Errorf("Expected all 4 children of a focused node, got %d", have)
+	}
+}
+
+func TestChildrenContainerResources(t *testing.T) {
+	r, pod := podWithContainers(
+		report.MakeNodeWith("a", map[string]string{
+			docker.ContainerName:     "a",
+			kubernetes.CPURequest:    "250",
+			kubernetes.MemoryLimit:   "134217728",
+			kubernetes.MemoryRequest: "67108864",
+		}),
+		report.MakeNodeWith("b", map[string]string{docker.ContainerName: "b"}),
+	)
+	r.Container = r.Container.WithMetadataTemplates(kubernetes.ContainerMetadataTemplates)
+	ns := report.Nodes{pod.ID: pod}
+	columnIDs := func(opts detailed.RenderOptions) []string {
+		ids := []string{}
+		for _, column := range detailed.MakeNodeWithOptions("pods", r, ns, pod, opts).Children[0].Columns {
+			ids = append(ids, column.ID)
+		}
+		return ids
+	}
+
+	// Only shown with the option
+	plain := columnIDs(detailed.RenderOptions{})
+	want := append(plain, kubernetes.CPURequest, kubernetes.MemoryRequest, kubernetes.MemoryLimit)
+	if have := columnIDs(detailed.RenderOptions{ContainerResources: true}); !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+
+	// Omitted when no container has resources
+	r, pod = podWithContainers(report.MakeNodeWith("b", map[string]string{docker.ContainerName: "b"}))
+	ns = report.Nodes{pod.ID: pod}
+	if have := columnIDs(detailed.RenderOptions{ContainerResources: true}); !reflect.DeepEqual(plain, have) {
+		t.Errorf("want %v, have %v", plain, have)
+	}
+}
+
+func TestChildrenProbeStatuses(t *testing.T) {
+	r, pod := podWithContainers(
+		report.MakeNodeWith("a", map[string]string{
+			docker.ContainerName:      "a",
+			kubernetes.ReadinessProbe: kubernetes.ProbeFailing,
+			kubernetes.LivenessProbe:  kubernetes.ProbePassing,
+		}),
+		report.MakeNodeWith("b", map[string]string{docker.ContainerName: "b"}),
+	)
+	r.Container = r.Container.WithMetadataTemplates(kubernetes.ContainerMetadataTemplates)
+	ns := report.Nodes{pod.ID: pod}
+	group := func(opts detailed.RenderOptions) detailed.NodeSummaryGroup {
+		return detailed.MakeNodeWithOptions("pods", r, ns, pod, opts).Children[0]
+	}
+	columnIDs := func(group detailed.NodeSummaryGroup) []string {
+		ids := []string{}
+		for _, column := range group.Columns {
+			ids = append(ids, column.ID)
+		}
+		return ids
+	}
+
+	// Only shown with the option
+	plain := columnIDs(group(detailed.RenderOptions{}))
+	want := append(plain, kubernetes.ReadinessProbe, kubernetes.LivenessProbe)
+	withStatuses := group(detailed.RenderOptions{ProbeStatuses: true})
+	if have := columnIDs(withStatuses); !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+	for _, node := range withStatuses.Nodes {
+		statuses := map[string]string{}
+		for _, row := range node.Metadata {
+			if row.ID == kubernetes.ReadinessProbe || row.ID == kubernetes.LivenessProbe {
+				statuses[row.ID] = row.Value
+			}
+		}
+		want := map[string]string{}
+		if node.ID == "a" {
+			want = map[string]string{
+				kubernetes.ReadinessProbe: kubernetes.ProbeFailing,
+				kubernetes.LivenessProbe:  kubernetes.ProbePassing,
+			}
+		}
+		if !reflect.DeepEqual(want, statuses) {
+			t.Errorf("%s: want %v, have %v", node.ID, want, statuses)
+		}
+	}
+
+	// Omitted when no container has probes
+	r, pod = podWithContainers(report.MakeNodeWith("b", map[string]string{docker.ContainerName: "b"}))
+	ns = report.Nodes{pod.ID: pod}
+	if have := columnIDs(group(detailed.RenderOptions{ProbeStatuses: true})); !reflect.DeepEqual(plain, have) {
+		t.Errorf("want %v, have %v", plain, have)
+	}
+}
+
+func TestChildrenHealthCheckStatus(t *testing.T) {
+	r, pod := podWithContainers(
+		report.MakeNodeWith("starting", map[string]string{docker.ContainerName: "starting", docker.ContainerHealth: docker.HealthStarting}),
+		report.MakeNodeWith("healthy", map[string]string{docker.ContainerName: "healthy", docker.ContainerHealth: docker.HealthHealthy}),
+		report.MakeNodeWith("unhealthy", map[string]string{docker.ContainerName: "unhealthy", docker.ContainerHealth: docker.HealthUnhealthy}),
+		report.MakeNodeWith("unchecked", map[string]string{docker.ContainerName: "unchecked"}),
+	)
+	ns := report.Nodes{pod.ID: pod}
+	group := func(opts detailed.RenderOptions) detailed.NodeSummaryGroup {
+		return detailed.MakeNodeWithOptions("pods", r, ns, pod, opts).Children[0]
+	}
+	hasColumn := func(group detailed.NodeSummaryGroup) bool {
+		for _, column := range group.Columns {
+			if column.ID == docker.ContainerHealth {
+				return true
+			}
+		}
+		return false
+	}
+	health := func(group detailed.NodeSummaryGroup) (map[string]string, map[string][]detailed.Badge) {
+		statuses, badges := map[string]string{}, map[string][]detailed.Badge{}
+		for _, node := range group.Nodes {
+			for _, row := range node.Metadata {
+				if row.ID == docker.ContainerHealth {
+					statuses[node.ID] = row.Value
+				}
+			}
+			if len(node.Badges) > 0 {
+				badges[node.ID] = node.Badges
+			}
+		}
+		return statuses, badges
+	}
+
+	// Only shown with the option
+	plain := group(detailed.RenderOptions{})
+	if hasColumn(plain) {
+		t.Errorf("Expected no health column without the option")
+	}
+	if statuses, badges := health(plain); len(statuses) != 0 || len(badges) != 0 {
+		t.Errorf("Expected no health without the option, got %v %v", statuses, badges)
+	}
+
+	withHealth := group(detailed.RenderOptions{HealthCheckStatus: true})
+	if !hasColumn(withHealth) {
+		t.Errorf("Expected a health column")
+	}
+	statuses, badges := health(withHealth)
+	wantStatuses := map[string]string{
+		"starting":  docker.HealthStarting,
+		"healthy":   docker.HealthHealthy,
+		"unhealthy": docker.HealthUnhealthy,
+	}
+	if !reflect.DeepEqual(wantStatuses, statuses) {
+		t.Errorf("want %v, have %v", wantStatuses, statuses)
+	}
+	wantBadges := map[string][]detailed.Badge{
+		"starting":  {{Label: "Health: starting", Level: "warning"}},
+		"healthy":   {{Label: "Health: healthy", Level: "info"}},
+		"unhealthy": {{Label: "Health: unhealthy", Level: "critical"}},
+	}
+	if !reflect.DeepEqual(wantBadges, badges) {
+		t.Errorf("want %v, have %v", wantBadges, badges)
+	}
+
+	// The container's own summary has the badge too
+	container := r.Container.Nodes["unhealthy"]
+	summary, _ := detailed.MakeNodeSummaryWithOptions(r, container, detailed.RenderOptions{HealthCheckStatus: true})
+	if want := wantBadges["unhealthy"]; !reflect.DeepEqual(want, summary.Badges) {
+		t.Errorf("want %v, have %v", want, summary.Badges)
+	}
+
+	// Omitted when no container has a health check
+	r, pod = podWithContainers(report.MakeNodeWith("unchecked", map[string]string{docker.ContainerName: "unchecked"}))
+	ns = report.Nodes{pod.ID: pod}
+	if hasColumn(group(detailed.RenderOptions{HealthCheckStatus: true})) {
+		t.Errorf("Expected no health column without health checks")
+	}
+}
+
+func TestChildrenContainerNetworking(t *testing.T) {
+	r, pod := podWithContainers(
+		report.MakeNodeWith("a", map[string]string{
+			docker.ContainerName:        "a",
+			docker.ContainerNetworkMode: "bridge",
+		}).WithSets(report.MakeSets().Add(docker.ContainerPorts, report.MakeStringSet(
+			"10.0.0.1:8080->80/tcp", "10.0.0.1:8443->443/tcp", "9000/tcp",
+		))),
+		report.MakeNodeWith("b", map[string]string{
+			docker.ContainerName:        "b",
+			docker.ContainerNetworkMode: "host",
+		}),
+		report.MakeNodeWith("c", map[string]string{docker.ContainerName: "c"}),
+	)
+	ns := report.Nodes{pod.ID: pod}
+	group := func(opts detailed.RenderOptions) detailed.NodeSummaryGroup {
+		return detailed.MakeNodeWithOptions("pods", r, ns, pod, opts).Children[0]
+	}
+	columnIDs := func(group detailed.NodeSummaryGroup) []string {
+		ids := []string{}
+		for _, column := range group.Columns {
+			ids = append(ids, column.ID)
+		}
+		return ids
+	}
+	values := func(group detailed.NodeSummaryGroup) map[string]map[string]string {
+		result := map[string]map[string]string{}
+		for _, node := range group.Nodes {
+			for _, row := range node.Metadata {
+				if row.ID == docker.ContainerNetworkMode || row.ID == detailed.PublishedPortsID {
+					if result[node.ID] == nil {
+						result[node.ID] = map[string]string{}
+					}
+					result[node.ID][row.ID] = row.Value
+				}
+			}
+		}
+		return result
+	}
+
+	// Only shown with the option
+	plain := group(detailed.RenderOptions{})
+	if have := values(plain); len(have) != 0 {
+		t.Errorf("Expected no networking without the option, got %v", have)
+	}
+	withNetworking := group(detailed.RenderOptions{ContainerNetworking: true})
+	if want, have := append(columnIDs(plain), docker.ContainerNetworkMode, detailed.PublishedPortsID), columnIDs(withNetworking); !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+	want := map[string]map[string]string{
+		"a": {
+			docker.ContainerNetworkMode: "bridge",
+			detailed.PublishedPortsID:   "10.0.0.1:8080->80/tcp, 10.0.0.1:8443->443/tcp",
+		},
+		"b": {docker.ContainerNetworkMode: "host"},
+	}
+	if have := values(withNetworking); !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+
+	// Omitted when no container reports them
+	r, pod = podWithContainers(report.MakeNodeWith("c", map[string]string{docker.ContainerName: "c"}))
+	ns = report.Nodes{pod.ID: pod}
+	if want, have := columnIDs(group(detailed.RenderOptions{})), columnIDs(group(detailed.RenderOptions{ContainerNetworking: true})); !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
+
+func TestChildrenImageProvenance(t *testing.T) {
+	imageID := report.MakeContainerImageNodeID("img1")
+	r, pod := podWithContainers(
+		report.MakeNodeWith("a", map[string]string{
+			docker.ContainerName:       "a",
+			kubernetes.ImagePullPolicy: "Always",
+		}).WithParents(report.MakeSets().Add(report.ContainerImage, report.MakeStringSet(imageID))),
+		report.MakeNodeWith("b", map[string]string{
+			docker.ContainerName:       "b",
+			kubernetes.ImagePullPolicy: "IfNotPresent",
+		}),
+		report.MakeNodeWith("c", map[string]string{docker.ContainerName: "c"}),
+	)
+	r.ContainerImage.AddNode(report.MakeNodeWith(imageID, map[string]string{
+		docker.ImageID:     "img1",
+		docker.ImageName:   "img1:latest",
+		docker.ImageDigest: "sha256:0123456789abcdef",
+	}).WithTopology(report.ContainerImage))
+	ns := report.Nodes{pod.ID: pod}
+	group := func(opts detailed.RenderOptions) detailed.NodeSummaryGroup {
+		return detailed.MakeNodeWithOptions("pods", r, ns, pod, opts).Children[0]
+	}
+	columnIDs := func(group detailed.NodeSummaryGroup) []string {
+		ids := []string{}
+		for _, column := range group.Columns {
+			ids = append(ids, column.ID)
+		}
+		return ids
+	}
+	values := func(group detailed.NodeSummaryGroup) map[string]map[string]string {
+		result := map[string]map[string]string{}
+		for _, node := range group.Nodes {
+			for _, row := range node.Metadata {
+				if row.ID == docker.ImageDigest || row.ID == kubernetes.ImagePullPolicy {
+					if result[node.ID] == nil {
+						result[node.ID] = map[string]string{}
+					}
+					result[node.ID][row.ID] = row.Value
+				}
+			}
+		}
+		return result
+	}
+
+	// Only shown with the option
+	plain := group(detailed.RenderOptions{})
+	if have := values(plain); len(have) != 0 {
+		t.Errorf("Expected no image provenance without the option, got %v", have)
+	}
+	withProvenance := group(detailed.RenderOptions{ImageProvenance: true})
+	if want, have := append(columnIDs(plain), docker.ImageDigest, kubernetes.ImagePullPolicy), columnIDs(withProvenance); !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+	// Containers take the digest of their image
+	want := map[string]map[string]string{
+		"a": {
+			docker.ImageDigest:         "sha256:0123456789abcdef",
+			kubernetes.ImagePullPolicy: "Always",
+		},
+		"b": {kubernetes.ImagePullPolicy: "IfNotPresent"},
+	}
+	if have := values(withProvenance); !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+
+	// Images have their own digest
+	summary, ok := detailed.MakeNodeSummaryWithOptions(r, r.ContainerImage.Nodes[imageID], detailed.RenderOptions{ImageProvenance: true})
+	if !ok {
+		t.Fatalf("Expected a summary of the image")
+	}
+	if have := values(detailed.NodeSummaryGroup{Nodes: []detailed.NodeSummary{summary}}); !reflect.DeepEqual(map[string]map[string]string{
+		imageID: {docker.ImageDigest: "sha256:0123456789abcdef"},
+	}, have) {
+		t.Errorf("Expected the digest of the image, got %v", have)
+	}
+
+	// Omitted when unknown
+	r, pod = podWithContainers(report.MakeNodeWith("c", map[string]string{docker.ContainerName: "c"}))
+	ns = report.Nodes{pod.ID: pod}
+	if want, have := columnIDs(group(detailed.RenderOptions{})), columnIDs(group(detailed.RenderOptions{ImageProvenance: true})); !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
+
+func TestChildrenSecurityContext(t *testing.T) {
+	r, pod := podWithContainers(
+		report.MakeNodeWith("privileged", map[string]string{
+			docker.ContainerName:         "privileged",
+			kubernetes.RunAsUser:         "0",
+			kubernetes.Privileged:        "true",
+			kubernetes.CapabilitiesAdded: "NET_ADMIN, SYS_TIME",
+		}),
+		report.MakeNodeWith("unprivileged", map[string]string{
+			docker.ContainerName:           "unprivileged",
+			kubernetes.RunAsUser:           "1000",
+			kubernetes.Privileged:          "false",
+			kubernetes.CapabilitiesDropped: "ALL",
+		}),
+		report.MakeNodeWith("plain", map[string]string{docker.ContainerName: "plain"}),
+	)
+	ns := report.Nodes{pod.ID: pod}
+	group := func(opts detailed.RenderOptions) detailed.NodeSummaryGroup {
+		return detailed.MakeNodeWithOptions("pods", r, ns, pod, opts).Children[0]
+	}
+	columnIDs := func(group detailed.NodeSummaryGroup) []string {
+		ids := []string{}
+		for _, column := range group.Columns {
+			ids = append(ids, column.ID)
+		}
+		return ids
+	}
+	values := func(group detailed.NodeSummaryGroup) map[string]map[string]string {
+		result := map[string]map[string]string{}
+		for _, node := range group.Nodes {
+			for _, row := range node.Metadata {
+				switch row.ID {
+				case kubernetes.RunAsUser, kubernetes.Privileged, kubernetes.CapabilitiesAdded, kubernetes.CapabilitiesDropped:
+					if result[node.ID] == nil {
+						result[node.ID] = map[string]string{}
+					}
+					result[node.ID][row.ID] = row.Value
+				}
+			}
+		}
+		return result
+	}
+	badges := func(group detailed.NodeSummaryGroup) map[string][]detailed.Badge {
+		result := map[string][]detailed.Badge{}
+		for _, node := range group.Nodes {
+			if node.Badges != nil {
+				result[node.ID] = node.Badges
+			}
+		}
+		return result
+	}
+
+	// Only shown with the option
+	plain := group(detailed.RenderOptions{})
+	if have := values(plain); len(have) != 0 {
+		t.Errorf("Expected no security context without the option, got %v", have)
+	}
+	if have := badges(plain); len(have) != 0 {
+		t.Errorf("Expected no badges without the option, got %v", have)
+	}
+	withSecurity := group(detailed.RenderOptions{SecurityContext: true})
+	if want, have := append(columnIDs(plain), kubernetes.RunAsUser, kubernetes.Privileged), columnIDs(withSecurity); !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+	wantValues := map[string]map[string]string{
+		"privileged": {
+			kubernetes.RunAsUser:         "0",
+			kubernetes.Privileged:        "true",
+			kubernetes.CapabilitiesAdded: "NET_ADMIN, SYS_TIME",
+		},
+		"unprivileged": {
+			kubernetes.RunAsUser:           "1000",
+			kubernetes.Privileged:          "false",
+			kubernetes.CapabilitiesDropped: "ALL",
+		},
+	}
+	if have := values(withSecurity); !reflect.DeepEqual(wantValues, have) {
+		t.Errorf("want %v, have %v", wantValues, have)
+	}
+	// Only the privileged container is flagged
+	wantBadges := map[string][]detailed.Badge{
+		"privileged": {
+			{Label: "Privileged", Level: "critical"},
+			{Label: "Runs as root", Level: "warning"},
+			{Label: "Capabilities: NET_ADMIN, SYS_TIME", Level: "warning"},
+		},
+	}
+	if have := badges(withSecurity); !reflect.DeepEqual(wantBadges, have) {
+		t.Errorf("want %v, have %v", wantBadges, have)
+	}
+
+	// Omitted when no container reports it
+	r, pod = podWithContainers(report.MakeNodeWith("plain", map[string]string{docker.ContainerName: "plain"}))
+	ns = report.Nodes{pod.ID: pod}
+	if want, have := columnIDs(group(detailed.RenderOptions{})), columnIDs(group(detailed.RenderOptions{SecurityContext: true})); !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
+
+func TestChildrenResourcePressure(t *testing.T) {
+	now := time.Now()
+	pressure := func(cpu, memory float64) report.Metrics {
+		return report.Metrics{
+			report.CPUPressure:    report.MakeSingletonMetric(now, cpu),
+			report.MemoryPressure: report.MakeSingletonMetric(now, memory),
+		}
+	}
+	r, pod := podWithContainers(
+		containerWithMetrics("a", 1, 1).WithMetrics(pressure(55, 12)),
+		containerWithMetrics("b", 1, 1).WithMetrics(pressure(1, 2)),
+		containerWithMetrics("c", 1, 1),
+	)
+	ns := report.Nodes{pod.ID: pod}
+	group := func(opts detailed.RenderOptions) detailed.NodeSummaryGroup {
+		return detailed.MakeNodeWithOptions("pods", r, ns, pod, opts).Children[0]
+	}
+	columnIDs := func(group detailed.NodeSummaryGroup) []string {
+		ids := []string{}
+		for _, column := range group.Columns {
+			ids = append(ids, column.ID)
+		}
+		return ids
+	}
+	badges := func(group detailed.NodeSummaryGroup) map[string][]detailed.Badge {
+		result := map[string][]detailed.Badge{}
+		for _, node := range group.Nodes {
+			if node.Badges != nil {
+				result[node.ID] = node.Badges
+			}
+		}
+		return result
+	}
+
+	// Only shown with the option
+	plain := group(detailed.RenderOptions{})
+	if have := metricValues(plain, report.CPUPressure); len(have) != 0 {
+		t.Errorf("Expected no pressure without the option, got %v", have)
+	}
+	withPressure := group(detailed.RenderOptions{ResourcePressure: true})
+	if want, have := append(columnIDs(plain), report.CPUPressure, report.MemoryPressure), columnIDs(withPressure); !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+	if want, have := []float64{55, 1}, metricValues(withPressure, report.CPUPressure); !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+	want := map[string][]detailed.Badge{
+		"a": {{Label: "CPU Pressure", Level: "critical"}, {Label: "Memory Pressure", Level: "warning"}},
+	}
+	if have := badges(withPressure); !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+
+	// Hosts get it too
+	host := report.MakeNodeWith("host", map[string]string{"host_name": "host"}).WithTopology(report.Host).WithMetrics(pressure(0, 45))
+	r.Host.AddNode(host)
+	node := detailed.MakeNodeWithOptions("hosts", r, report.Nodes{host.ID: host}, host, detailed.RenderOptions{ResourcePressure: true})
+	if want := []detailed.Badge{{Label: "Memory Pressure", Level: "critical"}}; !reflect.DeepEqual(want, node.Badges) {
+		t.Errorf("want %v, have %v", want, node.Badges)
+	}
+
+	// Omitted when no container reports it
+	r, pod = podWithContainers(containerWithMetrics("c", 1, 1))
+	ns = report.Nodes{pod.ID: pod}
+	if want, have := columnIDs(group(detailed.RenderOptions{})), columnIDs(group(detailed.RenderOptions{ResourcePressure: true})); !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
+
+func TestChildrenRuntime(t *testing.T) {
+	r, pod := podWithContainers(
+		report.MakeNodeWith("a", map[string]string{docker.ContainerName: "a", report.ContainerRuntime: "containerd"}),
+		report.MakeNodeWith("b", map[string]string{docker.ContainerName: "b", report.ContainerRuntime: "cri-o"}),
+		report.MakeNodeWith("c", map[string]string{docker.ContainerName: "c"}),
+	)
+	ns := report.Nodes{pod.ID: pod}
+	group := func(opts detailed.RenderOptions) detailed.NodeSummaryGroup {
+		return detailed.MakeNodeWithOptions("pods", r, ns, pod, opts).Children[0]
+	}
+	columnIDs := func(group detailed.NodeSummaryGroup) []string {
+		ids := []string{}
+		for _, column := range group.Columns {
+			ids = append(ids, column.ID)
+		}
+		return ids
+	}
+	runtimes := func(metadata []report.MetadataRow) []string {
+		values := []string{}
+		for _, row := range metadata {
+			if row.ID == report.ContainerRuntime {
+				values = append(values, row.Value)
+			}
+		}
+		return values
+	}
+	childRuntimes := func(group detailed.NodeSummaryGroup) map[string][]string {
+		result := map[string][]string{}
+		for _, node := range group.Nodes {
+			result[node.ID] = runtimes(node.Metadata)
+		}
+		return result
+	}
+
+	// Only shown with the option
+	plain := group(detailed.RenderOptions{})
+	if want, have := map[string][]string{"a": {}, "b": {}, "c": {}}, childRuntimes(plain); !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+	withRuntime := group(detailed.RenderOptions{Runtime: true})
+	if want, have := append(columnIDs(plain), report.ContainerRuntime), columnIDs(withRuntime); !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+	if want, have := map[string][]string{"a": {"containerd"}, "b": {"cri-o"}, "c": {}}, childRuntimes(withRuntime); !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+
+	// Hosts get it too
+	host := report.MakeNodeWith("host", map[string]string{"host_name": "host", report.ContainerRuntime: "docker"}).WithTopology(report.Host)
+	r.Host.AddNode(host)
+	node := detailed.MakeNodeWithOptions("hosts", r, report.Nodes{host.ID: host}, host, detailed.RenderOptions{Runtime: true})
+	if want, have := []string{"docker"}, runtimes(node.Metadata); !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+
+	// Omitted when no container reports it
+	r, pod = podWithContainers(report.MakeNodeWith("c", map[string]string{docker.ContainerName: "c"}))
+	ns = report.Nodes{pod.ID: pod}
+	if want, have := columnIDs(group(detailed.RenderOptions{})), columnIDs(group(detailed.RenderOptions{Runtime: true})); !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
+
+func TestChildrenPodScheduling(t *testing.T) {
+	r := report.MakeReport()
+	deployment := report.MakeNodeWith("deployment", map[string]string{kubernetes.Name: "deployment"}).WithTopology(report.Deployment)
+	for id, latest := range map[string]map[string]string{
+		"scheduled": {
+			kubernetes.NodeName:         "node-1",
+			kubernetes.SchedulingStatus: kubernetes.SchedulingScheduled,
+			kubernetes.NodeSelector:     "disk=ssd",
+		},
+		"pending": {kubernetes.SchedulingStatus: kubernetes.SchedulingPending},
+		"unknown": {},
+	} {
+		latest[kubernetes.Name] = id
+		p := report.MakeNodeWith(id, latest).WithTopology(report.Pod)
+		r.Pod.AddNode(p)
+		deployment = deployment.WithChild(p)
+	}
+	r.Deployment.AddNode(deployment)
+	ns := report.Nodes{deployment.ID: deployment}
+	group := func(opts detailed.RenderOptions) detailed.NodeSummaryGroup {
+		return detailed.MakeNodeWithOptions("deployments", r, ns, deployment, opts).Children[0]
+	}
+	columnIDs := func(group detailed.NodeSummaryGroup) []string {
+		ids := []string{}
+		for _, column := range group.Columns {
+			ids = append(ids, column.ID)
+		}
+		return ids
+	}
+	scheduling := func(group detailed.NodeSummaryGroup) map[string]map[string]string {
+		result := map[string]map[string]string{}
+		for _, node := range group.Nodes {
+			for _, row := range node.Metadata {
+				switch row.ID {
+				case kubernetes.NodeName, kubernetes.SchedulingStatus, kubernetes.NodeSelector:
+					if result[node.ID] == nil {
+						result[node.ID] = map[string]string{}
+					}
+					result[node.ID][row.ID] = row.Value
+				}
+			}
+		}
+		return result
+	}
+
+	// Only shown with the option
+	plain := group(detailed.RenderOptions{})
+	if have := scheduling(plain); len(have) != 0 {
+		t.Errorf("Expected no scheduling without the option, got %v", have)
+	}
+	withScheduling := group(detailed.RenderOptions{PodScheduling: true})
+	want := append(columnIDs(plain), kubernetes.NodeName, kubernetes.SchedulingStatus)
+	if have := columnIDs(withScheduling); !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+	wantScheduling := map[string]map[string]string{
+		"scheduled": {
+			kubernetes.NodeName:         "node-1",
+			kubernetes.SchedulingStatus: kubernetes.SchedulingScheduled,
+			kubernetes.NodeSelector:     "disk=ssd",
+		},
+		"pending": {kubernetes.SchedulingStatus: kubernetes.SchedulingPending},
+	}
+	if have := scheduling(withScheduling); !reflect.DeepEqual(wantScheduling, have) {
+		t.Errorf("want %v, have %v", wantScheduling, have)
+	}
+
+	// Omitted when no pod reports its scheduling
+	r.Pod = report.MakeTopology()
+	deployment = report.MakeNodeWith("deployment", map[string]string{kubernetes.Name: "deployment"}).WithTopology(report.Deployment)
+	p := report.MakeNodeWith("unknown", map[string]string{kubernetes.Name: "unknown"}).WithTopology(report.Pod)
+	r.Pod.AddNode(p)
+	deployment = deployment.WithChild(p)
+	ns = report.Nodes{deployment.ID: deployment}
+	if have := columnIDs(group(detailed.RenderOptions{PodScheduling: true})); !reflect.DeepEqual(columnIDs(plain), have) {
+		t.Errorf("want %v, have %v", columnIDs(plain), have)
+	}
+}
+
+func TestChildrenOpenFiles(t *testing.T) {
+	now := time.Now()
+	podWithProcesses := func(openFiles map[string]float64) (report.Report, report.Node) {
+		r, pod := podWithContainers()
+		r.Process = r.Process.WithMetricTemplates(process.MetricTemplates)
+		for _, pid := range []string{"1", "2"} {
+			p := report.MakeNodeWith("p"+pid, map[string]string{process.PID: pid, process.Name: "p" + pid}).WithTopology(report.Process)
+			if count, ok := openFiles[pid]; ok {
+				p = p.WithMetric(process.OpenFilesCount, report.MakeSingletonMetric(now, count))
+			}
+			r.Process.AddNode(p)
+			pod = pod.WithChild(p)
+		}
+		return r, pod
+	}
+	group := func(r report.Report, pod report.Node, opts detailed.RenderOptions) detailed.NodeSummaryGroup {
+		return detailed.MakeNodeWithOptions("pods", r, report.Nodes{pod.ID: pod}, pod, opts).Children[0]
+	}
+	columnIDs := func(group detailed.NodeSummaryGroup) []string {
+		ids := []string{}
+		for _, column := range group.Columns {
+			ids = append(ids, column.ID)
+		}
+		return ids
+	}
+
+	// Only shown with the option
+	r, pod := podWithProcesses(map[string]float64{"1": 12})
+	plain := columnIDs(group(r, pod, detailed.RenderOptions{}))
+	withOpenFiles := group(r, pod, detailed.RenderOptions{OpenFiles: true})
+	if want, have := append(plain, process.OpenFilesCount), columnIDs(withOpenFiles); !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+	if want, have := []float64{12}, metricValues(withOpenFiles, process.OpenFilesCount); !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+
+	// Omitted when no process reports it
+	r, pod = podWithProcesses(nil)
+	if have := columnIDs(group(r, pod, detailed.RenderOptions{OpenFiles: true})); !reflect.DeepEqual(plain, have) {
+		t.Errorf("want %v, have %v", plain, have)
+	}
+}
+
+func TestChildrenLabelTags(t *testing.T) {
+	r, pod := podWithContainers(
+		report.MakeNodeWith("a", map[string]string{
+			docker.ContainerName:                   "a",
+			docker.LabelPrefix + "tier":            "frontend",
+			docker.LabelPrefix + "app":             "shop",
+			docker.ImageLabelPrefix + "maintainer": "ops",
+			docker.EnvPrefix + "HOME":              "/root",
+		}),
+		report.MakeNodeWith("b", map[string]string{docker.ContainerName: "b"}),
+	)
+	pod = pod.WithLatests(map[string]string{kubernetes.LabelPrefix + "app": "shop"})
+	r.Pod.AddNode(pod)
+	ns := report.Nodes{pod.ID: pod}
+	tags := func(opts detailed.RenderOptions) map[string][]string {
+		node := detailed.MakeNodeWithOptions("pods", r, ns, pod, opts)
+		result := map[string][]string{}
+		if node.Tags != nil {
+			result[node.ID] = node.Tags
+		}
+		for _, child := range node.Children[0].Nodes {
+			if child.Tags != nil {
+				result[child.ID] = child.Tags
+			}
+		}
+		return result
+	}
+
+	if have := tags(detailed.RenderOptions{}); len(have) != 0 {
+		t.Errorf("Expected no tags without the option, got %v", have)
+	}
+	want := map[string][]string{
+		"pod": {"app=shop"},
+		"a":   {"app=shop", "maintainer=ops", "tier=frontend"},
+	}
+	if have := tags(detailed.RenderOptions{LabelTags: true}); !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
+
+func TestChildrenRestartHistory(t *testing.T) {
+	now := time.Now()
+	restarts := report.MakeMetric([]report.Sample{
+		{Timestamp: now.Add(-5 * time.Minute), Value: 1},
+		{Timestamp: now.Add(-time.Minute), Value: 2},
+	})
+	r, pod := podWithContainers(
+		containerWithMetrics("a", 1, 1).WithMetric(docker.ContainerRestarts, restarts),
+		containerWithMetrics("b", 1, 1),
+	)
+	ns := report.Nodes{pod.ID: pod}
+	columnIDs := func(group detailed.NodeSummaryGroup) []string {
+		ids := []string{}
+		for _, column := range group.Columns {
+			ids = append(ids, column.ID)
+		}
+		return ids
+	}
+
+	plain := detailed.MakeNode("pods", r, ns, pod).Children[0]
+	if have := metricValues(plain, docker.ContainerRestarts); len(have) != 0 {
+		t.Errorf("Expected no restart history without the option, got %v", have)
+	}
+	group := detailed.MakeNodeWithOptions("pods", r, ns, pod, detailed.RenderOptions{RestartHistory: true}).Children[0]
+	if want, have := append(columnIDs(plain), docker.ContainerRestarts), columnIDs(group); !reflect.DeepEqual(want, have) {
+		t.Errorf("want columns %v, have %v", want, have)
+	}
+	if want, have := []float64{2}, metricValues(group, docker.ContainerRestarts); !reflect.DeepEqual(want, have) {
+		t.Errorf("want restart counts %v, have %v", want, have)
+	}
+
+	// The container itself gets the whole series
+	container := r.Container.Nodes["a"]
+	node := detailed.MakeNodeWithOptions("containers", r, report.Nodes{container.ID: container}, container, detailed.RenderOptions{RestartHistory: true})
+	found := false
+	for _, row := range node.Metrics {
+		if row.ID == docker.ContainerRestarts {
+			found = true
+			if row.Metric == nil || row.Metric.Len() != 2 {
+				t.Errorf("Expected the restart history of the container, got %v", row.Metric)
+			}
+		}
+	}
+	if !found {
+		t.Errorf("Expected a restart history row for the container")
+	}
+
+	// Omitted when no container has restarted
+	r, pod = podWithContainers(containerWithMetrics("b", 1, 1))
+	ns = report.Nodes{pod.ID: pod}
+	group = detailed.MakeNodeWithOptions("pods", r, ns, pod, detailed.RenderOptions{RestartHistory: true}).Children[0]
+	if want, have := columnIDs(plain), columnIDs(group); !reflect.DeepEqual(want, have) {
+		t.Errorf("want columns %v, have %v", want, have)
+	}
+}
+
+func TestChildrenGroupLabel(t *testing.T) {
+	r, pod := podWithContainers(report.MakeNodeWith("a", map[string]string{docker.ContainerName: "a"}))
+	ns := report.Nodes{pod.ID: pod}
+	label := func(r report.Report) string {
+		return detailed.MakeNode("pods", r, ns, pod).Children[0].Label
+	}
+
+	// The report declares the default labels of topologies
+	if have := label(r); have != "Containers" {
+		t.Errorf("Expected the default label, got %q", have)
+	}
+	r.Container = r.Container.WithLabel("conteneur", "Conteneurs")
+	if have := label(r); have != "Conteneurs" {
+		t.Errorf("Expected the label declared by the topology, got %q", have)
+	}
+	r.Container = r.Container.WithLabel("Box", "")
+	if have := label(r); have != "Box" {
+		t.Errorf("Expected the singular label without a plural one, got %q", have)
+	}
+}
+
+func TestChildrenEmptyMessage(t *testing.T) {
+	hidden := docker.LabelPrefix + "scope.weave.works/hidden"
+	r, pod := podWithContainers(
+		report.MakeNodeWith("a", map[string]string{docker.ContainerName: "a", hidden: "true"}),
+	)
+	ns := report.Nodes{pod.ID: pod}
+
+	have := detailed.MakeNodeWithOptions("pods", r, ns, pod, detailed.RenderOptions{
+		ExcludeChildren: map[string]string{hidden: "true"},
+	})
+	if len(have.Children) != 1 {
+		t.Fatalf("Expected the emptied group to be kept, got %v", have.Children)
+	}
+	group := have.Children[0]
+	if len(group.Nodes) != 0 {
+		t.Errorf("Expected no children, got %v", group.Nodes)
+	}
+	if group.EmptyMessage != "No containers running" {
+		t.Errorf("Expected the containers empty message, got %q", group.EmptyMessage)
+	}
+
+	// Nodes without children of a topology don't get an empty group for it
+	if have := detailed.MakeNode("containers", r, ns, r.Container.Nodes["a"]); len(have.Children) != 0 {
+		t.Errorf("Expected no children groups, got %v", have.Children)
+	}
+}
+
+func TestChildrenMergeByImage(t *testing.T) {
+	image := func(c report.Node, imageID string) report.Node {
+		return c.WithLatests(map[string]string{docker.ImageID: imageID})
+	}
+	r, pod := podWithContainers(
+		image(containerWithMetrics("a", 1, 100), "nginx"),
+		image(containerWithMetrics("b", 2, 200), "nginx"),
+		image(containerWithMetrics("c", 4, 400), "redis"),
+		containerWithMetrics("d", 8, 800),
+	)
+	r.ContainerImage.AddNode(report.MakeNodeWith(report.MakeContainerImageNodeID("nginx"), map[string]string{
+		docker.ImageName: "nginx:1.11",
+	}))
+	ns := report.Nodes{pod.ID: pod}
+
+	have := detailed.MakeNodeWithOptions("pods", r, ns, pod, detailed.RenderOptions{MergeChildrenByImage: true})
+	if len(have.Children) != 1 {
+		t.Fatalf("Expected one group of children, got %v", have.Children)
+	}
+	group := have.Children[0]
+
+	ids := []string{}
+	for _, child := range group.Nodes {
+		ids = append(ids, child.ID)
+	}
+	if want := []string{"c", "d", report.MakeContainerImageNodeID("nginx")}; !reflect.DeepEqual(want, ids) {
+		t.Errorf("Expected the nginx containers to be merged, got %v", ids)
+	}
+	merged := group.Nodes[2]
+	if merged.Label != "nginx:1.11" || merged.Linkable {
+		t.Errorf("Expected an unlinkable row labelled with the image name, got %v", merged)
+	}
+	if want := []float64{4, 8, 3}; !reflect.DeepEqual(want, metricValues(group, docker.CPUTotalUsage)) {
+		t.Errorf("Expected summed CPU %v, got %v", want, metricValues(group, docker.CPUTotalUsage))
+	}
+	if len(merged.Metadata) != 1 || merged.Metadata[0].ID != report.Container || merged.Metadata[0].Value != "2" {
+		t.Errorf("Expected a count of 2 containers, got %v", merged.Metadata)
+	}
+	hasCount := false
+	for _, column := range group.Columns {
+		hasCount = hasCount || column.ID == report.Container
+	}
+	if !hasCount {
+		t.Errorf("Expected a container count column, got %v", group.Columns)
+	}
+
+	// By default containers aren't merged
+	plain := detailed.MakeNode("pods", r, ns, pod)
+	if len(plain.Children[0].Nodes) != 4 {
+		t.Errorf("Expected all containers by default, got %v", plain.Children[0].Nodes)
+	}
+}
+
+func TestChildrenSortBySize(t *testing.T) {
+	r, pod := podWithContainers(report.MakeNodeWith("c", map[string]string{docker.ContainerName: "c"}))
+	for _, pid := range []string{"1", "2", "3"} {
+		p := report.MakeNodeWith("p"+pid, map[string]string{process.PID: pid, process.Name: "p" + pid}).WithTopology(report.Process)
+		r.Process.AddNode(p)
+		pod = pod.WithChild(p)
+	}
+	ns := report.Nodes{pod.ID: pod}
+
+	labels := func(node detailed.Node) []string {
+		result := []string{}
+		for _, group := range node.Children {
+			result = append(result, group.Label)
+		}
+		return result
+	}
+	if have := labels(detailed.MakeNode("pods", r, ns, pod)); !reflect.DeepEqual([]string{"Containers", "Processes"}, have) {
+		t.Errorf("Expected groups in spec order by default, got %v", have)
+	}
+	have := labels(detailed.MakeNodeWithOptions("pods", r, ns, pod, detailed.RenderOptions{SortChildrenBySize: true}))
+	if !reflect.DeepEqual([]string{"Processes", "Containers"}, have) {
+		t.Errorf("Expected the biggest group first, got %v", have)
+	}
+}
+
+func TestChildrenInlineSingleChildGroups(t *testing.T) {
+	r, pod := podWithContainers(report.MakeNodeWith("c", map[string]string{docker.ContainerName: "c"}))
+	for _, pid := range []string{"1", "2"} {
+		p := report.MakeNodeWith("p"+pid, map[string]string{process.PID: pid, process.Name: "p" + pid}).WithTopology(report.Process)
+		r.Process.AddNode(p)
+		pod = pod.WithChild(p)
+	}
+	ns := report.Nodes{pod.ID: pod}
+
+	inline := func(node detailed.Node) map[string]bool {
+		result := map[string]bool{}
+		for _, group := range node.Children {
+			result[group.Label] = group.Inline
+		}
+		return result
+	}
+	want := map[string]bool{"Containers": false, "Processes": false}
+	if have := inline(detailed.MakeNode("pods", r, ns, pod)); !reflect.DeepEqual(want, have) {
+		t.Errorf("Expected no inline groups by default, got %v", have)
+	}
+	want = map[string]bool{"Containers": true, "Processes": false}
+	have := inline(detailed.MakeNodeWithOptions("pods", r, ns, pod, detailed.RenderOptions{InlineSingleChildGroups: true}))
+	if !reflect.DeepEqual(want, have) {
+		t.Errorf("Expected only the single container group inline, got %v", have)
+	}
+}
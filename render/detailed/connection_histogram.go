@@ -0,0 +1,43 @@
+package detailed
+
+import (
+	"sort"
+)
+
+// DefaultHistogramBounds are bounds for ConnectionHistogram suiting most
+// nodes.
+var DefaultHistogramBounds = []int{1, 5, 10, 50, 100}
+
+// HistogramBucket is a bucket of the connection histogram of a node: the
+// number of peers with between Min and Max connections to the node,
+// inclusive. The last bucket has no Max.
+type HistogramBucket struct {
+	Min   int `json:"min"`
+	Max   int `json:"max,omitempty"`
+	Peers int `json:"peers"`
+}
+
+// ConnectionHistogram buckets the peers of the node, as given by
+// TopTalkers, by their number of connections. The bounds are the maximums
+// of the buckets, followed by a bucket for the peers with more connections
+// than the last bound. Buckets without peers are kept.
+func (n Node) ConnectionHistogram(bounds []int) []HistogramBucket {
+	bounds = append([]int{}, bounds...)
+	sort.Ints(bounds)
+	buckets := make([]HistogramBucket, 0, len(bounds)+1)
+	min := 1
+	for _, bound := range bounds {
+		if bound < min {
+			continue
+		}
+		buckets = append(buckets, HistogramBucket{Min: min, Max: bound})
+		min = bound + 1
+	}
+	buckets = append(buckets, HistogramBucket{Min: min})
+
+	for _, peer := range n.TopTalkers(0) {
+		i := sort.Search(len(buckets)-1, func(i int) bool { return peer.Count <= buckets[i].Max })
+		buckets[i].Peers++
+	}
+	return buckets
+}
@@ -0,0 +1,85 @@
+package detailed_test
+
+import (
+	"testing"
+
+	"github.com/weaveworks/common/test"
+	"github.com/weaveworks/scope/render"
+	"github.com/weaveworks/scope/render/detailed"
+	"github.com/weaveworks/scope/report"
+	"github.com/weaveworks/scope/test/fixture"
+	"github.com/weaveworks/scope/test/reflect"
+)
+
+func TestNodeConnectionHistogram(t *testing.T) {
+	row := func(nodeID, port, count string) detailed.Connection {
+		return detailed.Connection{
+			ID:     nodeID + port,
+			NodeID: nodeID,
+			Label:  nodeID,
+			Metadata: []report.MetadataRow{
+				{ID: "port", Value: port},
+				{ID: "count", Value: count},
+			},
+		}
+	}
+	node := detailed.Node{Connections: []detailed.ConnectionsSummary{
+		{ID: "incoming-connections", Connections: []detailed.Connection{
+			row("a", "80", "1"),
+			row("b", "80", "5"),
+			row("c", "80", "6"),
+			row("d", "80", "10"),
+			row("e", "80", "11"),
+		}},
+		{ID: "outgoing-connections", Connections: []detailed.Connection{
+			// Summed with the inbound connections of b, to 9
+			row("b", "5432", "4"),
+			row("f", "5432", "200"),
+		}},
+	}}
+
+	// Bounds are inclusive maximums, in any order, with a last bucket for
+	// the rest.
+	want := []detailed.HistogramBucket{
+		{Min: 1, Max: 1, Peers: 1},
+		{Min: 2, Max: 5, Peers: 0},
+		{Min: 6, Max: 10, Peers: 3},
+		{Min: 11, Peers: 2},
+	}
+	if have := node.ConnectionHistogram([]int{10, 1, 5}); !reflect.DeepEqual(want, have) {
+		t.Error(test.Diff(want, have))
+	}
+
+	// Without bounds, there's a single bucket
+	want = []detailed.HistogramBucket{{Min: 1, Peers: 6}}
+	if have := node.ConnectionHistogram(nil); !reflect.DeepEqual(want, have) {
+		t.Error(test.Diff(want, have))
+	}
+
+	// Without connections, buckets are empty
+	want = []detailed.HistogramBucket{{Min: 1, Max: 1}, {Min: 2}}
+	if have := (detailed.Node{}).ConnectionHistogram([]int{1}); !reflect.DeepEqual(want, have) {
+		t.Error(test.Diff(want, have))
+	}
+}
+
+func TestMakeNodeConnectionHistogram(t *testing.T) {
+	renderableNodes := render.HostRenderer.Render(fixture.Report, nil)
+	n := renderableNodes[fixture.ServerHostNodeID]
+	if have := detailed.MakeNode("hosts", fixture.Report, renderableNodes, n).Histogram; have != nil {
+		t.Errorf("Expected no histogram without the option, got %v", have)
+	}
+	node := detailed.MakeNodeWithOptions("hosts", fixture.Report, renderableNodes, n, detailed.RenderOptions{
+		ConnectionHistogram: detailed.DefaultHistogramBounds,
+	})
+	if want, have := node.ConnectionHistogram(detailed.DefaultHistogramBounds), node.Histogram; !reflect.DeepEqual(want, have) {
+		t.Error(test.Diff(want, have))
+	}
+	peers := 0
+	for _, bucket := range node.Histogram {
+		peers += bucket.Peers
+	}
+	if want := len(node.TopTalkers(0)); peers != want || peers == 0 {
+		t.Errorf("Expected the histogram to hold all %d peers, got %d", want, peers)
+	}
+}
@@ -2,9 +2,11 @@ package detailed
 
 import (
 	"fmt"
+	"net"
 	"sort"
 	"strconv"
 
+	"github.com/weaveworks/scope/probe/endpoint"
 	"github.com/weaveworks/scope/render"
 	"github.com/weaveworks/scope/report"
 )
@@ -17,6 +19,9 @@ const (
 	remoteKey   = "remote"
 	remoteLabel = "Remote"
 	number      = "number"
+
+	incomingConnectionsID = "incoming-connections"
+	outgoingConnectionsID = "outgoing-connections"
 )
 
 // Exported for testing
@@ -39,6 +44,10 @@ type ConnectionsSummary struct {
 	Label       string       `json:"label"`
 	Columns     []Column     `json:"columns"`
 	Connections []Connection `json:"connections"`
+
+	// EmptyMessage, if set, is shown in place of the table when all the
+	// connections have been filtered out.
+	EmptyMessage string `json:"emptyMessage,omitempty"`
 }
 
 // Connection is a row in the connections table.
@@ -49,6 +58,8 @@ type Connection struct {
 	LabelMinor string               `json:"labelMinor,omitempty"`
 	Linkable   bool                 `json:"linkable"`
 	Metadata   []report.MetadataRow `json:"metadata,omitempty"`
+	Geo        *Geo                 `json:"geo,omitempty"`      // Where an internet peer is, with the GeoEnrichment option.
+	Flapping   bool                 `json:"flapping,omitempty"` // Whether the peer came and went, as set by ConnectionsOverRange.
 }
 
 type connectionsByID []Connection
@@ -61,16 +72,29 @@ func (s connectionsByID) Less(i, j int) bool { return s[i].ID < s[j].ID }
 type connection struct {
 	remoteNodeID          string
 	remoteAddr, localAddr string // for internet nodes only
+	remoteIP              string // for internet nodes only, without DNS names
+	remoteCIDR            string // for remotes aggregated by network only
 	port                  string // destination port
 }
 
 type connectionCounters struct {
-	counted map[string]struct{}
-	counts  map[connection]int
+	counted         map[string]struct{}
+	counts          map[connection]int
+	unestablished   int // connections left out by establishedOnly
+	cidrs           []*net.IPNet
+	establishedOnly bool
+	geo             bool // locate internet peers
+	summaries       summaryCache
 }
 
-func newConnectionCounters() *connectionCounters {
-	return &connectionCounters{counted: map[string]struct{}{}, counts: map[connection]int{}}
+func newConnectionCounters(opts RenderOptions, summaries summaryCache) *connectionCounters {
+	return &connectionCounters{
+		counted:         map[string]struct{}{},
+		counts:          map[connection]int{},
+		cidrs:           opts.ConnectionCIDRs,
+		establishedOnly: opts.EstablishedConnectionsOnly,
+		summaries:       summaries,
+	}
 }
 
 func (c *connectionCounters) add(outgoing bool, localNode, remoteNode, localEndpoint, remoteEndpoint report.Node) {
@@ -87,15 +111,25 @@ func (c *connectionCounters) add(outgoing bool, localNode, remoteNode, localEndp
 	if _, ok := c.counted[connectionID]; ok {
 		return
 	}
+	if c.establishedOnly && !(established(localEndpoint) && established(remoteEndpoint)) {
+		c.unestablished++
+		return
+	}
 
 	conn := connection{remoteNodeID: remoteNode.ID}
 	var ok bool
 	if _, _, conn.port, ok = report.ParseEndpointNodeID(dstEndpoint.ID); !ok {
 		return
 	}
-	// For internet nodes we break out individual addresses
-	if conn.remoteAddr, ok = internetAddr(remoteNode, remoteEndpoint); !ok {
+	if cidr, ok := c.cidrFor(remoteEndpoint); ok {
+		// Remotes within a configured network are aggregated into a
+		// single row, regardless of the node they belong to.
+		conn.remoteNodeID, conn.remoteCIDR = "", cidr
+	} else if conn.remoteAddr, ok = internetAddr(remoteNode, remoteEndpoint); !ok {
+		// For internet nodes we break out individual addresses
 		return
+	} else if conn.remoteAddr != "" {
+		_, conn.remoteIP, _, _ = report.ParseEndpointNodeID(remoteEndpoint.ID)
 	}
 	if conn.localAddr, ok = internetAddr(localNode, localEndpoint); !ok {
 		return
@@ -105,6 +139,38 @@ func (c *connectionCounters) add(outgoing bool, localNode, remoteNode, localEndp
 	c.counts[conn]++
 }
 
+// established says whether the endpoint is part of an established
+// connection, as far as we know.
+func established(ep report.Node) bool {
+	state, ok := ep.Latest.Lookup(endpoint.ConnectionState)
+	return !ok || state == endpoint.StateEstablished
+}
+
+// cidrFor returns the first configured network containing the address of
+// the endpoint, if any.
+func (c *connectionCounters) cidrFor(ep report.Node) (string, bool) {
+	if len(c.cidrs) == 0 {
+		return "", false
+	}
+	_, addr, _, ok := report.ParseEndpointNodeID(ep.ID)
+	if !ok {
+		return "", false
+	}
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return "", false
+	}
+	for _, cidr := range c.cidrs {
+		if cidr.Contains(ip) {
+			return cidr.String(), true
+		}
+	}
+	return "", false
+}
+
+// internetAddr returns the address of the endpoint of an internet node,
+// prefixed with the DNS name it was queried by, or else reverse-resolved
+// to, if any. It is empty for other nodes.
 func internetAddr(node report.Node, ep report.Node) (string, bool) {
 	if !isInternetNode(node) {
 		return "", true
@@ -121,13 +187,26 @@ func internetAddr(node report.Node, ep report.Node) (string, bool) {
 	return addr, true
 }
 
+// emptyMessage returns the message to show when all the connections were
+// left out for not being established, if they were.
+func (c *connectionCounters) emptyMessage(message string) string {
+	if len(c.counts) > 0 || c.unestablished == 0 {
+		return ""
+	}
+	return message
+}
+
 func (c *connectionCounters) rows(r report.Report, ns report.Nodes, includeLocal bool) []Connection {
 	output := []Connection{}
 	for row, count := range c.counts {
+		if row.remoteCIDR != "" {
+			output = append(output, c.cidrRow(row, count, includeLocal))
+			continue
+		}
 		// Use MakeNodeSummary to render the id and label of this node
 		// TODO(paulbellamy): Would be cleaner if we hade just a
 		// MakeNodeID(ns[row.remoteNodeID]). As we don't need the whole summary.
-		summary, _ := MakeNodeSummary(r, ns[row.remoteNodeID])
+		summary, _ := c.summaries.summarize(r, ns[row.remoteNodeID])
 		connection := Connection{
 			ID:         fmt.Sprintf("%s-%s-%s-%s", row.remoteNodeID, row.remoteAddr, row.localAddr, row.port),
 			NodeID:     summary.ID,
@@ -139,32 +218,51 @@ func (c *connectionCounters) rows(r report.Report, ns report.Nodes, includeLocal
 			connection.Label = row.remoteAddr
 			connection.LabelMinor = ""
 		}
-		if includeLocal {
-			connection.Metadata = append(connection.Metadata,
-				report.MetadataRow{
-					ID:    remoteKey,
-					Value: row.localAddr,
-				})
+		connection.Label = peerLabel(Peer{NodeID: row.remoteNodeID, Addr: row.remoteIP, Label: connection.Label})
+		connection.Metadata = connectionMetadata(row, count, includeLocal)
+		if c.geo && row.remoteIP != "" {
+			connection.Geo = peerGeo(row.remoteIP)
 		}
-		connection.Metadata = append(connection.Metadata,
-			report.MetadataRow{
-				ID:    portKey,
-				Value: row.port,
-			},
-			report.MetadataRow{
-				ID:    countKey,
-				Value: strconv.Itoa(count),
-			},
-		)
 		output = append(output, connection)
 	}
 	sort.Sort(connectionsByID(output))
 	return output
 }
 
-func incomingConnectionsSummary(topologyID string, r report.Report, n report.Node, ns report.Nodes) ConnectionsSummary {
+// cidrRow renders the row for remotes aggregated by network. There is no
+// single node behind it, so it isn't linkable.
+func (c *connectionCounters) cidrRow(row connection, count int, includeLocal bool) Connection {
+	return Connection{
+		ID:       fmt.Sprintf("%s-%s-%s", row.remoteCIDR, row.localAddr, row.port),
+		Label:    peerLabel(Peer{Label: row.remoteCIDR}),
+		Metadata: connectionMetadata(row, count, includeLocal),
+	}
+}
+
+func connectionMetadata(row connection, count int, includeLocal bool) []report.MetadataRow {
+	metadata := []report.MetadataRow{}
+	if includeLocal {
+		metadata = append(metadata,
+			report.MetadataRow{
+				ID:    remoteKey,
+				Value: row.localAddr,
+			})
+	}
+	return append(metadata,
+		report.MetadataRow{
+			ID:    portKey,
+			Value: row.port,
+		},
+		report.MetadataRow{
+			ID:    countKey,
+			Value: strconv.Itoa(count),
+		},
+	)
+}
+
+func incomingConnectionsSummary(topologyID string, r report.Report, n report.Node, ns report.Nodes, opts RenderOptions, summaries summaryCache) ConnectionsSummary {
 	localEndpointIDs, localEndpointIDCopies := endpointChildIDsAndCopyMapOf(n)
-	counts := newConnectionCounters()
+	counts := newConnectionCounters(opts, summaries)
 
 	// For each node which has an edge TO me
 	for _, node := range ns {
@@ -184,17 +282,19 @@ func incomingConnectionsSummary(topologyID string, r report.Report, n report.Nod
 		columnHeaders = InternetColumns
 	}
 	return ConnectionsSummary{
-		ID:          "incoming-connections",
-		TopologyID:  topologyID,
-		Label:       "Inbound",
-		Columns:     columnHeaders,
-		Connections: counts.rows(r, ns, isInternetNode(n)),
+		ID:           incomingConnectionsID,
+		TopologyID:   topologyID,
+		Label:        "Inbound",
+		Columns:      columnHeaders,
+		Connections:  counts.rows(r, ns, isInternetNode(n)),
+		EmptyMessage: counts.emptyMessage("No established inbound connections"),
 	}
 }
 
-func outgoingConnectionsSummary(topologyID string, r report.Report, n report.Node, ns report.Nodes) ConnectionsSummary {
+func outgoingConnectionsSummary(topologyID string, r report.Report, n report.Node, ns report.Nodes, opts RenderOptions, summaries summaryCache) ConnectionsSummary {
 	localEndpoints := endpointChildrenOf(n)
-	counts := newConnectionCounters()
+	counts := newConnectionCounters(opts, summaries)
+	counts.geo = opts.GeoEnrichment
 
 	// For each node which has an edge FROM me
 	for _, id := range n.Adjacency {
@@ -216,14 +316,28 @@ func outgoingConnectionsSummary(topologyID string, r report.Report, n report.Nod
 		columnHeaders = InternetColumns
 	}
 	return ConnectionsSummary{
-		ID:          "outgoing-connections",
-		TopologyID:  topologyID,
-		Label:       "Outbound",
-		Columns:     columnHeaders,
-		Connections: counts.rows(r, ns, isInternetNode(n)),
+		ID:           outgoingConnectionsID,
+		TopologyID:   topologyID,
+		Label:        "Outbound",
+		Columns:      columnHeaders,
+		Connections:  counts.rows(r, ns, isInternetNode(n)),
+		EmptyMessage: counts.emptyMessage("No established outbound connections"),
 	}
 }
 
+// nonEmptyConnectionsSummaries drops the summaries without any rows, so the
+// UI doesn't show empty tables, unless they have a message to show instead.
+// It returns nil if all of them are dropped.
+func nonEmptyConnectionsSummaries(summaries ...ConnectionsSummary) []ConnectionsSummary {
+	var result []ConnectionsSummary
+	for _, summary := range summaries {
+		if len(summary.Connections) > 0 || summary.EmptyMessage != "" {
+			result = append(result, summary)
+		}
+	}
+	return result
+}
+
 func endpointChildrenOf(n report.Node) []report.Node {
 	result := []report.Node{}
 	n.Children.ForEach(func(child report.Node) {
@@ -0,0 +1,65 @@
+package detailed
+
+import (
+	"sort"
+)
+
+// ConnectionsOverRange merges the connection summaries of renderings of a
+// node from a window of time-ordered reports, oldest first. Each summary
+// has the rows of all the renderings, as last rendered. Rows whose peer
+// came and went more than once over the window are marked Flapping, peers
+// being correlated across renderings by node and label, as the ports of
+// their connections may change.
+func ConnectionsOverRange(nodes []Node) []ConnectionsSummary {
+	type peer struct{ summaryID, nodeID, label string }
+	var (
+		summaries = map[string]*ConnectionsSummary{}
+		order     = []string{}
+		rows      = map[string]map[string]Connection{}
+		presence  = map[peer][]bool{}
+	)
+	for i, node := range nodes {
+		for _, summary := range node.Connections {
+			if _, ok := summaries[summary.ID]; !ok {
+				order = append(order, summary.ID)
+				rows[summary.ID] = map[string]Connection{}
+			}
+			s := summary
+			summaries[summary.ID] = &s
+			for _, row := range summary.Connections {
+				rows[summary.ID][row.ID] = row
+				p := peer{summary.ID, row.NodeID, row.Label}
+				if presence[p] == nil {
+					presence[p] = make([]bool, len(nodes))
+				}
+				presence[p][i] = true
+			}
+		}
+	}
+
+	result := make([]ConnectionsSummary, 0, len(order))
+	for _, id := range order {
+		summary := *summaries[id]
+		connections := make([]Connection, 0, len(rows[id]))
+		for _, row := range rows[id] {
+			row.Flapping = flapping(presence[peer{id, row.NodeID, row.Label}])
+			connections = append(connections, row)
+		}
+		sort.Sort(connectionsByID(connections))
+		summary.Connections = connections
+		result = append(result, summary)
+	}
+	return result
+}
+
+// flapping says whether the presence of a peer changed more than once over
+// a window. A peer appearing, or disappearing, once isn't flapping.
+func flapping(presence []bool) bool {
+	changes := 0
+	for i := 1; i < len(presence); i++ {
+		if presence[i] != presence[i-1] {
+			changes++
+		}
+	}
+	return changes > 1
+}
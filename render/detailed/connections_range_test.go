@@ -0,0 +1,71 @@
+package detailed_test
+
+import (
+	"testing"
+
+	"github.com/weaveworks/common/test"
+	"github.com/weaveworks/scope/render/detailed"
+	"github.com/weaveworks/scope/test/reflect"
+)
+
+func TestConnectionsOverRange(t *testing.T) {
+	row := func(nodeID, port string) detailed.Connection {
+		return detailed.Connection{ID: nodeID + "-" + port, NodeID: nodeID, Label: nodeID}
+	}
+	rendering := func(incoming ...detailed.Connection) detailed.Node {
+		return detailed.Node{Connections: []detailed.ConnectionsSummary{
+			{ID: "incoming-connections", Label: "Inbound", Connections: incoming},
+		}}
+	}
+	// Over five reports:
+	// - steady is always there;
+	// - flapper comes and goes;
+	// - newcomer appears once, and stays;
+	// - leaver disappears once;
+	// - porter is always there, on changing ports, so doesn't flap.
+	nodes := []detailed.Node{
+		rendering(row("steady", "80"), row("flapper", "80"), row("leaver", "80"), row("porter", "1")),
+		rendering(row("steady", "80"), row("leaver", "80"), row("porter", "2")),
+		rendering(row("steady", "80"), row("flapper", "80"), row("newcomer", "80"), row("porter", "1")),
+		rendering(row("steady", "80"), row("newcomer", "80"), row("porter", "2")),
+		rendering(row("steady", "80"), row("flapper", "80"), row("newcomer", "80"), row("porter", "1")),
+	}
+
+	flap := func(c detailed.Connection) detailed.Connection {
+		c.Flapping = true
+		return c
+	}
+	want := []detailed.ConnectionsSummary{{
+		ID:    "incoming-connections",
+		Label: "Inbound",
+		Connections: []detailed.Connection{
+			flap(row("flapper", "80")),
+			row("leaver", "80"),
+			row("newcomer", "80"),
+			row("porter", "1"),
+			row("porter", "2"),
+			row("steady", "80"),
+		},
+	}}
+	if have := detailed.ConnectionsOverRange(nodes); !reflect.DeepEqual(want, have) {
+		t.Error(test.Diff(want, have))
+	}
+
+	// Peers are correlated within a summary only
+	outgoing := detailed.ConnectionsSummary{ID: "outgoing-connections", Connections: []detailed.Connection{row("peer", "80")}}
+	nodes = []detailed.Node{
+		rendering(row("peer", "80")),
+		{Connections: []detailed.ConnectionsSummary{outgoing}},
+		{Connections: append(rendering(row("peer", "80")).Connections, outgoing)},
+	}
+	for _, summary := range detailed.ConnectionsOverRange(nodes) {
+		for _, c := range summary.Connections {
+			if summary.ID == "incoming-connections" && !c.Flapping {
+				t.Errorf("Expected the inbound peer to be flapping")
+			}
+			if summary.ID == "outgoing-connections" && c.Flapping {
+				t.Errorf("Expected the outbound peer not to be flapping")
+			}
+		}
+	}
+}
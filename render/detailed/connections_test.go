@@ -0,0 +1,193 @@
+package detailed_test
+
+import (
+	"net"
+	"testing"
+
+	"github.com/weaveworks/common/test"
+	"github.com/weaveworks/scope/probe/endpoint"
+	"github.com/weaveworks/scope/render"
+	"github.com/weaveworks/scope/render/detailed"
+	"github.com/weaveworks/scope/report"
+	"github.com/weaveworks/scope/test/fixture"
+	"github.com/weaveworks/scope/test/reflect"
+)
+
+func mustParseCIDR(t *testing.T, s string) *net.IPNet {
+	_, cidr, err := net.ParseCIDR(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cidr
+}
+
+func TestConnectionsCIDRAggregation(t *testing.T) {
+	renderableNodes := render.ContainerRenderer.Render(fixture.Report, nil)
+	renderableNode := renderableNodes[fixture.ServerContainerNodeID]
+	have := detailed.MakeNodeWithOptions("containers", fixture.Report, renderableNodes, renderableNode, detailed.RenderOptions{
+		ConnectionCIDRs: []*net.IPNet{
+			mustParseCIDR(t, "192.168.0.0/16"),
+			mustParseCIDR(t, "10.0.0.0/8"),
+		},
+	})
+
+	// The client container (10.10.10.20) falls inside 10.0.0.0/8, the
+	// internet client (51.52.53.54) is outside all configured networks.
+	want := []detailed.Connection{
+		{
+			ID:    "10.0.0.0/8--80",
+			Label: "10.0.0.0/8",
+			Metadata: []report.MetadataRow{
+				{ID: "port", Value: "80"},
+				{ID: "count", Value: "2"},
+			},
+		},
+		{
+			ID:       connectionID(render.IncomingInternetID, fixture.RandomClientIP),
+			NodeID:   render.IncomingInternetID,
+			Label:    fixture.RandomClientIP,
+			Linkable: true,
+			Metadata: []report.MetadataRow{
+				{ID: "port", Value: "80"},
+				{ID: "count", Value: "1"},
+			},
+		},
+	}
+	if !reflect.DeepEqual(want, have.Connections[0].Connections) {
+		t.Error(test.Diff(want, have.Connections[0].Connections))
+	}
+
+	// Networks containing none of the peers leave the rows untouched
+	plain := detailed.MakeNode("containers", fixture.Report, renderableNodes, renderableNode)
+	other := detailed.MakeNodeWithOptions("containers", fixture.Report, renderableNodes, renderableNode, detailed.RenderOptions{
+		ConnectionCIDRs: []*net.IPNet{mustParseCIDR(t, "172.16.0.0/12")},
+	})
+	if !reflect.DeepEqual(plain.Connections, other.Connections) {
+		t.Error(test.Diff(plain.Connections, other.Connections))
+	}
+}
+
+func TestConnectionsOmitEmpty(t *testing.T) {
+	// A node without any connections has no connection tables at all
+	r, pod := podWithContainers(report.MakeNodeWith("a", nil))
+	if have := detailed.MakeNode("pods", r, report.Nodes{pod.ID: pod}, pod); have.Connections != nil {
+		t.Errorf("Expected no connection summaries, got %v", have.Connections)
+	}
+
+	// A node with only inbound connections only has the inbound table
+	renderableNodes := render.ContainerRenderer.Render(fixture.Report, nil)
+	have := detailed.MakeNode("containers", fixture.Report, renderableNodes, renderableNodes[fixture.ServerContainerNodeID])
+	if len(have.Connections) != 1 || have.Connections[0].ID != "incoming-connections" {
+		t.Errorf("Expected only the inbound summary, got %v", have.Connections)
+	}
+	for _, summary := range have.Connections {
+		if len(summary.Columns) == 0 || len(summary.Connections) == 0 {
+			t.Errorf("Expected a complete summary, got %v", summary)
+		}
+	}
+
+	// A node whose connections are all filtered out keeps the table, to
+	// show its message instead
+	rpt := fixture.Report.Copy()
+	for id, ep := range rpt.Endpoint.Nodes {
+		rpt.Endpoint.Nodes[id] = ep.WithLatests(map[string]string{endpoint.ConnectionState: "TIME_WAIT"})
+	}
+	renderableNodes = render.ContainerRenderer.Render(rpt, nil)
+	have = detailed.MakeNodeWithOptions("containers", rpt, renderableNodes, renderableNodes[fixture.ServerContainerNodeID], detailed.RenderOptions{
+		EstablishedConnectionsOnly: true,
+	})
+	if len(have.Connections) != 1 || have.Connections[0].EmptyMessage != "No established inbound connections" || len(have.Connections[0].Connections) != 0 {
+		t.Errorf("Expected only the empty inbound summary with its message, got %v", have.Connections)
+	}
+}
+
+func TestConnectionsEstablishedOnly(t *testing.T) {
+	rpt := fixture.Report.Copy()
+	for id, state := range map[string]string{
+		fixture.Client54001NodeID: "TIME_WAIT",
+		fixture.Client54002NodeID: endpoint.StateEstablished,
+		// fixture.RandomClientNodeID doesn't report any state
+	} {
+		rpt.Endpoint.Nodes[id] = rpt.Endpoint.Nodes[id].WithLatests(map[string]string{
+			endpoint.ConnectionState: state,
+		})
+	}
+	renderableNodes := render.ContainerRenderer.Render(rpt, nil)
+	renderableNode := renderableNodes[fixture.ServerContainerNodeID]
+
+	counts := func(node detailed.Node) map[string]string {
+		result := map[string]string{}
+		for _, row := range node.Connections[0].Connections {
+			for _, m := range row.Metadata {
+				if m.ID == "count" {
+					result[row.NodeID] = m.Value
+				}
+			}
+		}
+		return result
+	}
+
+	want := map[string]string{
+		fixture.ClientContainerNodeID: "2",
+		render.IncomingInternetID:     "1",
+	}
+	if have := counts(detailed.MakeNode("containers", rpt, renderableNodes, renderableNode)); !reflect.DeepEqual(want, have) {
+		t.Error(test.Diff(want, have))
+	}
+
+	want = map[string]string{
+		fixture.ClientContainerNodeID: "1",
+		render.IncomingInternetID:     "1",
+	}
+	have := counts(detailed.MakeNodeWithOptions("containers", rpt, renderableNodes, renderableNode, detailed.RenderOptions{
+		EstablishedConnectionsOnly: true,
+	}))
+	if !reflect.DeepEqual(want, have) {
+		t.Error(test.Diff(want, have))
+	}
+}
+
+func TestConnectionsOutboundDNSNames(t *testing.T) {
+	outbound := func(names map[string][]string) []string {
+		rpt := fixture.Report.Copy()
+		ep := rpt.Endpoint.Nodes[fixture.GoogleEndpointNodeID]
+		for key, values := range names {
+			ep = ep.WithSet(key, report.MakeStringSet(values...))
+		}
+		rpt.Endpoint.Nodes[fixture.GoogleEndpointNodeID] = ep
+		renderableNodes := render.HostRenderer.Render(rpt, nil)
+		have := detailed.MakeNode("hosts", rpt, renderableNodes, renderableNodes[fixture.ServerHostNodeID])
+		labels := []string{}
+		for _, summary := range have.Connections {
+			if summary.ID != "outgoing-connections" {
+				continue
+			}
+			for _, row := range summary.Connections {
+				labels = append(labels, row.Label)
+			}
+		}
+		return labels
+	}
+
+	for _, tc := range []struct {
+		names map[string][]string
+		want  []string
+	}{
+		{nil, []string{fixture.GoogleIP}},
+		{
+			map[string][]string{endpoint.ReverseDNSNames: {"dns.google"}},
+			[]string{"dns.google (" + fixture.GoogleIP + ")"},
+		},
+		{
+			map[string][]string{
+				endpoint.SnoopedDNSNames: {"google.com"},
+				endpoint.ReverseDNSNames: {"dns.google"},
+			},
+			[]string{"google.com (" + fixture.GoogleIP + ")"},
+		},
+	} {
+		if have := outbound(tc.names); !reflect.DeepEqual(tc.want, have) {
+			t.Error(test.Diff(tc.want, have))
+		}
+	}
+}
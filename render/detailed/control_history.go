@@ -0,0 +1,200 @@
+package detailed
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultControlHistorySize is the number of results kept per node by
+	// default.
+	DefaultControlHistorySize = 10
+	// DefaultControlHistoryNodes is the number of nodes results are kept
+	// for by default.
+	DefaultControlHistoryNodes = 1000
+)
+
+// ControlResult is the outcome of a single control execution, as kept in
+// a ControlHistory.
+type ControlResult struct {
+	Timestamp time.Time   `json:"timestamp"`
+	ProbeID   string      `json:"probeId"`
+	Control   string      `json:"control"`
+	Value     interface{} `json:"value,omitempty"`
+	Error     string      `json:"error,omitempty"`
+}
+
+// ControlHistory is a bounded buffer of the most recent control results,
+// keyed by node ID. It is safe for concurrent use.
+type ControlHistory struct {
+	mtx      sync.Mutex
+	size     int
+	maxNodes int
+	results  map[string]*nodeResults
+	added    uint64
+}
+
+// nodeResults are the results kept for a node, with when the last one was
+// added, in the order of all the results added to the history.
+type nodeResults struct {
+	results []ControlResult
+	added   uint64
+}
+
+// NewControlHistory makes a new ControlHistory, keeping at most size
+// results per node, for at most maxNodes nodes.
+func NewControlHistory(size, maxNodes int) *ControlHistory {
+	return &ControlHistory{
+		size:     size,
+		maxNodes: maxNodes,
+		results:  map[string]*nodeResults{},
+	}
+}
+
+// Add records a control result for a node, evicting the oldest result if
+// the node's buffer is full. The results of the node least recently added
+// to are evicted, making room for a node beyond maxNodes.
+func (h *ControlHistory) Add(nodeID string, result ControlResult) {
+	if h.size <= 0 || h.maxNodes <= 0 {
+		return
+	}
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	node, ok := h.results[nodeID]
+	if !ok {
+		if len(h.results) >= h.maxNodes {
+			h.evict()
+		}
+		node = &nodeResults{}
+		h.results[nodeID] = node
+	}
+	h.added++
+	node.added = h.added
+	node.results = append(node.results, result)
+	if len(node.results) > h.size {
+		node.results = node.results[len(node.results)-h.size:]
+	}
+}
+
+// evict drops the results of the node least recently added to.
+func (h *ControlHistory) evict() {
+	var (
+		oldestID string
+		oldest   uint64
+	)
+	for nodeID, node := range h.results {
+		if oldestID == "" || node.added < oldest {
+			oldestID, oldest = nodeID, node.added
+		}
+	}
+	delete(h.results, oldestID)
+}
+
+// lookup returns the results kept for a node. The lock must be held.
+func (h *ControlHistory) lookup(nodeID string) []ControlResult {
+	if node, ok := h.results[nodeID]; ok {
+		return node.results
+	}
+	return nil
+}
+
+// Lookup returns the recorded results for a node, oldest first.
+func (h *ControlHistory) Lookup(nodeID string) []ControlResult {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	node, ok := h.results[nodeID]
+	if !ok {
+		return nil
+	}
+	return append([]ControlResult{}, node.results...)
+}
+
+// Latest returns the most recent result of the given control on a node.
+func (h *ControlHistory) Latest(nodeID, controlID string) (ControlResult, bool) {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	results := h.lookup(nodeID)
+	for i := len(results) - 1; i >= 0; i-- {
+		if results[i].Control == controlID {
+			return results[i], true
+		}
+	}
+	return ControlResult{}, false
+}
+
+// ControlResultDiff is the change in the output of a control between two
+// of its executions, as returned by DiffControlResults.
+type ControlResultDiff struct {
+	Added   []string `json:"added"`
+	Removed []string `json:"removed"`
+}
+
+// DiffControlResults diffs the text output of two executions of a control,
+// line by line. Lines are matched regardless of where they are, as e.g. ps
+// lists processes in no particular order: a line is added or removed as
+// many times as it appears more often in one output than in the other.
+// Added lines are in the order of cur, removed ones in that of prev. It
+// returns false unless both results have text values.
+func DiffControlResults(prev, cur ControlResult) (ControlResultDiff, bool) {
+	prevText, ok := prev.Value.(string)
+	if !ok {
+		return ControlResultDiff{}, false
+	}
+	curText, ok := cur.Value.(string)
+	if !ok {
+		return ControlResultDiff{}, false
+	}
+	prevLines, curLines := splitLines(prevText), splitLines(curText)
+
+	counts := map[string]int{}
+	for _, line := range prevLines {
+		counts[line]++
+	}
+	diff := ControlResultDiff{Added: []string{}, Removed: []string{}}
+	for _, line := range curLines {
+		if counts[line] > 0 {
+			counts[line]--
+		} else {
+			diff.Added = append(diff.Added, line)
+		}
+	}
+	for i := len(prevLines) - 1; i >= 0; i-- {
+		// Walking backwards, the occurrences left over are the last ones.
+		if line := prevLines[i]; counts[line] > 0 {
+			counts[line]--
+			diff.Removed = append(diff.Removed, line)
+		}
+	}
+	for i, j := 0, len(diff.Removed)-1; i < j; i, j = i+1, j-1 {
+		diff.Removed[i], diff.Removed[j] = diff.Removed[j], diff.Removed[i]
+	}
+	return diff, true
+}
+
+func splitLines(text string) []string {
+	text = strings.TrimSuffix(text, "\n")
+	if text == "" {
+		return nil
+	}
+	return strings.Split(text, "\n")
+}
+
+// Diff diffs the two most recent results of the given control on a node,
+// with DiffControlResults. It returns false if the control wasn't run
+// twice, or its results aren't text.
+func (h *ControlHistory) Diff(nodeID, controlID string) (ControlResultDiff, bool) {
+	h.mtx.Lock()
+	var found []ControlResult
+	results := h.lookup(nodeID)
+	for i := len(results) - 1; i >= 0 && len(found) < 2; i-- {
+		if results[i].Control == controlID {
+			found = append(found, results[i])
+		}
+	}
+	h.mtx.Unlock()
+	if len(found) < 2 {
+		return ControlResultDiff{}, false
+	}
+	return DiffControlResults(found[1], found[0])
+}
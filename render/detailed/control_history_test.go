@@ -0,0 +1,116 @@
+package detailed_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/weaveworks/common/test"
+	"github.com/weaveworks/scope/render"
+	"github.com/weaveworks/scope/render/detailed"
+	"github.com/weaveworks/scope/test/fixture"
+	"github.com/weaveworks/scope/test/reflect"
+)
+
+func TestControlHistory(t *testing.T) {
+	history := detailed.NewControlHistory(3, detailed.DefaultControlHistoryNodes)
+	base := time.Unix(0, 0)
+	want := []detailed.ControlResult{}
+	for i := 0; i < 5; i++ {
+		result := detailed.ControlResult{
+			Timestamp: base.Add(time.Duration(i) * time.Second),
+			ProbeID:   "probe",
+			Control:   "control",
+			Value:     i,
+		}
+		history.Add(fixture.ClientContainerNodeID, result)
+		if i >= 2 {
+			want = append(want, result)
+		}
+	}
+
+	// It should keep only the last N results, oldest first
+	if have := history.Lookup(fixture.ClientContainerNodeID); !reflect.DeepEqual(want, have) {
+		t.Error(test.Diff(want, have))
+	}
+
+	// Other nodes should have no history
+	if have := history.Lookup(fixture.ServerContainerNodeID); len(have) != 0 {
+		t.Errorf("Expected no history, got %v", have)
+	}
+
+	// It should be included in the detailed node when requested
+	renderableNodes := render.ContainerRenderer.Render(fixture.Report, nil)
+	renderableNode := renderableNodes[fixture.ClientContainerNodeID]
+	have := detailed.MakeNodeWithOptions("containers", fixture.Report, renderableNodes, renderableNode, detailed.RenderOptions{
+		ControlHistory: history,
+	})
+	if !reflect.DeepEqual(want, have.History) {
+		t.Error(test.Diff(want, have.History))
+	}
+	if have := detailed.MakeNode("containers", fixture.Report, renderableNodes, renderableNode); have.History != nil {
+		t.Errorf("Expected no history by default, got %v", have.History)
+	}
+}
+
+func TestControlHistoryMaxNodes(t *testing.T) {
+	history := detailed.NewControlHistory(3, 2)
+	result := detailed.ControlResult{ProbeID: "probe", Control: "control"}
+	history.Add("a", result)
+	history.Add("b", result)
+	history.Add("a", result)
+
+	// Making room for c evicts b, added to the least recently
+	history.Add("c", result)
+	for nodeID, want := range map[string]int{"a": 2, "b": 0, "c": 1} {
+		if have := len(history.Lookup(nodeID)); have != want {
+			t.Errorf("%s: expected %d results, got %d", nodeID, want, have)
+		}
+	}
+}
+
+func TestControlHistoryDiff(t *testing.T) {
+	history := detailed.NewControlHistory(5, detailed.DefaultControlHistoryNodes)
+	base := time.Unix(0, 0)
+	add := func(i int, control string, value interface{}) {
+		history.Add(fixture.ClientContainerNodeID, detailed.ControlResult{
+			Timestamp: base.Add(time.Duration(i) * time.Second),
+			ProbeID:   "probe",
+			Control:   control,
+			Value:     value,
+		})
+	}
+
+	add(0, "ps", "PID CMD\n1 init\n20 nginx\n21 nginx\n30 cron\n")
+	if _, ok := history.Diff(fixture.ClientContainerNodeID, "ps"); ok {
+		t.Errorf("Expected no diff of a single result")
+	}
+	add(1, "restart", "")
+	add(2, "ps", "PID CMD\n1 init\n21 nginx\n31 cron\n40 sshd\n")
+
+	want := detailed.ControlResultDiff{
+		Added:   []string{"31 cron", "40 sshd"},
+		Removed: []string{"20 nginx", "30 cron"},
+	}
+	have, ok := history.Diff(fixture.ClientContainerNodeID, "ps")
+	if !ok {
+		t.Fatal("Expected a diff")
+	}
+	if !reflect.DeepEqual(want, have) {
+		t.Error(test.Diff(want, have))
+	}
+
+	// Lines are matched wherever they are, as many times as they appear
+	want = detailed.ControlResultDiff{Added: []string{"b"}, Removed: []string{}}
+	have, _ = detailed.DiffControlResults(
+		detailed.ControlResult{Value: "a\nb\nc"},
+		detailed.ControlResult{Value: "c\nb\na\nb"},
+	)
+	if !reflect.DeepEqual(want, have) {
+		t.Error(test.Diff(want, have))
+	}
+
+	// Only text results are diffed
+	if _, ok := detailed.DiffControlResults(detailed.ControlResult{Value: 1}, detailed.ControlResult{Value: "1"}); ok {
+		t.Errorf("Expected no diff of a non-text result")
+	}
+}
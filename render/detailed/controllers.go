@@ -0,0 +1,57 @@
+package detailed
+
+import (
+	"github.com/weaveworks/scope/report"
+)
+
+// controllerTopologies are the topologies of the Kubernetes controllers
+// owning pods, lowest level first, so a pod parented by both its replica
+// set and deployment walks through the replica set.
+var controllerTopologies = []string{
+	report.DaemonSet,
+	report.ReplicaSet,
+	report.Deployment,
+}
+
+// ControllerChain walks the controller parents of the node, returning the
+// chain of controllers owning it, nearest first, e.g. the replica set then
+// the deployment of a pod. Each node is visited at most once, so cycles in
+// the parents end the chain.
+func ControllerChain(r report.Report, n report.Node) []Parent {
+	var result []Parent
+	seen := map[string]struct{}{n.Topology + ":" + n.ID: {}}
+	for {
+		next, ok := controllerParent(r, n, seen)
+		if !ok {
+			return result
+		}
+		seen[next.Topology+":"+next.ID] = struct{}{}
+		result = append(result, Parent{
+			ID:         next.ID,
+			Label:      getLabelForTopology[next.Topology](next),
+			TopologyID: primaryAPITopology[next.Topology],
+		})
+		n = next
+	}
+}
+
+// controllerParent returns the first controller parent of the node in the
+// report which wasn't seen already.
+func controllerParent(r report.Report, n report.Node, seen map[string]struct{}) (report.Node, bool) {
+	for _, topologyID := range controllerTopologies {
+		topology, ok := r.Topology(topologyID)
+		if !ok {
+			continue
+		}
+		ids, _ := n.Parents.Lookup(topologyID)
+		for _, id := range ids {
+			if _, ok := seen[topologyID+":"+id]; ok {
+				continue
+			}
+			if parent, ok := topology.Nodes[id]; ok {
+				return parent.WithTopology(topologyID), true
+			}
+		}
+	}
+	return report.Node{}, false
+}
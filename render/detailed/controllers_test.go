@@ -0,0 +1,46 @@
+package detailed_test
+
+import (
+	"testing"
+
+	"github.com/weaveworks/common/test"
+	"github.com/weaveworks/scope/probe/kubernetes"
+	"github.com/weaveworks/scope/render/detailed"
+	"github.com/weaveworks/scope/report"
+	"github.com/weaveworks/scope/test/reflect"
+)
+
+func TestControllerChain(t *testing.T) {
+	parents := func(topologyID string, ids ...string) report.Sets {
+		return report.EmptySets.Add(topologyID, report.MakeStringSet(ids...))
+	}
+	r := report.MakeReport()
+	// The deployment names the replica set as its parent, making a cycle
+	r.Deployment.AddNode(report.MakeNodeWith("deployment", map[string]string{kubernetes.Name: "frontend"}).
+		WithTopology(report.Deployment).
+		WithParents(parents(report.ReplicaSet, "replicaset")))
+	r.ReplicaSet.AddNode(report.MakeNodeWith("replicaset", map[string]string{kubernetes.Name: "frontend-1234"}).
+		WithTopology(report.ReplicaSet).
+		WithParents(parents(report.Deployment, "deployment")))
+	pod := report.MakeNodeWith("pod", map[string]string{kubernetes.Name: "frontend-1234-abcd"}).
+		WithTopology(report.Pod).
+		WithParents(parents(report.ReplicaSet, "replicaset").Merge(parents(report.Deployment, "deployment")))
+	r.Pod.AddNode(pod)
+
+	want := []detailed.Parent{
+		{ID: "replicaset", Label: "frontend-1234", TopologyID: "replica-sets"},
+		{ID: "deployment", Label: "frontend", TopologyID: "deployments"},
+	}
+	if have := detailed.ControllerChain(r, pod); !reflect.DeepEqual(want, have) {
+		t.Error(test.Diff(want, have))
+	}
+
+	ns := report.Nodes{pod.ID: pod}
+	if have := detailed.MakeNode("pods", r, ns, pod).Controllers; have != nil {
+		t.Errorf("Expected no controllers by default, got %v", have)
+	}
+	node := detailed.MakeNodeWithOptions("pods", r, ns, pod, detailed.RenderOptions{ControllerChain: true})
+	if !reflect.DeepEqual(want, node.Controllers) {
+		t.Error(test.Diff(want, node.Controllers))
+	}
+}
@@ -0,0 +1,117 @@
+package detailed
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/weaveworks/scope/report"
+)
+
+var (
+	pinnedControlsMtx sync.RWMutex
+	pinnedControls    = map[string]int{}
+)
+
+// RegisterPinnedControls pins controls, by ID, ahead of all others. Pinned
+// controls are ordered as they were registered, regardless of their rank;
+// the rest follow ordered by rank.
+func RegisterPinnedControls(controlIDs ...string) {
+	pinnedControlsMtx.Lock()
+	defer pinnedControlsMtx.Unlock()
+	for _, id := range controlIDs {
+		if _, ok := pinnedControls[id]; !ok {
+			pinnedControls[id] = len(pinnedControls)
+		}
+	}
+}
+
+func pinnedControlPosition(controlID string) (int, bool) {
+	pinnedControlsMtx.RLock()
+	defer pinnedControlsMtx.RUnlock()
+	position, ok := pinnedControls[controlID]
+	return position, ok
+}
+
+type controlInstancesByPin []ControlInstance
+
+func (s controlInstancesByPin) Len() int      { return len(s) }
+func (s controlInstancesByPin) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s controlInstancesByPin) Less(i, j int) bool {
+	pi, iPinned := pinnedControlPosition(s[i].Control.ID)
+	pj, jPinned := pinnedControlPosition(s[j].Control.ID)
+	switch {
+	case iPinned && jPinned:
+		return pi < pj
+	case iPinned != jPinned:
+		return iPinned
+	case s[i].Control.Rank != s[j].Control.Rank:
+		return s[i].Control.Rank < s[j].Control.Rank
+	}
+	return s[i].Control.ID < s[j].Control.ID
+}
+
+// controlsFor returns the live controls of the node, leaving out those
+// requiring a capability not in granted, and those not allowed to the
+// role.
+func controlsFor(topology report.Topology, nodeID string, granted map[string]bool, role string) []ControlInstance {
+	result := []ControlInstance{}
+	node, ok := topology.Nodes[nodeID]
+	if !ok {
+		return result
+	}
+	// The latest entries of a node reported by several probes are merged,
+	// so its controls are those of the probe which reported it last.
+	probeID, ok := node.Latest.Lookup(report.ControlProbeID)
+	if !ok {
+		return result
+	}
+	node.LatestControls.ForEach(func(controlID string, _ time.Time, data report.NodeControlData) {
+		if data.Dead {
+			return
+		}
+		if control, ok := topology.Controls[controlID]; ok {
+			if !CapabilityGranted(control, granted) {
+				return
+			}
+			if !RoleAllowed(control, role) {
+				return
+			}
+			result = append(result, ControlInstance{
+				ProbeID: probeID,
+				NodeID:  nodeID,
+				Control: control,
+			})
+		}
+	})
+	sort.Sort(controlInstancesByPin(result))
+	return result
+}
+
+// CapabilityGranted says whether the control is offered to users granted
+// the capabilities.
+func CapabilityGranted(control report.Control, granted map[string]bool) bool {
+	return control.Capability == "" || granted[control.Capability]
+}
+
+// RoleAllowed says whether the control is offered to users of the role.
+// Controls restricted to some roles are offered to none of them without a
+// role.
+func RoleAllowed(control report.Control, role string) bool {
+	if len(control.AllowedRoles) == 0 {
+		return true
+	}
+	for _, allowed := range control.AllowedRoles {
+		if allowed == role {
+			return true
+		}
+	}
+	return false
+}
+
+func controls(r report.Report, n report.Node, granted map[string]bool, role string) []ControlInstance {
+	if t, ok := r.Topology(n.Topology); ok {
+		return controlsFor(t, n.ID, granted, role)
+	}
+	return []ControlInstance{}
+}
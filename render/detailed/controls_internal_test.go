@@ -0,0 +1,117 @@
+package detailed
+
+import (
+	"testing"
+	"time"
+
+	"github.com/weaveworks/scope/report"
+	"github.com/weaveworks/scope/test/reflect"
+)
+
+func controlIDs(controls []ControlInstance) []string {
+	result := []string{}
+	for _, c := range controls {
+		result = append(result, c.Control.ID)
+	}
+	return result
+}
+
+func topologyWithControls(controls ...report.Control) report.Topology {
+	t := report.MakeTopology()
+	ids := []string{}
+	for _, c := range controls {
+		t.Controls.AddControl(c)
+		ids = append(ids, c.ID)
+	}
+	t.AddNode(report.MakeNodeWith("node", map[string]string{report.ControlProbeID: "probe"}).
+		WithLatestActiveControls(ids...))
+	return t
+}
+
+func TestControlsPinnedOrder(t *testing.T) {
+	defer func() { pinnedControls = map[string]int{} }()
+
+	topology := topologyWithControls(
+		report.Control{ID: "stop", Rank: 3},
+		report.Control{ID: "start", Rank: 1},
+		report.Control{ID: "restart", Rank: 2},
+		report.Control{ID: "attach", Rank: 0},
+		report.Control{ID: "logs", Rank: 4},
+	)
+
+	// Without pins, controls are ordered by rank
+	if have, want := controlIDs(controlsFor(topology, "node", nil, "")), []string{"attach", "start", "restart", "stop", "logs"}; !reflect.DeepEqual(want, have) {
+		t.Errorf("Expected %v, got %v", want, have)
+	}
+
+	// Pinned controls lead in the order they were pinned, the rest
+	// follow by rank. Pinning controls the node doesn't have is harmless.
+	RegisterPinnedControls("logs", "missing", "restart")
+	if have, want := controlIDs(controlsFor(topology, "node", nil, "")), []string{"logs", "restart", "attach", "start", "stop"}; !reflect.DeepEqual(want, have) {
+		t.Errorf("Expected %v, got %v", want, have)
+	}
+}
+
+func TestControlsForNodeReportedByTwoProbes(t *testing.T) {
+	now := time.Now()
+	probeReport := func(probeID string, heartbeat time.Time) report.Report {
+		rpt := report.MakeReport()
+		rpt.Container.Controls.AddControl(report.Control{ID: "restart"})
+		rpt.Container.AddNode(report.MakeNode("node").WithTopology(report.Container).
+			WithLatest(report.ControlProbeID, heartbeat, probeID).
+			WithLatestControl("restart", heartbeat, report.NodeControlData{}))
+		return rpt
+	}
+	fresh, stale := probeReport("fresh", now), probeReport("stale", now.Add(-time.Minute))
+
+	// The fresh probe's instance is kept whichever report the app merges
+	// first.
+	for _, merged := range []report.Report{fresh.Merge(stale), stale.Merge(fresh)} {
+		have := controls(merged, merged.Container.Nodes["node"], nil, "")
+		if len(have) != 1 || have[0].ProbeID != "fresh" {
+			t.Errorf("Expected a single restart control from the fresh probe, got %v", have)
+		}
+	}
+}
+
+func TestControlsForGrantedCapabilities(t *testing.T) {
+	topology := topologyWithControls(
+		report.Control{ID: "logs", Rank: 0},
+		report.Control{ID: "exec", Rank: 1, Capability: "exec"},
+		report.Control{ID: "stop", Rank: 2, Capability: "lifecycle"},
+	)
+	for _, c := range []struct {
+		granted map[string]bool
+		want    []string
+	}{
+		{nil, []string{"logs"}},
+		{map[string]bool{"exec": true}, []string{"logs", "exec"}},
+		{map[string]bool{"exec": false, "lifecycle": true}, []string{"logs", "stop"}},
+		{map[string]bool{"exec": true, "lifecycle": true}, []string{"logs", "exec", "stop"}},
+	} {
+		if have := controlIDs(controlsFor(topology, "node", c.granted, "")); !reflect.DeepEqual(c.want, have) {
+			t.Errorf("granted %v: want %v, have %v", c.granted, c.want, have)
+		}
+	}
+}
+
+func TestControlsForRole(t *testing.T) {
+	topology := topologyWithControls(
+		report.Control{ID: "logs", Rank: 0},
+		report.Control{ID: "exec", Rank: 1, AllowedRoles: []string{"admin", "operator"}},
+		report.Control{ID: "stop", Rank: 2, AllowedRoles: []string{"admin"}},
+	)
+	for _, c := range []struct {
+		role string
+		want []string
+	}{
+		{"", []string{"logs"}}, // restricted controls are denied without a role
+		{"admin", []string{"logs", "exec", "stop"}},
+		{"operator", []string{"logs", "exec"}},
+		{"viewer", []string{"logs"}},
+	} {
+		if have := controlIDs(controlsFor(topology, "node", nil, c.role)); !reflect.DeepEqual(c.want, have) {
+			t.Errorf("role %q: want %v, have %v", c.role, c.want, have)
+		}
+	}
+}
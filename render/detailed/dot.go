@@ -0,0 +1,42 @@
+package detailed
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+var dotEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func dotQuote(s string) string { return `"` + dotEscaper.Replace(s) + `"` }
+
+// DOT renders the node, its connection peers and its children as a
+// Graphviz digraph. Connections are solid edges, labelled with their port
+// and count, and children hang off the node with dashed edges.
+func (n Node) DOT() string {
+	var buf bytes.Buffer
+	graph := n.Graph()
+	fmt.Fprintf(&buf, "digraph %s {\n", dotQuote(n.ID))
+	for _, node := range graph.Nodes {
+		fmt.Fprintf(&buf, "\t%s [label=%s];\n", dotQuote(node.ID), dotQuote(node.Label))
+	}
+	for _, group := range n.Children {
+		for _, child := range group.Nodes {
+			fmt.Fprintf(&buf, "\t%s [label=%s, shape=box];\n", dotQuote(child.ID), dotQuote(child.Label))
+		}
+	}
+	for _, edge := range graph.Edges {
+		label := fmt.Sprintf("%d", edge.Count)
+		if edge.Port != "" {
+			label = fmt.Sprintf(":%s (%d)", edge.Port, edge.Count)
+		}
+		fmt.Fprintf(&buf, "\t%s -> %s [label=%s];\n", dotQuote(edge.Source), dotQuote(edge.Target), dotQuote(label))
+	}
+	for _, group := range n.Children {
+		for _, child := range group.Nodes {
+			fmt.Fprintf(&buf, "\t%s -> %s [style=dashed];\n", dotQuote(n.ID), dotQuote(child.ID))
+		}
+	}
+	buf.WriteString("}\n")
+	return buf.String()
+}
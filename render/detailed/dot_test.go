@@ -0,0 +1,48 @@
+package detailed_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/weaveworks/scope/render"
+	"github.com/weaveworks/scope/render/detailed"
+	"github.com/weaveworks/scope/test/fixture"
+)
+
+func TestNodeDOT(t *testing.T) {
+	renderableNodes := render.ContainerRenderer.Render(fixture.Report, nil)
+	server := detailed.MakeNode("containers", fixture.Report, renderableNodes, renderableNodes[fixture.ServerContainerNodeID])
+	have := server.DOT()
+
+	if !strings.HasPrefix(have, `digraph "`+fixture.ServerContainerNodeID+`" {`) || !strings.HasSuffix(have, "}\n") {
+		t.Errorf("Expected a digraph named after the node, got:\n%s", have)
+	}
+	if strings.Count(have, "{") != 1 || strings.Count(have, "}") != 1 {
+		t.Errorf("Expected balanced braces, got:\n%s", have)
+	}
+	for _, want := range []string{
+		`"` + fixture.ServerContainerNodeID + `" [label="` + server.Label + `"];`,
+		`"` + fixture.ClientContainerNodeID + `" [label="` + fixture.ClientContainerName + `"];`,
+		`"` + fixture.ClientContainerNodeID + `" -> "` + fixture.ServerContainerNodeID + `" [label=":80 (2)"];`,
+		`"` + render.IncomingInternetID + `" -> "` + fixture.ServerContainerNodeID + `" [label=":80 (1)"];`,
+	} {
+		if !strings.Contains(have, want) {
+			t.Errorf("Expected %s in:\n%s", want, have)
+		}
+	}
+	for _, group := range server.Children {
+		for _, child := range group.Nodes {
+			if want := `"` + server.ID + `" -> "` + child.ID + `" [style=dashed];`; !strings.Contains(have, want) {
+				t.Errorf("Expected %s in:\n%s", want, have)
+			}
+		}
+	}
+}
+
+func TestNodeDOTEscaping(t *testing.T) {
+	node := detailed.Node{NodeSummary: detailed.NodeSummary{ID: `a"b`, Label: `say "hi"\`}}
+	want := "digraph \"a\\\"b\" {\n\t\"a\\\"b\" [label=\"say \\\"hi\\\"\\\\\"];\n}\n"
+	if have := node.DOT(); have != want {
+		t.Errorf("want %q, have %q", want, have)
+	}
+}
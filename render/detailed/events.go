@@ -0,0 +1,51 @@
+package detailed
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/weaveworks/scope/probe/kubernetes"
+	"github.com/weaveworks/scope/report"
+)
+
+// An Event is something which happened to a node, as reported by
+// Kubernetes, e.g. its pod being scheduled, its image pulled, or its
+// containers started or killed.
+type Event struct {
+	Timestamp time.Time `json:"timestamp"`
+	Reason    string    `json:"reason"`
+	Message   string    `json:"message,omitempty"`
+}
+
+// NodeEvents returns the timeline of the events of the node, oldest first,
+// or nil if the node has none.
+func NodeEvents(n report.Node) []Event {
+	var result []Event
+	n.Latest.ForEach(func(key string, timestamp time.Time, value string) {
+		if !strings.HasPrefix(key, kubernetes.EventPrefix) {
+			return
+		}
+		event := Event{Timestamp: timestamp, Reason: value}
+		if i := strings.Index(value, ": "); i >= 0 {
+			event.Reason, event.Message = value[:i], value[i+2:]
+		}
+		result = append(result, event)
+	})
+	sort.Sort(eventsByTime(result))
+	return result
+}
+
+type eventsByTime []Event
+
+func (s eventsByTime) Len() int      { return len(s) }
+func (s eventsByTime) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s eventsByTime) Less(i, j int) bool {
+	if !s[i].Timestamp.Equal(s[j].Timestamp) {
+		return s[i].Timestamp.Before(s[j].Timestamp)
+	}
+	if s[i].Reason != s[j].Reason {
+		return s[i].Reason < s[j].Reason
+	}
+	return s[i].Message < s[j].Message
+}
@@ -0,0 +1,42 @@
+package detailed_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/weaveworks/common/test"
+	"github.com/weaveworks/scope/probe/kubernetes"
+	"github.com/weaveworks/scope/render/detailed"
+	"github.com/weaveworks/scope/report"
+)
+
+func TestMakeDetailedNodeEvents(t *testing.T) {
+	now := time.Now().UTC()
+	pod := report.MakeNodeWith("pod", map[string]string{kubernetes.Name: "pod"}).
+		WithTopology(report.Pod).
+		WithLatest(kubernetes.EventPrefix+"started", now.Add(-1*time.Minute), "Started: Started container app").
+		WithLatest(kubernetes.EventPrefix+"scheduled", now.Add(-3*time.Minute), "Scheduled: Assigned to node1").
+		WithLatest(kubernetes.EventPrefix+"killing", now, "Killing").
+		WithLatest(kubernetes.EventPrefix+"pulled", now.Add(-2*time.Minute), "Pulled: Container image pulled")
+	r := report.MakeReport()
+	r.Pod.AddNode(pod)
+
+	node := detailed.MakeNode("pods", r, r.Pod.Nodes, pod)
+	want := []detailed.Event{
+		{Timestamp: now.Add(-3 * time.Minute), Reason: "Scheduled", Message: "Assigned to node1"},
+		{Timestamp: now.Add(-2 * time.Minute), Reason: "Pulled", Message: "Container image pulled"},
+		{Timestamp: now.Add(-1 * time.Minute), Reason: "Started", Message: "Started container app"},
+		{Timestamp: now, Reason: "Killing"},
+	}
+	if !reflect.DeepEqual(want, node.Events) {
+		t.Errorf("%s", test.Diff(want, node.Events))
+	}
+
+	// Omitted when the node has no events
+	other := report.MakeNodeWith("other", map[string]string{kubernetes.Name: "other"}).WithTopology(report.Pod)
+	r.Pod.AddNode(other)
+	if have := detailed.MakeNode("pods", r, r.Pod.Nodes, other).Events; have != nil {
+		t.Errorf("Expected no events, got %v", have)
+	}
+}
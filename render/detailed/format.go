@@ -0,0 +1,247 @@
+package detailed
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/weaveworks/common/mtime"
+	"github.com/weaveworks/scope/probe/awsecs"
+	"github.com/weaveworks/scope/probe/kubernetes"
+	"github.com/weaveworks/scope/probe/process"
+	"github.com/weaveworks/scope/report"
+)
+
+const (
+	datetime = "datetime"
+	duration = "duration"
+)
+
+// formatNode applies the value formatting requested in opts to the summary
+// of the node and to those of its children. The command lines of focused
+// nodes are left whole.
+func formatNode(node Node, opts RenderOptions) Node {
+	if opts.Focused {
+		opts.TruncateCommands = 0
+	}
+	if !opts.RFC3339Timestamps && !opts.RelativeTimestamps && opts.Locale == "" && opts.MetricPrecision <= 0 && opts.TruncateCommands <= 0 {
+		return node
+	}
+	node.NodeSummary = formatSummary(node.NodeSummary, opts)
+	children := make([]NodeSummaryGroup, len(node.Children))
+	for i, group := range node.Children {
+		nodes := make([]NodeSummary, len(group.Nodes))
+		for j, child := range group.Nodes {
+			nodes[j] = formatSummary(child, opts)
+		}
+		group.Nodes = nodes
+		children[i] = group
+	}
+	node.Children = children
+	return node
+}
+
+// numberIdentifiers are the number metadata identifying things rather than
+// counting them, which are not localized: "1,234" is no way to write a PID.
+var numberIdentifiers = map[string]bool{
+	process.PID:                   true,
+	process.PPID:                  true,
+	kubernetes.ObservedGeneration: true,
+	awsecs.TaskDefinitionRevision: true,
+}
+
+// formatSummary returns a copy of the summary with its metadata and metric
+// values formatted as requested in opts.
+func formatSummary(summary NodeSummary, opts RenderOptions) NodeSummary {
+	format, localized := lookupNumberFormat(opts.Locale)
+	if summary.Metadata != nil {
+		metadata := make([]report.MetadataRow, len(summary.Metadata))
+		now := mtime.Now()
+		for i, row := range summary.Metadata {
+			if row.Datatype == datetime && opts.RFC3339Timestamps {
+				row.Value = formatRFC3339(row.Value)
+			}
+			if row.Datatype == datetime && opts.RelativeTimestamps {
+				if relative, ok := formatRelative(row.Value, now); ok {
+					row.Tooltip, row.Value = row.Value, relative
+				}
+			}
+			if row.Datatype == number && localized && !numberIdentifiers[row.ID] {
+				row.Value = format.format(row.Value)
+			}
+			if row.ID == process.Cmdline && opts.TruncateCommands > 0 {
+				row.Truncate = opts.TruncateCommands
+			}
+			metadata[i] = row
+		}
+		summary.Metadata = metadata
+	}
+	if summary.Metrics != nil && (opts.MetricPrecision > 0 || localized) {
+		metrics := make([]report.MetricRow, len(summary.Metrics))
+		for i, row := range summary.Metrics {
+			if opts.MetricPrecision > 0 {
+				row = roundMetricRow(row, opts.MetricPrecision)
+			}
+			if localized {
+				row.FormattedValue = format.format(strconv.FormatFloat(row.Value, 'f', -1, 64))
+			}
+			metrics[i] = row
+		}
+		summary.Metrics = metrics
+	}
+	if summary.Processes != nil {
+		processes := make([]NodeSummary, len(summary.Processes))
+		for i, p := range summary.Processes {
+			processes[i] = formatSummary(p, opts)
+		}
+		summary.Processes = processes
+	}
+	return summary
+}
+
+// roundMetricRow returns a copy of the row with its value, minimum and
+// maximum rounded to the given number of decimals.
+func roundMetricRow(row report.MetricRow, decimals int) report.MetricRow {
+	row.Value = round(row.Value, decimals)
+	if row.Metric != nil {
+		metric := *row.Metric
+		metric.Min = round(metric.Min, decimals)
+		metric.Max = round(metric.Max, decimals)
+		row.Metric = &metric
+	}
+	return row
+}
+
+// round rounds num half away from zero to the given number of decimals.
+// Unlike toFixed, it doesn't truncate.
+func round(num float64, decimals int) float64 {
+	output := math.Pow(10, float64(decimals))
+	if num < 0 {
+		return -math.Floor(-num*output+0.5) / output
+	}
+	return math.Floor(num*output+0.5) / output
+}
+
+// formatRFC3339 reformats a timestamp, as reported by the probes, to
+// RFC3339 in UTC. Values which don't parse are left alone.
+func formatRFC3339(value string) string {
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return value
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// formatRelative writes a timestamp, as reported by the probes, relative to
+// now in its most significant unit, e.g. "5m ago".
+func formatRelative(value string, now time.Time) (string, bool) {
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return "", false
+	}
+	d := now.Sub(t)
+	switch {
+	case d < -time.Second:
+		return "in " + strings.SplitN(formatDuration(-d), " ", 2)[0], true
+	case d < time.Second:
+		return "just now", true
+	}
+	return strings.SplitN(formatDuration(d), " ", 2)[0] + " ago", true
+}
+
+// numberFormat is how a locale writes numbers.
+type numberFormat struct {
+	thousands, decimal string
+}
+
+// numberFormats are the locales known to Locale, by language tag.
+var numberFormats = map[string]numberFormat{
+	"en":    {thousands: ",", decimal: "."},
+	"de":    {thousands: ".", decimal: ","},
+	"de-CH": {thousands: "'", decimal: "."},
+	"es":    {thousands: ".", decimal: ","},
+	"fr":    {thousands: "\u202f", decimal: ","},
+	"it":    {thousands: ".", decimal: ","},
+	"ja":    {thousands: ",", decimal: "."},
+	"nl":    {thousands: ".", decimal: ","},
+	"pt":    {thousands: ".", decimal: ","},
+}
+
+// lookupNumberFormat finds the number format of a locale, falling back
+// from e.g. "de-AT" to "de".
+func lookupNumberFormat(locale string) (numberFormat, bool) {
+	if locale == "" {
+		return numberFormat{}, false
+	}
+	locale = strings.Replace(locale, "_", "-", -1)
+	if f, ok := numberFormats[locale]; ok {
+		return f, true
+	}
+	if i := strings.Index(locale, "-"); i >= 0 {
+		f, ok := numberFormats[locale[:i]]
+		return f, ok
+	}
+	return numberFormat{}, false
+}
+
+// format rewrites a number, as reported by the probes, in this format.
+// Values which don't parse are left alone.
+func (f numberFormat) format(value string) string {
+	if _, err := strconv.ParseFloat(value, 64); err != nil {
+		return value
+	}
+	sign, integer, fraction := "", value, ""
+	if strings.HasPrefix(integer, "-") || strings.HasPrefix(integer, "+") {
+		sign, integer = integer[:1], integer[1:]
+	}
+	if i := strings.Index(integer, "."); i >= 0 {
+		integer, fraction = integer[:i], integer[i+1:]
+	}
+	if strings.ContainsAny(integer, "eExXpP") || strings.ContainsAny(fraction, "eExXpP+-") {
+		// Scientific and hex notations are left alone
+		return value
+	}
+	groups := []string{}
+	for len(integer) > 3 {
+		groups = append([]string{integer[len(integer)-3:]}, groups...)
+		integer = integer[:len(integer)-3]
+	}
+	groups = append([]string{integer}, groups...)
+	result := sign + strings.Join(groups, f.thousands)
+	if fraction != "" {
+		result += f.decimal + fraction
+	}
+	return result
+}
+
+// formatDuration writes a duration in its two most significant units,
+// from days down to seconds, e.g. "3d 4h" or "5m 6s".
+func formatDuration(d time.Duration) string {
+	if d < 0 {
+		d = 0
+	}
+	units := []struct {
+		suffix string
+		size   time.Duration
+	}{
+		{"d", 24 * time.Hour},
+		{"h", time.Hour},
+		{"m", time.Minute},
+		{"s", time.Second},
+	}
+	parts := []string{}
+	for _, unit := range units {
+		if n := d / unit.size; n > 0 || len(parts) > 0 {
+			parts = append(parts, strconv.FormatInt(int64(n), 10)+unit.suffix)
+			d -= n * unit.size
+		}
+		if len(parts) == 2 {
+			break
+		}
+	}
+	if len(parts) == 0 {
+		return "0s"
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,261 @@
+package detailed_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/weaveworks/common/mtime"
+	"github.com/weaveworks/scope/probe/awsecs"
+	"github.com/weaveworks/scope/probe/docker"
+	"github.com/weaveworks/scope/probe/process"
+	"github.com/weaveworks/scope/render/detailed"
+	"github.com/weaveworks/scope/report"
+	"github.com/weaveworks/scope/test/reflect"
+)
+
+func ecsServiceWithTasks(createdAt ...string) (report.Report, report.Node) {
+	r := report.MakeReport()
+	r.ECSTask = r.ECSTask.WithMetadataTemplates(report.MetadataTemplates{
+		awsecs.CreatedAt: {ID: awsecs.CreatedAt, Label: "Created At", From: report.FromLatest, Datatype: "datetime"},
+	})
+	service := report.MakeNode(report.MakeECSServiceNodeID("cluster", "service")).WithTopology(report.ECSService)
+	for i, ts := range createdAt {
+		task := report.MakeNodeWith(fmt.Sprintf("task%d", i), map[string]string{
+			awsecs.TaskFamily: "family",
+			awsecs.CreatedAt:  ts,
+		}).WithTopology(report.ECSTask)
+		r.ECSTask.AddNode(task)
+		service = service.WithChild(task)
+	}
+	r.ECSService.AddNode(service)
+	return r, service
+}
+
+func childMetadataValue(node detailed.Node, id string) []string {
+	values := []string{}
+	for _, group := range node.Children {
+		for _, child := range group.Nodes {
+			for _, row := range child.Metadata {
+				if row.ID == id {
+					values = append(values, row.Value)
+				}
+			}
+		}
+	}
+	return values
+}
+
+func TestMakeDetailedNodeRFC3339Timestamps(t *testing.T) {
+	r, service := ecsServiceWithTasks("2017-03-14T15:09:26.535897932+01:00", "not a timestamp")
+	ns := report.Nodes{service.ID: service}
+
+	have := childMetadataValue(detailed.MakeNode("ecs-services", r, ns, service), awsecs.CreatedAt)
+	if len(have) != 2 || have[0] != "2017-03-14T15:09:26.535897932+01:00" {
+		t.Errorf("Expected timestamps to be left alone by default, got %v", have)
+	}
+
+	have = childMetadataValue(detailed.MakeNodeWithOptions("ecs-services", r, ns, service, detailed.RenderOptions{
+		RFC3339Timestamps: true,
+	}), awsecs.CreatedAt)
+	if len(have) != 2 || have[0] != "2017-03-14T14:09:26Z" || have[1] != "not a timestamp" {
+		t.Errorf("Expected RFC3339 timestamps, got %v", have)
+	}
+}
+
+func TestMakeDetailedNodeRelativeTimestamps(t *testing.T) {
+	now := time.Date(2017, 3, 14, 15, 9, 26, 0, time.UTC)
+	mtime.NowForce(now)
+	defer mtime.NowReset()
+
+	timestamps := []string{
+		now.Add(-5*time.Minute - 6*time.Second).Format(time.RFC3339Nano),
+		now.Add(-400 * 24 * time.Hour).Format(time.RFC3339Nano),
+		now.Format(time.RFC3339Nano),
+		now.Add(2 * time.Hour).Format(time.RFC3339Nano),
+		"not a timestamp",
+	}
+	r, service := ecsServiceWithTasks(timestamps...)
+	ns := report.Nodes{service.ID: service}
+	node := detailed.MakeNodeWithOptions("ecs-services", r, ns, service, detailed.RenderOptions{
+		RelativeTimestamps: true,
+	})
+
+	want := map[string]report.MetadataRow{
+		"task0": {Value: "5m ago", Tooltip: timestamps[0]},
+		"task1": {Value: "400d ago", Tooltip: timestamps[1]},
+		"task2": {Value: "just now", Tooltip: timestamps[2]},
+		"task3": {Value: "in 2h", Tooltip: timestamps[3]},
+		"task4": {Value: "not a timestamp"},
+	}
+	have := map[string]report.MetadataRow{}
+	for _, child := range node.Children[0].Nodes {
+		for _, row := range child.Metadata {
+			if row.ID == awsecs.CreatedAt {
+				have[child.ID] = report.MetadataRow{Value: row.Value, Tooltip: row.Tooltip}
+			}
+		}
+	}
+	if !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
+
+func TestMakeDetailedNodeLocale(t *testing.T) {
+	r := report.MakeReport()
+	r.Process = r.Process.WithMetadataTemplates(report.MetadataTemplates{
+		process.PID:     {ID: process.PID, Label: "PID", From: report.FromLatest, Datatype: "number"},
+		process.PPID:    {ID: process.PPID, Label: "Parent PID", From: report.FromLatest, Datatype: "number"},
+		process.Threads: {ID: process.Threads, Label: "# Threads", From: report.FromLatest, Datatype: "number"},
+		process.Name:    {ID: process.Name, Label: "Name", From: report.FromLatest},
+	}).WithMetricTemplates(report.MetricTemplates{
+		process.MemoryUsage: {ID: process.MemoryUsage, Label: "Memory", Format: report.FilesizeFormat},
+	})
+	node := report.MakeNodeWith(report.MakeProcessNodeID("host", "1234567"), map[string]string{
+		process.PID:     "1234567",
+		process.PPID:    "12345",
+		process.Threads: "-12345.25",
+		process.Name:    "1234",
+	}).WithTopology(report.Process).WithMetrics(report.Metrics{
+		process.MemoryUsage: report.MakeSingletonMetric(time.Now(), 2345678.5),
+	})
+	r.Process.AddNode(node)
+	ns := report.Nodes{node.ID: node}
+
+	for _, c := range []struct {
+		locale                string
+		threads, name, memory string
+	}{
+		{"", "-12345.25", "1234", ""},
+		{"en", "-12,345.25", "1234", "2,345,678.5"},
+		{"de-AT", "-12.345,25", "1234", "2.345.678,5"},
+		{"de_CH", "-12'345.25", "1234", "2'345'678.5"},
+		{"fr", "-12\u202f345,25", "1234", "2\u202f345\u202f678,5"},
+		{"xx", "-12345.25", "1234", ""},
+	} {
+		have := detailed.MakeNodeWithOptions("processes", r, ns, node, detailed.RenderOptions{Locale: c.locale})
+		values := map[string]string{}
+		for _, row := range have.Metadata {
+			values[row.ID] = row.Value
+		}
+		// Identifiers are left alone
+		if values[process.PID] != "1234567" || values[process.PPID] != "12345" {
+			t.Errorf("%q: expected the PIDs to be left alone, have %v", c.locale, values)
+		}
+		if values[process.Threads] != c.threads || values[process.Name] != c.name {
+			t.Errorf("%q: want %s, %s, have %v", c.locale, c.threads, c.name, values)
+		}
+		if len(have.Metrics) != 1 {
+			t.Fatalf("%q: expected a metric, have %v", c.locale, have.Metrics)
+		}
+		if row := have.Metrics[0]; row.Value != 2345678.5 || row.FormattedValue != c.memory {
+			t.Errorf("%q: want memory 2345678.5 formatted as %q, have %v formatted as %q", c.locale, c.memory, row.Value, row.FormattedValue)
+		}
+	}
+}
+
+func TestMakeDetailedNodeMetricPrecision(t *testing.T) {
+	r, pod := podWithContainers(
+		containerWithMetrics("a", 12.3456, 1024),
+		containerWithMetrics("b", -0.125, 1024.55),
+	)
+	ns := report.Nodes{pod.ID: pod}
+
+	// Metric templates already truncate values to two decimals
+	plain := detailed.MakeNode("pods", r, ns, pod)
+	if have := metricValues(plain.Children[0], docker.CPUTotalUsage); !reflect.DeepEqual([]float64{12.34, -0.12}, have) {
+		t.Errorf("Expected values to be left alone by default, got %v", have)
+	}
+
+	for _, c := range []struct {
+		precision   int
+		cpu, memory []float64
+	}{
+		{1, []float64{12.3, -0.1}, []float64{1024, 1024.6}},
+		{3, []float64{12.34, -0.12}, []float64{1024, 1024.55}},
+	} {
+		have := detailed.MakeNodeWithOptions("pods", r, ns, pod, detailed.RenderOptions{MetricPrecision: c.precision})
+		if cpu := metricValues(have.Children[0], docker.CPUTotalUsage); !reflect.DeepEqual(c.cpu, cpu) {
+			t.Errorf("%d: want %v, have %v", c.precision, c.cpu, cpu)
+		}
+		if memory := metricValues(have.Children[0], docker.MemoryUsage); !reflect.DeepEqual(c.memory, memory) {
+			t.Errorf("%d: want %v, have %v", c.precision, c.memory, memory)
+		}
+	}
+}
+
+func TestMakeDetailedNodeTruncateCommands(t *testing.T) {
+	r := report.MakeReport()
+	r.Process = r.Process.WithMetadataTemplates(process.MetadataTemplates)
+	container := report.MakeNodeWith("c", map[string]string{docker.ContainerName: "c"}).WithTopology(report.Container)
+	commands := map[string]string{
+		"1": "nginx",
+		"2": "java -Xmx2g -jar /opt/app/service.jar --config=/etc/app/config.yaml",
+		"3": "python3 -m héllo",
+	}
+	for pid, cmdline := range commands {
+		p := report.MakeNodeWith("p"+pid, map[string]string{process.PID: pid, process.Name: "p" + pid, process.Cmdline: cmdline}).WithTopology(report.Process)
+		r.Process.AddNode(p)
+		container = container.WithChild(p)
+	}
+	r.Container.AddNode(container)
+	ns := report.Nodes{container.ID: container}
+	rows := func(node detailed.Node) map[string]report.MetadataRow {
+		result := map[string]report.MetadataRow{}
+		for _, child := range node.Children[0].Nodes {
+			for _, row := range child.Metadata {
+				if row.ID == process.Cmdline {
+					result[child.ID] = row
+				}
+			}
+		}
+		return result
+	}
+	hasColumn := func(node detailed.Node) bool {
+		for _, column := range node.Children[0].Columns {
+			if column.ID == process.Cmdline {
+				return true
+			}
+		}
+		return false
+	}
+
+	plain := detailed.MakeNode("containers", r, ns, container)
+	if hasColumn(plain) {
+		t.Errorf("Expected no command column by default")
+	}
+	for id, row := range rows(plain) {
+		if row.Value != commands[id[1:]] || row.Truncate != 0 {
+			t.Errorf("Expected the command line of %s to be left alone by default, got %+v", id, row)
+		}
+	}
+
+	node := detailed.MakeNodeWithOptions("containers", r, ns, container, detailed.RenderOptions{TruncateCommands: 15})
+	if !hasColumn(node) {
+		t.Errorf("Expected a command column")
+	}
+	// The command lines are kept whole, for the UI to truncate them
+	want := map[string]report.MetadataRow{
+		"p1": {Value: commands["1"], Truncate: 15},
+		"p2": {Value: commands["2"], Truncate: 15},
+		"p3": {Value: commands["3"], Truncate: 15},
+	}
+	have := rows(node)
+	for id, row := range have {
+		have[id] = report.MetadataRow{Value: row.Value, Truncate: row.Truncate, Tooltip: row.Tooltip}
+	}
+	if !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+
+	// Focused nodes have their command lines shown whole
+	focused := detailed.MakeNodeWithOptions("containers", r, ns, container, detailed.RenderOptions{TruncateCommands: 15, Focused: true})
+	if !hasColumn(focused) {
+		t.Errorf("Expected a command column on a focused node")
+	}
+	for id, row := range rows(focused) {
+		if row.Value != commands[id[1:]] || row.Truncate != 0 {
+			t.Errorf("Expected the command line of %s to be whole on a focused node, got %+v", id, row)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package detailed
+
+import (
+	"sync"
+)
+
+// Geo is where the address of an internet peer of a connection row is, as
+// given by a GeoResolver.
+type Geo struct {
+	Country string `json:"country,omitempty"`
+	ASN     uint32 `json:"asn,omitempty"`
+	ASOrg   string `json:"asOrg,omitempty"`
+}
+
+// GeoResolver locates an IP address, e.g. by looking it up in a local
+// GeoIP database. It returns false for addresses it can't locate.
+// Resolvers are consulted while rendering, so they mustn't make network
+// calls.
+type GeoResolver func(ip string) (Geo, bool)
+
+var (
+	geoResolversMtx sync.RWMutex
+	geoResolvers    []GeoResolver
+)
+
+// RegisterGeoResolver adds a resolver which is consulted when building
+// outgoing connection rows with the GeoEnrichment option. Resolvers are
+// consulted in the order they were registered, and the first one to locate
+// the address wins.
+func RegisterGeoResolver(resolver GeoResolver) {
+	geoResolversMtx.Lock()
+	defer geoResolversMtx.Unlock()
+	geoResolvers = append(geoResolvers, resolver)
+}
+
+// peerGeo locates the address with the registered resolvers, returning nil
+// if none of them can.
+func peerGeo(ip string) *Geo {
+	geoResolversMtx.RLock()
+	defer geoResolversMtx.RUnlock()
+	for _, resolver := range geoResolvers {
+		if geo, ok := resolver(ip); ok {
+			return &geo
+		}
+	}
+	return nil
+}
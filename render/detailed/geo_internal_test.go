@@ -0,0 +1,77 @@
+package detailed
+
+import (
+	"testing"
+
+	"github.com/weaveworks/common/test"
+	"github.com/weaveworks/scope/probe/docker"
+	"github.com/weaveworks/scope/render"
+	"github.com/weaveworks/scope/report"
+	"github.com/weaveworks/scope/test/reflect"
+)
+
+func TestGeoResolvers(t *testing.T) {
+	defer func() { geoResolvers = nil }()
+
+	r := report.MakeReport()
+	client := report.MakeNodeWith("client;<container>", map[string]string{docker.ContainerName: "client"}).WithTopology(report.Container)
+	r.Container.AddNode(client)
+	ns := report.Nodes{client.ID: client}
+	geos := func(enabled bool) map[string]*Geo {
+		counts := newConnectionCounters(RenderOptions{}, nil)
+		counts.geo = enabled
+		counts.counts[connection{remoteNodeID: client.ID, port: "80"}] = 2
+		counts.counts[connection{remoteNodeID: render.OutgoingInternetID, remoteAddr: "dns.google (8.8.8.8)", remoteIP: "8.8.8.8", port: "53"}] = 1
+		counts.counts[connection{remoteNodeID: render.OutgoingInternetID, remoteAddr: "1.2.3.4", remoteIP: "1.2.3.4", port: "80"}] = 1
+		result := map[string]*Geo{}
+		for _, row := range counts.rows(r, ns, false) {
+			result[row.ID] = row.Geo
+		}
+		return result
+	}
+	var (
+		clientRow = "client;<container>---80"
+		googleRow = render.OutgoingInternetID + "-dns.google (8.8.8.8)--53"
+		otherRow  = render.OutgoingInternetID + "-1.2.3.4--80"
+	)
+
+	looked := []string{}
+	RegisterGeoResolver(func(ip string) (Geo, bool) {
+		looked = append(looked, ip)
+		if ip == "8.8.8.8" {
+			return Geo{Country: "US", ASN: 15169, ASOrg: "Google LLC"}, true
+		}
+		return Geo{}, false
+	})
+	RegisterGeoResolver(func(ip string) (Geo, bool) {
+		return Geo{Country: "ZZ"}, true
+	})
+
+	// Without the option, nothing is looked up
+	want := map[string]*Geo{clientRow: nil, googleRow: nil, otherRow: nil}
+	if have := geos(false); !reflect.DeepEqual(want, have) {
+		t.Error(test.Diff(want, have))
+	}
+	if len(looked) != 0 {
+		t.Errorf("Expected no lookups, got %v", looked)
+	}
+
+	// With it, internet peers are located by the first resolver which
+	// can, and other peers are left alone.
+	want = map[string]*Geo{
+		clientRow: nil,
+		googleRow: {Country: "US", ASN: 15169, ASOrg: "Google LLC"},
+		otherRow:  {Country: "ZZ"},
+	}
+	if have := geos(true); !reflect.DeepEqual(want, have) {
+		t.Error(test.Diff(want, have))
+	}
+
+	// Peers no resolver locates carry no geo fields
+	geoResolvers = nil
+	RegisterGeoResolver(func(string) (Geo, bool) { return Geo{}, false })
+	want = map[string]*Geo{clientRow: nil, googleRow: nil, otherRow: nil}
+	if have := geos(true); !reflect.DeepEqual(want, have) {
+		t.Error(test.Diff(want, have))
+	}
+}
@@ -0,0 +1,59 @@
+package detailed
+
+import (
+	"sync"
+
+	"github.com/weaveworks/scope/report"
+)
+
+// HealthScorer scores the health of a node from 0, unhealthy, to 100,
+// healthy. Scorers without an opinion on the node return false.
+type HealthScorer func(report.Node) (float64, bool)
+
+var (
+	healthScorersMtx sync.RWMutex
+	healthScorers    = map[string][]HealthScorer{}
+)
+
+// RegisterHealthScorer adds a scorer for the nodes of the given topology.
+// When several scorers apply to a node, they are composed with
+// MinHealthScore.
+func RegisterHealthScorer(topologyID string, scorer HealthScorer) {
+	healthScorersMtx.Lock()
+	defer healthScorersMtx.Unlock()
+	healthScorers[topologyID] = append(healthScorers[topologyID], scorer)
+}
+
+// MinHealthScore composes scorers into one, scoring a node as its worst
+// score, so a single failing check makes the node unhealthy.
+func MinHealthScore(scorers ...HealthScorer) HealthScorer {
+	return func(n report.Node) (float64, bool) {
+		result, found := 0.0, false
+		for _, scorer := range scorers {
+			score, ok := scorer(n)
+			if ok && (!found || score < result) {
+				result, found = score, true
+			}
+		}
+		return result, found
+	}
+}
+
+// NodeHealthScore scores the node with the scorers registered for its
+// topology, clamped to [0, 100]. It returns nil if no scorer has an
+// opinion on the node.
+func NodeHealthScore(n report.Node) *float64 {
+	healthScorersMtx.RLock()
+	scorers := healthScorers[n.Topology]
+	healthScorersMtx.RUnlock()
+	score, ok := MinHealthScore(scorers...)(n)
+	if !ok {
+		return nil
+	}
+	if score < 0 {
+		score = 0
+	} else if score > 100 {
+		score = 100
+	}
+	return &score
+}
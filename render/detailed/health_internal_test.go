@@ -0,0 +1,76 @@
+package detailed
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/weaveworks/scope/probe/docker"
+	"github.com/weaveworks/scope/probe/host"
+	"github.com/weaveworks/scope/report"
+)
+
+func TestHealthScorers(t *testing.T) {
+	defer func() { healthScorers = map[string][]HealthScorer{} }()
+
+	restarts := func(n report.Node) (float64, bool) {
+		value, ok := n.Latest.Lookup(docker.ContainerRestartCount)
+		if !ok {
+			return 0, false
+		}
+		count, err := strconv.Atoi(value)
+		if err != nil {
+			return 0, false
+		}
+		return 100 - 20*float64(count), true
+	}
+	running := func(n report.Node) (float64, bool) {
+		state, ok := n.Latest.Lookup(docker.ContainerState)
+		if !ok {
+			return 0, false
+		}
+		if state == docker.StateRunning {
+			return 100, true
+		}
+		return 10, true
+	}
+	RegisterHealthScorer(report.Container, restarts)
+	RegisterHealthScorer(report.Container, running)
+
+	r := report.MakeReport()
+	for id, latest := range map[string]map[string]string{
+		"healthy":    {docker.ContainerState: docker.StateRunning, docker.ContainerRestartCount: "0"},
+		"restarting": {docker.ContainerState: docker.StateRunning, docker.ContainerRestartCount: "2"},
+		"exited":     {docker.ContainerState: docker.StateExited, docker.ContainerRestartCount: "1"},
+		"flapping":   {docker.ContainerState: docker.StateRunning, docker.ContainerRestartCount: "9"},
+		"unknown":    {docker.ContainerName: "unknown"},
+	} {
+		latest[docker.ContainerName] = id
+		r.Container.AddNode(report.MakeNodeWith(id, latest).WithTopology(report.Container))
+	}
+	r.Host.AddNode(report.MakeNodeWith("host", map[string]string{host.HostName: "host"}).WithTopology(report.Host))
+
+	for _, tc := range []struct {
+		node report.Node
+		want *float64
+	}{
+		{r.Container.Nodes["healthy"], score(100)},
+		{r.Container.Nodes["restarting"], score(60)},
+		{r.Container.Nodes["exited"], score(10)},
+		{r.Container.Nodes["flapping"], score(0)},
+		{r.Container.Nodes["unknown"], nil},
+		{r.Host.Nodes["host"], nil},
+	} {
+		summary, ok := MakeNodeSummary(r, tc.node)
+		if !ok {
+			t.Fatalf("Expected %s to be summarizable", tc.node.ID)
+		}
+		switch have := summary.HealthScore; {
+		case tc.want == nil && have != nil:
+			t.Errorf("%s: expected no health score, got %v", tc.node.ID, *have)
+		case tc.want != nil && (have == nil || *have != *tc.want):
+			t.Errorf("%s: expected health score %v, got %v", tc.node.ID, *tc.want, have)
+		}
+	}
+}
+
+func score(f float64) *float64 { return &f }
@@ -0,0 +1,43 @@
+package detailed
+
+import (
+	"sync"
+
+	"github.com/weaveworks/scope/report"
+)
+
+// ExternalLink is a deep-link from a node to an external system, e.g. a
+// dashboard filtered to that node.
+type ExternalLink struct {
+	ID    string `json:"id"`
+	Label string `json:"label"`
+	URL   string `json:"url"`
+}
+
+// LinkProvider produces the external links for a node, if any.
+type LinkProvider func(report.Node) []ExternalLink
+
+var (
+	linkProvidersMtx sync.RWMutex
+	linkProviders    []LinkProvider
+)
+
+// RegisterLinkProvider adds a provider which is consulted when summarizing
+// nodes. Providers are consulted in the order they were registered and
+// their links concatenated.
+func RegisterLinkProvider(provider LinkProvider) {
+	linkProvidersMtx.Lock()
+	defer linkProvidersMtx.Unlock()
+	linkProviders = append(linkProviders, provider)
+}
+
+// NodeLinks returns the links of all registered providers for a node.
+func NodeLinks(n report.Node) []ExternalLink {
+	linkProvidersMtx.RLock()
+	defer linkProvidersMtx.RUnlock()
+	var result []ExternalLink
+	for _, provider := range linkProviders {
+		result = append(result, provider(n)...)
+	}
+	return result
+}
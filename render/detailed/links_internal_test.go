@@ -0,0 +1,68 @@
+package detailed
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/weaveworks/common/test"
+	"github.com/weaveworks/scope/probe/docker"
+	"github.com/weaveworks/scope/probe/host"
+	"github.com/weaveworks/scope/probe/process"
+	"github.com/weaveworks/scope/report"
+	"github.com/weaveworks/scope/test/reflect"
+)
+
+func TestLinkProviders(t *testing.T) {
+	defer func() { linkProviders = nil }()
+
+	RegisterLinkProvider(func(n report.Node) []ExternalLink {
+		if n.Topology != report.Container {
+			return nil
+		}
+		return []ExternalLink{{
+			ID:    "grafana",
+			Label: "Grafana",
+			URL:   "https://grafana.example.com/d/containers?var-container=" + url.QueryEscape(n.ID),
+		}}
+	})
+	RegisterLinkProvider(func(n report.Node) []ExternalLink {
+		if n.Topology != report.Container && n.Topology != report.Host {
+			return nil
+		}
+		return []ExternalLink{{ID: "logs", Label: "Logs", URL: "https://logs.example.com/?q=" + url.QueryEscape(n.ID)}}
+	})
+
+	r := report.MakeReport()
+	r.Container.AddNode(report.MakeNodeWith("a;<container>", map[string]string{docker.ContainerName: "a"}).WithTopology(report.Container))
+	r.Host.AddNode(report.MakeNodeWith("b;<host>", map[string]string{host.HostName: "b"}).WithTopology(report.Host))
+	r.Process.AddNode(report.MakeNodeWith("c;1", map[string]string{process.PID: "1", process.Name: "c"}).WithTopology(report.Process))
+
+	for _, tc := range []struct {
+		node report.Node
+		want []ExternalLink
+	}{
+		{
+			node: r.Container.Nodes["a;<container>"],
+			want: []ExternalLink{
+				{ID: "grafana", Label: "Grafana", URL: "https://grafana.example.com/d/containers?var-container=a%3B%3Ccontainer%3E"},
+				{ID: "logs", Label: "Logs", URL: "https://logs.example.com/?q=a%3B%3Ccontainer%3E"},
+			},
+		},
+		{
+			node: r.Host.Nodes["b;<host>"],
+			want: []ExternalLink{{ID: "logs", Label: "Logs", URL: "https://logs.example.com/?q=b%3B%3Chost%3E"}},
+		},
+		{
+			node: r.Process.Nodes["c;1"],
+			want: nil,
+		},
+	} {
+		summary, ok := MakeNodeSummary(r, tc.node)
+		if !ok {
+			t.Fatalf("Expected %s to be summarizable", tc.node.ID)
+		}
+		if !reflect.DeepEqual(tc.want, summary.Links) {
+			t.Errorf("%s: %s", tc.node.ID, test.Diff(tc.want, summary.Links))
+		}
+	}
+}
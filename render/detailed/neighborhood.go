@@ -0,0 +1,72 @@
+package detailed
+
+import (
+	"sort"
+
+	"github.com/weaveworks/scope/report"
+)
+
+// Directions of the edges of a Neighborhood, from the point of view of
+// the node.
+const (
+	ParentDirection = "parent"
+	ChildDirection  = "child"
+)
+
+// Neighborhood lists the parents and children of a node together, for UIs
+// drawing a mini-map around it.
+type Neighborhood struct {
+	Edges []NeighborhoodEdge `json:"edges"`
+}
+
+// NeighborhoodEdge links the node to one of its parents or children.
+type NeighborhoodEdge struct {
+	NodeID     string `json:"nodeId"`
+	TopologyID string `json:"topologyId"`
+	Direction  string `json:"direction"`
+}
+
+// neighborhood collects the parents and children of the node, parents
+// first, each ordered by topology and ID.
+func neighborhood(n report.Node) *Neighborhood {
+	parents := []NeighborhoodEdge{}
+	for _, topologyID := range n.Parents.Keys() {
+		ids, _ := n.Parents.Lookup(topologyID)
+		for _, id := range ids {
+			if topologyID == n.Topology && id == n.ID {
+				continue
+			}
+			parents = append(parents, neighborhoodEdge(id, topologyID, ParentDirection))
+		}
+	}
+	children := []NeighborhoodEdge{}
+	n.Children.ForEach(func(child report.Node) {
+		if child.ID == n.ID {
+			return
+		}
+		children = append(children, neighborhoodEdge(child.ID, child.Topology, ChildDirection))
+	})
+	sort.Sort(neighborhoodEdgesByTopologyAndID(parents))
+	sort.Sort(neighborhoodEdgesByTopologyAndID(children))
+	return &Neighborhood{Edges: append(parents, children...)}
+}
+
+// neighborhoodEdge builds an edge to the node, referencing the API
+// topology the UI can find it in, when known.
+func neighborhoodEdge(id, topologyID, direction string) NeighborhoodEdge {
+	if apiTopology, ok := primaryAPITopology[topologyID]; ok {
+		topologyID = apiTopology
+	}
+	return NeighborhoodEdge{NodeID: id, TopologyID: topologyID, Direction: direction}
+}
+
+type neighborhoodEdgesByTopologyAndID []NeighborhoodEdge
+
+func (s neighborhoodEdgesByTopologyAndID) Len() int      { return len(s) }
+func (s neighborhoodEdgesByTopologyAndID) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s neighborhoodEdgesByTopologyAndID) Less(i, j int) bool {
+	if s[i].TopologyID != s[j].TopologyID {
+		return s[i].TopologyID < s[j].TopologyID
+	}
+	return s[i].NodeID < s[j].NodeID
+}
@@ -0,0 +1,33 @@
+package detailed_test
+
+import (
+	"testing"
+
+	"github.com/weaveworks/common/test"
+	"github.com/weaveworks/scope/render/detailed"
+	"github.com/weaveworks/scope/report"
+	"github.com/weaveworks/scope/test/reflect"
+)
+
+func TestMakeDetailedNodeNeighborhood(t *testing.T) {
+	r, pod := podWithContainers(report.MakeNode("c1"), report.MakeNode("c2"))
+	pod = pod.WithParents(report.MakeSets().
+		Add(report.Host, report.MakeStringSet("host")).
+		Add(report.Deployment, report.MakeStringSet("deployment")))
+	ns := report.Nodes{pod.ID: pod}
+
+	if have := detailed.MakeNode("pods", r, ns, pod); have.Neighborhood != nil {
+		t.Errorf("Expected no neighborhood by default, got %v", have.Neighborhood)
+	}
+
+	have := detailed.MakeNodeWithOptions("pods", r, ns, pod, detailed.RenderOptions{Neighborhood: true})
+	want := &detailed.Neighborhood{Edges: []detailed.NeighborhoodEdge{
+		{NodeID: "deployment", TopologyID: "deployments", Direction: detailed.ParentDirection},
+		{NodeID: "host", TopologyID: "hosts", Direction: detailed.ParentDirection},
+		{NodeID: "c1", TopologyID: "containers", Direction: detailed.ChildDirection},
+		{NodeID: "c2", TopologyID: "containers", Direction: detailed.ChildDirection},
+	}}
+	if !reflect.DeepEqual(want, have.Neighborhood) {
+		t.Errorf("%s", test.Diff(want, have.Neighborhood))
+	}
+}
@@ -17,9 +17,14 @@ import (
 // we want deep information about an individual node.
 type Node struct {
 	NodeSummary
-	Controls    []ControlInstance    `json:"controls"`
-	Children    []NodeSummaryGroup   `json:"children,omitempty"`
-	Connections []ConnectionsSummary `json:"connections,omitempty"`
+	Controls     []ControlInstance    `json:"controls"`
+	Children     []NodeSummaryGroup   `json:"children,omitempty"`
+	Connections  []ConnectionsSummary `json:"connections,omitempty"`
+	History      []ControlResult      `json:"controlHistory,omitempty"`
+	Events       []Event              `json:"events,omitempty"`
+	Neighborhood *Neighborhood        `json:"neighborhood,omitempty"`
+	Histogram    []HistogramBucket    `json:"connectionHistogram,omitempty"`
+	Debug        map[string]string    `json:"debug,omitempty"`
 }
 
 // ControlInstance contains a control description, and all the info
@@ -47,6 +52,13 @@ type wiredControlInstance struct {
 	Human   string `json:"human"`
 	Icon    string `json:"icon"`
 	Rank    int    `json:"rank"`
+
+	ConfirmationText string `json:"confirmationText,omitempty"`
+	TimeoutMillis    int64  `json:"timeout,omitempty"`
+	ReadOnly         bool   `json:"readOnly,omitempty"`
+	ResultFormat     string `json:"resultFormat,omitempty"`
+
+	Params []report.ControlParam `json:"params,omitempty"`
 }
 
 // CodecEncodeSelf marshals this ControlInstance. It takes the basic Metric
@@ -59,6 +71,13 @@ func (c *ControlInstance) CodecEncodeSelf(encoder *codec.Encoder) {
 		Human:   c.Control.Human,
 		Icon:    c.Control.Icon,
 		Rank:    c.Control.Rank,
+
+		ConfirmationText: c.Control.ConfirmationText,
+		TimeoutMillis:    int64(c.Control.Timeout / time.Millisecond),
+		ReadOnly:         c.Control.ReadOnly,
+		ResultFormat:     c.Control.ResultFormat,
+
+		Params: c.Control.Params,
 	})
 }
 
@@ -74,6 +93,13 @@ func (c *ControlInstance) CodecDecodeSelf(decoder *codec.Decoder) {
 			Human: in.Human,
 			Icon:  in.Icon,
 			Rank:  in.Rank,
+
+			ConfirmationText: in.ConfirmationText,
+			Timeout:          time.Duration(in.TimeoutMillis) * time.Millisecond,
+			ReadOnly:         in.ReadOnly,
+			ResultFormat:     in.ResultFormat,
+
+			Params: in.Params,
 		},
 	}
 }
@@ -81,60 +107,97 @@ func (c *ControlInstance) CodecDecodeSelf(decoder *codec.Decoder) {
 // MakeNode transforms a renderable node to a detailed node. It uses
 // aggregate metadata, plus the set of origin node IDs, to produce tables.
 func MakeNode(topologyID string, r report.Report, ns report.Nodes, n report.Node) Node {
-	summary, _ := MakeNodeSummary(r, n)
-	return Node{
-		NodeSummary: summary,
-		Controls:    controls(r, n),
-		Children:    children(r, n),
-		Connections: []ConnectionsSummary{
-			incomingConnectionsSummary(topologyID, r, n, ns),
-			outgoingConnectionsSummary(topologyID, r, n, ns),
-		},
-	}
+	return MakeNodeWithOptions(topologyID, r, ns, n, RenderOptions{})
+}
+
+// MakeNodeWithOptions is MakeNode, with the rendering tweaked by opts.
+func MakeNodeWithOptions(topologyID string, r report.Report, ns report.Nodes, n report.Node, opts RenderOptions) Node {
+	return makeNode(topologyID, r, ns, n, opts, nil, nil)
+}
+
+// MakeNodes transforms all the renderable nodes to detailed nodes, sorted
+// by ID. It is equivalent to, but cheaper than, calling MakeNode for each
+// of them, as summaries of children and peers shared between the nodes
+// are only computed once.
+func MakeNodes(topologyID string, r report.Report, ns report.Nodes) []Node {
+	return MakeNodesWithOptions(topologyID, r, ns, RenderOptions{})
 }
 
-func controlsFor(topology report.Topology, nodeID string) []ControlInstance {
-	result := []ControlInstance{}
-	node, ok := topology.Nodes[nodeID]
-	if !ok {
-		return result
+// MakeNodesWithOptions is MakeNodes, with the rendering tweaked by opts.
+func MakeNodesWithOptions(topologyID string, r report.Report, ns report.Nodes, opts RenderOptions) []Node {
+	var (
+		childSummaries = summaryCache{}
+		peerSummaries  = summaryCache{}
+		ids            = make([]string, 0, len(ns))
+		result         = make([]Node, 0, len(ns))
+	)
+	for id := range ns {
+		ids = append(ids, id)
 	}
-	probeID, ok := node.Latest.Lookup(report.ControlProbeID)
-	if !ok {
-		return result
+	sort.Strings(ids)
+	for _, id := range ids {
+		result = append(result, makeNode(topologyID, r, ns, ns[id], opts, childSummaries, peerSummaries))
 	}
-	node.LatestControls.ForEach(func(controlID string, _ time.Time, data report.NodeControlData) {
-		if data.Dead {
-			return
-		}
-		if control, ok := topology.Controls[controlID]; ok {
-			result = append(result, ControlInstance{
-				ProbeID: probeID,
-				NodeID:  nodeID,
-				Control: control,
-			})
-		}
-	})
 	return result
 }
 
-func controls(r report.Report, n report.Node) []ControlInstance {
-	if t, ok := r.Topology(n.Topology); ok {
-		return controlsFor(t, n.ID)
+// makeNode renders a detailed node. Summaries of children and of
+// connection peers are memoized in the given caches, which may be nil.
+// They are kept apart as peers are rendered nodes, whereas children come
+// straight from the report.
+func makeNode(topologyID string, r report.Report, ns report.Nodes, n report.Node, opts RenderOptions, childSummaries, peerSummaries summaryCache) Node {
+	summary, _ := MakeNodeSummaryWithOptions(r, n, opts)
+	node := Node{
+		NodeSummary: summary,
+		Controls:    controls(r, n, opts.GrantedCapabilities, opts.Role),
+		Children:    children(r, n, opts, childSummaries),
+		Connections: nonEmptyConnectionsSummaries(
+			incomingConnectionsSummary(topologyID, r, n, ns, opts, peerSummaries),
+			outgoingConnectionsSummary(topologyID, r, n, ns, opts, peerSummaries),
+		),
+		Events: NodeEvents(n),
+	}
+	if opts.ControlHistory != nil {
+		node.History = opts.ControlHistory.Lookup(n.ID)
+	}
+	if opts.Debug {
+		node.Debug = rawLatest(n)
 	}
-	return []ControlInstance{}
+	if opts.Neighborhood {
+		node.Neighborhood = neighborhood(n)
+	}
+	if opts.ConnectionHistogram != nil {
+		node.Histogram = node.ConnectionHistogram(opts.ConnectionHistogram)
+	}
+	node = prefixNode(formatNode(node, opts), opts.Tenant)
+	if opts.Canonical {
+		node = canonicalNode(node)
+	}
+	return node
+}
+
+// rawLatest returns the node's latest metadata, as an unadorned map.
+func rawLatest(n report.Node) map[string]string {
+	result := map[string]string{}
+	n.Latest.ForEach(func(k string, _ time.Time, v string) {
+		result[k] = v
+	})
+	return result
 }
 
 // We only need to include topologies here where the nodes may appear
 // as children of other nodes in some topology.
 var nodeSummaryGroupSpecs = []struct {
 	topologyID string
+	// optionalColumns are only shown when a child in the group has them.
+	optionalColumns []Column
 	NodeSummaryGroup
 }{
 	{
 		topologyID: report.ReplicaSet,
 		NodeSummaryGroup: NodeSummaryGroup{
-			Label: "Replica Sets",
+			Label:        "Replica Sets",
+			EmptyMessage: "No replica sets",
 			Columns: []Column{
 				{ID: report.Pod, Label: "# Pods", Datatype: "number"},
 				{ID: kubernetes.ObservedGeneration, Label: "Observed Gen.", Datatype: "number"},
@@ -144,48 +207,66 @@ var nodeSummaryGroupSpecs = []struct {
 	{
 		topologyID: report.Pod,
 		NodeSummaryGroup: NodeSummaryGroup{
-			Label: "Pods",
-
+			Label:        "Pods",
+			EmptyMessage: "No pods running",
 			Columns: []Column{
 				{ID: kubernetes.State, Label: "State"},
 				{ID: report.Container, Label: "# Containers", Datatype: "number"},
 				{ID: kubernetes.IP, Label: "IP", Datatype: "ip"},
 			},
 		},
+		optionalColumns: []Column{
+			{ID: kubernetes.PhaseTransitions, Label: "Transitions", Datatype: "number"},
+		},
 	},
 	{
 		topologyID: report.ECSTask,
 		NodeSummaryGroup: NodeSummaryGroup{
-			Label: "Tasks",
+			Label:        "Tasks",
+			EmptyMessage: "No tasks running",
 			Columns: []Column{
 				{ID: awsecs.CreatedAt, Label: "Created At", Datatype: "datetime"},
 			},
 		},
+		optionalColumns: []Column{
+			{ID: awsecs.TaskDefinitionRevision, Label: "Revision", Datatype: "number"},
+		},
 	},
 	{
 		topologyID: report.Container,
 		NodeSummaryGroup: NodeSummaryGroup{
-			Label: "Containers", Columns: []Column{
+			Label:        "Containers",
+			EmptyMessage: "No containers running",
+			Columns: []Column{
 				{ID: docker.CPUTotalUsage, Label: "CPU", Datatype: "number"},
 				{ID: docker.MemoryUsage, Label: "Memory", Datatype: "number"},
+				{ID: UptimeID, Label: "Uptime", Datatype: "duration"},
 			},
 		},
+		optionalColumns: []Column{
+			{ID: docker.BlockIOReadRate, Label: "Disk Read/s", Datatype: "number"},
+			{ID: docker.BlockIOWriteRate, Label: "Disk Write/s", Datatype: "number"},
+		},
 	},
 	{
 		topologyID: report.Process,
 		NodeSummaryGroup: NodeSummaryGroup{
-			Label: "Processes", Columns: []Column{
+			Label:        "Processes",
+			EmptyMessage: "No processes running",
+			Columns: []Column{
 				{ID: process.PID, Label: "PID", Datatype: "number"},
 				{ID: process.CPUUsage, Label: "CPU", Datatype: "number"},
 				{ID: process.MemoryUsage, Label: "Memory", Datatype: "number"},
+				{ID: UptimeID, Label: "Uptime", Datatype: "duration"},
 			},
 		},
 	},
 	{
 		topologyID: report.ContainerImage,
 		NodeSummaryGroup: NodeSummaryGroup{
-			TopologyID: "containers-by-image",
-			Label:      "Container Images",
+			TopologyID:   "containers-by-image",
+			Label:        "Container Images",
+			EmptyMessage: "No container images",
 			Columns: []Column{
 				{ID: report.Container, Label: "# Containers", DefaultSort: true, Datatype: "number"},
 			},
@@ -193,23 +274,88 @@ var nodeSummaryGroupSpecs = []struct {
 	},
 }
 
-func children(r report.Report, n report.Node) []NodeSummaryGroup {
+func children(r report.Report, n report.Node, opts RenderOptions, cache summaryCache) []NodeSummaryGroup {
 	summaries := map[string][]NodeSummary{}
+	groupValues := map[string]string{}
+	imageIDs := map[string]string{}
+	netNamespaces := map[string]string{}
+	processContainers := map[string][]string{}
 	n.Children.ForEach(func(child report.Node) {
 		if child.ID == n.ID {
 			return
 		}
-		summary, ok := MakeNodeSummary(r, child)
+		if _, ok := summaries[child.Topology]; !ok {
+			summaries[child.Topology] = []NodeSummary{}
+		}
+		if (!opts.Focused && excluded(child, opts.ExcludeChildren)) || stale(child, opts.StaleAfter) {
+			return
+		}
+		summary, ok := cache.summarize(r, child)
 		if !ok {
 			return
 		}
-		summaries[child.Topology] = append(summaries[child.Topology], summary.SummarizeMetrics())
+		summary = withUptime(summary, child)
+		if opts.AgeColumn {
+			summary = withAge(summary, child)
+		}
+		if opts.RestartHistory {
+			summary = withRestartHistory(summary, child)
+		}
+		if opts.HealthCheckStatus {
+			summary = withHealthCheck(summary, child)
+		}
+		if opts.PodScheduling {
+			summary = withScheduling(summary, child)
+		}
+		if opts.Runtime {
+			summary = withRuntime(summary, child)
+		}
+		if opts.ResourcePressure {
+			summary = withPressure(summary, child)
+		}
+		if opts.ContainerNetworking {
+			summary = withNetworking(summary, child)
+		}
+		if opts.ImageProvenance {
+			summary = withImageProvenance(summary, r, child)
+		}
+		if opts.SecurityContext {
+			summary = withSecurityContext(summary, child)
+		}
+		if opts.LabelTags {
+			summary = withTags(summary, child)
+		}
+		if opts.OriginProbeIDs {
+			summary = withProbeIDs(summary, child)
+		}
+		if opts.GroupChildrenBy != "" {
+			groupValues[summary.ID], _ = child.Latest.Lookup(opts.GroupChildrenBy)
+		}
+		if opts.MergeChildrenByImage && child.Topology == report.Container {
+			imageIDs[summary.ID], _ = child.Latest.Lookup(docker.ImageID)
+		}
+		if opts.GroupProcessesByNetNamespace && child.Topology == report.Process {
+			netNamespaces[summary.ID], _ = child.Latest.Lookup(process.NetNamespace)
+		}
+		if opts.NestProcessesInContainers && child.Topology == report.Process {
+			processContainers[summary.ID], _ = child.Parents.Lookup(report.Container)
+		}
+		if !opts.Focused {
+			summary = summary.SummarizeMetrics()
+		}
+		summaries[child.Topology] = append(summaries[child.Topology], summary)
 	})
 
+	if opts.NestProcessesInContainers {
+		nestProcesses(summaries, processContainers)
+	}
+
 	nodeSummaryGroups := []NodeSummaryGroup{}
 	// Apply specific group specs in the order they're listed
 	for _, spec := range nodeSummaryGroupSpecs {
-		if len(summaries[spec.topologyID]) == 0 {
+		// Keep a group whose children were all filtered out, so the UI can
+		// show its EmptyMessage.
+		if nodes, ok := summaries[spec.topologyID]; !ok || (len(nodes) == 0 && spec.EmptyMessage == "") {
 			continue
 		}
 		apiTopology, ok := primaryAPITopology[spec.topologyID]
@@ -220,7 +366,56 @@ func children(r report.Report, n report.Node) []NodeSummaryGroup {
 		group := spec.NodeSummaryGroup
 		group.Nodes = summaries[spec.topologyID]
 		group.TopologyID = apiTopology
-		nodeSummaryGroups = append(nodeSummaryGroups, group)
+		group.Label = groupLabel(r, spec.topologyID, group.Label)
+		if opts.MergeChildrenByImage && spec.topologyID == report.Container {
+			group = mergeChildrenByImage(group, r, imageIDs)
+		}
+		group = withOptionalColumns(group, spec.optionalColumns)
+		if opts.ContainerResources && spec.topologyID == report.Container {
+			group = withOptionalColumns(group, containerResourceColumns)
+		}
+		if opts.ProbeStatuses && spec.topologyID == report.Container {
+			group = withOptionalColumns(group, probeStatusColumns)
+		}
+		if opts.RestartHistory && spec.topologyID == report.Container {
+			group = withOptionalColumns(group, restartHistoryColumns)
+		}
+		if opts.HealthCheckStatus && spec.topologyID == report.Container {
+			group = withOptionalColumns(group, healthCheckColumns)
+		}
+		if opts.Runtime && spec.topologyID == report.Container {
+			group = withOptionalColumns(group, runtimeColumns)
+		}
+		if opts.ResourcePressure && spec.topologyID == report.Container {
+			group = withOptionalColumns(group, pressureColumns)
+		}
+		if opts.ContainerNetworking && spec.topologyID == report.Container {
+			group = withOptionalColumns(group, networkingColumns)
+		}
+		if opts.ImageProvenance && (spec.topologyID == report.Container || spec.topologyID == report.ContainerImage) {
+			group = withOptionalColumns(group, provenanceColumns)
+		}
+		if opts.SecurityContext && spec.topologyID == report.Container {
+			group = withOptionalColumns(group, securityColumns)
+		}
+		if opts.PodScheduling && spec.topologyID == report.Pod {
+			group = withOptionalColumns(group, schedulingColumns)
+		}
+		if opts.OpenFiles && spec.topologyID == report.Process {
+			group = withOptionalColumns(group, openFilesColumns)
+		}
+		if opts.TruncateCommands > 0 && spec.topologyID == report.Process {
+			group = withOptionalColumns(group, commandColumns)
+		}
+		if opts.AgeColumn {
+			group = withOptionalColumns(group, ageColumns)
+		}
+		group = withChildColumns(group, spec.topologyID, opts.ControlHistory)
+		if opts.GroupProcessesByNetNamespace && spec.topologyID == report.Process {
+			nodeSummaryGroups = append(nodeSummaryGroups, groupByNetNamespace(group, netNamespaces)...)
+		} else {
+			nodeSummaryGroups = append(nodeSummaryGroups, group)
+		}
 		delete(summaries, spec.topologyID)
 	}
 	// As a fallback, in case a topology has no group spec defined, add any remaining at the end
@@ -242,8 +437,34 @@ func children(r report.Report, n report.Node) []NodeSummaryGroup {
 			Label:      topology.LabelPlural,
 			Columns:    []Column{},
 		}
-		nodeSummaryGroups = append(nodeSummaryGroups, group)
+		nodeSummaryGroups = append(nodeSummaryGroups, withChildColumns(group, topologyID, opts.ControlHistory))
 	}
 
+	if opts.GroupChildrenBy != "" {
+		nodeSummaryGroups = regroupChildren(nodeSummaryGroups, groupValues)
+	}
+	if opts.SortChildrenBySize {
+		sort.Stable(nodeSummaryGroupsBySize(nodeSummaryGroups))
+	}
+	if opts.TopChildren > 0 && !opts.Focused {
+		for i, group := range nodeSummaryGroups {
+			nodeSummaryGroups[i] = topChildren(group, opts.TopChildren, opts.TopChildrenBy)
+		}
+	}
+	if opts.PercentOfGroup {
+		for i, group := range nodeSummaryGroups {
+			nodeSummaryGroups[i] = percentOfGroup(group)
+		}
+	}
+	if opts.HideZeroMetricColumns {
+		for i, group := range nodeSummaryGroups {
+			nodeSummaryGroups[i] = withoutZeroMetricColumns(group)
+		}
+	}
+	if opts.InlineSingleChildGroups {
+		for i, group := range nodeSummaryGroups {
+			nodeSummaryGroups[i].Inline = len(group.Nodes) == 1
+		}
+	}
 	return nodeSummaryGroups
 }
@@ -0,0 +1,137 @@
+package detailed
+
+import (
+	"reflect"
+	"sort"
+)
+
+// NodeDiff is returned by DiffNodes. It represents the changes between two
+// renderings of a detailed node.
+type NodeDiff struct {
+	Controls ControlsDiff `json:"controls"`
+	// Children is the diff of the children of the node, from all groups.
+	Children Diff `json:"children"`
+	// Connections holds the diff of the rows of each connections
+	// summary, by summary ID, for the summaries which changed.
+	Connections map[string]ConnectionsDiff `json:"connections,omitempty"`
+}
+
+// ControlsDiff represents the changes between two sets of controls,
+// identified by control ID.
+type ControlsDiff struct {
+	Add    []ControlInstance `json:"add"`
+	Update []ControlInstance `json:"update"`
+	Remove []string          `json:"remove"`
+}
+
+// ConnectionsDiff represents the changes between two sets of connection
+// rows, identified by row ID.
+type ConnectionsDiff struct {
+	Add    []Connection `json:"add"`
+	Update []Connection `json:"update"`
+	Remove []string     `json:"remove"`
+}
+
+// DiffNodes gives you the diff to get from the controls, children and
+// connection rows of prev to those of cur. Entries are sorted by ID.
+func DiffNodes(prev, cur Node) NodeDiff {
+	return NodeDiff{
+		Controls:    diffControls(prev.Controls, cur.Controls),
+		Children:    diffChildren(prev.Children, cur.Children),
+		Connections: diffConnections(prev.Connections, cur.Connections),
+	}
+}
+
+func diffControls(a, b []ControlInstance) ControlsDiff {
+	diff := ControlsDiff{}
+	prev := map[string]ControlInstance{}
+	for _, c := range a {
+		prev[c.Control.ID] = c
+	}
+	for _, c := range b {
+		if old, ok := prev[c.Control.ID]; !ok {
+			diff.Add = append(diff.Add, c)
+		} else if !reflect.DeepEqual(old, c) {
+			diff.Update = append(diff.Update, c)
+		}
+		delete(prev, c.Control.ID)
+	}
+	for id := range prev {
+		diff.Remove = append(diff.Remove, id)
+	}
+	sort.Sort(controlsByID(diff.Add))
+	sort.Sort(controlsByID(diff.Update))
+	sort.Strings(diff.Remove)
+	return diff
+}
+
+func diffChildren(a, b []NodeSummaryGroup) Diff {
+	flatten := func(groups []NodeSummaryGroup) NodeSummaries {
+		result := NodeSummaries{}
+		for _, group := range groups {
+			for _, node := range group.Nodes {
+				result[node.ID] = node
+			}
+		}
+		return result
+	}
+	diff := TopoDiff(flatten(a), flatten(b))
+	sort.Sort(nodeSummariesByID(diff.Add))
+	sort.Sort(nodeSummariesByID(diff.Update))
+	sort.Strings(diff.Remove)
+	return diff
+}
+
+func diffConnections(a, b []ConnectionsSummary) map[string]ConnectionsDiff {
+	rows := func(summaries []ConnectionsSummary) map[string]map[string]Connection {
+		result := map[string]map[string]Connection{}
+		for _, summary := range summaries {
+			result[summary.ID] = map[string]Connection{}
+			for _, row := range summary.Connections {
+				result[summary.ID][row.ID] = row
+			}
+		}
+		return result
+	}
+	prev, cur := rows(a), rows(b)
+	for id := range prev {
+		if _, ok := cur[id]; !ok {
+			cur[id] = map[string]Connection{}
+		}
+	}
+
+	var result map[string]ConnectionsDiff
+	for id, curRows := range cur {
+		diff := ConnectionsDiff{}
+		prevRows := prev[id]
+		for rowID, row := range curRows {
+			if old, ok := prevRows[rowID]; !ok {
+				diff.Add = append(diff.Add, row)
+			} else if !reflect.DeepEqual(old, row) {
+				diff.Update = append(diff.Update, row)
+			}
+		}
+		for rowID := range prevRows {
+			if _, ok := curRows[rowID]; !ok {
+				diff.Remove = append(diff.Remove, rowID)
+			}
+		}
+		if len(diff.Add)+len(diff.Update)+len(diff.Remove) == 0 {
+			continue
+		}
+		sort.Sort(connectionsByID(diff.Add))
+		sort.Sort(connectionsByID(diff.Update))
+		sort.Strings(diff.Remove)
+		if result == nil {
+			result = map[string]ConnectionsDiff{}
+		}
+		result[id] = diff
+	}
+	return result
+}
+
+type controlsByID []ControlInstance
+
+func (s controlsByID) Len() int           { return len(s) }
+func (s controlsByID) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s controlsByID) Less(i, j int) bool { return s[i].Control.ID < s[j].Control.ID }
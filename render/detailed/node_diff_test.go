@@ -0,0 +1,94 @@
+package detailed_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/weaveworks/common/test"
+	"github.com/weaveworks/scope/render/detailed"
+	"github.com/weaveworks/scope/report"
+)
+
+func TestDiffNodes(t *testing.T) {
+	control := func(id, human string) detailed.ControlInstance {
+		return detailed.ControlInstance{ProbeID: "probe", NodeID: "node", Control: report.Control{ID: id, Human: human}}
+	}
+	children := func(ns ...detailed.NodeSummary) []detailed.NodeSummaryGroup {
+		return []detailed.NodeSummaryGroup{{TopologyID: "containers", Nodes: ns}}
+	}
+	connections := func(id string, rows ...detailed.Connection) detailed.ConnectionsSummary {
+		return detailed.ConnectionsSummary{ID: id, Connections: rows}
+	}
+	var (
+		childA  = detailed.NodeSummary{ID: "a", Label: "a"}
+		childAp = detailed.NodeSummary{ID: "a", Label: "a'"}
+		childB  = detailed.NodeSummary{ID: "b", Label: "b"}
+		connA   = detailed.Connection{ID: "a:80", Label: "a"}
+		connAp  = detailed.Connection{ID: "a:80", Label: "a", LabelMinor: "busy"}
+		connB   = detailed.Connection{ID: "b:80", Label: "b"}
+	)
+
+	for _, c := range []struct {
+		label     string
+		prev, cur detailed.Node
+		want      detailed.NodeDiff
+	}{
+		{
+			label: "no change",
+			prev:  detailed.Node{Controls: []detailed.ControlInstance{control("stop", "Stop")}, Children: children(childA)},
+			cur:   detailed.Node{Controls: []detailed.ControlInstance{control("stop", "Stop")}, Children: children(childA)},
+			want:  detailed.NodeDiff{},
+		},
+		{
+			label: "controls",
+			prev:  detailed.Node{Controls: []detailed.ControlInstance{control("stop", "Stop"), control("pause", "Pause")}},
+			cur:   detailed.Node{Controls: []detailed.ControlInstance{control("stop", "Stop now"), control("exec", "Exec")}},
+			want: detailed.NodeDiff{Controls: detailed.ControlsDiff{
+				Add:    []detailed.ControlInstance{control("exec", "Exec")},
+				Update: []detailed.ControlInstance{control("stop", "Stop now")},
+				Remove: []string{"pause"},
+			}},
+		},
+		{
+			label: "children",
+			prev:  detailed.Node{Children: children(childA)},
+			cur: detailed.Node{Children: []detailed.NodeSummaryGroup{
+				{TopologyID: "containers", Nodes: []detailed.NodeSummary{childAp}},
+				{TopologyID: "processes", Nodes: []detailed.NodeSummary{childB}},
+			}},
+			want: detailed.NodeDiff{Children: detailed.Diff{
+				Add:    []detailed.NodeSummary{childB},
+				Update: []detailed.NodeSummary{childAp},
+			}},
+		},
+		{
+			label: "children removed",
+			prev:  detailed.Node{Children: children(childA, childB)},
+			cur:   detailed.Node{Children: children(childA)},
+			want:  detailed.NodeDiff{Children: detailed.Diff{Remove: []string{"b"}}},
+		},
+		{
+			label: "connections",
+			prev: detailed.Node{Connections: []detailed.ConnectionsSummary{
+				connections("incoming-connections", connA),
+				connections("outgoing-connections", connB),
+			}},
+			cur: detailed.Node{Connections: []detailed.ConnectionsSummary{
+				connections("incoming-connections", connAp, connB),
+			}},
+			want: detailed.NodeDiff{Connections: map[string]detailed.ConnectionsDiff{
+				"incoming-connections": {
+					Add:    []detailed.Connection{connB},
+					Update: []detailed.Connection{connAp},
+				},
+				"outgoing-connections": {
+					Remove: []string{"b:80"},
+				},
+			}},
+		},
+	} {
+		if have := detailed.DiffNodes(c.prev, c.cur); !reflect.DeepEqual(c.want, have) {
+			t.Errorf("%s - %s", c.label, test.Diff(c.want, have))
+		}
+	}
+}
@@ -1,9 +1,13 @@
 package detailed_test
 
 import (
+	"bytes"
 	"fmt"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/ugorji/go/codec"
 	"github.com/weaveworks/common/test"
 	"github.com/weaveworks/scope/probe/docker"
 	"github.com/weaveworks/scope/probe/host"
@@ -101,8 +105,9 @@ func TestMakeDetailedHostNode(t *testing.T) {
 		Controls: []detailed.ControlInstance{},
 		Children: []detailed.NodeSummaryGroup{
 			{
-				Label:      "Pods",
-				TopologyID: "pods",
+				Label:        "Pods",
+				EmptyMessage: "No pods running",
+				TopologyID:   "pods",
 				Columns: []detailed.Column{
 					{ID: kubernetes.State, Label: "State"},
 					{ID: report.Container, Label: "# Containers", Datatype: "number"},
@@ -111,27 +116,32 @@ func TestMakeDetailedHostNode(t *testing.T) {
 				Nodes: []detailed.NodeSummary{podNodeSummary},
 			},
 			{
-				Label:      "Containers",
-				TopologyID: "containers",
+				Label:        "Containers",
+				EmptyMessage: "No containers running",
+				TopologyID:   "containers",
 				Columns: []detailed.Column{
 					{ID: docker.CPUTotalUsage, Label: "CPU", Datatype: "number"},
 					{ID: docker.MemoryUsage, Label: "Memory", Datatype: "number"},
+					{ID: detailed.UptimeID, Label: "Uptime", Datatype: "duration"},
 				},
 				Nodes: []detailed.NodeSummary{containerNodeSummary},
 			},
 			{
-				Label:      "Processes",
-				TopologyID: "processes",
+				Label:        "Processes",
+				EmptyMessage: "No processes running",
+				TopologyID:   "processes",
 				Columns: []detailed.Column{
 					{ID: process.PID, Label: "PID", Datatype: "number"},
 					{ID: process.CPUUsage, Label: "CPU", Datatype: "number"},
 					{ID: process.MemoryUsage, Label: "Memory", Datatype: "number"},
+					{ID: detailed.UptimeID, Label: "Uptime", Datatype: "duration"},
 				},
 				Nodes: []detailed.NodeSummary{process1NodeSummary, process2NodeSummary},
 			},
 			{
-				Label:      "Container Images",
-				TopologyID: "containers-by-image",
+				Label:        "Container Images",
+				EmptyMessage: "No container images",
+				TopologyID:   "containers-by-image",
 				Columns: []detailed.Column{
 					{ID: report.Container, Label: "# Containers", DefaultSort: true, Datatype: "number"},
 				},
@@ -139,13 +149,6 @@ func TestMakeDetailedHostNode(t *testing.T) {
 			},
 		},
 		Connections: []detailed.ConnectionsSummary{
-			{
-				ID:          "incoming-connections",
-				TopologyID:  "hosts",
-				Label:       "Inbound",
-				Columns:     detailed.NormalColumns,
-				Connections: []detailed.Connection{},
-			},
 			{
 				ID:         "outgoing-connections",
 				TopologyID: "hosts",
@@ -242,12 +245,14 @@ func TestMakeDetailedContainerNode(t *testing.T) {
 		Controls: []detailed.ControlInstance{},
 		Children: []detailed.NodeSummaryGroup{
 			{
-				Label:      "Processes",
-				TopologyID: "processes",
+				Label:        "Processes",
+				EmptyMessage: "No processes running",
+				TopologyID:   "processes",
 				Columns: []detailed.Column{
 					{ID: process.PID, Label: "PID", Datatype: "number"},
 					{ID: process.CPUUsage, Label: "CPU", Datatype: "number"},
 					{ID: process.MemoryUsage, Label: "Memory", Datatype: "number"},
+					{ID: detailed.UptimeID, Label: "Uptime", Datatype: "duration"},
 				},
 				Nodes: []detailed.NodeSummary{serverProcessNodeSummary},
 			},
@@ -294,13 +299,6 @@ func TestMakeDetailedContainerNode(t *testing.T) {
 					},
 				},
 			},
-			{
-				ID:          "outgoing-connections",
-				TopologyID:  "containers",
-				Label:       "Outbound",
-				Columns:     detailed.NormalColumns,
-				Connections: []detailed.Connection{},
-			},
 		},
 	}
 	if !reflect.DeepEqual(want, have) {
@@ -350,21 +348,25 @@ func TestMakeDetailedPodNode(t *testing.T) {
 		Controls: []detailed.ControlInstance{},
 		Children: []detailed.NodeSummaryGroup{
 			{
-				Label:      "Containers",
-				TopologyID: "containers",
+				Label:        "Containers",
+				EmptyMessage: "No containers running",
+				TopologyID:   "containers",
 				Columns: []detailed.Column{
 					{ID: docker.CPUTotalUsage, Label: "CPU", Datatype: "number"},
 					{ID: docker.MemoryUsage, Label: "Memory", Datatype: "number"},
+					{ID: detailed.UptimeID, Label: "Uptime", Datatype: "duration"},
 				},
 				Nodes: []detailed.NodeSummary{containerNodeSummary},
 			},
 			{
-				Label:      "Processes",
-				TopologyID: "processes",
+				Label:        "Processes",
+				EmptyMessage: "No processes running",
+				TopologyID:   "processes",
 				Columns: []detailed.Column{
 					{ID: process.PID, Label: "PID", Datatype: "number"},
 					{ID: process.CPUUsage, Label: "CPU", Datatype: "number"},
 					{ID: process.MemoryUsage, Label: "Memory", Datatype: "number"},
+					{ID: detailed.UptimeID, Label: "Uptime", Datatype: "duration"},
 				},
 				Nodes: []detailed.NodeSummary{serverProcessNodeSummary},
 			},
@@ -411,16 +413,115 @@ func TestMakeDetailedPodNode(t *testing.T) {
 					},
 				},
 			},
-			{
-				ID:          "outgoing-connections",
-				TopologyID:  "pods",
-				Label:       "Outbound",
-				Columns:     detailed.NormalColumns,
-				Connections: []detailed.Connection{},
-			},
 		},
 	}
 	if !reflect.DeepEqual(want, have) {
 		t.Errorf("%s", test.Diff(want, have))
 	}
 }
+
+func TestMakeDetailedNodeDebug(t *testing.T) {
+	renderableNodes := render.ContainerRenderer.Render(fixture.Report, nil)
+	renderableNode := renderableNodes[fixture.ClientContainerNodeID]
+
+	if have := detailed.MakeNode("containers", fixture.Report, renderableNodes, renderableNode); have.Debug != nil {
+		t.Errorf("Expected no debug metadata by default, got %v", have.Debug)
+	}
+
+	have := detailed.MakeNodeWithOptions("containers", fixture.Report, renderableNodes, renderableNode, detailed.RenderOptions{Debug: true})
+	want := map[string]string{}
+	renderableNode.Latest.ForEach(func(k string, _ time.Time, v string) {
+		want[k] = v
+	})
+	if len(want) == 0 {
+		t.Fatalf("Expected the fixture node to carry some metadata")
+	}
+	if !reflect.DeepEqual(want, have.Debug) {
+		t.Error(test.Diff(want, have.Debug))
+	}
+}
+
+func TestMakeDetailedNodes(t *testing.T) {
+	for _, tc := range []struct {
+		topologyID string
+		renderer   render.Renderer
+	}{
+		{"hosts", render.HostRenderer},
+		{"containers", render.ContainerWithImageNameRenderer},
+		{"pods", render.PodRenderer},
+		{"processes", render.ProcessRenderer},
+	} {
+		renderableNodes := tc.renderer.Render(fixture.Report, nil)
+		have := detailed.MakeNodes(tc.topologyID, fixture.Report, renderableNodes)
+		if len(have) != len(renderableNodes) {
+			t.Fatalf("%s: expected %d nodes, got %d", tc.topologyID, len(renderableNodes), len(have))
+		}
+		for i, node := range have {
+			if i > 0 && have[i-1].ID >= node.ID {
+				t.Errorf("%s: expected nodes sorted by ID, got %s before %s", tc.topologyID, have[i-1].ID, node.ID)
+			}
+			want := detailed.MakeNode(tc.topologyID, fixture.Report, renderableNodes, renderableNodes[node.ID])
+			if !reflect.DeepEqual(want, node) {
+				t.Errorf("%s: %s", tc.topologyID, test.Diff(want, node))
+			}
+		}
+	}
+}
+
+func BenchmarkMakeDetailedNodesLoop(b *testing.B) {
+	renderableNodes := render.ContainerWithImageNameRenderer.Render(fixture.Report, nil)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, n := range renderableNodes {
+			detailed.MakeNode("containers", fixture.Report, renderableNodes, n)
+		}
+	}
+}
+
+func BenchmarkMakeDetailedNodesBulk(b *testing.B) {
+	renderableNodes := render.ContainerWithImageNameRenderer.Render(fixture.Report, nil)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		detailed.MakeNodes("containers", fixture.Report, renderableNodes)
+	}
+}
+
+func TestControlInstanceCodec(t *testing.T) {
+	for _, control := range []report.Control{
+		{ID: "delete", Human: "Delete", Icon: "fa-trash-o", Rank: 1, ConfirmationText: "Are you sure you want to delete pod X?"},
+		{ID: "logs", Human: "Get logs", Icon: "fa-desktop"},
+		{ID: "backup", Human: "Backup", Icon: "fa-archive", Timeout: 10 * time.Minute},
+		{ID: "describe", Human: "Describe", Icon: "fa-info", ReadOnly: true},
+		{ID: "scale", Human: "Scale", Icon: "fa-arrows-v", Params: []report.ControlParam{
+			{Name: "replicas", Label: "Replicas", Type: report.IntegerControlParam, Required: true},
+			{Name: "force", Label: "Force", Type: report.BooleanControlParam, Default: "false"},
+		}},
+		{ID: "status", Human: "Status", Icon: "fa-info", ResultFormat: report.TextResultFormat},
+		{ID: "top", Human: "Top", Icon: "fa-terminal", ResultFormat: report.ANSIResultFormat},
+		{ID: "readme", Human: "Readme", Icon: "fa-book", ResultFormat: report.MarkdownResultFormat},
+	} {
+		in := detailed.ControlInstance{ProbeID: "probe", NodeID: "node", Control: control}
+		buf := &bytes.Buffer{}
+		if err := codec.NewEncoder(buf, &codec.JsonHandle{}).Encode(&in); err != nil {
+			t.Fatal(err)
+		}
+		var out detailed.ControlInstance
+		if err := codec.NewDecoderBytes(buf.Bytes(), &codec.JsonHandle{}).Decode(&out); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(in, out) {
+			t.Error(test.Diff(in, out))
+		}
+		if control.ConfirmationText == "" && strings.Contains(buf.String(), "confirmationText") {
+			t.Errorf("Expected no confirmation text to be encoded, got %s", buf.String())
+		}
+		if control.Timeout == 0 && strings.Contains(buf.String(), "timeout") {
+			t.Errorf("Expected no timeout to be encoded, got %s", buf.String())
+		}
+		if control.ResultFormat == "" && strings.Contains(buf.String(), "resultFormat") {
+			t.Errorf("Expected no result format to be encoded, got %s", buf.String())
+		}
+	}
+}
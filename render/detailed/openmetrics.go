@@ -0,0 +1,96 @@
+package detailed
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/weaveworks/scope/probe/docker"
+	"github.com/weaveworks/scope/probe/kubernetes"
+	"github.com/weaveworks/scope/report"
+)
+
+// OpenMetricsContentType is the content type of the exposition written by
+// WriteOpenMetrics.
+const OpenMetricsContentType = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+
+// OpenMetricsTraceID is the latest metadata key holding the ID of a trace
+// related to the node. When set, it is attached to the exported counters
+// as an exemplar.
+const OpenMetricsTraceID = "trace_id"
+
+// openMetricsCounters are the latest metadata of nodes exported as
+// counters. They count events one at a time, so an exemplar stands for an
+// increment of 1.
+var openMetricsCounters = []string{
+	docker.ContainerRestartCount,
+	kubernetes.PhaseTransitions,
+}
+
+// WriteOpenMetrics exports the metrics of the node, as shown in its
+// summary, in the OpenMetrics text format. Each metric becomes a gauge
+// named after its ID, labelled with the node and its topology. The counts
+// of restarts and phase transitions in its latest metadata become counters,
+// which alone get the trace of the node as an exemplar: OpenMetrics only
+// allows exemplars on counters and histograms.
+func WriteOpenMetrics(w io.Writer, r report.Report, n report.Node) error {
+	bw := bufio.NewWriter(w)
+	summary, ok := MakeNodeSummary(r, n)
+	if ok {
+		traceID, _ := n.Latest.Lookup(OpenMetricsTraceID)
+		labels := fmt.Sprintf("node_id=\"%s\",topology=\"%s\"", escapeLabelValue(summary.ID), escapeLabelValue(n.Topology))
+		for _, row := range summary.Metrics {
+			name := openMetricsName(row.ID)
+			writeOpenMetricsFamily(bw, name, "gauge", row.Label)
+			fmt.Fprintf(bw, "%s{%s} %s\n", name, labels, strconv.FormatFloat(row.Value, 'g', -1, 64))
+		}
+		topology, _ := r.Topology(n.Topology)
+		for _, id := range openMetricsCounters {
+			latest, ok := n.Latest.Lookup(id)
+			if !ok {
+				continue
+			}
+			value, err := strconv.ParseFloat(latest, 64)
+			if err != nil {
+				continue
+			}
+			name := openMetricsName(id)
+			writeOpenMetricsFamily(bw, name, "counter", topology.MetadataTemplates[id].Label)
+			fmt.Fprintf(bw, "%s_total{%s} %s", name, labels, strconv.FormatFloat(value, 'g', -1, 64))
+			if traceID != "" {
+				fmt.Fprintf(bw, " # {trace_id=\"%s\"} 1", escapeLabelValue(traceID))
+			}
+			bw.WriteString("\n")
+		}
+	}
+	bw.WriteString("# EOF\n")
+	return bw.Flush()
+}
+
+func writeOpenMetricsFamily(w io.Writer, name, kind, help string) {
+	fmt.Fprintf(w, "# TYPE %s %s\n", name, kind)
+	if help != "" {
+		fmt.Fprintf(w, "# HELP %s %s\n", name, escapeHelp(help))
+	}
+}
+
+// openMetricsName turns a metric ID into a valid metric name.
+func openMetricsName(id string) string {
+	name := []rune("scope_" + id)
+	for i, c := range name {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == ':') {
+			name[i] = '_'
+		}
+	}
+	return string(name)
+}
+
+var (
+	labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	helpEscaper       = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+)
+
+func escapeLabelValue(s string) string { return labelValueEscaper.Replace(s) }
+func escapeHelp(s string) string       { return helpEscaper.Replace(s) }
@@ -0,0 +1,64 @@
+package detailed_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/weaveworks/scope/probe/docker"
+	"github.com/weaveworks/scope/render/detailed"
+)
+
+func TestWriteOpenMetrics(t *testing.T) {
+	r, _ := podWithContainers(containerWithMetrics("a", 12.5, 1024))
+	container := r.Container.Nodes["a"]
+
+	buf := &bytes.Buffer{}
+	if err := detailed.WriteOpenMetrics(buf, r, container); err != nil {
+		t.Fatal(err)
+	}
+	have := buf.String()
+	for _, want := range []string{
+		"# TYPE scope_" + docker.CPUTotalUsage + " gauge\n",
+		"# HELP scope_" + docker.CPUTotalUsage + " CPU\n",
+		"scope_" + docker.CPUTotalUsage + `{node_id="a",topology="container"} 12.5` + "\n",
+		"scope_" + docker.MemoryUsage + `{node_id="a",topology="container"} 1024` + "\n",
+	} {
+		if !strings.Contains(have, want) {
+			t.Errorf("Expected %q in:\n%s", want, have)
+		}
+	}
+	if !strings.HasSuffix(have, "\n# EOF\n") {
+		t.Errorf("Expected exposition to end with # EOF:\n%s", have)
+	}
+	if strings.Contains(have, "trace_id") {
+		t.Errorf("Expected no exemplars without trace metadata:\n%s", have)
+	}
+}
+
+func TestWriteOpenMetricsExemplars(t *testing.T) {
+	r, _ := podWithContainers(containerWithMetrics("a", 12.5, 1024).WithLatests(map[string]string{
+		docker.ContainerRestartCount: "3",
+		detailed.OpenMetricsTraceID:  "4bf92f3577b34da6",
+	}))
+	container := r.Container.Nodes["a"]
+
+	buf := &bytes.Buffer{}
+	if err := detailed.WriteOpenMetrics(buf, r, container); err != nil {
+		t.Fatal(err)
+	}
+	have := buf.String()
+	for _, want := range []string{
+		"# TYPE scope_" + docker.ContainerRestartCount + " counter\n",
+		"scope_" + docker.ContainerRestartCount + `_total{node_id="a",topology="container"} 3 # {trace_id="4bf92f3577b34da6"} 1` + "\n",
+		"scope_" + docker.CPUTotalUsage + `{node_id="a",topology="container"} 12.5` + "\n",
+	} {
+		if !strings.Contains(have, want) {
+			t.Errorf("Expected %q in:\n%s", want, have)
+		}
+	}
+	// Gauges don't get exemplars.
+	if strings.Count(have, "trace_id") != 1 {
+		t.Errorf("Expected an exemplar on the counter only:\n%s", have)
+	}
+}
@@ -0,0 +1,232 @@
+package detailed
+
+import (
+	"net"
+	"time"
+)
+
+// RenderOptions tweaks how a detailed node is rendered. The zero value
+// produces the default rendering.
+type RenderOptions struct {
+	// ControlHistory, if set, is consulted for the recent control
+	// results of the node being rendered.
+	ControlHistory *ControlHistory
+
+	// ConnectionCIDRs, if set, aggregates connections whose remote
+	// address falls within one of these networks into a single row per
+	// network, labelled with the network.
+	ConnectionCIDRs []*net.IPNet
+
+	// EstablishedConnectionsOnly leaves out of the connection summaries
+	// the connections whose endpoints are reported in a TCP state other
+	// than ESTABLISHED, e.g. TIME_WAIT. Endpoints without a reported state
+	// are kept.
+	EstablishedConnectionsOnly bool
+
+	// GeoEnrichment locates the internet peers of outgoing connection
+	// rows with the resolvers registered with RegisterGeoResolver.
+	GeoEnrichment bool
+
+	// Neighborhood includes the parents and children of the node in a
+	// single list of edges, for UIs drawing a mini-map around it.
+	Neighborhood bool
+
+	// ConnectionHistogram, if set, includes the histogram of the number
+	// of connections of the peers of the node, bucketed by these bounds,
+	// e.g. DefaultHistogramBounds.
+	ConnectionHistogram []int
+
+	// ControllerChain attaches to the summary of the node the chain of
+	// Kubernetes controllers owning it, e.g. the replica set and then the
+	// deployment of a pod, for breadcrumb navigation.
+	ControllerChain bool
+
+	// Debug includes the raw latest metadata of the node, as fed to the
+	// summary. This bloats the payload, so it's only meant for debugging.
+	Debug bool
+
+	// RFC3339Timestamps formats the values of datetime columns and
+	// metadata as RFC3339, in UTC, for interoperability with external
+	// tools.
+	RFC3339Timestamps bool
+
+	// RelativeTimestamps formats the values of datetime metadata relative
+	// to now, e.g. "5m ago", moving the absolute time to their tooltip.
+	RelativeTimestamps bool
+
+	// TruncateCommands, if positive, adds a column of the command lines of
+	// processes to the groups of process children, and has the UI truncate
+	// longer command lines to this many characters, with the Truncate of
+	// their rows. The command lines of Focused nodes are shown whole.
+	TruncateCommands int
+
+	// PercentOfGroup renders the metric columns of children groups as
+	// the percentage each child contributes to the group's total.
+	PercentOfGroup bool
+
+	// Focused renders the node the user is looking at, rather than one of
+	// its neighbours, so the limits keeping the payload lean are relaxed:
+	// children keep their metric samples and whole command lines, and
+	// ExcludeChildren and TopChildren are ignored.
+	Focused bool
+
+	// StaleAfter, if positive, leaves out the children not seen for
+	// longer than this, going by the most recent of their latest
+	// metadata timestamps.
+	StaleAfter time.Duration
+
+	// SortChildrenBySize orders the groups of children by their number
+	// of nodes, biggest first, rather than in the fixed order of the
+	// group specs.
+	SortChildrenBySize bool
+
+	// GroupChildrenBy, if set, groups children by the value of this
+	// latest metadata key, e.g. a docker label, rather than by topology.
+	// Groups are labelled by the value; children without it are grouped
+	// last, under OtherChildrenLabel.
+	GroupChildrenBy string
+
+	// HideZeroMetricColumns leaves out of the groups of children the metric
+	// columns for which every child reports zero, or nothing.
+	HideZeroMetricColumns bool
+
+	// InlineSingleChildGroups marks the groups of children with a single
+	// node as Inline, so it is shown in place rather than as a table.
+	InlineSingleChildGroups bool
+
+	// NestProcessesInContainers moves the process children into the
+	// Processes of the container children they run in, rather than
+	// listing them in a group of their own.
+	NestProcessesInContainers bool
+
+	// GroupProcessesByNetNamespace splits the processes children into a
+	// group per network namespace, which on a host tells apart the
+	// processes of each container.
+	GroupProcessesByNetNamespace bool
+
+	// MergeChildrenByImage merges the container children sharing an
+	// image into a single row per image, with their metrics summed and a
+	// count of the containers.
+	MergeChildrenByImage bool
+
+	// TopChildren, if positive, keeps only this many children per group,
+	// those with the highest value of the TopChildrenBy metric, and
+	// summarizes the others into a single row with their metrics summed.
+	// Focused nodes keep all their children.
+	TopChildren   int
+	TopChildrenBy string
+
+	// ContainerResources adds columns for the resource requests and limits
+	// of Kubernetes containers to the groups of container children, when
+	// some of the containers have them.
+	ContainerResources bool
+
+	// ProbeStatuses adds columns for the status of the readiness and
+	// liveness probes of Kubernetes containers to the groups of container
+	// children, when some of the containers have probes.
+	ProbeStatuses bool
+
+	// AgeColumn adds a column of how long ago children were created to
+	// the groups of children, when some of them report their creation
+	// time.
+	AgeColumn bool
+
+	// RestartHistory adds the restart history of containers which have
+	// restarted to their summaries, and a column of their restart count to
+	// the groups of container children.
+	RestartHistory bool
+
+	// HealthCheckStatus adds the status of the Docker health check of
+	// containers with one to their summaries, as a badge, and a column of
+	// it to the groups of container children.
+	HealthCheckStatus bool
+
+	// PodScheduling adds how Kubernetes pods are scheduled, i.e. their
+	// node, whether they are scheduled yet and their node selector, to
+	// their summaries, and columns of the first two to the groups of pod
+	// children, when the pods report them.
+	PodScheduling bool
+
+	// Runtime adds the container runtime of containers and hosts, e.g.
+	// docker, containerd or cri-o, to their summaries, and a column of it
+	// to the groups of container children, when they report it.
+	Runtime bool
+
+	// ResourcePressure adds the pressure stall information of Linux hosts
+	// and containers to their summaries, with a badge for each resource
+	// under pressure, and columns of it to the groups of container
+	// children, when they report it.
+	ResourcePressure bool
+
+	// ContainerNetworking adds the network mode of containers and the
+	// ports they publish on their host to their summaries, and columns of
+	// them to the groups of container children, when the containers report
+	// them.
+	ContainerNetworking bool
+
+	// ImageProvenance adds the digest of the image of containers and
+	// images, and the pull policy of the image of Kubernetes containers, to
+	// their summaries, and columns of them to the groups of container and
+	// image children, when they are known.
+	ImageProvenance bool
+
+	// SecurityContext adds the security context of Kubernetes containers,
+	// the user they run as, whether they are privileged and the
+	// capabilities they add or drop, to their summaries, with badges
+	// flagging privileged containers, containers running as root and
+	// added capabilities, and columns of them to the groups of container
+	// children, when the containers report them.
+	SecurityContext bool
+
+	// OpenFiles adds a column of the number of open file descriptors of
+	// processes to the groups of process children, when some of the
+	// processes report it.
+	OpenFiles bool
+
+	// LabelTags flattens the docker and Kubernetes labels of nodes into the
+	// tags of their summaries, as sorted key=value pairs, for the UI to
+	// filter by.
+	LabelTags bool
+
+	// OriginProbeIDs attaches to the summaries of children the IDs of the
+	// probes which reported them, for debugging setups with several
+	// probes.
+	OriginProbeIDs bool
+
+	// GrantedCapabilities are the capabilities of the user the node is
+	// rendered for. Controls requiring a capability not granted are left
+	// out.
+	GrantedCapabilities map[string]bool
+
+	// Role is the role of the user the node is rendered for, in RBAC
+	// deployments. Controls not allowed to the role are left out, as are
+	// all the controls restricted to some roles when it isn't set.
+	Role string
+
+	// ExcludeChildren hides the children having any of these latest
+	// metadata key/value pairs, e.g. the docker label
+	// docker.LabelPrefix+"scope.weave.works/hidden" set to "true".
+	ExcludeChildren map[string]string
+
+	// MetricPrecision, if positive, rounds the values of metrics to this
+	// many decimals, to trim long floating point values from the output.
+	MetricPrecision int
+
+	// Tenant, if set, prefixes the IDs of the node, and of the nodes it
+	// references, with the tenant, for multi-tenant apps where IDs of
+	// different tenants may collide. See TenantNodeID.
+	Tenant string
+
+	// Canonical sorts all the lists of the node, e.g. controls, children,
+	// connections and columns, by ID, so that rendering the same input
+	// always gives the same result, as golden tests need. Encode the node
+	// with CanonicalJSON to get map keys sorted too.
+	Canonical bool
+
+	// Locale, if set, formats the values of number metadata with the
+	// thousands separator and decimal mark of that locale, e.g. "de" or
+	// "en-GB", leaving identifiers such as PIDs alone. Metric values are
+	// formatted into their FormattedValue, their Value staying a number.
+	// Unknown locales leave the values alone.
+	Locale string
+}
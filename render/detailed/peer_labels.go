@@ -0,0 +1,49 @@
+package detailed
+
+import (
+	"sync"
+)
+
+// Peer is the remote end of a connection row, as passed to a
+// PeerLabelResolver.
+type Peer struct {
+	// NodeID is the ID of the remote node, unless the row aggregates the
+	// remotes of a network.
+	NodeID string
+	// Addr is the address of the remote, for internet nodes only.
+	Addr string
+	// Label is the label the row has by default.
+	Label string
+}
+
+// PeerLabelResolver labels the peer of a connection row, e.g. by looking
+// its address up in a table of service names. It returns "" to keep the
+// default label.
+type PeerLabelResolver func(Peer) string
+
+var (
+	peerLabelResolversMtx sync.RWMutex
+	peerLabelResolvers    []PeerLabelResolver
+)
+
+// RegisterPeerLabelResolver adds a resolver which is consulted when
+// building connection rows. Resolvers are consulted in the order they were
+// registered, and the first label returned wins.
+func RegisterPeerLabelResolver(resolver PeerLabelResolver) {
+	peerLabelResolversMtx.Lock()
+	defer peerLabelResolversMtx.Unlock()
+	peerLabelResolvers = append(peerLabelResolvers, resolver)
+}
+
+// peerLabel returns the label of the peer given by the registered
+// resolvers, falling back to its default label.
+func peerLabel(peer Peer) string {
+	peerLabelResolversMtx.RLock()
+	defer peerLabelResolversMtx.RUnlock()
+	for _, resolver := range peerLabelResolvers {
+		if label := resolver(peer); label != "" {
+			return label
+		}
+	}
+	return peer.Label
+}
@@ -0,0 +1,72 @@
+package detailed
+
+import (
+	"testing"
+
+	"github.com/weaveworks/common/test"
+	"github.com/weaveworks/scope/probe/docker"
+	"github.com/weaveworks/scope/render"
+	"github.com/weaveworks/scope/report"
+	"github.com/weaveworks/scope/test/reflect"
+)
+
+func TestPeerLabelResolvers(t *testing.T) {
+	defer func() { peerLabelResolvers = nil }()
+
+	r := report.MakeReport()
+	client := report.MakeNodeWith("client;<container>", map[string]string{docker.ContainerName: "client"}).WithTopology(report.Container)
+	r.Container.AddNode(client)
+	ns := report.Nodes{client.ID: client}
+	labels := func() map[string]string {
+		counts := newConnectionCounters(RenderOptions{}, nil)
+		counts.counts[connection{remoteNodeID: client.ID, port: "80"}] = 2
+		counts.counts[connection{remoteNodeID: render.IncomingInternetID, remoteAddr: "partner.example.com (51.52.53.54)", remoteIP: "51.52.53.54", port: "80"}] = 1
+		counts.counts[connection{remoteNodeID: render.IncomingInternetID, remoteAddr: "1.2.3.4", remoteIP: "1.2.3.4", port: "80"}] = 1
+		result := map[string]string{}
+		for _, row := range counts.rows(r, ns, false) {
+			result[row.ID] = row.Label
+		}
+		return result
+	}
+	var (
+		clientRow  = "client;<container>---80"
+		partnerRow = render.IncomingInternetID + "-partner.example.com (51.52.53.54)--80"
+		otherRow   = render.IncomingInternetID + "-1.2.3.4--80"
+	)
+
+	RegisterPeerLabelResolver(func(peer Peer) string {
+		if peer.Addr == "51.52.53.54" {
+			return "partner-api"
+		}
+		return ""
+	})
+	RegisterPeerLabelResolver(func(peer Peer) string {
+		switch peer.NodeID {
+		case client.ID:
+			return "frontend"
+		case render.IncomingInternetID:
+			return "internet peer " + peer.Label
+		}
+		return ""
+	})
+	want := map[string]string{
+		clientRow:  "frontend",
+		partnerRow: "partner-api",
+		otherRow:   "internet peer 1.2.3.4",
+	}
+	if have := labels(); !reflect.DeepEqual(want, have) {
+		t.Error(test.Diff(want, have))
+	}
+
+	// Peers the resolvers return nothing for keep their default label
+	peerLabelResolvers = nil
+	RegisterPeerLabelResolver(func(Peer) string { return "" })
+	want = map[string]string{
+		clientRow:  "client",
+		partnerRow: "partner.example.com (51.52.53.54)",
+		otherRow:   "1.2.3.4",
+	}
+	if have := labels(); !reflect.DeepEqual(want, have) {
+		t.Error(test.Diff(want, have))
+	}
+}
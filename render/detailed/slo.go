@@ -0,0 +1,52 @@
+package detailed
+
+import (
+	"sync"
+
+	"github.com/weaveworks/scope/report"
+)
+
+// SLOStatus is the status of the service level objective of a node, e.g. of
+// a service, as given by an SLOProvider.
+type SLOStatus struct {
+	Name      string  `json:"name,omitempty"`
+	Objective float64 `json:"objective,omitempty"` // e.g. 0.999 for 99.9% of requests succeeding
+	// BudgetRemaining is the fraction of the error budget left over the
+	// window of the objective: 1 when untouched, negative once overspent.
+	BudgetRemaining float64 `json:"budgetRemaining"`
+	// BurnRate is how fast the budget is being spent, relative to the rate
+	// which would spend it exactly over the window.
+	BurnRate float64 `json:"burnRate"`
+}
+
+// SLOProvider gives the SLO status of a node, e.g. from a monitoring
+// system, or nil for nodes without an objective. Providers are consulted
+// while summarizing nodes, so they mustn't make network calls.
+type SLOProvider func(report.Node) *SLOStatus
+
+var (
+	sloProvidersMtx sync.RWMutex
+	sloProviders    []SLOProvider
+)
+
+// RegisterSLOProvider adds a provider which is consulted when summarizing
+// nodes. Providers are consulted in the order they were registered, and the
+// first status given wins.
+func RegisterSLOProvider(provider SLOProvider) {
+	sloProvidersMtx.Lock()
+	defer sloProvidersMtx.Unlock()
+	sloProviders = append(sloProviders, provider)
+}
+
+// NodeSLOStatus returns the SLO status of the node given by the registered
+// providers, or nil if none of them has one.
+func NodeSLOStatus(n report.Node) *SLOStatus {
+	sloProvidersMtx.RLock()
+	defer sloProvidersMtx.RUnlock()
+	for _, provider := range sloProviders {
+		if status := provider(n); status != nil {
+			return status
+		}
+	}
+	return nil
+}
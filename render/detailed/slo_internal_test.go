@@ -0,0 +1,48 @@
+package detailed
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/weaveworks/scope/probe/kubernetes"
+	"github.com/weaveworks/scope/report"
+)
+
+func TestSLOProviders(t *testing.T) {
+	defer func() { sloProviders = nil }()
+
+	checkout := &SLOStatus{Name: "availability", Objective: 0.999, BudgetRemaining: 0.25, BurnRate: 3}
+	RegisterSLOProvider(func(n report.Node) *SLOStatus {
+		if name, _ := n.Latest.Lookup(kubernetes.Name); name == "checkout" {
+			return checkout
+		}
+		return nil
+	})
+	RegisterSLOProvider(func(n report.Node) *SLOStatus {
+		if name, _ := n.Latest.Lookup(kubernetes.Name); name == "checkout" || name == "cart" {
+			return &SLOStatus{Name: "latency", Objective: 0.99, BudgetRemaining: 1}
+		}
+		return nil
+	})
+
+	r := report.MakeReport()
+	for _, name := range []string{"checkout", "cart", "search"} {
+		r.Service.AddNode(report.MakeNodeWith(name, map[string]string{kubernetes.Name: name}).WithTopology(report.Service))
+	}
+	for _, tc := range []struct {
+		id   string
+		want *SLOStatus
+	}{
+		{"checkout", checkout}, // the first provider wins
+		{"cart", &SLOStatus{Name: "latency", Objective: 0.99, BudgetRemaining: 1}},
+		{"search", nil},
+	} {
+		summary, ok := MakeNodeSummary(r, r.Service.Nodes[tc.id])
+		if !ok {
+			t.Fatalf("Expected %s to be summarizable", tc.id)
+		}
+		if !reflect.DeepEqual(tc.want, summary.SLO) {
+			t.Errorf("%s: want %v, have %v", tc.id, tc.want, summary.SLO)
+		}
+	}
+}
@@ -31,6 +31,14 @@ type NodeSummaryGroup struct {
 	Nodes      []NodeSummary `json:"nodes"`
 	TopologyID string        `json:"topologyId"`
 	Columns    []Column      `json:"columns"`
+
+	// EmptyMessage, if set, is shown in place of the table when all the
+	// children of the group have been filtered out.
+	EmptyMessage string `json:"emptyMessage,omitempty"`
+
+	// Inline, if set, asks for the single node of the group to be shown
+	// in place, rather than as a table with a row.
+	Inline bool `json:"inline,omitempty"`
 }
 
 // Column provides special json serialization for column ids, so they include
@@ -40,23 +48,38 @@ type Column struct {
 	Label       string `json:"label"`
 	DefaultSort bool   `json:"defaultSort"`
 	Datatype    string `json:"dataType"`
+	// Whether the values are a percentage of the sum over the group,
+	// rather than absolute.
+	PercentOfGroup bool `json:"percentOfGroup,omitempty"`
+	// If set, the cells hold the value of the latest result of this
+	// control on each node. See RegisterChildColumns.
+	ControlID string `json:"controlId,omitempty"`
 }
 
 // NodeSummary is summary information about a child for a Node.
 type NodeSummary struct {
-	ID         string               `json:"id"`
-	Label      string               `json:"label"`
-	LabelMinor string               `json:"labelMinor"`
-	Rank       string               `json:"rank"`
-	Shape      string               `json:"shape,omitempty"`
-	Stack      bool                 `json:"stack,omitempty"`
-	Linkable   bool                 `json:"linkable,omitempty"` // Whether this node can be linked-to
-	Pseudo     bool                 `json:"pseudo,omitempty"`
-	Metadata   []report.MetadataRow `json:"metadata,omitempty"`
-	Parents    []Parent             `json:"parents,omitempty"`
-	Metrics    []report.MetricRow   `json:"metrics,omitempty"`
-	Tables     []report.Table       `json:"tables,omitempty"`
-	Adjacency  report.IDList        `json:"adjacency,omitempty"`
+	ID          string               `json:"id"`
+	Label       string               `json:"label"`
+	LabelMinor  string               `json:"labelMinor"`
+	Rank        string               `json:"rank"`
+	Shape       string               `json:"shape,omitempty"`
+	Stack       bool                 `json:"stack,omitempty"`
+	Linkable    bool                 `json:"linkable,omitempty"` // Whether this node can be linked-to
+	Pseudo      bool                 `json:"pseudo,omitempty"`
+	Metadata    []report.MetadataRow `json:"metadata,omitempty"`
+	Parents     []Parent             `json:"parents,omitempty"`
+	Metrics     []report.MetricRow   `json:"metrics,omitempty"`
+	Tables      []report.Table       `json:"tables,omitempty"`
+	Adjacency   report.IDList        `json:"adjacency,omitempty"`
+	Annotations []Annotation         `json:"annotations,omitempty"`
+	HealthScore *float64             `json:"healthScore,omitempty"`
+	SLO         *SLOStatus           `json:"slo,omitempty"`
+	Badges      []Badge              `json:"badges,omitempty"`
+	Links       []ExternalLink       `json:"links,omitempty"`
+	Controllers []Parent             `json:"controllers,omitempty"`
+	Processes   []NodeSummary        `json:"processes,omitempty"`
+	ProbeIDs    []string             `json:"probeIds,omitempty"`
+	Tags        []string             `json:"tags,omitempty"`
 }
 
 var renderers = map[string]func(NodeSummary, report.Node) (NodeSummary, bool){
@@ -117,6 +140,68 @@ func MakeNodeSummary(r report.Report, n report.Node) (NodeSummary, bool) {
 	return NodeSummary{}, false
 }
 
+// MakeNodeSummaryWithOptions is MakeNodeSummary, tweaked by the options.
+func MakeNodeSummaryWithOptions(r report.Report, n report.Node, opts RenderOptions) (NodeSummary, bool) {
+	summary, ok := MakeNodeSummary(r, n)
+	if ok && opts.ControllerChain {
+		summary.Controllers = ControllerChain(r, n)
+	}
+	if ok && opts.RestartHistory {
+		summary = withRestartHistory(summary, n)
+	}
+	if ok && opts.HealthCheckStatus {
+		summary = withHealthCheck(summary, n)
+	}
+	if ok && opts.PodScheduling {
+		summary = withScheduling(summary, n)
+	}
+	if ok && opts.Runtime {
+		summary = withRuntime(summary, n)
+	}
+	if ok && opts.ResourcePressure {
+		summary = withPressure(summary, n)
+	}
+	if ok && opts.ContainerNetworking {
+		summary = withNetworking(summary, n)
+	}
+	if ok && opts.ImageProvenance {
+		summary = withImageProvenance(summary, r, n)
+	}
+	if ok && opts.SecurityContext {
+		summary = withSecurityContext(summary, n)
+	}
+	if ok && opts.LabelTags {
+		summary = withTags(summary, n)
+	}
+	return summary, ok
+}
+
+type summaryCacheKey struct {
+	topology, id string
+}
+
+type summaryCacheEntry struct {
+	summary NodeSummary
+	ok      bool
+}
+
+// summaryCache memoizes MakeNodeSummary, for use when rendering many
+// nodes from the same report. A nil summaryCache memoizes nothing.
+type summaryCache map[summaryCacheKey]summaryCacheEntry
+
+func (c summaryCache) summarize(r report.Report, n report.Node) (NodeSummary, bool) {
+	if c == nil {
+		return MakeNodeSummary(r, n)
+	}
+	key := summaryCacheKey{n.Topology, n.ID}
+	if entry, ok := c[key]; ok {
+		return entry.summary, entry.ok
+	}
+	summary, ok := MakeNodeSummary(r, n)
+	c[key] = summaryCacheEntry{summary, ok}
+	return summary, ok
+}
+
 // SummarizeMetrics returns a copy of the NodeSummary where the metrics are
 // replaced with their summaries
 func (n NodeSummary) SummarizeMetrics() NodeSummary {
@@ -131,14 +216,19 @@ func (n NodeSummary) SummarizeMetrics() NodeSummary {
 func baseNodeSummary(r report.Report, n report.Node) NodeSummary {
 	t, _ := r.Topology(n.Topology)
 	return NodeSummary{
-		ID:        n.ID,
-		Shape:     t.GetShape(),
-		Linkable:  true,
-		Metadata:  NodeMetadata(r, n),
-		Metrics:   NodeMetrics(r, n),
-		Parents:   Parents(r, n),
-		Tables:    NodeTables(r, n),
-		Adjacency: n.Adjacency,
+		ID:          n.ID,
+		Shape:       t.GetShape(),
+		Linkable:    true,
+		Metadata:    NodeMetadata(r, n),
+		Metrics:     NodeMetrics(r, n),
+		Parents:     Parents(r, n),
+		Tables:      NodeTables(r, n),
+		Adjacency:   n.Adjacency,
+		Annotations: NodeAnnotations(n),
+		HealthScore: NodeHealthScore(n),
+		SLO:         NodeSLOStatus(n),
+		Badges:      NodeBadges(n),
+		Links:       NodeLinks(n),
 	}
 }
 
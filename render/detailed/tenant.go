@@ -0,0 +1,91 @@
+package detailed
+
+import (
+	"github.com/weaveworks/scope/report"
+)
+
+// TenantSeparator separates the tenant from the node ID in the IDs
+// rendered with RenderOptions.Tenant.
+const TenantSeparator = "/"
+
+// TenantNodeID returns the ID of a node, as rendered for the tenant.
+func TenantNodeID(tenant, id string) string {
+	if tenant == "" || id == "" {
+		return id
+	}
+	return tenant + TenantSeparator + id
+}
+
+// prefixNode prefixes all the node IDs referenced by the node with the
+// tenant, so the UI can't link across tenants. Control instances are left
+// alone, as their node IDs address the probe, not the UI.
+func prefixNode(node Node, tenant string) Node {
+	if tenant == "" {
+		return node
+	}
+	node.NodeSummary = prefixSummary(node.NodeSummary, tenant)
+
+	children := make([]NodeSummaryGroup, len(node.Children))
+	for i, group := range node.Children {
+		nodes := make([]NodeSummary, len(group.Nodes))
+		for j, child := range group.Nodes {
+			nodes[j] = prefixSummary(child, tenant)
+		}
+		group.Nodes = nodes
+		children[i] = group
+	}
+	node.Children = children
+
+	if node.Connections != nil {
+		summaries := make([]ConnectionsSummary, len(node.Connections))
+		for i, summary := range node.Connections {
+			connections := make([]Connection, len(summary.Connections))
+			for j, connection := range summary.Connections {
+				connection.NodeID = TenantNodeID(tenant, connection.NodeID)
+				connections[j] = connection
+			}
+			summary.Connections = connections
+			summaries[i] = summary
+		}
+		node.Connections = summaries
+	}
+
+	if node.Neighborhood != nil {
+		edges := make([]NeighborhoodEdge, len(node.Neighborhood.Edges))
+		for i, edge := range node.Neighborhood.Edges {
+			edge.NodeID = TenantNodeID(tenant, edge.NodeID)
+			edges[i] = edge
+		}
+		node.Neighborhood = &Neighborhood{Edges: edges}
+	}
+	return node
+}
+
+// prefixSummary returns a copy of the summary, with its ID, those of its
+// parents, its adjacency and its nested processes prefixed with the tenant.
+func prefixSummary(summary NodeSummary, tenant string) NodeSummary {
+	summary.ID = TenantNodeID(tenant, summary.ID)
+	if summary.Parents != nil {
+		parents := make([]Parent, len(summary.Parents))
+		for i, parent := range summary.Parents {
+			parent.ID = TenantNodeID(tenant, parent.ID)
+			parents[i] = parent
+		}
+		summary.Parents = parents
+	}
+	if summary.Adjacency != nil {
+		adjacency := make([]string, len(summary.Adjacency))
+		for i, id := range summary.Adjacency {
+			adjacency[i] = TenantNodeID(tenant, id)
+		}
+		summary.Adjacency = report.MakeIDList(adjacency...)
+	}
+	if summary.Processes != nil {
+		processes := make([]NodeSummary, len(summary.Processes))
+		for i, p := range summary.Processes {
+			processes[i] = prefixSummary(p, tenant)
+		}
+		summary.Processes = processes
+	}
+	return summary
+}
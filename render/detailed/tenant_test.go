@@ -0,0 +1,55 @@
+package detailed_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/weaveworks/scope/render"
+	"github.com/weaveworks/scope/render/detailed"
+	"github.com/weaveworks/scope/test/fixture"
+)
+
+func TestMakeDetailedNodeTenant(t *testing.T) {
+	const tenant = "tenant-a"
+	renderableNodes := render.ContainerWithImageNameRenderer.Render(fixture.Report, nil)
+	renderableNode := renderableNodes[fixture.ServerContainerNodeID]
+	have := detailed.MakeNodeWithOptions("containers", fixture.Report, renderableNodes, renderableNode, detailed.RenderOptions{
+		Tenant: tenant,
+	})
+
+	ids := []string{have.ID}
+	for _, parent := range have.Parents {
+		ids = append(ids, parent.ID)
+	}
+	ids = append(ids, have.Adjacency...)
+	for _, group := range have.Children {
+		for _, child := range group.Nodes {
+			ids = append(ids, child.ID)
+			for _, parent := range child.Parents {
+				ids = append(ids, parent.ID)
+			}
+		}
+	}
+	for _, summary := range have.Connections {
+		for _, connection := range summary.Connections {
+			ids = append(ids, connection.NodeID)
+		}
+	}
+	if len(have.Parents) == 0 || len(have.Children) == 0 || len(have.Connections) == 0 {
+		t.Fatalf("Expected parents, children and connections to check, got %v", have)
+	}
+	for _, id := range ids {
+		if !strings.HasPrefix(id, tenant+detailed.TenantSeparator) {
+			t.Errorf("Expected %q to be prefixed with the tenant", id)
+		}
+	}
+	if want := detailed.TenantNodeID(tenant, fixture.ServerContainerNodeID); have.ID != want {
+		t.Errorf("want %q, have %q", want, have.ID)
+	}
+
+	// Without a tenant, IDs are left alone
+	plain := detailed.MakeNode("containers", fixture.Report, renderableNodes, renderableNode)
+	if plain.ID != fixture.ServerContainerNodeID {
+		t.Errorf("want %q, have %q", fixture.ServerContainerNodeID, plain.ID)
+	}
+}
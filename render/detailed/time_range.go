@@ -0,0 +1,132 @@
+package detailed
+
+import (
+	"github.com/weaveworks/scope/report"
+)
+
+// Suffixes of the IDs of the columns and metric rows rendered by
+// MakeNodeOverRange, in place of each child metric.
+const (
+	RangeMinSuffix = "_min"
+	RangeMaxSuffix = "_max"
+	RangeAvgSuffix = "_avg"
+)
+
+// MakeNodeOverRange is MakeNode over a window of time-ordered reports,
+// oldest first. The node is rendered from the most recent report, but each
+// child metric column is replaced by the minimum, maximum and average of
+// that metric across all reports the child appears in.
+func MakeNodeOverRange(topologyID string, rs []report.Report, ns report.Nodes, n report.Node) Node {
+	latest := report.MakeReport()
+	if len(rs) > 0 {
+		latest = rs[len(rs)-1]
+	}
+	node := MakeNode(topologyID, latest, ns, n)
+
+	childTopologies := map[string]string{}
+	n.Children.ForEach(func(child report.Node) {
+		childTopologies[child.ID] = child.Topology
+	})
+	for i, group := range node.Children {
+		node.Children[i] = metricRanges(group, rs, childTopologies)
+	}
+	return node
+}
+
+// metricRange accumulates the values of a metric over several reports.
+type metricRange struct {
+	min, max, sum float64
+	count         int
+}
+
+func (m *metricRange) add(value float64) {
+	if m.count == 0 || value < m.min {
+		m.min = value
+	}
+	if m.count == 0 || value > m.max {
+		m.max = value
+	}
+	m.sum += value
+	m.count++
+}
+
+// childMetricRanges correlates the child with the given ID across reports,
+// and returns the range of each of its metrics.
+func childMetricRanges(rs []report.Report, topologyID, id string) map[string]*metricRange {
+	ranges := map[string]*metricRange{}
+	for _, r := range rs {
+		topology, ok := r.Topology(topologyID)
+		if !ok {
+			continue
+		}
+		child, ok := topology.Nodes[id]
+		if !ok {
+			continue
+		}
+		for _, row := range NodeMetrics(r, child) {
+			if ranges[row.ID] == nil {
+				ranges[row.ID] = &metricRange{}
+			}
+			ranges[row.ID].add(row.Value)
+		}
+	}
+	return ranges
+}
+
+// metricRanges returns a copy of the group, where every metric column is
+// replaced by min, max and avg columns computed over the reports.
+func metricRanges(group NodeSummaryGroup, rs []report.Report, childTopologies map[string]string) NodeSummaryGroup {
+	isMetric := map[string]bool{}
+	for _, node := range group.Nodes {
+		for _, row := range node.Metrics {
+			isMetric[row.ID] = true
+		}
+	}
+
+	columns := []Column{}
+	for _, column := range group.Columns {
+		if !isMetric[column.ID] {
+			columns = append(columns, column)
+			continue
+		}
+		for _, suffix := range []string{RangeMinSuffix, RangeMaxSuffix, RangeAvgSuffix} {
+			c := column
+			c.ID = column.ID + suffix
+			c.Label = column.Label + " (" + suffix[1:] + ")"
+			c.DefaultSort = column.DefaultSort && suffix == RangeAvgSuffix
+			columns = append(columns, c)
+		}
+	}
+	group.Columns = columns
+
+	nodes := make([]NodeSummary, len(group.Nodes))
+	for i, node := range group.Nodes {
+		ranges := childMetricRanges(rs, childTopologies[node.ID], node.ID)
+		metrics := []report.MetricRow{}
+		for _, row := range node.Metrics {
+			rng, ok := ranges[row.ID]
+			if !ok {
+				metrics = append(metrics, row)
+				continue
+			}
+			for _, v := range []struct {
+				suffix string
+				value  float64
+			}{
+				{RangeMinSuffix, rng.min},
+				{RangeMaxSuffix, rng.max},
+				{RangeAvgSuffix, rng.sum / float64(rng.count)},
+			} {
+				r := row
+				r.ID = row.ID + v.suffix
+				r.Label = row.Label + " (" + v.suffix[1:] + ")"
+				r.Value = v.value
+				metrics = append(metrics, r)
+			}
+		}
+		node.Metrics = metrics
+		nodes[i] = node
+	}
+	group.Nodes = nodes
+	return group
+}
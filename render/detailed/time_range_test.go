@@ -0,0 +1,86 @@
+package detailed_test
+
+import (
+	"testing"
+
+	"github.com/weaveworks/scope/probe/docker"
+	"github.com/weaveworks/scope/render/detailed"
+	"github.com/weaveworks/scope/report"
+	"github.com/weaveworks/scope/test/reflect"
+)
+
+func TestMakeNodeOverRange(t *testing.T) {
+	older, _ := podWithContainers(
+		containerWithMetrics("a", 10, 100),
+		containerWithMetrics("b", 1, 50),
+	)
+	newer, pod := podWithContainers(
+		containerWithMetrics("a", 30, 200),
+		containerWithMetrics("b", 3, 50),
+	)
+	ns := report.Nodes{pod.ID: pod}
+
+	have := detailed.MakeNodeOverRange("pods", []report.Report{older, newer}, ns, pod)
+	if len(have.Children) != 1 {
+		t.Fatalf("Expected one group of children, got %d", len(have.Children))
+	}
+	group := have.Children[0]
+
+	columns := []string{}
+	for _, column := range group.Columns {
+		columns = append(columns, column.ID)
+	}
+	for _, id := range []string{docker.CPUTotalUsage, docker.MemoryUsage} {
+		for _, suffix := range []string{detailed.RangeMinSuffix, detailed.RangeMaxSuffix, detailed.RangeAvgSuffix} {
+			if !contains(columns, id+suffix) {
+				t.Errorf("Expected column %s, got %v", id+suffix, columns)
+			}
+		}
+		if contains(columns, id) {
+			t.Errorf("Expected point column %s to be replaced, got %v", id, columns)
+		}
+	}
+
+	for _, c := range []struct {
+		id   string
+		want []float64
+	}{
+		{docker.CPUTotalUsage + detailed.RangeMinSuffix, []float64{10, 1}},
+		{docker.CPUTotalUsage + detailed.RangeMaxSuffix, []float64{30, 3}},
+		{docker.CPUTotalUsage + detailed.RangeAvgSuffix, []float64{20, 2}},
+		{docker.MemoryUsage + detailed.RangeMinSuffix, []float64{100, 50}},
+		{docker.MemoryUsage + detailed.RangeMaxSuffix, []float64{200, 50}},
+		{docker.MemoryUsage + detailed.RangeAvgSuffix, []float64{150, 50}},
+	} {
+		if have := metricValues(group, c.id); !reflect.DeepEqual(c.want, have) {
+			t.Errorf("%s: want %v, have %v", c.id, c.want, have)
+		}
+	}
+}
+
+func TestMakeNodeOverRangeMissingChild(t *testing.T) {
+	// A child which only appears in the latest report has a range of one
+	// sample.
+	older, _ := podWithContainers(containerWithMetrics("a", 10, 100))
+	newer, pod := podWithContainers(
+		containerWithMetrics("a", 30, 200),
+		containerWithMetrics("b", 3, 50),
+	)
+	ns := report.Nodes{pod.ID: pod}
+
+	have := detailed.MakeNodeOverRange("pods", []report.Report{older, newer}, ns, pod)
+	group := have.Children[0]
+	want := []float64{10, 3}
+	if have := metricValues(group, docker.CPUTotalUsage+detailed.RangeMinSuffix); !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
+
+func contains(ss []string, s string) bool {
+	for _, x := range ss {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,58 @@
+package detailed
+
+import (
+	"sort"
+	"strconv"
+)
+
+// TopTalker is a peer of a node, with the number of connections between
+// them, inbound and outbound, across all ports.
+type TopTalker struct {
+	NodeID string `json:"nodeId,omitempty"`
+	Label  string `json:"label"`
+	Count  int    `json:"count"`
+}
+
+// TopTalkers derives from the connection summaries of the node its limit
+// peers with the most connections, busiest first. A limit of zero or less
+// returns all peers. Internet addresses are told apart by their label, as
+// they share the node of the internet.
+func (n Node) TopTalkers(limit int) []TopTalker {
+	type key struct{ nodeID, label string }
+	counts := map[key]int{}
+	for _, summary := range n.Connections {
+		for _, row := range summary.Connections {
+			count := 0
+			for _, m := range row.Metadata {
+				if m.ID == countKey {
+					count, _ = strconv.Atoi(m.Value)
+				}
+			}
+			counts[key{row.NodeID, row.Label}] += count
+		}
+	}
+
+	result := make([]TopTalker, 0, len(counts))
+	for k, count := range counts {
+		result = append(result, TopTalker{NodeID: k.nodeID, Label: k.label, Count: count})
+	}
+	sort.Sort(topTalkersByCount(result))
+	if limit > 0 && len(result) > limit {
+		result = result[:limit]
+	}
+	return result
+}
+
+type topTalkersByCount []TopTalker
+
+func (s topTalkersByCount) Len() int      { return len(s) }
+func (s topTalkersByCount) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s topTalkersByCount) Less(i, j int) bool {
+	if s[i].Count != s[j].Count {
+		return s[i].Count > s[j].Count
+	}
+	if s[i].Label != s[j].Label {
+		return s[i].Label < s[j].Label
+	}
+	return s[i].NodeID < s[j].NodeID
+}
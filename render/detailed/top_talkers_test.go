@@ -0,0 +1,55 @@
+package detailed_test
+
+import (
+	"testing"
+
+	"github.com/weaveworks/common/test"
+	"github.com/weaveworks/scope/render/detailed"
+	"github.com/weaveworks/scope/report"
+	"github.com/weaveworks/scope/test/reflect"
+)
+
+func TestNodeTopTalkers(t *testing.T) {
+	row := func(nodeID, label, port, count string) detailed.Connection {
+		return detailed.Connection{
+			ID:     nodeID + label + port,
+			NodeID: nodeID,
+			Label:  label,
+			Metadata: []report.MetadataRow{
+				{ID: "port", Value: port},
+				{ID: "count", Value: count},
+			},
+		}
+	}
+	node := detailed.Node{Connections: []detailed.ConnectionsSummary{
+		{ID: "incoming-connections", Connections: []detailed.Connection{
+			row("db", "db", "5432", "3"),
+			row("web", "web", "80", "4"),
+			row("in-theinternet", "1.2.3.4", "80", "1"),
+			row("in-theinternet", "5.6.7.8", "80", "2"),
+		}},
+		{ID: "outgoing-connections", Connections: []detailed.Connection{
+			row("db", "db", "5433", "5"),
+			row("cache", "cache", "6379", "2"),
+		}},
+	}}
+
+	// Counts are summed across directions and ports, and addresses on the
+	// internet are peers of their own.
+	want := []detailed.TopTalker{
+		{NodeID: "db", Label: "db", Count: 8},
+		{NodeID: "web", Label: "web", Count: 4},
+		{NodeID: "in-theinternet", Label: "5.6.7.8", Count: 2},
+		{NodeID: "cache", Label: "cache", Count: 2},
+		{NodeID: "in-theinternet", Label: "1.2.3.4", Count: 1},
+	}
+	if have := node.TopTalkers(0); !reflect.DeepEqual(want, have) {
+		t.Error(test.Diff(want, have))
+	}
+	if have := node.TopTalkers(2); !reflect.DeepEqual(want[:2], have) {
+		t.Error(test.Diff(want[:2], have))
+	}
+	if have := (detailed.Node{}).TopTalkers(3); len(have) != 0 {
+		t.Errorf("Expected no top talkers without connections, got %v", have)
+	}
+}
@@ -1,6 +1,8 @@
 package report
 
 import (
+	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/ugorji/go/codec"
@@ -16,6 +18,96 @@ type Control struct {
 	Human string `json:"human"`
 	Icon  string `json:"icon"` // from https://fortawesome.github.io/Font-Awesome/cheatsheet/ please
 	Rank  int    `json:"rank"`
+	// If set, the UI asks the user to confirm with this text before
+	// executing the control.
+	ConfirmationText string `json:"confirmationText,omitempty"`
+	// How long the control is expected to take at most, after which the
+	// app gives up on it. Zero means the server default.
+	Timeout time.Duration `json:"timeout,omitempty"`
+	// Whether the control only reads the state of the node, e.g. to
+	// describe it, so its results can be cached by the probe.
+	ReadOnly bool `json:"readOnly,omitempty"`
+	// The parameters the UI asks the user for, and sends along as the
+	// control arguments.
+	Params []ControlParam `json:"params,omitempty"`
+	// The capability users must be granted to be offered the control. No
+	// capability means the control is offered to everyone.
+	Capability string `json:"capability,omitempty"`
+	// The roles users must have one of to be offered the control. No roles
+	// means the control is offered to users of any role.
+	AllowedRoles []string `json:"allowedRoles,omitempty"`
+	// How the UI should render the results of the control. No format means
+	// plain text.
+	ResultFormat string `json:"resultFormat,omitempty"`
+}
+
+// Formats of control results.
+const (
+	TextResultFormat     = "text"
+	ANSIResultFormat     = "ansi"
+	MarkdownResultFormat = "markdown"
+)
+
+// Types of control parameters.
+const (
+	IntegerControlParam = "integer"
+	StringControlParam  = "string"
+	BooleanControlParam = "boolean"
+)
+
+// ControlParam describes a parameter of a control.
+type ControlParam struct {
+	Name     string `json:"name"`
+	Label    string `json:"label"`
+	Type     string `json:"type"`
+	Required bool   `json:"required,omitempty"`
+	// The value used when the parameter is not submitted.
+	Default string `json:"default,omitempty"`
+}
+
+// validate checks value is of the type of the parameter.
+func (p ControlParam) validate(value string) error {
+	var err error
+	switch p.Type {
+	case IntegerControlParam:
+		_, err = strconv.Atoi(value)
+	case BooleanControlParam:
+		_, err = strconv.ParseBool(value)
+	}
+	if err != nil {
+		return fmt.Errorf("Invalid %s parameter %q: %q", p.Type, p.Name, value)
+	}
+	return nil
+}
+
+// ValidateArgs checks the arguments of a request for the control against
+// its parameters, and returns them with the defaults of the missing ones
+// filled in. Arguments without a parameter are passed through.
+func (c Control) ValidateArgs(args map[string]string) (map[string]string, error) {
+	if len(c.Params) == 0 {
+		return args, nil
+	}
+	result := make(map[string]string, len(args)+len(c.Params))
+	for k, v := range args {
+		result[k] = v
+	}
+	for _, p := range c.Params {
+		value, ok := result[p.Name]
+		if !ok || value == "" {
+			if p.Default == "" {
+				if p.Required {
+					return nil, fmt.Errorf("Missing parameter %q", p.Name)
+				}
+				continue
+			}
+			value = p.Default
+			result[p.Name] = value
+		}
+		if err := p.validate(value); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
 }
 
 // Merge merges other with cs, returning a fresh Controls.
@@ -28,14 +28,43 @@ func (s *dummySelfer) CodecEncodeSelf(encoder *codec.Encoder) {
 
 // WriteBinary writes a Report as a gzipped msgpack.
 func (rep Report) WriteBinary(w io.Writer, compressionLevel int) error {
+	return WriteGzipped(w, compressionLevel, func(w io.Writer) error {
+		return codec.NewEncoder(w, &codec.MsgpackHandle{}).Encode(&rep)
+	})
+}
+
+// WriteGzipped gzips what write writes onto w, at the compression level,
+// logging the sizes before and after compression at debug level.
+func WriteGzipped(w io.Writer, compressionLevel int, write func(io.Writer) error) error {
+	var compressedSize, uncompressedSize uint64
+
+	// As in ReadBinary, this instrumentation helps tune the compression.
+	debug := log.GetLevel() == log.DebugLevel
+	if debug {
+		w = byteCountingWriter{next: w, count: &compressedSize}
+	}
 	gzwriter, err := gzip.NewWriterLevel(w, compressionLevel)
 	if err != nil {
 		return err
 	}
-	if err = codec.NewEncoder(gzwriter, &codec.MsgpackHandle{}).Encode(&rep); err != nil {
+	var uncompressed io.Writer = gzwriter
+	if debug {
+		uncompressed = byteCountingWriter{next: gzwriter, count: &uncompressedSize}
+	}
+	if err := write(uncompressed); err != nil {
+		return err
+	}
+	if err := gzwriter.Close(); err != nil { // otherwise the content won't get flushed to the output stream
 		return err
 	}
-	gzwriter.Close() // otherwise the content won't get flushed to the output stream
+	if debug && compressedSize > 0 {
+		log.Debugf(
+			"Wrote report sizes: uncompressed %d bytes, compressed %d bytes (ratio %.2f)",
+			uncompressedSize,
+			compressedSize,
+			float64(uncompressedSize)/float64(compressedSize),
+		)
+	}
 	return nil
 }
 
@@ -50,6 +79,17 @@ func (c byteCounter) Read(p []byte) (n int, err error) {
 	return n, err
 }
 
+type byteCountingWriter struct {
+	next  io.Writer
+	count *uint64
+}
+
+func (c byteCountingWriter) Write(p []byte) (n int, err error) {
+	n, err = c.next.Write(p)
+	*c.count += uint64(n)
+	return n, err
+}
+
 // ReadBinary reads bytes into a Report.
 //
 // Will decompress the binary if gzipped is true, and will use the given
@@ -5,6 +5,7 @@ import (
 	"compress/gzip"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/weaveworks/scope/report"
 )
@@ -55,3 +56,45 @@ func TestMoreCompressionMeansSmaller(t *testing.T) {
 		t.Errorf("Compression doesn't change size: %v >= %v", buf1.Len(), buf2.Len())
 	}
 }
+
+func TestRoundtripControlTimeout(t *testing.T) {
+	var buf bytes.Buffer
+	r1 := report.MakeReport()
+	r1.Container.Controls.AddControl(report.Control{
+		ID:      "backup",
+		Human:   "Backup",
+		Timeout: 10 * time.Minute,
+	})
+	r1.WriteBinary(&buf, gzip.DefaultCompression)
+	r2, err := report.MakeFromBinary(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have := r2.Container.Controls["backup"].Timeout; have != 10*time.Minute {
+		t.Errorf("Expected timeout to survive encoding, got %v", have)
+	}
+}
+
+func TestRoundtripControlResultFormat(t *testing.T) {
+	var buf bytes.Buffer
+	r1 := report.MakeReport()
+	formats := map[string]string{
+		"status": report.TextResultFormat,
+		"top":    report.ANSIResultFormat,
+		"readme": report.MarkdownResultFormat,
+		"logs":   "",
+	}
+	for id, format := range formats {
+		r1.Container.Controls.AddControl(report.Control{ID: id, Human: id, ResultFormat: format})
+	}
+	r1.WriteBinary(&buf, gzip.DefaultCompression)
+	r2, err := report.MakeFromBinary(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for id, format := range formats {
+		if have := r2.Container.Controls[id].ResultFormat; have != format {
+			t.Errorf("Expected result format %q of %s to survive encoding, got %q", format, id, have)
+		}
+	}
+}
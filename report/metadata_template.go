@@ -88,6 +88,9 @@ type MetadataRow struct {
 	Priority float64 `json:"priority,omitempty"`
 	Datatype string  `json:"dataType,omitempty"`
 	Truncate int     `json:"truncate,omitempty"`
+	// Tooltip, if set, is shown when hovering the value, e.g. with the
+	// absolute time of a relative timestamp.
+	Tooltip string `json:"tooltip,omitempty"`
 }
 
 // Copy returns a value copy of a metadata row.
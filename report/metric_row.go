@@ -23,6 +23,9 @@ type MetricRow struct {
 	Value    float64
 	Priority float64
 	Metric   *Metric
+	// FormattedValue, if set, is the value as written in the locale the
+	// node was rendered for.
+	FormattedValue string
 }
 
 // Summary returns a copy of the MetricRow, without the samples, just the value if there is one.
@@ -60,6 +63,8 @@ type wiredMetricRow struct {
 	Max      float64  `json:"max"`
 	First    string   `json:"first,omitempty"`
 	Last     string   `json:"last,omitempty"`
+
+	FormattedValue string `json:"formattedValue,omitempty"`
 }
 
 // CodecEncodeSelf marshals this MetricRow. It takes the basic Metric
@@ -78,6 +83,8 @@ func (m *MetricRow) CodecEncodeSelf(encoder *codec.Encoder) {
 		Max:      in.Max,
 		First:    in.First,
 		Last:     in.Last,
+
+		FormattedValue: m.FormattedValue,
 	})
 }
 
@@ -101,6 +108,8 @@ func (m *MetricRow) CodecDecodeSelf(decoder *codec.Decoder) {
 		Value:    in.Value,
 		Priority: in.Priority,
 		Metric:   &metric,
+
+		FormattedValue: in.FormattedValue,
 	}
 }
 
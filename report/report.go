@@ -389,4 +389,16 @@ const (
 	HostNodeID = "host_node_id"
 	// ControlProbeID is the random ID of the probe which controls the specific node.
 	ControlProbeID = "control_probe_id"
+
+	// CPUPressure, MemoryPressure and IOPressure are the metrics of the
+	// pressure stall information (PSI) of Linux hosts and containers: the
+	// percentage of time, over the last 10 seconds, some of their tasks
+	// were stalled waiting for the resource.
+	CPUPressure    = "cpu_pressure"
+	MemoryPressure = "memory_pressure"
+	IOPressure     = "io_pressure"
+
+	// ContainerRuntime is the container runtime of a container, e.g.
+	// docker, containerd or cri-o, or that a host runs its containers with.
+	ContainerRuntime = "container_runtime"
 )